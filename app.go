@@ -4,6 +4,7 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -22,13 +23,13 @@ import (
 	"github.com/odvcencio/fluffyui/runtime"
 	"github.com/odvcencio/fluffyui/state"
 	"github.com/odvcencio/fluffyui/style"
-	"github.com/odvcencio/fluffyui/terminal"
 	"github.com/odvcencio/fluffyui/widgets"
 
 	"github.com/odvcencio/gotreesitter"
 	"github.com/odvcencio/gotreesitter/grammars"
-	"github.com/odvcencio/mane/commands"
 	"github.com/odvcencio/mane/editor"
+	"github.com/odvcencio/mane/filewatch"
+	"github.com/odvcencio/mane/keymap"
 	"github.com/odvcencio/mane/lsp"
 )
 
@@ -61,7 +62,7 @@ type highlightState struct {
 }
 
 func newHighlightState() *highlightState {
-	return &highlightState{debounceMs: 50}
+	return &highlightState{debounceMs: editor.DefaultSettings().HighlightDebounceMs}
 }
 
 // setup initializes the highlighter for a given file extension.
@@ -319,10 +320,11 @@ type maneApp struct {
 	breadcrumbs *widgets.Breadcrumb
 	status      *state.Signal[string]
 	palette     *widgets.CommandPalette
-	search      *widgets.SearchWidget
+	search      *findWidget
 	replaceW    *replaceWidget
 	gotoLineW   *gotoLineWidget
 	fileFinder  *fileFinderWidget
+	undoHistory *undoHistoryWidget
 	cancel      context.CancelFunc // for quit command
 	highlight   *highlightState
 	theme       *style.Stylesheet
@@ -333,13 +335,17 @@ type maneApp struct {
 	slot           *contentSlot
 
 	// Search state
+	findOptions       editor.FindOptions // shared by the search and replace widgets
 	searchMatches     []editor.Range
 	searchCurrent     int
 	syntaxHighlights  []widgets.TextAreaHighlight // cached syntax highlights
 	bracketHighlights []widgets.TextAreaHighlight // bracket match highlights
 	multiCursor       *editor.MultiCursor
+	cursorClipboard   []string                    // one entry per cursor, from the last multi-cursor copy
 	multiHighlights   []widgets.TextAreaHighlight // cached multi-cursor highlights
 	blockHighlights   []widgets.TextAreaHighlight // cached block selection highlights
+	// longLines marks text past the .editorconfig max_line_length.
+	longLines []widgets.TextAreaHighlight
 
 	// File finder cache.
 	finderRoot string
@@ -360,6 +366,11 @@ type maneApp struct {
 	blockAnchorCol int
 	blockFocusRow  int
 	blockFocusCol  int
+	blockDragging  bool     // an Alt+drag is extending the block selection
+	blockClipboard []string // lines of the last block copied
+
+	// filterCancel stops the running filter command; nil if none runs.
+	filterCancel context.CancelFunc
 
 	// LSP integration.
 	lspClients     map[string]*lsp.Client
@@ -376,6 +387,44 @@ type maneApp struct {
 
 	notifierMu sync.RWMutex
 	notifier   ResourceNotifier
+
+	// External change detection.
+	watcher *filewatch.Watcher
+	watched map[string]bool
+
+	// Large-file mode.
+	pane      *editorPane    // shows textArea or largeView
+	largeView *largeFileView // view for buffers in large-file mode
+	rt        *runtime.App   // set once the event loop runs
+
+	// Crash recovery.
+	backups  *editor.BackupStore // nil if there is no state directory
+	quitting bool                // a quit command already handled the backups
+
+	// Per-buffer view state.
+	shown       *editor.Buffer // buffer the editor widgets show
+	viewPending bool           // shown changed and its view is not restored yet
+
+	// Session persistence.
+	sessions    *editor.SessionStore // nil if the session is not saved
+	sessionName string               // named session, or "" for the project's
+
+	// User settings and keybindings.
+	settings      *editor.SettingsLayers // nil means the defaults
+	configWatcher *filewatch.Watcher     // watches the settings and keybindings files
+
+	// Commands and the keys bound to them.
+	commandList []widgets.PaletteCommand
+	keyCommands map[string]func() runtime.HandleResult // by command ID
+	keymap      *keymap.Keymap
+	// vim is the modal editing state, nil unless the vim keymap is on.
+	vim *editor.Vim
+	// emacs is the state of the mark, kill ring and search commands.
+	emacs emacsState
+	// macro is the keyboard macro state and the stored macros.
+	macro macroState
+	// prompt is the question shown in the status bar, if any.
+	prompt *statusPrompt
 }
 
 // newManeApp creates a maneApp with the given root directory for the file tree.
@@ -398,6 +447,8 @@ func newManeApp(treeRoot string) *maneApp {
 		wordWrap:       false,
 		foldState:      editor.NewFoldState(),
 		blockSelection: editor.NewBlockSelection(),
+		watched:        make(map[string]bool),
+		macro:          macroState{store: &editor.MacroStore{Registers: make(map[string]editor.Macro)}},
 	}
 
 	app.tabBar = newTabBar()
@@ -420,6 +471,12 @@ func newManeApp(treeRoot string) *maneApp {
 	app.textArea.SetTabMode(true) // literal tabs for code editing
 	app.textArea.SetWordWrap(app.wordWrap)
 
+	app.largeView = newLargeFileView()
+	app.largeView.onChange = app.onLargeFileChange
+	app.largeView.onMove = app.updateStatus
+	app.largeView.onReload = app.onLargeFileReload
+	app.pane = newEditorPane(app.textArea, app.largeView)
+
 	app.fileTree = widgets.NewDirectoryTree(treeRoot,
 		widgets.WithLazyLoad(true),
 		widgets.WithOnSelect(func(path string) {
@@ -435,9 +492,6 @@ func newManeApp(treeRoot string) *maneApp {
 		if buf == nil {
 			return
 		}
-		buf.SetText(text)
-		app.updateStatus()
-
 		// Auto-indent: detect if newline was just inserted
 		offset := app.textArea.CursorOffset()
 		runes := []rune(text)
@@ -452,7 +506,7 @@ func newManeApp(treeRoot string) *maneApp {
 				lineStart++
 			}
 			lineAbove := text[lineStart:byteOffset]
-			indent := editor.ComputeIndent(lineAbove)
+			indent := editor.ComputeIndentWith(lineAbove, app.indentUnit(buf))
 			if indent != "" {
 				// Insert indent after the newline
 				newRunes := make([]rune, 0, len(runes)+len([]rune(indent)))
@@ -460,7 +514,6 @@ func newManeApp(treeRoot string) *maneApp {
 				newRunes = append(newRunes, []rune(indent)...)
 				newRunes = append(newRunes, runes[offset:]...)
 				newText := string(newRunes)
-				buf.SetText(newText)
 				app.suppressChange = true
 				app.textArea.SetText(newText)
 				app.textArea.SetCursorOffset(offset + len([]rune(indent)))
@@ -469,6 +522,11 @@ func newManeApp(treeRoot string) *maneApp {
 			}
 		}
 
+		// Record the keystroke, including any auto-indent, as one edit.
+		buf.ApplyTyping("Typing", text)
+		app.textArea.ClearHistory()
+		app.updateStatus()
+
 		// Debounced re-highlight on text change.
 		path := buf.Path()
 		app.syncMultiCursorFromTextArea()
@@ -484,6 +542,7 @@ func newManeApp(treeRoot string) *maneApp {
 		app.mergeAllHighlights()
 	})
 
+	app.initCommands()
 	return app
 }
 
@@ -734,6 +793,7 @@ func (a *maneApp) setCursorFromLSPPosition(uri string, pos lsp.Position) error {
 			text := a.tabs.ActiveBuffer().Text()
 			a.applyHighlights(text, a.highlight.highlight([]byte(text)))
 			a.updateFoldRegions(text)
+			a.enterView()
 		} else {
 			return err
 		}
@@ -770,6 +830,10 @@ func (a *maneApp) lspDiagnosticsForActive() []lsp.Diagnostic {
 // applyHighlights converts gotreesitter.HighlightRange values to TextAreaHighlight
 // and sets them on the TextArea.
 func (a *maneApp) applyHighlights(text string, ranges []gotreesitter.HighlightRange) {
+	a.longLines = nil
+	if buf := a.tabs.ActiveBuffer(); buf != nil {
+		a.longLines = longLineHighlights(text, buf.EditorConfig().MaxLineLength)
+	}
 	if len(ranges) == 0 || a.theme == nil {
 		a.syntaxHighlights = nil
 		a.mergeAllHighlights()
@@ -816,6 +880,17 @@ func (a *maneApp) openFile(path string) error {
 		return err
 	}
 
+	buf := a.tabs.ActiveBuffer()
+	if buf != nil && buf.Large() {
+		// No highlighting, folding or LSP: they all need the whole text.
+		a.syncTextArea()
+		a.enterView()
+		a.syncTabBar()
+		a.updateStatus()
+		a.status.Set(fmt.Sprintf(" %s opened read-only in large-file mode; use Toggle Read-Only to edit", buf.Title()))
+		return nil
+	}
+
 	// Set up syntax highlighting for the file's language.
 	a.highlight.setup(filepath.Base(path))
 
@@ -824,7 +899,6 @@ func (a *maneApp) openFile(path string) error {
 	a.syncBreadcrumbs()
 	a.updateStatus()
 
-	buf := a.tabs.ActiveBuffer()
 	if buf != nil {
 		text := buf.Text()
 		ranges := a.highlight.highlight([]byte(text))
@@ -832,7 +906,11 @@ func (a *maneApp) openFile(path string) error {
 		a.openLSPDocument(buf)
 		a.applyDiagnosticsForActiveBuffer()
 		a.updateFoldRegions(text)
+		a.enterView()
 		a.updateStatus()
+		if buf.MixedLineEndings() {
+			a.status.Set(fmt.Sprintf(" warning: %s has mixed line endings; saving converts them to %s", buf.Title(), buf.LineEnding()))
+		}
 	}
 	return nil
 }
@@ -840,6 +918,7 @@ func (a *maneApp) openFile(path string) error {
 // syncTextArea loads the active buffer's text into the TextArea widget.
 func (a *maneApp) syncTextArea() {
 	buf := a.tabs.ActiveBuffer()
+	a.leaveView(buf)
 	if buf == nil {
 		a.textArea.SetText("")
 		a.bracketHighlights = nil
@@ -858,6 +937,10 @@ func (a *maneApp) syncTextArea() {
 		a.applyDiagnosticsForActiveBuffer()
 		return
 	}
+	if buf.Large() {
+		a.showLargeFile(buf)
+		return
+	}
 	a.textArea.SetText(buf.Text())
 	a.syncMultiCursorFromTextArea()
 	a.clearBlockSelection()
@@ -913,12 +996,23 @@ func (a *maneApp) syncTextAreaFromMultiCursor() {
 }
 
 func (a *maneApp) applyMultiCursorText(newText string) {
+	a.applyMultiCursorEdit("Multi-Cursor Edit", newText, true)
+}
+
+// applyMultiCursorEdit replaces the text edited at multiple cursors as an
+// undo step labelled label. With typing set, it joins the step before as
+// typed text does.
+func (a *maneApp) applyMultiCursorEdit(label, newText string, typing bool) {
 	buf := a.tabs.ActiveBuffer()
 	if buf == nil {
 		return
 	}
 
-	buf.SetText(newText)
+	if typing {
+		buf.ApplyTyping(label, newText)
+	} else {
+		buf.ApplyText(label, newText)
+	}
 	a.suppressChange = true
 	a.textArea.SetText(newText)
 	a.suppressChange = false
@@ -943,20 +1037,82 @@ func (a *maneApp) applyMultiCursorInsert(text string) {
 }
 
 func (a *maneApp) applyPaste(text string) {
+	// Buffers hold LF line endings; the file's style is restored on save.
+	text = editor.NormalizeLineEndings(text)
 	if text == "" {
 		return
 	}
+	if a.activeLargeBuffer() != nil {
+		a.largeView.insert(text)
+		return
+	}
+	if !a.isMultiCursorMode() && len(a.blockClipboard) > 0 && text == strings.Join(a.blockClipboard, "\n") {
+		a.pasteBlock(a.blockClipboard)
+		return
+	}
 	if a.isBlockSelectionMode() {
 		a.applyBlockInsert(text)
 		return
 	}
 	if a.isMultiCursorMode() {
-		a.applyMultiCursorInsert(text)
+		a.applyMultiCursorPaste(text)
 		return
 	}
 	a.textArea.ClipboardPaste(text)
 }
 
+// applyMultiCursorPaste pastes text at every cursor. Text copied from as
+// many cursors, or with a line for each cursor, is split so each cursor
+// gets its own part.
+func (a *maneApp) applyMultiCursorPaste(text string) {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil {
+		return
+	}
+	n := a.multiCursor.Count()
+	parts := editor.SplitPaste(text, n)
+	if len(a.cursorClipboard) == n && strings.Join(a.cursorClipboard, "\n") == text {
+		parts = a.cursorClipboard
+	}
+	if parts == nil {
+		a.applyMultiCursorInsert(text)
+		return
+	}
+	a.applyMultiCursorText(a.multiCursor.InsertEach(buf.Text(), parts))
+}
+
+// copyCursorSelections copies the selection of each cursor, one line each,
+// and keeps them apart so pasting at as many cursors puts one back at each.
+// With cut, the selections are deleted.
+func (a *maneApp) copyCursorSelections(cut bool) {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil {
+		return
+	}
+	parts := a.multiCursor.SelectedTexts(buf.Text())
+	if strings.Join(parts, "") == "" {
+		a.status.Set(" Nothing selected")
+		return
+	}
+	a.cursorClipboard = parts
+	a.setClipboardText(strings.Join(parts, "\n"))
+	verb := "Copied"
+	if cut {
+		a.applyMultiCursorText(a.multiCursor.InsertAtAll(buf.Text(), ""))
+		verb = "Cut"
+	}
+	a.status.Set(fmt.Sprintf(" %s %d selections", verb, len(parts)))
+}
+
+// cursorClipboardText returns the text to paste at multiple cursors: the
+// system clipboard's, or the last multi-cursor copy without one.
+func (a *maneApp) cursorClipboardText() string {
+	if text := a.clipboardText(); text != "" {
+		return text
+	}
+	return strings.Join(a.cursorClipboard, "\n")
+}
+
 func (a *maneApp) clipboardText() string {
 	cb := clipboard.NewAutoClipboard(os.Stdout)
 	if cb == nil || !cb.Available() {
@@ -969,6 +1125,15 @@ func (a *maneApp) clipboardText() string {
 	return text
 }
 
+// setClipboardText puts text on the system clipboard, if there is one.
+func (a *maneApp) setClipboardText(text string) {
+	cb := clipboard.NewAutoClipboard(os.Stdout)
+	if cb == nil || !cb.Available() {
+		return
+	}
+	_ = cb.Write(text)
+}
+
 func (a *maneApp) applyMultiCursorDeleteBackspace() {
 	buf := a.tabs.ActiveBuffer()
 	if buf == nil {
@@ -988,18 +1153,65 @@ func (a *maneApp) applyMultiCursorDeleteForward() {
 }
 
 func (a *maneApp) addNextCursorOccurrence() {
-	if a.multiCursor == nil {
-		a.multiCursor = editor.NewMultiCursor()
-	}
+	a.changeCursors("no further occurrences", func(text string) bool {
+		return a.multiCursor.AddNextOccurrence(text, a.findOptions)
+	})
+}
+
+// skipCursorOccurrence moves the last selection to the next occurrence.
+func (a *maneApp) skipCursorOccurrence() {
+	a.changeCursors("no further occurrences", func(text string) bool {
+		return a.multiCursor.SkipOccurrence(text, a.findOptions)
+	})
+}
+
+// selectAllCursorOccurrences puts a cursor on every occurrence of the
+// selection.
+func (a *maneApp) selectAllCursorOccurrences() {
+	a.changeCursors("no further occurrences", func(text string) bool {
+		return a.multiCursor.SelectAllOccurrences(text, a.findOptions)
+	})
+}
+
+// addCursorVertically adds a cursor on the line above (dir < 0) or below
+// the cursors.
+func (a *maneApp) addCursorVertically(dir int) {
+	a.changeCursors("no line to add a cursor on", func(text string) bool {
+		if dir < 0 {
+			return a.multiCursor.AddCursorAbove(text)
+		}
+		return a.multiCursor.AddCursorBelow(text)
+	})
+}
+
+// moveCursors applies motion at every cursor, extending the selections
+// with extend.
+func (a *maneApp) moveCursors(motion editor.Motion, extend bool) {
+	a.multiCursor.Move(a.textArea.Text(), motion, extend)
+	a.syncTextAreaFromMultiCursor()
+	a.updateStatus()
+	a.mergeAllHighlights()
+}
+
+// undoCursorAdd takes back the last cursor added.
+func (a *maneApp) undoCursorAdd() {
+	a.changeCursors("no cursor to undo", func(string) bool {
+		return a.multiCursor.UndoCursorAdd()
+	})
+}
+
+// changeCursors applies change to the cursors, starting from the text
+// area's cursor outside multi-cursor mode, and shows the result. Occurrences
+// are matched with the match case and whole word options of the find
+// widget. If change reports that nothing changed, fail is shown instead.
+func (a *maneApp) changeCursors(fail string, change func(text string) bool) {
 	if !a.isMultiCursorMode() {
 		a.syncMultiCursorFromTextArea()
 	}
-
-	if !a.multiCursor.AddNextOccurrence(a.textArea.Text()) {
-		a.status.Set(" no further occurrences")
+	if !change(a.textArea.Text()) {
+		a.status.Set(" " + fail)
 		return
 	}
-
 	a.syncTextAreaFromMultiCursor()
 	a.updateStatus()
 	a.mergeAllHighlights()
@@ -1072,11 +1284,12 @@ func clampInt(v, min, max int) int {
 	return v
 }
 
-func (a *maneApp) maxLineColumn(text string) int {
+// maxLineColumn returns the width in columns of the widest line of text.
+func (a *maneApp) maxLineColumn(text string, tabSize int) int {
 	lines := strings.Split(text, "\n")
 	maxCol := 0
 	for _, line := range lines {
-		if col := utf8.RuneCountInString(line); col > maxCol {
+		if col := editor.VisualWidth(line, tabSize); col > maxCol {
 			maxCol = col
 		}
 	}
@@ -1094,13 +1307,14 @@ func (a *maneApp) expandBlockSelection(deltaRow, deltaCol int) {
 	if len(lines) == 0 {
 		return
 	}
+	tabSize := a.tabSize(buf)
 	maxRow := len(lines) - 1
-	maxCol := a.maxLineColumn(text) + 1
+	maxCol := a.maxLineColumn(text, tabSize) + 1
 
 	if !a.blockSelection.Active {
 		col, row := a.textArea.CursorPosition()
 		a.blockAnchorRow = clampInt(row, 0, maxRow)
-		a.blockAnchorCol = clampInt(col, 0, maxCol)
+		a.blockAnchorCol = clampInt(editor.VisualColumn(lines[a.blockAnchorRow], col, tabSize), 0, maxCol)
 		a.blockFocusRow = a.blockAnchorRow
 		a.blockFocusCol = a.blockAnchorCol
 		// Block selection and multi-cursor are mutually exclusive.
@@ -1109,6 +1323,13 @@ func (a *maneApp) expandBlockSelection(deltaRow, deltaCol int) {
 
 	a.blockFocusRow = clampInt(a.blockFocusRow+deltaRow, 0, maxRow)
 	a.blockFocusCol = clampInt(a.blockFocusCol+deltaCol, 0, maxCol)
+	a.showBlockSelection(tabSize)
+}
+
+// showBlockSelection sets the block selection from its anchor to its focus
+// and highlights it.
+func (a *maneApp) showBlockSelection(tabSize int) {
+	a.blockSelection.TabSize = tabSize
 	a.blockSelection.Set(
 		a.blockAnchorRow,
 		a.blockFocusRow,
@@ -1120,6 +1341,57 @@ func (a *maneApp) expandBlockSelection(deltaRow, deltaCol int) {
 	a.updateStatus()
 }
 
+// handleBlockDrag makes a block selection with the mouse: an Alt+press in
+// the text starts one and dragging stretches it to the pointer. It reports
+// whether mouse was part of such a drag.
+func (a *maneApp) handleBlockDrag(mouse runtime.MouseMsg) bool {
+	if mouse.Action == runtime.MouseRelease {
+		dragging := a.blockDragging
+		a.blockDragging = false
+		return dragging
+	}
+	if mouse.Button != runtime.MouseLeft || a.activeLargeBuffer() != nil {
+		return false
+	}
+	switch {
+	case mouse.Action == runtime.MousePress && mouse.Alt:
+		row, col, ok := a.blockPointAt(mouse)
+		if !ok {
+			return false
+		}
+		a.resetMultiCursor()
+		a.blockAnchorRow, a.blockAnchorCol = row, col
+		a.blockFocusRow, a.blockFocusCol = row, col
+		a.blockDragging = true
+	case mouse.Action == runtime.MouseMove && a.blockDragging:
+		row, col, ok := a.blockPointAt(mouse)
+		if !ok {
+			return true // dragged off the text
+		}
+		a.blockFocusRow, a.blockFocusCol = row, col
+	default:
+		a.blockDragging = false
+		return false
+	}
+	a.showBlockSelection(a.tabSize(a.tabs.ActiveBuffer()))
+	return true
+}
+
+// blockPointAt returns the line and visual column of the text under the
+// pointer, by letting the text area place its cursor there.
+func (a *maneApp) blockPointAt(mouse runtime.MouseMsg) (int, int, bool) {
+	click := runtime.MouseMsg{X: mouse.X, Y: mouse.Y, Button: runtime.MouseLeft, Action: runtime.MousePress}
+	if !a.textArea.HandleMessage(click).Handled {
+		return 0, 0, false
+	}
+	col, row := a.textArea.CursorPosition()
+	lines := strings.Split(a.textArea.Text(), "\n")
+	if row >= len(lines) {
+		return 0, 0, false
+	}
+	return row, editor.VisualColumn(lines[row], col, a.tabSize(a.tabs.ActiveBuffer())), true
+}
+
 func (a *maneApp) syncBlockHighlights() {
 	if !a.isBlockSelectionMode() {
 		a.blockHighlights = nil
@@ -1134,7 +1406,6 @@ func (a *maneApp) syncBlockHighlights() {
 	}
 
 	startLine, endLine := a.blockSelection.Lines()
-	startCol, endCol := a.blockSelection.Cols()
 	if startLine < 0 {
 		startLine = 0
 	}
@@ -1160,21 +1431,11 @@ func (a *maneApp) syncBlockHighlights() {
 	style := backend.DefaultStyle().Background(backend.ColorRGB(0x2f, 0x5f, 0x87))
 	highlights := make([]widgets.TextAreaHighlight, 0, endLine-startLine+1)
 	for line := startLine; line <= endLine; line++ {
-		runes := []rune(lines[line])
-		start := startCol
-		end := endCol
-		if start > len(runes) {
-			start = len(runes)
-		}
-		if end > len(runes) {
-			end = len(runes)
-		}
-		if start > end {
-			start = end
-		}
+		n := utf8.RuneCountInString(lines[line])
+		start, end := a.blockSelection.RuneRange(lines[line])
 		// Keep caret-visible highlight for zero-width column selections.
 		if end == start {
-			if start < len(runes) {
+			if start < n {
 				end = start + 1
 			} else {
 				continue
@@ -1191,13 +1452,23 @@ func (a *maneApp) syncBlockHighlights() {
 	a.mergeAllHighlights()
 }
 
+// blockRuneColumn returns the rune column of visual column col on the given
+// line of text.
+func (a *maneApp) blockRuneColumn(text string, line, col int) int {
+	lines := strings.Split(text, "\n")
+	if line >= len(lines) {
+		return 0
+	}
+	return editor.RuneColumn(lines[line], col, a.tabSize(a.tabs.ActiveBuffer()))
+}
+
 func (a *maneApp) applyBlockSelectionText(newText string, cursorCol, cursorRow int) {
 	buf := a.tabs.ActiveBuffer()
 	if buf == nil {
 		return
 	}
 
-	buf.SetText(newText)
+	buf.ApplyTyping("Block Edit", newText)
 	a.suppressChange = true
 	a.textArea.SetText(newText)
 	a.textArea.SetCursorPosition(cursorCol, cursorRow)
@@ -1228,7 +1499,7 @@ func (a *maneApp) applyBlockInsert(insert string) {
 	}
 	newText := bs.InsertAtBlock(text, insert)
 	insertWidth := utf8.RuneCountInString(insert)
-	a.applyBlockSelectionText(newText, bs.StartCol+insertWidth, bs.EndLine)
+	a.applyBlockSelectionText(newText, a.blockRuneColumn(newText, bs.EndLine, bs.StartCol)+insertWidth, bs.EndLine)
 }
 
 func (a *maneApp) applyBlockBackspace() {
@@ -1242,15 +1513,13 @@ func (a *maneApp) applyBlockBackspace() {
 
 	bs := *a.blockSelection
 	text := buf.Text()
-	cursorCol := bs.StartCol
+	cursorCol := a.blockRuneColumn(text, bs.EndLine, bs.StartCol)
 
 	if bs.EndCol > bs.StartCol {
 		text = bs.DeleteBlock(text)
 	} else if bs.StartCol > 0 {
-		bs.StartCol--
-		bs.EndCol = bs.StartCol + 1
-		text = bs.DeleteBlock(text)
-		cursorCol = bs.StartCol
+		text = bs.DeleteBefore(text)
+		cursorCol = max(cursorCol-1, 0)
 	} else {
 		return
 	}
@@ -1271,7 +1540,107 @@ func (a *maneApp) applyBlockDeleteForward() {
 		bs.EndCol = bs.StartCol + 1
 	}
 	newText := bs.DeleteBlock(buf.Text())
-	a.applyBlockSelectionText(newText, bs.StartCol, bs.EndLine)
+	a.applyBlockSelectionText(newText, a.blockRuneColumn(newText, bs.EndLine, bs.StartCol), bs.EndLine)
+}
+
+// copyBlock copies the lines of the block selection, one under the other,
+// and keeps them to paste back as a block. With cut, the block is deleted.
+func (a *maneApp) copyBlock(cut bool) {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil || !a.isBlockSelectionMode() {
+		return
+	}
+	bs := *a.blockSelection
+	lines := bs.ExtractBlock(buf.Text())
+	if strings.Join(lines, "") == "" {
+		a.status.Set(" Nothing selected")
+		return
+	}
+	a.blockClipboard = lines
+	a.setClipboardText(strings.Join(lines, "\n"))
+	verb := "Copied"
+	if cut {
+		newText := bs.DeleteBlock(buf.Text())
+		a.applyBlockSelectionText(newText, a.blockRuneColumn(newText, bs.StartLine, bs.StartCol), bs.StartLine)
+		verb = "Cut"
+	}
+	a.status.Set(fmt.Sprintf(" %s block of %d lines", verb, len(lines)))
+}
+
+// pasteBlock pastes lines as a block: one under the other from the cursor,
+// or in place of the block selection, padding short lines with spaces.
+func (a *maneApp) pasteBlock(lines []string) {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil || len(lines) == 0 {
+		return
+	}
+	text := buf.Text()
+	tabSize := a.tabSize(buf)
+	var row, col int
+	if a.isBlockSelectionMode() {
+		bs := *a.blockSelection
+		text = bs.DeleteBlock(text)
+		row, col = bs.StartLine, bs.StartCol
+	} else {
+		col, row = a.textArea.CursorPosition()
+		col = editor.VisualColumn(strings.Split(text, "\n")[row], col, tabSize)
+	}
+	newText := editor.PasteBlock(text, lines, row, col, tabSize)
+	last := row + len(lines) - 1
+	endCol := a.blockRuneColumn(newText, last, col) + utf8.RuneCountInString(lines[len(lines)-1])
+	a.applyBlockSelectionText(newText, endCol, last)
+}
+
+// cmdPasteBlock pastes the clipboard as a block, or the last block copied
+// without one.
+func (a *maneApp) cmdPasteBlock() {
+	lines := a.blockClipboard
+	if text := editor.NormalizeLineEndings(a.clipboardText()); text != "" {
+		lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+	if len(lines) == 0 {
+		a.status.Set(" Nothing to paste")
+		return
+	}
+	a.pasteBlock(lines)
+}
+
+// cmdPaste pastes the clipboard with Ctrl+V. A block copied from a block
+// selection is pasted back as a block; other text is left to the text area.
+func (a *maneApp) cmdPaste() runtime.HandleResult {
+	text := editor.NormalizeLineEndings(a.clipboardText())
+	if len(a.blockClipboard) == 0 || text != strings.Join(a.blockClipboard, "\n") {
+		return runtime.Unhandled()
+	}
+	a.pasteBlock(a.blockClipboard)
+	return runtime.Handled()
+}
+
+// cmdBlockFill asks for a first number and replaces the block selection
+// with numbers counting up from it, one per line.
+func (a *maneApp) cmdBlockFill() {
+	if !a.isBlockSelectionMode() {
+		a.status.Set(" No block selection")
+		return
+	}
+	a.ask(" Fill column starting at (1): ", promptNumber, func(answer string) {
+		start := 1
+		if answer != "" {
+			n, err := strconv.Atoi(answer)
+			if err != nil {
+				a.status.Set(" Invalid number")
+				return
+			}
+			start = n
+		}
+		buf := a.tabs.ActiveBuffer()
+		if buf == nil || !a.isBlockSelectionMode() {
+			return
+		}
+		bs := *a.blockSelection
+		newText := bs.FillNumbers(buf.Text(), start)
+		a.applyBlockSelectionText(newText, a.blockRuneColumn(newText, bs.EndLine, bs.StartCol), bs.EndLine)
+	})
 }
 
 // updateStatus refreshes the status bar signal with the current buffer info.
@@ -1295,9 +1664,23 @@ func (a *maneApp) updateStatus() {
 	}
 
 	// Extra editor metadata.
-	encoding := " UTF-8"
-	lineEnding := detectLineEnding(buf.Text())
-	indent := detectIndentMode(buf.Text())
+	encoding := " " + string(buf.Encoding())
+	lineEnding := string(buf.LineEnding())
+	if buf.MixedLineEndings() {
+		lineEnding += " (mixed)"
+	}
+	var indent string
+	col, row := a.textArea.CursorPosition()
+	if buf.Large() {
+		// Detecting the indent style would read the whole file.
+		indent = "large file"
+		if buf.ReadOnly() {
+			indent += " [read-only]"
+		}
+		col, row = a.largeView.cursorPosition()
+	} else {
+		indent = indentStatus(buf)
+	}
 	selectionCount := a.selectionCount()
 	branch := a.currentGitBranch(buf.Path())
 	wrap := "off"
@@ -1305,7 +1688,6 @@ func (a *maneApp) updateStatus() {
 		wrap = "on"
 	}
 
-	col, row := a.textArea.CursorPosition()
 	status := fmt.Sprintf(
 		" %s%s%s  Ln %d, Col %d  %s  %s  %s",
 		buf.Title(),
@@ -1318,9 +1700,15 @@ func (a *maneApp) updateStatus() {
 		indent,
 	)
 	status += fmt.Sprintf("  wrap:%s", wrap)
+	if a.vim != nil {
+		status = " -- " + a.vim.Status() + " --" + status
+	}
 	if branch != "" {
 		status += "  " + branch
 	}
+	if a.macro.recording {
+		status += "  recording macro"
+	}
 	if selectionCount > 0 {
 		status += fmt.Sprintf("  Sel %d", selectionCount)
 		if a.isMultiCursorMode() {
@@ -1543,9 +1931,11 @@ func (a *maneApp) syncBreadcrumbs() {
 		items = append(items, item)
 	}
 
-	col, row := a.textArea.CursorPosition()
-	for _, symbol := range a.currentSymbolPath(buf.Text(), row, col) {
-		items = append(items, widgets.BreadcrumbItem{Label: symbol})
+	if !buf.Large() {
+		col, row := a.textArea.CursorPosition()
+		for _, symbol := range a.currentSymbolPath(buf.Text(), row, col) {
+			items = append(items, widgets.BreadcrumbItem{Label: symbol})
+		}
 	}
 
 	if len(items) == 0 {
@@ -1570,7 +1960,7 @@ func (a *maneApp) toggleSidebar() {
 	if a.sidebarVisible {
 		a.slot.setChild(a.splitter)
 	} else {
-		a.slot.setChild(a.textArea)
+		a.slot.setChild(a.pane)
 	}
 }
 
@@ -1582,6 +1972,11 @@ func (a *maneApp) cmdFind() runtime.HandleResult {
 
 // onSearch handles search query changes from the SearchWidget.
 func (a *maneApp) onSearch(query string) {
+	if buf := a.activeLargeBuffer(); buf != nil {
+		a.largeView.match = editor.Range{}
+		a.largeFileSearch(buf, query, false)
+		return
+	}
 	if query == "" {
 		a.searchMatches = nil
 		a.searchCurrent = 0
@@ -1597,7 +1992,7 @@ func (a *maneApp) onSearch(query string) {
 	}
 
 	// Find matches (byte ranges)
-	a.searchMatches = buf.Find(query)
+	a.searchMatches = a.findInBuffer(buf, query)
 	a.searchCurrent = 0
 
 	if len(a.searchMatches) > 0 {
@@ -1611,6 +2006,16 @@ func (a *maneApp) onSearch(query string) {
 	a.applySearchHighlights()
 }
 
+// findInBuffer returns the matches of query in buf under the current find
+// options. An invalid regular expression is reported in the status bar.
+func (a *maneApp) findInBuffer(buf *editor.Buffer, query string) []editor.Range {
+	matches, err := buf.FindWithOptions(query, a.findOptions)
+	if err != nil {
+		a.status.Set(fmt.Sprintf(" Invalid regex: %v", err))
+	}
+	return matches
+}
+
 // fileFinderRoot returns the active tree root used for file searching.
 func (a *maneApp) fileFinderRoot() string {
 	if a.fileTree != nil {
@@ -1632,7 +2037,7 @@ func (a *maneApp) cmdOpenFileFinder() runtime.HandleResult {
 	}
 
 	if a.finderRoot != root || len(a.finderPath) == 0 {
-		items, err := collectFinderFiles(root)
+		items, err := collectFinderFiles(root, a.settings.Global().FinderExclude)
 		if err != nil {
 			a.status.Set(fmt.Sprintf(" file finder error: %v", err))
 			return runtime.Handled()
@@ -1665,6 +2070,10 @@ func (a *maneApp) cmdShowPalette() {
 
 // onSearchNext moves to the next search match.
 func (a *maneApp) onSearchNext() {
+	if buf := a.activeLargeBuffer(); buf != nil {
+		a.largeFileSearch(buf, a.search.Query(), false)
+		return
+	}
 	if len(a.searchMatches) == 0 {
 		return
 	}
@@ -1676,6 +2085,10 @@ func (a *maneApp) onSearchNext() {
 
 // onSearchPrev moves to the previous search match.
 func (a *maneApp) onSearchPrev() {
+	if buf := a.activeLargeBuffer(); buf != nil {
+		a.largeFileSearch(buf, a.search.Query(), true)
+		return
+	}
 	if len(a.searchMatches) == 0 {
 		return
 	}
@@ -1690,6 +2103,7 @@ func (a *maneApp) onSearchPrev() {
 
 // onSearchClose clears search state when the search widget is dismissed.
 func (a *maneApp) onSearchClose() {
+	a.largeView.match = editor.Range{}
 	a.searchMatches = nil
 	a.searchCurrent = 0
 	// Restore syntax-only highlights
@@ -1718,7 +2132,7 @@ func (a *maneApp) onReplaceSearch(query string) {
 		return
 	}
 
-	a.searchMatches = buf.Find(query)
+	a.searchMatches = a.findInBuffer(buf, query)
 	a.searchCurrent = 0
 
 	if len(a.searchMatches) > 0 {
@@ -1769,7 +2183,10 @@ func (a *maneApp) onReplace(search, replace string) {
 	// Replace the current match.
 	if a.searchCurrent >= 0 && a.searchCurrent < len(a.searchMatches) {
 		r := a.searchMatches[a.searchCurrent]
-		buf.Replace(search, replace, editor.Range{Start: r.Start, End: r.End})
+		if err := buf.ReplaceWithOptions(search, replace, r, a.findOptions); err != nil {
+			a.status.Set(fmt.Sprintf(" Replace error: %v", err))
+			return
+		}
 	}
 
 	// Re-sync the text area from the buffer.
@@ -1777,7 +2194,7 @@ func (a *maneApp) onReplace(search, replace string) {
 	a.updateStatus()
 
 	// Re-run search to refresh matches with updated text.
-	a.searchMatches = buf.Find(search)
+	a.searchMatches = a.findInBuffer(buf, search)
 	if len(a.searchMatches) == 0 {
 		a.searchCurrent = 0
 		a.replaceW.SetMatchInfo(0, 0)
@@ -1814,7 +2231,11 @@ func (a *maneApp) onReplaceAll(search, replace string) {
 		return
 	}
 
-	count := buf.ReplaceAll(search, replace)
+	count, err := buf.ReplaceAllWithOptions(search, replace, a.findOptions)
+	if err != nil {
+		a.status.Set(fmt.Sprintf(" Replace error: %v", err))
+		return
+	}
 
 	// Re-sync the text area from the buffer.
 	a.syncTextArea()
@@ -1853,6 +2274,9 @@ func (a *maneApp) cmdGotoLine() runtime.HandleResult {
 		return runtime.Unhandled()
 	}
 	_, row := a.textArea.CursorPosition()
+	if buf.Large() {
+		_, row = a.largeView.cursorPosition()
+	}
 	a.gotoLineW.SetQuery(strconv.Itoa(row + 1))
 	a.gotoLineW.Focus()
 	return runtime.WithCommand(runtime.PushOverlay{Widget: a.gotoLineW})
@@ -1869,6 +2293,11 @@ func (a *maneApp) onGotoLine(line int) {
 	if buf == nil {
 		return
 	}
+	if buf.Large() {
+		a.largeView.gotoLine(line - 1)
+		a.updateStatus()
+		return
+	}
 
 	maxLine := editor.LineCount(buf.Text())
 	if maxLine == 0 {
@@ -2201,7 +2630,7 @@ func (a *maneApp) applyLSPCompletion(item lsp.CompletionItem) {
 	newText := text[:offsetBytes] + insert + text[offsetBytes:]
 
 	a.suppressChange = true
-	buf.SetText(newText)
+	buf.ApplyText("Completion", newText)
 	a.textArea.SetText(newText)
 	a.suppressChange = false
 	a.textArea.SetCursorOffset(offsetRunes + cursorDelta)
@@ -2521,6 +2950,7 @@ func (a *maneApp) restoreActiveBuffer(index int, cursorOffset int) {
 	}
 	a.tabs.SetActive(index)
 	a.syncTextArea()
+	a.enterView()
 	a.syncTabBar()
 	a.syncBreadcrumbs()
 	maxOffset := utf8.RuneCountInString(a.textArea.Text())
@@ -2586,41 +3016,20 @@ func (a *maneApp) applyWorkspaceEdits(changes map[string][]lsp.TextEdit) error {
 		}
 
 		text := buf.Text()
-		type replacement struct {
-			Start int
-			End   int
-			Text  string
-		}
-		ranges := make([]replacement, 0, len(edits))
+		textEdits := make([]editor.TextEdit, 0, len(edits))
 		for _, edit := range edits {
 			start, end := lspRangeToByteOffsets(text, edit.Range)
-			ranges = append(ranges, replacement{
+			textEdits = append(textEdits, editor.TextEdit{
 				Start: start,
 				End:   end,
 				Text:  edit.NewText,
 			})
 		}
-		sort.Slice(ranges, func(i, j int) bool {
-			if ranges[i].Start == ranges[j].Start {
-				return ranges[i].End > ranges[j].End
-			}
-			return ranges[i].Start > ranges[j].Start
-		})
-
-		newText := text
-		for _, r := range ranges {
-			if r.Start < 0 || r.End < r.Start || r.Start > len(newText) {
-				continue
-			}
-			if r.End > len(newText) {
-				r.End = len(newText)
-			}
-			newText = newText[:r.Start] + r.Text + newText[r.End:]
-		}
 
 		a.suppressChange = true
-		buf.SetText(newText)
+		buf.ApplyEdits("Workspace Edit", textEdits)
 		a.suppressChange = false
+		newText := buf.Text()
 		a.scheduleLspDidChange(buf, newText)
 		if buf == originalBuffer {
 			a.textArea.SetText(newText)
@@ -2740,6 +3149,7 @@ func (a *maneApp) updateBracketMatch() {
 func (a *maneApp) mergeAllHighlights() {
 	var merged []widgets.TextAreaHighlight
 	merged = append(merged, a.syntaxHighlights...)
+	merged = append(merged, a.longLines...)
 	merged = append(merged, a.bracketHighlights...)
 	merged = append(merged, a.diagnostics...)
 	merged = append(merged, a.multiHighlights...)
@@ -3005,7 +3415,7 @@ func (a *maneApp) withRetryingLSPClient(langID string, op func(*lsp.Client) erro
 }
 
 func (a *maneApp) openLSPDocument(buf *editor.Buffer) {
-	if buf == nil || buf.Path() == "" || a.lspCtx == nil {
+	if buf == nil || buf.Path() == "" || buf.Large() || a.lspCtx == nil {
 		return
 	}
 	uri := fileURI(buf.Path())
@@ -3033,7 +3443,7 @@ func (a *maneApp) openLSPDocument(buf *editor.Buffer) {
 }
 
 func (a *maneApp) scheduleLspDidChange(buf *editor.Buffer, text string) {
-	if buf == nil || buf.Path() == "" || a.lspCtx == nil {
+	if buf == nil || buf.Path() == "" || buf.Large() || a.lspCtx == nil {
 		return
 	}
 
@@ -3059,7 +3469,7 @@ func (a *maneApp) scheduleLspDidChange(buf *editor.Buffer, text string) {
 }
 
 func (a *maneApp) notifyLSPDidSave(buf *editor.Buffer) {
-	if buf == nil || buf.Path() == "" || a.lspCtx == nil {
+	if buf == nil || buf.Path() == "" || buf.Large() || a.lspCtx == nil {
 		return
 	}
 	uri := fileURI(buf.Path())
@@ -3073,7 +3483,7 @@ func (a *maneApp) notifyLSPDidSave(buf *editor.Buffer) {
 }
 
 func (a *maneApp) notifyLSPDidClose(buf *editor.Buffer) {
-	if buf == nil || buf.Path() == "" || a.lspCtx == nil {
+	if buf == nil || buf.Path() == "" || buf.Large() || a.lspCtx == nil {
 		return
 	}
 	uri := fileURI(buf.Path())
@@ -3101,13 +3511,19 @@ func (a *maneApp) syncTabBar() {
 		}
 	}
 	a.tabBar.setTabs(tabs, a.tabs.Active())
+	// The tab bar is resynced whenever buffers are opened or closed.
+	a.syncFileWatches()
+	a.syncEditorPane()
 }
 
 // switchTab switches to the tab at the given index and reloads the TextArea.
 func (a *maneApp) switchTab(index int) {
 	a.tabs.SetActive(index)
 	buf := a.tabs.ActiveBuffer()
-	if buf != nil {
+	a.leaveView(buf)
+	if buf != nil && buf.Large() {
+		a.showLargeFile(buf)
+	} else if buf != nil {
 		text := buf.Text()
 		a.textArea.SetText(text)
 		a.syncMultiCursorFromTextArea()
@@ -3130,6 +3546,7 @@ func (a *maneApp) switchTab(index int) {
 		a.syncMultiCursorFromTextArea()
 		a.mergeAllHighlights()
 	}
+	a.enterView()
 	a.syncTabBar()
 	a.syncBreadcrumbs()
 	a.updateStatus()
@@ -3158,33 +3575,136 @@ func (a *maneApp) nextTab() {
 
 // cmdSaveFile saves the active buffer to disk.
 func (a *maneApp) cmdSaveFile() {
-	buf := a.tabs.ActiveBuffer()
+	switch err := a.saveBuffer(a.tabs.ActiveBuffer()); {
+	case errors.Is(err, errSaveUntitled):
+		a.status.Set("Cannot save untitled file")
+	case err != nil && !errors.Is(err, editor.ErrFileChanged):
+		a.status.Set(fmt.Sprintf("Save error: %v", err))
+	}
+}
+
+// errSaveUntitled is returned by saveBuffer for a buffer without a file.
+var errSaveUntitled = errors.New("cannot save untitled file")
+
+// saveBuffer saves buf to disk. If the file changed on disk since it was
+// read, the user is asked whether to overwrite it and ErrFileChanged is
+// returned.
+func (a *maneApp) saveBuffer(buf *editor.Buffer) error {
 	if buf == nil {
-		return
+		return nil
 	}
 	if buf.Untitled() {
-		a.status.Set("Cannot save untitled file")
-		return
+		return errSaveUntitled
+	}
+	if !buf.Large() && buf == a.tabs.ActiveBuffer() {
+		buf.ApplyTyping("Typing", a.textArea.Text())
 	}
-	buf.SetText(a.textArea.Text())
+	version := buf.Version()
 	if err := buf.Save(); err != nil {
-		a.status.Set(fmt.Sprintf("Save error: %v", err))
-		return
+		if errors.Is(err, editor.ErrFileChanged) {
+			a.showExternalChangePrompt(buf, true)
+		}
+		return err
 	}
+	a.afterSave(buf, version)
+	return nil
+}
+
+// afterSave shows any edits the save made to buf, whose version before the
+// save is given, persists its undo history and notifies listeners of the
+// save.
+func (a *maneApp) afterSave(buf *editor.Buffer, version uint64) {
+	a.syncSaveFormatting(buf, version)
+	_ = a.tabs.SaveUndo(buf)
 	a.notifyLSPDidSave(buf)
 	a.status.Set(fmt.Sprintf("Saved %s", buf.Title()))
 	a.updateStatus()
 }
 
+// cmdSaveWithEncoding offers the supported encodings and saves the active
+// buffer in the chosen one.
+func (a *maneApp) cmdSaveWithEncoding() {
+	a.showEncodingPalette("Save with encoding", func(buf *editor.Buffer, enc editor.Encoding) {
+		if buf.Untitled() {
+			a.status.Set("Cannot save untitled file")
+			return
+		}
+		buf.ApplyTyping("Typing", a.textArea.Text())
+		version := buf.Version()
+		if err := buf.SaveWithEncoding(enc); err != nil {
+			a.status.Set(fmt.Sprintf(" Save error: %v", err))
+			return
+		}
+		a.afterSave(buf, version)
+	})
+}
+
+// cmdReopenWithEncoding offers the supported encodings and decodes the
+// active buffer's file again in the chosen one.
+func (a *maneApp) cmdReopenWithEncoding() {
+	a.showEncodingPalette("Reopen with encoding", func(buf *editor.Buffer, enc editor.Encoding) {
+		if buf.Untitled() {
+			a.status.Set(" Cannot reopen untitled file")
+			return
+		}
+		buf.ApplyTyping("Typing", a.textArea.Text())
+		before := buf.Text()
+		if err := buf.ReopenWithEncoding(enc); err != nil {
+			a.status.Set(fmt.Sprintf(" Reopen error: %v", err))
+			return
+		}
+		a.syncBufferChange(buf, before)
+	})
+}
+
+// showEncodingPalette lists the supported encodings, marking the active
+// buffer's, and calls apply with the chosen one.
+func (a *maneApp) showEncodingPalette(status string, apply func(*editor.Buffer, editor.Encoding)) {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil {
+		return
+	}
+	cmds := make([]widgets.PaletteCommand, 0, len(editor.Encodings))
+	for _, enc := range editor.Encodings {
+		desc := ""
+		if enc == buf.Encoding() {
+			desc = "(current)"
+		}
+		cmds = append(cmds, widgets.PaletteCommand{
+			ID:          "encoding." + string(enc),
+			Label:       string(enc),
+			Description: desc,
+			OnExecute: func() {
+				a.lspPalette.Hide()
+				apply(buf, enc)
+			},
+		})
+	}
+	a.showLSPPalette(cmds, status)
+}
+
+// cmdSetLineEnding sets the line ending the active buffer is saved with.
+func (a *maneApp) cmdSetLineEnding(style editor.LineEnding) {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil {
+		return
+	}
+	buf.SetLineEnding(style)
+	a.syncTabBar()
+	a.updateStatus()
+}
+
 // cmdNewFile creates a new untitled buffer and switches to it.
 func (a *maneApp) cmdNewFile() {
 	a.tabs.NewUntitled()
+	a.leaveView(a.tabs.ActiveBuffer())
 	a.textArea.SetText("")
 	a.highlight.setup("") // no language for untitled
 	a.clearBlockSelection()
 	a.foldState.SetRegions(nil)
 	a.textArea.SetVisibleLines(nil)
 	a.syncMultiCursorFromTextArea()
+	a.enterView()
 	a.syncTabBar()
 	a.syncBreadcrumbs()
 	a.updateStatus()
@@ -3199,7 +3719,10 @@ func (a *maneApp) cmdCloseTab() {
 	a.notifyLSPDidClose(closingBuf)
 	a.tabs.Close(a.tabs.Active())
 	buf := a.tabs.ActiveBuffer()
-	if buf != nil {
+	a.leaveView(buf)
+	if buf != nil && buf.Large() {
+		a.showLargeFile(buf)
+	} else if buf != nil {
 		text := buf.Text()
 		a.textArea.SetText(text)
 		a.syncMultiCursorFromTextArea()
@@ -3222,19 +3745,148 @@ func (a *maneApp) cmdCloseTab() {
 		a.syncMultiCursorFromTextArea()
 		a.mergeAllHighlights()
 	}
+	a.enterView()
 	a.syncTabBar()
 	a.syncBreadcrumbs()
 	a.updateStatus()
 }
 
-// cmdUndo undoes the last edit via the TextArea's built-in undo.
+// cmdUndo reverts the last undo step recorded by the active buffer.
 func (a *maneApp) cmdUndo() {
-	a.textArea.Undo()
+	a.stepHistory((*editor.Buffer).Undo, "nothing to undo")
 }
 
-// cmdRedo redoes the last undone edit via the TextArea's built-in redo.
+// cmdRedo reapplies the last undo step reverted by cmdUndo.
 func (a *maneApp) cmdRedo() {
-	a.textArea.Redo()
+	a.stepHistory((*editor.Buffer).Redo, "nothing to redo")
+}
+
+// cmdUndoHistory lists every state of the active buffer's undo tree, with a
+// diff preview of the selected state.
+func (a *maneApp) cmdUndoHistory() {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil || a.undoHistory == nil {
+		return
+	}
+	a.undoHistory.preview = func(id int) []editor.DiffLine {
+		return editor.LineDiff(buf.Text(), buf.StateText(id))
+	}
+	a.undoHistory.onJump = func(id int) {
+		a.stepHistory(func(b *editor.Buffer) bool { return b.GoToState(id) }, "already at that state")
+	}
+	a.undoHistory.SetStates(buf.UndoStates(), time.Now())
+	a.undoHistory.Show()
+	a.undoHistory.Focus()
+}
+
+// timeTravelSteps are the offsets offered by the time-travel commands.
+var timeTravelSteps = []struct {
+	label string
+	d     time.Duration
+}{
+	{"30 seconds", 30 * time.Second},
+	{"1 minute", time.Minute},
+	{"5 minutes", 5 * time.Minute},
+	{"15 minutes", 15 * time.Minute},
+	{"1 hour", time.Hour},
+	{"1 day", 24 * time.Hour},
+}
+
+// cmdTimeTravel offers a list of offsets to move the active buffer through
+// its undo history by time, across branches.
+func (a *maneApp) cmdTimeTravel(back bool) {
+	cmds := make([]widgets.PaletteCommand, 0, len(timeTravelSteps))
+	for _, step := range timeTravelSteps {
+		d := step.d
+		label := "Go forward " + step.label
+		move := func(b *editor.Buffer) bool { return b.Later(d) }
+		empty := "no later state"
+		if back {
+			label = "Go back " + step.label
+			move = func(b *editor.Buffer) bool { return b.Earlier(d) }
+			empty = "no earlier state"
+		}
+		cmds = append(cmds, widgets.PaletteCommand{
+			ID:    "undo.time." + step.label,
+			Label: label,
+			OnExecute: func() {
+				a.lspPalette.Hide()
+				a.stepHistory(move, empty)
+			},
+		})
+	}
+	a.showLSPPalette(cmds, "Time travel")
+}
+
+// stepHistory moves the active buffer through its undo history and mirrors
+// the result into the TextArea, placing the cursor at the end of the change.
+// empty is shown in the status bar when step reports nothing to do.
+func (a *maneApp) stepHistory(step func(*editor.Buffer) bool, empty string) {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil {
+		return
+	}
+	if buf.Large() {
+		if !step(buf) {
+			a.status.Set(" " + empty)
+			return
+		}
+		a.largeView.clampCursor()
+		a.onLargeFileChange()
+		return
+	}
+	before := buf.Text()
+	if !step(buf) {
+		a.status.Set(" " + empty)
+		return
+	}
+	a.syncBufferChange(buf, before)
+}
+
+// replaceText replaces the text of the active buffer as one undo step
+// labelled label and puts the cursor at the rune offset cursor. It reports
+// false if there was no change to make.
+func (a *maneApp) replaceText(label, text string, cursor int) bool {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil || buf.Large() {
+		return false
+	}
+	before := buf.Text()
+	if !buf.ApplyText(label, text) {
+		return false
+	}
+	a.syncBufferChange(buf, before)
+	a.textArea.SetCursorOffset(clampRuneOffset(cursor, utf8.RuneCountInString(text)))
+	a.syncMultiCursorFromTextArea()
+	return true
+}
+
+// syncBufferChange propagates a change made directly to buf, whose text was
+// before. If buf is active, the TextArea is updated and the cursor placed at
+// the end of the change.
+func (a *maneApp) syncBufferChange(buf *editor.Buffer, before string) {
+	text := buf.Text()
+	if buf != a.tabs.ActiveBuffer() {
+		a.scheduleLspDidChange(buf, text)
+		a.notifyFileResource(buf.Path())
+		a.syncTabBar()
+		return
+	}
+	_, _, end := editor.ChangedRegion(before, text)
+
+	a.suppressChange = true
+	a.textArea.SetText(text)
+	a.textArea.ClearHistory()
+	a.textArea.SetCursorOffset(utf8.RuneCountInString(text[:end]))
+	a.suppressChange = false
+	a.syncMultiCursorFromTextArea()
+	a.clearBlockSelection()
+	a.rehighlight(text)
+	a.scheduleLspDidChange(buf, text)
+	a.notifyFileResource(buf.Path())
+	a.updateBracketMatch()
+	a.syncTabBar()
+	a.updateStatus()
 }
 
 // cmdToggleWordWrap toggles the editor word-wrap mode.
@@ -3353,7 +4005,7 @@ func (a *maneApp) cmdDeleteLine() {
 	col, row := a.textArea.CursorPosition()
 	text := buf.Text()
 	text = editor.DeleteLine(text, row)
-	buf.SetText(text)
+	buf.ApplyText("Delete Line", text)
 	a.textArea.SetText(text)
 	// Clamp cursor row if it fell off the end.
 	maxRow := editor.LineCount(text) - 1
@@ -3378,7 +4030,7 @@ func (a *maneApp) cmdMoveLineUp() {
 	if newText == text {
 		return
 	}
-	buf.SetText(newText)
+	buf.ApplyText("Move Line", newText)
 	a.textArea.SetText(newText)
 	a.textArea.SetCursorPosition(col, row-1)
 	a.syncMultiCursorFromTextArea()
@@ -3398,7 +4050,7 @@ func (a *maneApp) cmdMoveLineDown() {
 	if newText == text {
 		return
 	}
-	buf.SetText(newText)
+	buf.ApplyText("Move Line", newText)
 	a.textArea.SetText(newText)
 	a.textArea.SetCursorPosition(col, row+1)
 	a.syncMultiCursorFromTextArea()
@@ -3415,7 +4067,7 @@ func (a *maneApp) cmdDuplicateLine() {
 	col, row := a.textArea.CursorPosition()
 	text := buf.Text()
 	text = editor.DuplicateLine(text, row)
-	buf.SetText(text)
+	buf.ApplyText("Duplicate Line", text)
 	a.textArea.SetText(text)
 	a.textArea.SetCursorPosition(col, row+1)
 	a.syncMultiCursorFromTextArea()
@@ -3423,9 +4075,20 @@ func (a *maneApp) cmdDuplicateLine() {
 	a.updateStatus()
 }
 
-// run constructs the editor layout and starts the FluffyUI app.
-func run(ctx context.Context, paths []string, theme string, opts ...fluffy.AppOption) error {
-	sheet := loadTheme(theme)
+// runConfig holds the settings run takes from the command line.
+type runConfig struct {
+	theme         string
+	largeFileSize int64  // files bigger than this open in large-file mode; 0 never
+	session       string // named session to use, or "" for the project's
+}
+
+// run constructs the editor layout and starts the FluffyUI app. Files bigger
+// than cfg.largeFileSize bytes open in large-file mode; zero disables it.
+func run(ctx context.Context, paths []string, cfg runConfig, opts ...fluffy.AppOption) error {
+	if cfg.session != "" && !editor.ValidSessionName(cfg.session) {
+		return fmt.Errorf("invalid session name %q: use letters, digits, '.', '-' and '_'", cfg.session)
+	}
+	sheet := loadTheme(cfg.theme)
 	if sheet != nil {
 		opts = append(opts, fluffy.WithStylesheet(sheet))
 	}
@@ -3459,6 +4122,26 @@ func run(ctx context.Context, paths []string, theme string, opts ...fluffy.AppOp
 		}
 	}
 
+	// The session is restored and saved when mane is opened on a directory
+	// rather than on files, or when it is named. A named session also
+	// brings back its directory if none was given.
+	var sessions *editor.SessionStore
+	if cfg.session != "" || len(filesToOpen) == 0 {
+		if dir, err := editor.DefaultSessionDir(); err == nil {
+			sessions = editor.NewSessionStore(dir)
+		}
+	}
+	var sess *editor.Session
+	var sessionErr error
+	if sessions != nil && cfg.session != "" {
+		sess, sessionErr = sessions.Load(cfg.session, "")
+		if sess != nil && treeRoot == "" {
+			if info, err := os.Stat(sess.Root); err == nil && info.IsDir() {
+				treeRoot = sess.Root
+			}
+		}
+	}
+
 	if treeRoot == "" {
 		cwd, err := os.Getwd()
 		if err != nil {
@@ -3468,7 +4151,24 @@ func run(ctx context.Context, paths []string, theme string, opts ...fluffy.AppOp
 		}
 	}
 
+	if sessions != nil && cfg.session == "" {
+		sess, sessionErr = sessions.Load("", treeRoot)
+	}
+
 	app := newManeApp(treeRoot)
+	settingsErr := app.loadSettings()
+	app.applySettings(nil)
+	macroErr := app.loadMacros()
+	app.tabs.SetLargeFileThreshold(cfg.largeFileSize)
+	if dir, err := editor.DefaultUndoDir(); err == nil {
+		app.tabs.SetUndoStore(editor.NewUndoStore(dir))
+	}
+	defer app.tabs.SaveAllUndo()
+	if dir, err := editor.DefaultBackupDir(); err == nil {
+		app.backups = editor.NewBackupStore(dir)
+		app.tabs.SetBackupStore(app.backups)
+	}
+	defer app.finishBackups()
 	setMCPActiveEditor(app)
 	defer setMCPActiveEditor(nil)
 	app.lspCancel = cancel
@@ -3478,20 +4178,23 @@ func run(ctx context.Context, paths []string, theme string, opts ...fluffy.AppOp
 		app.shutdownLSP()
 	}
 	defer app.shutdownLSP()
+	defer app.stopFilter()
 	app.theme = sheet
 	if sheet != nil {
 		// Set gutter style from theme's .comment class (dimmed text).
 		gutterResolved := sheet.ResolveClass("comment")
 		if !gutterResolved.IsZero() {
 			app.textArea.SetGutterStyle(gutterResolved.ToBackend())
+			app.largeView.SetGutterStyle(gutterResolved.ToBackend())
 		}
 	}
 
 	// Set up search widget
-	app.search = widgets.NewSearchWidget()
+	app.search = newFindWidget(&app.findOptions)
 	app.search.SetOnSearch(app.onSearch)
 	app.search.SetOnNavigate(app.onSearchNext, app.onSearchPrev)
 	app.search.SetOnClose(app.onSearchClose)
+	app.search.onToggle = func() { app.onSearch(app.search.Query()) }
 
 	// Set up replace widget
 	app.replaceW = newReplaceWidget()
@@ -3501,6 +4204,8 @@ func run(ctx context.Context, paths []string, theme string, opts ...fluffy.AppOp
 	app.replaceW.onReplace = app.onReplace
 	app.replaceW.onReplaceAll = app.onReplaceAll
 	app.replaceW.onClose = app.onReplaceClose
+	app.replaceW.options = &app.findOptions
+	app.replaceW.onToggle = func() { app.onReplaceSearch(app.replaceW.searchText) }
 
 	// Set up go-to-line widget
 	app.gotoLineW = newGotoLineWidget()
@@ -3511,6 +4216,7 @@ func run(ctx context.Context, paths []string, theme string, opts ...fluffy.AppOp
 	app.fileFinder = newFileFinderWidget()
 	app.fileFinder.onOpen = app.onFileFinder
 	app.fileFinder.onClose = app.onFileFinderClose
+	app.undoHistory = newUndoHistoryWidget()
 
 	// Set up LSP helper palette for completion, references, and code actions.
 	app.lspPalette = widgets.NewCommandPalette()
@@ -3520,40 +4226,28 @@ func run(ctx context.Context, paths []string, theme string, opts ...fluffy.AppOp
 	app.renameW.onSubmit = app.cmdRenameSubmit
 	app.renameW.onClose = func() {}
 
-	// Build the command palette with editor actions.
-	app.palette = widgets.NewCommandPalette(commands.AllCommands(commands.Actions{
-		SaveFile:       app.cmdSaveFile,
-		NewFile:        app.cmdNewFile,
-		CloseTab:       app.cmdCloseTab,
-		ToggleSidebar:  app.toggleSidebar,
-		ToggleWordWrap: app.cmdToggleWordWrap,
-		Quit:           func() { app.cancel() },
-		Undo:           app.cmdUndo,
-		Redo:           app.cmdRedo,
-		Find:           func() { app.cmdFind() },
-		Replace:        func() { app.cmdReplace() },
-		GotoLine:       func() { app.cmdGotoLine() },
-		DeleteLine:     app.cmdDeleteLine,
-		MoveLineUp:     app.cmdMoveLineUp,
-		MoveLineDown:   app.cmdMoveLineDown,
-		DuplicateLine:  app.cmdDuplicateLine,
-		FoldAtCursor:   app.cmdFoldAtCursor,
-		UnfoldAtCursor: app.cmdUnfoldAtCursor,
-		FoldAll:        app.cmdFoldAll,
-		UnfoldAll:      app.cmdUnfoldAll,
-		LspComplete:    app.cmdLspComplete,
-		LspDefinition:  app.cmdLspDefinition,
-		LspReferences:  app.cmdLspReferences,
-		LspHover:       func() { app.cmdLspHoverPanel() },
-		LspDiagnostics: app.cmdLspDiagnostics,
-		LspRename:      func() { app.cmdLspRename() },
-		LspCodeAction:  app.cmdLspCodeAction,
-	})...)
-
-	// Open files from CLI args, or create an untitled buffer if none.
+	// Build the command palette with editor actions; the keymap, with the
+	// user's keybindings, supplies the shortcuts it shows.
+	keymapErr := app.loadKeymap()
+	app.palette = widgets.NewCommandPalette(app.commandList...)
+	app.palette.SetOnExecute(app.recordMacroCommand)
+
+	// Open the tabs of the session, files from CLI args and the buffers kept
+	// by a hot exit, or create an untitled buffer if none.
+	app.sessions = sessions
+	app.sessionName = cfg.session
+	defer app.saveSession()
+	sessionActive := -1
+	if sess != nil {
+		sessionActive = app.restoreSessionTabs(sess)
+	}
 	for _, f := range filesToOpen {
 		_ = app.openFile(f)
 	}
+	pendingBackups := app.restoreHotExit()
+	if sessionActive >= 0 && len(filesToOpen) == 0 {
+		app.switchTab(sessionActive)
+	}
 	if app.tabs.Count() == 0 {
 		app.tabs.NewUntitled()
 		app.syncTextArea()
@@ -3567,12 +4261,27 @@ func run(ctx context.Context, paths []string, theme string, opts ...fluffy.AppOp
 		return app.status.Get()
 	}, app.status)
 
-	// Horizontal split: file tree (22%) | editor (78%).
-	app.splitter = widgets.NewSplitter(app.fileTree, app.textArea)
-	app.splitter.Ratio = 0.22
+	// Horizontal split: file tree | editor, sized by the sidebarRatio setting.
+	app.splitter = widgets.NewSplitter(app.fileTree, app.pane)
+	app.splitter.Ratio = app.settings.Global().SidebarRatio
 
 	// Content slot: swappable between splitter (sidebar visible) and textArea only.
 	app.slot = &contentSlot{child: app.splitter}
+	if sess != nil {
+		app.restoreSessionLayout(sess)
+	}
+	if sessionErr != nil {
+		app.status.Set(fmt.Sprintf(" Session error: %v", sessionErr))
+	}
+	if settingsErr != nil {
+		app.status.Set(fmt.Sprintf(" Settings error: %v", settingsErr))
+	}
+	if keymapErr != nil {
+		app.status.Set(fmt.Sprintf(" Keybindings error: %v", keymapErr))
+	}
+	if macroErr != nil {
+		app.status.Set(fmt.Sprintf(" Macro error: %v", macroErr))
+	}
 
 	// Vertical layout: tab bar, content fills space, status bar fixed at bottom.
 	layout := fluffy.VFlex(
@@ -3590,12 +4299,24 @@ func run(ctx context.Context, paths []string, theme string, opts ...fluffy.AppOp
 	}
 
 	// Stack: layout at bottom, palettes in the middle, global keys on top (gets events first).
-	rootWidget := widgets.NewStack(layout, app.palette, app.fileFinder, app.lspPalette, app.renameW, keys)
+	rootWidget := widgets.NewStack(layout, app.palette, app.fileFinder, app.undoHistory, app.lspPalette, app.renameW, keys)
+
+	// Watch open files once the event loop can receive their changes.
+	opts = append(opts, fluffy.WithOnReady(func(rt *runtime.App) {
+		app.rt = rt
+		app.startFileWatcher(ctx, rt)
+		app.startBackups(ctx, rt)
+		app.startConfigWatcher(ctx, rt)
+		app.offerBackups(pendingBackups)
+	}))
 
 	return fluffy.RunContext(ctx, rootWidget, opts...)
 }
 
 func (a *maneApp) handleGlobalMouse(mouse runtime.MouseMsg) runtime.HandleResult {
+	if a.handleBlockDrag(mouse) {
+		return runtime.Handled()
+	}
 	if a.isBlockSelectionMode() && mouse.Button != runtime.MouseNone && mouse.Action == runtime.MousePress {
 		a.clearBlockSelection()
 	}
@@ -3610,210 +4331,6 @@ func (a *maneApp) handleGlobalPaste(paste runtime.PasteMsg) runtime.HandleResult
 	return runtime.Handled()
 }
 
-func (a *maneApp) handleGlobalKey(key runtime.KeyMsg) runtime.HandleResult {
-	if key.Alt && key.Shift {
-		switch key.Key {
-		case terminal.KeyUp:
-			a.expandBlockSelection(-1, 0)
-			return runtime.Handled()
-		case terminal.KeyDown:
-			a.expandBlockSelection(1, 0)
-			return runtime.Handled()
-		case terminal.KeyLeft:
-			a.expandBlockSelection(0, -1)
-			return runtime.Handled()
-		case terminal.KeyRight:
-			a.expandBlockSelection(0, 1)
-			return runtime.Handled()
-		}
-	}
-
-	if a.isBlockSelectionMode() {
-		switch key.Key {
-		case terminal.KeyEscape:
-			a.clearBlockSelection()
-			a.updateStatus()
-			return runtime.Handled()
-		case terminal.KeyBackspace:
-			a.applyBlockBackspace()
-			return runtime.Handled()
-		case terminal.KeyDelete:
-			a.applyBlockDeleteForward()
-			return runtime.Handled()
-		case terminal.KeyEnter:
-			a.applyBlockInsert("\n")
-			return runtime.Handled()
-		case terminal.KeyTab:
-			a.applyBlockInsert("\t")
-			return runtime.Handled()
-		case terminal.KeyRune:
-			if !key.Ctrl && !key.Alt && key.Rune != 0 {
-				a.applyBlockInsert(string(key.Rune))
-				return runtime.Handled()
-			}
-		}
-
-		// Leave block mode when another command/navigation key is used.
-		a.clearBlockSelection()
-		a.updateStatus()
-	}
-
-	if a.isMultiCursorMode() {
-		switch key.Key {
-		case terminal.KeyCtrlD:
-			a.addNextCursorOccurrence()
-			return runtime.Handled()
-		case terminal.KeyCtrlV:
-			if text := a.clipboardText(); text != "" {
-				a.applyPaste(text)
-				return runtime.Handled()
-			}
-			return runtime.Unhandled()
-		case terminal.KeyRune:
-			if !key.Ctrl && !key.Alt && key.Rune != 0 {
-				a.applyMultiCursorInsert(string(key.Rune))
-				return runtime.Handled()
-			}
-		case terminal.KeyBackspace:
-			a.applyMultiCursorDeleteBackspace()
-			return runtime.Handled()
-		case terminal.KeyDelete:
-			a.applyMultiCursorDeleteForward()
-			return runtime.Handled()
-		case terminal.KeyEnter:
-			a.applyMultiCursorInsert("\n")
-			return runtime.Handled()
-		case terminal.KeyTab:
-			a.applyMultiCursorInsert("\t")
-			return runtime.Handled()
-		case terminal.KeyEscape:
-			a.resetMultiCursor()
-			return runtime.Handled()
-		default:
-			// Exit multi-cursor mode for all other navigation or editing
-			// keys, and let the underlying widget handle movement/commands.
-			a.resetMultiCursor()
-		}
-	}
-
-	switch key.Key {
-	case terminal.KeyCtrlD:
-		a.addNextCursorOccurrence()
-		return runtime.Handled()
-	case terminal.KeyCtrlP:
-		return a.cmdOpenFileFinder()
-	case terminal.KeyRune:
-		if key.Ctrl && key.Shift && (key.Rune == 'P' || key.Rune == 'p') {
-			a.cmdShowPalette()
-			return runtime.Handled()
-		}
-		if key.Ctrl && !key.Shift && (key.Rune == 'P' || key.Rune == 'p') {
-			return a.cmdOpenFileFinder()
-		}
-		if key.Ctrl && key.Rune == 'h' {
-			return a.cmdReplace()
-		}
-		if key.Ctrl && key.Shift && key.Rune == '{' {
-			a.cmdFoldAtCursor()
-			return runtime.Handled()
-		}
-		if key.Ctrl && key.Shift && key.Rune == '}' {
-			a.cmdUnfoldAtCursor()
-			return runtime.Handled()
-		}
-		if key.Ctrl && key.Rune == ']' {
-			a.cmdGotoMatchingBracket()
-			return runtime.Handled()
-		}
-		if key.Ctrl && key.Shift && key.Rune == 'K' {
-			a.cmdDeleteLine()
-			return runtime.Handled()
-		}
-		if key.Ctrl && key.Shift && key.Rune == 'D' {
-			a.cmdDuplicateLine()
-			return runtime.Handled()
-		}
-		if key.Ctrl && key.Rune == ' ' {
-			a.cmdLspComplete()
-			return runtime.Handled()
-		}
-		if key.Ctrl && key.Rune == '.' {
-			a.cmdLspCodeAction()
-			return runtime.Handled()
-		}
-		if key.Ctrl && (key.Rune == 's' || key.Rune == 'S') {
-			a.cmdSaveFile()
-			return runtime.Handled()
-		}
-		if key.Ctrl && (key.Rune == 'n' || key.Rune == 'N') {
-			a.cmdNewFile()
-			return runtime.Handled()
-		}
-		if key.Ctrl && (key.Rune == 'w' || key.Rune == 'W') {
-			a.cmdCloseTab()
-			return runtime.Handled()
-		}
-		if key.Ctrl && (key.Rune == 'q' || key.Rune == 'Q') {
-			a.cancel()
-			return runtime.Handled()
-		}
-		if key.Ctrl && key.Alt && (key.Rune == 'w' || key.Rune == 'W') {
-			a.cmdToggleWordWrap()
-			return runtime.Handled()
-		}
-	case terminal.KeyCtrlF:
-		return a.cmdFind()
-	case terminal.KeyCtrlG:
-		return a.cmdGotoLine()
-	case terminal.KeyF1:
-		return a.cmdLspHoverPanel()
-	case terminal.KeyF2:
-		return a.cmdLspRename()
-	case terminal.KeyF8:
-		a.cmdLspDiagnostics()
-		return runtime.Handled()
-	case terminal.KeyF12:
-		if key.Shift {
-			a.cmdLspReferences()
-		} else {
-			a.cmdLspDefinition()
-		}
-		return runtime.Handled()
-	case terminal.KeyCtrlB:
-		a.toggleSidebar()
-		return runtime.Handled()
-	case terminal.KeyUp:
-		if key.Alt && !key.Shift {
-			a.cmdMoveLineUp()
-			return runtime.Handled()
-		}
-	case terminal.KeyDown:
-		if key.Alt && !key.Shift {
-			a.cmdMoveLineDown()
-			return runtime.Handled()
-		}
-	case terminal.KeyPageUp:
-		if key.Ctrl {
-			a.prevTab()
-			return runtime.Handled()
-		}
-	case terminal.KeyPageDown:
-		if key.Ctrl {
-			a.nextTab()
-			return runtime.Handled()
-		}
-	}
-	return runtime.Unhandled()
-}
-
-// detectLineEnding returns the dominant line ending string.
-func detectLineEnding(text string) string {
-	if strings.Contains(text, "\r\n") {
-		return "CRLF"
-	}
-	return "LF"
-}
-
 // detectIndentMode returns the current indent style string for status reporting.
 func detectIndentMode(text string) string {
 	indent := editor.DetectIndentStyle(text)
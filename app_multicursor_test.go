@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/odvcencio/fluffyui/widgets"
 	"github.com/odvcencio/mane/editor"
 	"github.com/odvcencio/mane/lsp"
+	"github.com/odvcencio/mane/mcptools"
 )
 
 func newTestAppWithText(t *testing.T, text string) *maneApp {
@@ -26,6 +28,7 @@ func newTestAppWithText(t *testing.T, text string) *maneApp {
 	}
 	buf.SetText(text)
 	app.textArea.SetText(text)
+	app.textArea.Focus()
 	app.syncMultiCursorFromTextArea()
 	return app
 }
@@ -183,16 +186,24 @@ func TestHandleGlobalKeyExitAndStatus(t *testing.T) {
 		t.Fatalf("expected status with no further occurrences, got %q", got)
 	}
 
-	if result := app.handleGlobalKey(runtime.KeyMsg{
-		Key:   terminal.KeyUp,
-		Shift: false,
-		Ctrl:  false,
-		Alt:   false,
-	}); result.Handled {
-		t.Fatalf("handleGlobalKey(navigation) in multicursor = %#v, expected unhandled", result)
+	// Arrow keys move every cursor, leaving the start of each selection.
+	if result := app.handleGlobalKey(runtime.KeyMsg{Key: terminal.KeyUp}); !result.Handled {
+		t.Fatalf("handleGlobalKey(Up) in multicursor = %#v, want Handled", result)
+	}
+	var offsets []int
+	for _, c := range app.multiCursor.Cursors() {
+		offsets = append(offsets, c.Offset)
+	}
+	slices.Sort(offsets)
+	if want := []int{0, 4, 8}; !slices.Equal(offsets, want) {
+		t.Fatalf("cursor offsets after Up = %v, want %v", offsets, want)
+	}
+
+	if result := app.handleGlobalKey(runtime.KeyMsg{Key: terminal.KeyEscape}); !result.Handled {
+		t.Fatalf("handleGlobalKey(Escape) in multicursor = %#v, want Handled", result)
 	}
 	if app.multiCursor.IsMulti() {
-		t.Fatal("expected multi-cursor mode cleared on non-command key")
+		t.Fatal("expected multi-cursor mode cleared on Escape")
 	}
 }
 
@@ -221,6 +232,58 @@ func TestHandleGlobalKeyCtrlDStartsMultiCursor(t *testing.T) {
 	}
 }
 
+func TestSelectAllOccurrencesThenType(t *testing.T) {
+	app := newTestAppWithText(t, "foo bar foo")
+	setPrimarySelection(app, 0, 3, 3)
+	app.handleGlobalKey(runtime.KeyMsg{Key: terminal.KeyRune, Rune: 'L', Ctrl: true, Shift: true})
+	if got := app.multiCursor.Count(); got != 2 {
+		t.Fatalf("multiCursor.Count() after Ctrl+Shift+L = %d, want 2", got)
+	}
+	app.handleGlobalKey(runtime.KeyMsg{Key: terminal.KeyRune, Rune: 'X'})
+	if got := app.tabs.ActiveBuffer().Text(); got != "X bar X" {
+		t.Fatalf("buffer text = %q, want %q", got, "X bar X")
+	}
+}
+
+func TestAddCursorBelowAndUndo(t *testing.T) {
+	app := newTestAppWithText(t, "abc\nd\nefg")
+	setPrimarySelection(app, 2, 2, 2)
+	down := runtime.KeyMsg{Key: terminal.KeyDown, Ctrl: true, Alt: true}
+	app.handleGlobalKey(down)
+	app.handleGlobalKey(down)
+	var offsets []int
+	for _, c := range app.multiCursor.Cursors() {
+		offsets = append(offsets, c.Offset)
+	}
+	if want := []int{2, 5, 8}; !slices.Equal(offsets, want) {
+		t.Fatalf("cursor offsets = %v, want %v", offsets, want)
+	}
+	app.handleGlobalKey(runtime.KeyMsg{Key: terminal.KeyRune, Rune: 'u', Ctrl: true})
+	if got := app.multiCursor.Count(); got != 2 {
+		t.Fatalf("multiCursor.Count() after Ctrl+U = %d, want 2", got)
+	}
+}
+
+func TestMultiCursorCutPastesOnePerCursor(t *testing.T) {
+	app := newTestAppWithText(t, "a1\nb22\nc333")
+	app.multiCursor.SetCursors([]editor.Cursor{{Offset: 2, Anchor: 1}, {Offset: 6, Anchor: 4}, {Offset: 11, Anchor: 8}})
+	app.syncTextAreaFromMultiCursor()
+
+	app.handleGlobalKey(runtime.KeyMsg{Key: terminal.KeyCtrlX})
+	if got := app.tabs.ActiveBuffer().Text(); got != "a\nb\nc" {
+		t.Fatalf("buffer text after cut = %q, want %q", got, "a\nb\nc")
+	}
+	if want := []string{"1", "22", "333"}; !slices.Equal(app.cursorClipboard, want) {
+		t.Fatalf("cursorClipboard = %q, want %q", app.cursorClipboard, want)
+	}
+
+	app.handleGlobalKey(runtime.KeyMsg{Key: terminal.KeyHome})
+	app.applyPaste(strings.Join(app.cursorClipboard, "\n"))
+	if got := app.tabs.ActiveBuffer().Text(); got != "1a\n22b\n333c" {
+		t.Fatalf("buffer text after paste = %q, want %q", got, "1a\n22b\n333c")
+	}
+}
+
 func TestSwitchTabResetsMultiCursorState(t *testing.T) {
 	app := newManeApp("")
 
@@ -292,10 +355,19 @@ func TestApplyPasteAcrossMultiCursorsWithMultilineText(t *testing.T) {
 	setPrimarySelection(app, 0, 3, 3)
 	app.multiCursor.AddSelection(4, 7)
 
+	// As many lines as cursors: one line goes to each cursor.
 	app.applyPaste("x\ny")
+	if got := app.tabs.ActiveBuffer().Text(); got != "x y" {
+		t.Fatalf("applyPaste() = %q, want %q", got, "x y")
+	}
 
-	if got := app.tabs.ActiveBuffer().Text(); got != "x\ny x\ny" {
-		t.Fatalf("applyPaste() = %q, want %q", got, "x\ny x\ny")
+	// Any other number of lines: the whole text goes to each cursor.
+	app = newTestAppWithText(t, "foo bar")
+	setPrimarySelection(app, 0, 3, 3)
+	app.multiCursor.AddSelection(4, 7)
+	app.applyPaste("x\ny\nz")
+	if got := app.tabs.ActiveBuffer().Text(); got != "x\ny\nz x\ny\nz" {
+		t.Fatalf("applyPaste() = %q, want %q", got, "x\ny\nz x\ny\nz")
 	}
 }
 
@@ -618,6 +690,36 @@ func TestBlockSelectionDeleteAndBackspace(t *testing.T) {
 	}
 }
 
+func TestBlockCopyPastesAsBlock(t *testing.T) {
+	app := newTestAppWithText(t, "ab12\ncd34\nx")
+	app.blockSelection.Set(0, 1, 2, 4)
+	app.handleGlobalKey(runtime.KeyMsg{Key: terminal.KeyCtrlX})
+	if got := app.tabs.ActiveBuffer().Text(); got != "ab\ncd\nx" {
+		t.Fatalf("buffer text after block cut = %q, want %q", got, "ab\ncd\nx")
+	}
+	if want := []string{"12", "34"}; !slices.Equal(app.blockClipboard, want) {
+		t.Fatalf("blockClipboard = %q, want %q", app.blockClipboard, want)
+	}
+
+	// Pasted at the start of the last line, the block adds a line below.
+	app.textArea.SetCursorPosition(0, 2)
+	app.applyPaste("12\n34")
+	if got, want := app.tabs.ActiveBuffer().Text(), "ab\ncd\n12x\n34"; got != want {
+		t.Fatalf("buffer text after block paste = %q, want %q", got, want)
+	}
+}
+
+func TestBlockFillNumbers(t *testing.T) {
+	app := newTestAppWithText(t, "a\tb\na\tb")
+	app.blockSelection.Set(0, 1, 1, 1)
+	app.cmdBlockFill()
+	app.handleGlobalKey(runtime.KeyMsg{Key: terminal.KeyRune, Rune: '9'})
+	app.handleGlobalKey(runtime.KeyMsg{Key: terminal.KeyEnter})
+	if got, want := app.tabs.ActiveBuffer().Text(), "a 9\tb\na10\tb"; got != want {
+		t.Fatalf("buffer text after fill = %q, want %q", got, want)
+	}
+}
+
 func TestSelectionCountForBlockSelection(t *testing.T) {
 	app := newTestAppWithText(t, "abcd\nef")
 	app.blockSelection.Set(0, 1, 1, 3)
@@ -677,10 +779,16 @@ func TestMCPAccessMethods(t *testing.T) {
 		t.Fatal("expected at least one symbol")
 	}
 
-	search := app.Search("main")
+	search, err := app.Search("main", mcptools.SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
 	if len(search) == 0 {
 		t.Fatal("expected search matches")
 	}
+	if _, err := app.Search("(", mcptools.SearchOptions{Regex: true}); err == nil {
+		t.Fatal("expected Search error for invalid regex")
+	}
 
 	if err := app.RunCommand("unknown.command"); err == nil {
 		t.Fatal("expected RunCommand error for unknown command")
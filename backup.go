@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/editor"
+)
+
+// backupInterval is how often unsaved changes are written to the backup
+// store.
+const backupInterval = 30 * time.Second
+
+// startBackups writes backups of buffers with unsaved changes every
+// backupInterval, on the UI loop of rt.
+func (a *maneApp) startBackups(ctx context.Context, rt *runtime.App) {
+	if a.backups == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(backupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = rt.Call(ctx, func(*runtime.App) error {
+					a.backupBuffers()
+					return nil
+				})
+			}
+		}
+	}()
+}
+
+// backupBuffers brings the backups in line with the open buffers.
+func (a *maneApp) backupBuffers() {
+	if a.quitting {
+		return
+	}
+	if err := a.tabs.BackupAll(false); err != nil {
+		a.status.Set(fmt.Sprintf(" Backup error: %v", err))
+	}
+}
+
+// finishBackups runs when the editor exits. Unless a quit command already
+// took care of them, the unsaved changes are backed up once more, so an exit
+// by signal loses nothing; the next start offers to restore them.
+func (a *maneApp) finishBackups() {
+	if !a.quitting {
+		_ = a.tabs.BackupAll(false)
+	}
+}
+
+// cmdQuit quits with a hot exit: unsaved changes are kept in the backup
+// store and come back on the next start without a prompt. Unsaved changes
+// to a large file cannot be kept, so it refuses to quit while there are any.
+func (a *maneApp) cmdQuit() {
+	if err := a.tabs.BackupAll(true); err != nil {
+		a.status.Set(fmt.Sprintf(" Could not keep unsaved changes: %v (use Quit and Discard Unsaved Changes to quit anyway)", err))
+		return
+	}
+	a.quitting = true
+	a.cancel()
+}
+
+// cmdQuitDiscard quits and throws away the unsaved changes of every buffer.
+func (a *maneApp) cmdQuitDiscard() {
+	_ = a.tabs.DiscardBackups()
+	a.quitting = true
+	a.cancel()
+}
+
+// restoreHotExit reopens the buffers kept by a hot exit and returns the
+// other backups, which were left behind by a session that ended without
+// quitting and are only restored if the user agrees.
+func (a *maneApp) restoreHotExit() []editor.Backup {
+	backups, err := a.backups.List()
+	if err != nil {
+		a.status.Set(fmt.Sprintf(" Backup error: %v", err))
+		return nil
+	}
+	var pending []editor.Backup
+	restored, conflicts := 0, 0
+	for _, bk := range backups {
+		if !bk.HotExit {
+			pending = append(pending, bk)
+			continue
+		}
+		if ok, conflict := a.restoreBackup(bk); ok {
+			restored++
+			if conflict {
+				conflicts++
+			}
+		}
+	}
+	if restored > 0 {
+		a.reportRestored(restored, conflicts)
+	}
+	return pending
+}
+
+// restoreBackup opens bk in a tab and shows it, reporting whether it worked
+// and whether it conflicted with changes made to the file on disk since,
+// which are merged in.
+func (a *maneApp) restoreBackup(bk editor.Backup) (ok, conflict bool) {
+	idx, err := a.tabs.RestoreBackup(bk)
+	if idx >= 0 {
+		a.switchTab(idx)
+	}
+	if errors.Is(err, editor.ErrBackupConflict) {
+		return true, true
+	}
+	if err != nil {
+		a.status.Set(fmt.Sprintf(" Could not restore %s: %v", bk.Title(), err))
+		return false, false
+	}
+	return true, false
+}
+
+// reportRestored shows how many buffers got their unsaved changes back.
+func (a *maneApp) reportRestored(restored, conflicts int) {
+	if conflicts > 0 {
+		a.status.Set(fmt.Sprintf(" Restored unsaved changes in %d buffer(s), %d with conflicts against changes on disk", restored, conflicts))
+		return
+	}
+	a.status.Set(fmt.Sprintf(" Restored unsaved changes in %d buffer(s)", restored))
+}
+
+// offerBackups asks whether to restore backups left behind by a session
+// that did not quit normally. Dismissing the prompt keeps them for the next
+// start.
+func (a *maneApp) offerBackups(backups []editor.Backup) {
+	if len(backups) == 0 {
+		return
+	}
+	titles := make([]string, len(backups))
+	for i, bk := range backups {
+		titles[i] = bk.Title()
+	}
+	run := func(fn func()) func() {
+		return func() {
+			a.lspPalette.Hide()
+			fn()
+		}
+	}
+	cmds := []widgets.PaletteCommand{
+		{
+			ID:          "backup.restore",
+			Label:       "Restore unsaved changes",
+			Description: "Reopen " + strings.Join(titles, ", "),
+			OnExecute: run(func() {
+				restored, conflicts := 0, 0
+				for _, bk := range backups {
+					if ok, conflict := a.restoreBackup(bk); ok {
+						restored++
+						if conflict {
+							conflicts++
+						}
+					}
+				}
+				if restored == len(backups) {
+					a.reportRestored(restored, conflicts)
+				}
+			}),
+		},
+		{
+			ID:          "backup.discard",
+			Label:       "Discard unsaved changes",
+			Description: "Delete the backups of " + strings.Join(titles, ", "),
+			OnExecute: run(func() {
+				for _, bk := range backups {
+					_ = a.backups.Discard(bk.ID)
+				}
+				a.status.Set(" Discarded unsaved changes from the last session")
+			}),
+		},
+	}
+	a.showLSPPalette(cmds, fmt.Sprintf("Found unsaved changes from a session that did not quit normally (%d buffer(s))", len(backups)))
+}
@@ -1,24 +1,54 @@
 package commands
 
-import "github.com/odvcencio/fluffyui/widgets"
+import (
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/keymap"
+)
 
 // Actions holds callbacks for all editor commands.
 type Actions struct {
-	SaveFile       func()
-	NewFile        func()
-	CloseTab       func()
-	ToggleSidebar  func()
-	ToggleWordWrap func()
-	Quit           func()
-	Undo           func()
-	Redo           func()
-	Find           func()
-	Replace        func()
-	GotoLine       func()
-	DeleteLine     func()
-	MoveLineUp     func()
-	MoveLineDown   func()
-	DuplicateLine  func()
+	SaveFile        func()
+	NewFile         func()
+	CloseTab        func()
+	ToggleSidebar   func()
+	ToggleWordWrap  func()
+	Quit            func()
+	QuitDiscard     func()
+	OpenSettings    func()
+	OpenKeybindings func()
+	GoToFile        func()
+	NextTab         func()
+	PrevTab         func()
+	Undo            func()
+	Redo            func()
+	UndoHistory     func()
+	GoBackInTime    func()
+	GoForwardInTime func()
+	Find            func()
+	Replace         func()
+	GotoLine        func()
+	DeleteLine      func()
+	MoveLineUp      func()
+	MoveLineDown    func()
+	DuplicateLine   func()
+	GotoBracket     func()
+	// Multi-cursor and block selection actions.
+	AddNextOccurrence    func()
+	SkipOccurrence       func()
+	SelectAllOccurrences func()
+	AddCursorAbove       func()
+	AddCursorBelow       func()
+	UndoCursorAdd        func()
+	ToggleMatchCase      func()
+	ToggleWholeWord      func()
+	BlockSelectUp        func()
+	BlockSelectDown      func()
+	BlockSelectLeft      func()
+	BlockSelectRight     func()
+	BlockCopy            func()
+	BlockCut             func()
+	BlockPaste           func()
+	BlockFill            func()
 	// Folding actions.
 	FoldAtCursor   func()
 	UnfoldAtCursor func()
@@ -32,36 +62,279 @@ type Actions struct {
 	LspDiagnostics func()
 	LspRename      func()
 	LspCodeAction  func()
+	// File format actions.
+	SaveWithEncoding   func()
+	ReopenWithEncoding func()
+	LineEndingLF       func()
+	LineEndingCRLF     func()
+	ToggleReadOnly     func()
+	// Keyboard macro actions.
+	RecordMacro       func()
+	PlayMacro         func()
+	PlayMacroTimes    func()
+	PlayMacroOnLines  func()
+	SaveMacroRegister func()
+	PlayMacroRegister func()
+	// Line and text transformation actions.
+	SortLines           func()
+	SortLinesNumeric    func()
+	SortLinesIgnoreCase func()
+	SortLinesDescending func()
+	UniqueLines         func()
+	ShuffleLines        func()
+	ReverseLines        func()
+	JoinLines           func()
+	SplitLines          func()
+	TransposeChars      func()
+	TransposeWords      func()
+	TransposeLines      func()
+	UpperCase           func()
+	LowerCase           func()
+	TitleCase           func()
+	SnakeCase           func()
+	CamelCase           func()
+	KebabCase           func()
+	FilterCommand       func()
+	InsertCommandOutput func()
 }
 
 // AllCommands returns the full command list for the palette.
 func AllCommands(a Actions) []widgets.PaletteCommand {
 	return []widgets.PaletteCommand{
-		{ID: "file.save", Label: "Save File", Shortcut: "Ctrl+S", Category: "File", OnExecute: a.SaveFile},
-		{ID: "file.new", Label: "New File", Shortcut: "Ctrl+N", Category: "File", OnExecute: a.NewFile},
-		{ID: "file.close", Label: "Close Tab", Shortcut: "Ctrl+W", Category: "File", OnExecute: a.CloseTab},
-		{ID: "view.sidebar", Label: "Toggle Sidebar", Shortcut: "Ctrl+B", Category: "View", OnExecute: a.ToggleSidebar},
-		{ID: "view.wrap", Label: "Toggle Word Wrap", Shortcut: "Ctrl+Alt+W", Category: "View", OnExecute: a.ToggleWordWrap},
-		{ID: "app.quit", Label: "Quit", Shortcut: "Ctrl+Q", Category: "App", OnExecute: a.Quit},
-		{ID: "edit.undo", Label: "Undo", Shortcut: "Ctrl+Z", Category: "Edit", OnExecute: a.Undo},
-		{ID: "edit.redo", Label: "Redo", Shortcut: "Ctrl+Shift+Z", Category: "Edit", OnExecute: a.Redo},
-		{ID: "edit.find", Label: "Find", Shortcut: "Ctrl+F", Category: "Edit", OnExecute: a.Find},
-		{ID: "edit.replace", Label: "Replace", Shortcut: "Ctrl+H", Category: "Edit", OnExecute: a.Replace},
-		{ID: "edit.gotoLine", Label: "Go To Line", Shortcut: "Ctrl+G", Category: "Navigation", OnExecute: a.GotoLine},
-		{ID: "edit.deleteLine", Label: "Delete Line", Shortcut: "Ctrl+Shift+K", Category: "Edit", OnExecute: a.DeleteLine},
-		{ID: "edit.moveLineUp", Label: "Move Line Up", Shortcut: "Alt+Up", Category: "Edit", OnExecute: a.MoveLineUp},
-		{ID: "edit.moveLineDown", Label: "Move Line Down", Shortcut: "Alt+Down", Category: "Edit", OnExecute: a.MoveLineDown},
-		{ID: "edit.duplicateLine", Label: "Duplicate Line", Shortcut: "Ctrl+Shift+D", Category: "Edit", OnExecute: a.DuplicateLine},
-		{ID: "edit.fold", Label: "Fold", Shortcut: "Ctrl+Shift+[", Category: "Edit", OnExecute: a.FoldAtCursor},
-		{ID: "edit.unfold", Label: "Unfold", Shortcut: "Ctrl+Shift+]", Category: "Edit", OnExecute: a.UnfoldAtCursor},
+		{ID: "file.save", Label: "Save File", Category: "File", OnExecute: a.SaveFile},
+		{ID: "file.saveWithEncoding", Label: "Save with Encoding", Category: "File", OnExecute: a.SaveWithEncoding},
+		{ID: "file.reopenWithEncoding", Label: "Reopen with Encoding", Category: "File", OnExecute: a.ReopenWithEncoding},
+		{ID: "file.lineEndingLF", Label: "Change Line Endings to LF", Category: "File", OnExecute: a.LineEndingLF},
+		{ID: "file.lineEndingCRLF", Label: "Change Line Endings to CRLF", Category: "File", OnExecute: a.LineEndingCRLF},
+		{ID: "file.toggleReadOnly", Label: "Toggle Read-Only", Category: "File", OnExecute: a.ToggleReadOnly},
+		{ID: "file.new", Label: "New File", Category: "File", OnExecute: a.NewFile},
+		{ID: "file.goToFile", Label: "Go to File", Category: "File", OnExecute: a.GoToFile},
+		{ID: "file.close", Label: "Close Tab", Category: "File", OnExecute: a.CloseTab},
+		{ID: "view.sidebar", Label: "Toggle Sidebar", Category: "View", OnExecute: a.ToggleSidebar},
+		{ID: "view.wrap", Label: "Toggle Word Wrap", Category: "View", OnExecute: a.ToggleWordWrap},
+		{ID: "view.nextTab", Label: "Next Tab", Category: "View", OnExecute: a.NextTab},
+		{ID: "view.prevTab", Label: "Previous Tab", Category: "View", OnExecute: a.PrevTab},
+		{ID: "app.quit", Label: "Quit", Category: "App", OnExecute: a.Quit},
+		{ID: "app.quitDiscard", Label: "Quit and Discard Unsaved Changes", Category: "App", OnExecute: a.QuitDiscard},
+		{ID: "app.openSettings", Label: "Open Settings", Category: "App", OnExecute: a.OpenSettings},
+		{ID: "app.openKeybindings", Label: "Open Keyboard Shortcuts", Category: "App", OnExecute: a.OpenKeybindings},
+		{ID: "edit.undo", Label: "Undo", Category: "Edit", OnExecute: a.Undo},
+		{ID: "edit.redo", Label: "Redo", Category: "Edit", OnExecute: a.Redo},
+		{ID: "edit.undoHistory", Label: "Undo History", Category: "Edit", OnExecute: a.UndoHistory},
+		{ID: "edit.goBackInTime", Label: "Go Back in Time", Category: "Edit", OnExecute: a.GoBackInTime},
+		{ID: "edit.goForwardInTime", Label: "Go Forward in Time", Category: "Edit", OnExecute: a.GoForwardInTime},
+		{ID: "edit.find", Label: "Find", Category: "Edit", OnExecute: a.Find},
+		{ID: "edit.replace", Label: "Replace", Category: "Edit", OnExecute: a.Replace},
+		{ID: "edit.gotoLine", Label: "Go To Line", Category: "Navigation", OnExecute: a.GotoLine},
+		{ID: "edit.deleteLine", Label: "Delete Line", Category: "Edit", OnExecute: a.DeleteLine},
+		{ID: "edit.moveLineUp", Label: "Move Line Up", Category: "Edit", OnExecute: a.MoveLineUp},
+		{ID: "edit.moveLineDown", Label: "Move Line Down", Category: "Edit", OnExecute: a.MoveLineDown},
+		{ID: "edit.duplicateLine", Label: "Duplicate Line", Category: "Edit", OnExecute: a.DuplicateLine},
+		{ID: "edit.gotoBracket", Label: "Go to Matching Bracket", Category: "Navigation", OnExecute: a.GotoBracket},
+		{ID: "edit.addNextOccurrence", Label: "Add Next Occurrence", Category: "Edit", OnExecute: a.AddNextOccurrence},
+		{ID: "edit.skipOccurrence", Label: "Skip Occurrence and Add Next", Category: "Edit", OnExecute: a.SkipOccurrence},
+		{ID: "edit.selectAllOccurrences", Label: "Select All Occurrences", Category: "Edit", OnExecute: a.SelectAllOccurrences},
+		{ID: "edit.addCursorAbove", Label: "Add Cursor Above", Category: "Edit", OnExecute: a.AddCursorAbove},
+		{ID: "edit.addCursorBelow", Label: "Add Cursor Below", Category: "Edit", OnExecute: a.AddCursorBelow},
+		{ID: "edit.undoCursorAdd", Label: "Undo Last Cursor Add", Category: "Edit", OnExecute: a.UndoCursorAdd},
+		{ID: "edit.toggleMatchCase", Label: "Toggle Match Case", Category: "Edit", OnExecute: a.ToggleMatchCase},
+		{ID: "edit.toggleWholeWord", Label: "Toggle Whole Word", Category: "Edit", OnExecute: a.ToggleWholeWord},
+		{ID: "edit.blockSelectUp", Label: "Block Select Up", Category: "Edit", OnExecute: a.BlockSelectUp},
+		{ID: "edit.blockSelectDown", Label: "Block Select Down", Category: "Edit", OnExecute: a.BlockSelectDown},
+		{ID: "edit.blockSelectLeft", Label: "Block Select Left", Category: "Edit", OnExecute: a.BlockSelectLeft},
+		{ID: "edit.blockSelectRight", Label: "Block Select Right", Category: "Edit", OnExecute: a.BlockSelectRight},
+		{ID: "edit.blockCopy", Label: "Copy Block", Category: "Edit", OnExecute: a.BlockCopy},
+		{ID: "edit.blockCut", Label: "Cut Block", Category: "Edit", OnExecute: a.BlockCut},
+		{ID: "edit.blockPaste", Label: "Paste as Block", Category: "Edit", OnExecute: a.BlockPaste},
+		{ID: "edit.blockFill", Label: "Fill Column with Numbers", Category: "Edit", OnExecute: a.BlockFill},
+		{ID: "edit.fold", Label: "Fold", Category: "Edit", OnExecute: a.FoldAtCursor},
+		{ID: "edit.unfold", Label: "Unfold", Category: "Edit", OnExecute: a.UnfoldAtCursor},
 		{ID: "edit.foldAll", Label: "Fold All", Category: "Edit", OnExecute: a.FoldAll},
 		{ID: "edit.unfoldAll", Label: "Unfold All", Category: "Edit", OnExecute: a.UnfoldAll},
-		{ID: "lsp.complete", Label: "LSP Completion", Shortcut: "Ctrl+Space", Category: "Language", OnExecute: a.LspComplete},
-		{ID: "lsp.definition", Label: "Go to Definition", Shortcut: "F12", Category: "Language", OnExecute: a.LspDefinition},
-		{ID: "lsp.references", Label: "Find References", Shortcut: "Shift+F12", Category: "Language", OnExecute: a.LspReferences},
-		{ID: "lsp.hover", Label: "Show Hover", Shortcut: "F1", Category: "Language", OnExecute: a.LspHover},
-		{ID: "lsp.diagnostics", Label: "Show Diagnostics", Shortcut: "F8", Category: "Language", OnExecute: a.LspDiagnostics},
-		{ID: "lsp.rename", Label: "Rename Symbol", Shortcut: "F2", Category: "Language", OnExecute: a.LspRename},
-		{ID: "lsp.codeAction", Label: "Code Actions", Shortcut: "Ctrl+.", Category: "Language", OnExecute: a.LspCodeAction},
+		{ID: "lsp.complete", Label: "LSP Completion", Category: "Language", OnExecute: a.LspComplete},
+		{ID: "lsp.definition", Label: "Go to Definition", Category: "Language", OnExecute: a.LspDefinition},
+		{ID: "lsp.references", Label: "Find References", Category: "Language", OnExecute: a.LspReferences},
+		{ID: "lsp.hover", Label: "Show Hover", Category: "Language", OnExecute: a.LspHover},
+		{ID: "lsp.diagnostics", Label: "Show Diagnostics", Category: "Language", OnExecute: a.LspDiagnostics},
+		{ID: "lsp.rename", Label: "Rename Symbol", Category: "Language", OnExecute: a.LspRename},
+		{ID: "lsp.codeAction", Label: "Code Actions", Category: "Language", OnExecute: a.LspCodeAction},
+		{ID: "macro.record", Label: "Start or Stop Recording Macro", Category: "Macro", OnExecute: a.RecordMacro},
+		{ID: "macro.play", Label: "Play Macro", Category: "Macro", OnExecute: a.PlayMacro},
+		{ID: "macro.playTimes", Label: "Play Macro Several Times", Category: "Macro", OnExecute: a.PlayMacroTimes},
+		{ID: "macro.playOnLines", Label: "Play Macro on Each Selected Line", Category: "Macro", OnExecute: a.PlayMacroOnLines},
+		{ID: "macro.saveRegister", Label: "Save Macro to Register", Category: "Macro", OnExecute: a.SaveMacroRegister},
+		{ID: "macro.playRegister", Label: "Play Macro from Register", Category: "Macro", OnExecute: a.PlayMacroRegister},
+		{ID: "transform.sortLines", Label: "Sort Lines", Category: "Transform", OnExecute: a.SortLines},
+		{ID: "transform.sortLinesNumeric", Label: "Sort Lines Numerically", Category: "Transform", OnExecute: a.SortLinesNumeric},
+		{ID: "transform.sortLinesIgnoreCase", Label: "Sort Lines Ignoring Case", Category: "Transform", OnExecute: a.SortLinesIgnoreCase},
+		{ID: "transform.sortLinesDescending", Label: "Sort Lines Descending", Category: "Transform", OnExecute: a.SortLinesDescending},
+		{ID: "transform.uniqueLines", Label: "Remove Duplicate Lines", Category: "Transform", OnExecute: a.UniqueLines},
+		{ID: "transform.shuffleLines", Label: "Shuffle Lines", Category: "Transform", OnExecute: a.ShuffleLines},
+		{ID: "transform.reverseLines", Label: "Reverse Lines", Category: "Transform", OnExecute: a.ReverseLines},
+		{ID: "transform.joinLines", Label: "Join Lines", Category: "Transform", OnExecute: a.JoinLines},
+		{ID: "transform.splitLines", Label: "Split Lines on Delimiter", Category: "Transform", OnExecute: a.SplitLines},
+		{ID: "transform.transposeChars", Label: "Transpose Characters", Category: "Transform", OnExecute: a.TransposeChars},
+		{ID: "transform.transposeWords", Label: "Transpose Words", Category: "Transform", OnExecute: a.TransposeWords},
+		{ID: "transform.transposeLines", Label: "Transpose Lines", Category: "Transform", OnExecute: a.TransposeLines},
+		{ID: "transform.upperCase", Label: "Transform to Upper Case", Category: "Transform", OnExecute: a.UpperCase},
+		{ID: "transform.lowerCase", Label: "Transform to Lower Case", Category: "Transform", OnExecute: a.LowerCase},
+		{ID: "transform.titleCase", Label: "Transform to Title Case", Category: "Transform", OnExecute: a.TitleCase},
+		{ID: "transform.snakeCase", Label: "Transform to Snake Case", Category: "Transform", OnExecute: a.SnakeCase},
+		{ID: "transform.camelCase", Label: "Transform to Camel Case", Category: "Transform", OnExecute: a.CamelCase},
+		{ID: "transform.kebabCase", Label: "Transform to Kebab Case", Category: "Transform", OnExecute: a.KebabCase},
+		{ID: "transform.filterCommand", Label: "Filter Through Command", Category: "Transform", OnExecute: a.FilterCommand},
+		{ID: "transform.insertCommandOutput", Label: "Insert Command Output", Category: "Transform", OnExecute: a.InsertCommandOutput},
 	}
 }
+
+// SetShortcuts shows the keys bound to each command in km.
+func SetShortcuts(cmds []widgets.PaletteCommand, km *keymap.Keymap) {
+	for i := range cmds {
+		cmds[i].Shortcut = km.Shortcut(cmds[i].ID)
+	}
+}
+
+// editing is the context of commands that act on the text at the cursor.
+const editing = "editorFocus && !largeFile"
+
+// DefaultKeybindings returns the built-in key bindings. Besides the palette
+// commands, keys are bound to app.commandPalette, which opens the palette,
+// to edit.cancelBlockSelection and edit.cancelMultiCursor, to
+// edit.copyCursors and edit.cutCursors, which copy and cut the selection of
+// each of multiple cursors, and to edit.paste, which pastes a copied block
+// back as a block.
+func DefaultKeybindings() []keymap.Binding {
+	return []keymap.Binding{
+		keymap.Bind("ctrl+s", "file.save", ""),
+		keymap.Bind("ctrl+n", "file.new", ""),
+		keymap.Bind("ctrl+w", "file.close", ""),
+		keymap.Bind("ctrl+p", "file.goToFile", ""),
+		keymap.Bind("ctrl+b", "view.sidebar", ""),
+		keymap.Bind("ctrl+alt+w", "view.wrap", "!largeFile"),
+		keymap.Bind("ctrl+pagedown", "view.nextTab", ""),
+		keymap.Bind("ctrl+pageup", "view.prevTab", ""),
+		keymap.Bind("ctrl+q", "app.quit", ""),
+		keymap.Bind("ctrl+shift+p", "app.commandPalette", ""),
+		keymap.Bind("ctrl+y", "edit.redo", ""),
+		keymap.Bind("ctrl+shift+z", "edit.redo", ""),
+		keymap.Bind("ctrl+z", "edit.undo", ""),
+		keymap.Bind("ctrl+f", "edit.find", ""),
+		keymap.Bind("ctrl+h", "edit.replace", "!largeFile"),
+		keymap.Bind("ctrl+g", "edit.gotoLine", ""),
+		keymap.Bind("ctrl+shift+k", "edit.deleteLine", editing),
+		keymap.Bind("alt+up", "edit.moveLineUp", editing),
+		keymap.Bind("alt+down", "edit.moveLineDown", editing),
+		keymap.Bind("ctrl+shift+d", "edit.duplicateLine", editing),
+		keymap.Bind("ctrl+]", "edit.gotoBracket", editing),
+		keymap.Bind("ctrl+shift+[", "edit.fold", editing),
+		keymap.Bind("ctrl+shift+]", "edit.unfold", editing),
+		keymap.Bind("ctrl+d", "edit.addNextOccurrence", editing),
+		keymap.Bind("ctrl+k ctrl+d", "edit.skipOccurrence", editing),
+		keymap.Bind("ctrl+shift+l", "edit.selectAllOccurrences", editing),
+		keymap.Bind("ctrl+alt+up", "edit.addCursorAbove", editing),
+		keymap.Bind("ctrl+alt+down", "edit.addCursorBelow", editing),
+		keymap.Bind("ctrl+u", "edit.undoCursorAdd", editing),
+		keymap.Bind("alt+shift+up", "edit.blockSelectUp", editing),
+		keymap.Bind("alt+shift+down", "edit.blockSelectDown", editing),
+		keymap.Bind("alt+shift+left", "edit.blockSelectLeft", editing),
+		keymap.Bind("alt+shift+right", "edit.blockSelectRight", editing),
+		keymap.Bind("escape", "edit.cancelBlockSelection", "editorFocus && blockSelection"),
+		keymap.Bind("escape", "edit.cancelMultiCursor", "editorFocus && multiCursor"),
+		keymap.Bind("ctrl+c", "edit.copyCursors", editing+" && multiCursor"),
+		keymap.Bind("ctrl+x", "edit.cutCursors", editing+" && multiCursor"),
+		keymap.Bind("ctrl+c", "edit.blockCopy", editing+" && blockSelection"),
+		keymap.Bind("ctrl+x", "edit.blockCut", editing+" && blockSelection"),
+		keymap.Bind("ctrl+v", "edit.paste", editing),
+		keymap.Bind("ctrl+space", "lsp.complete", editing),
+		keymap.Bind("f12", "lsp.definition", editing),
+		keymap.Bind("shift+f12", "lsp.references", editing),
+		keymap.Bind("f1", "lsp.hover", editing),
+		keymap.Bind("f8", "lsp.diagnostics", editing),
+		keymap.Bind("f2", "lsp.rename", editing),
+		keymap.Bind("ctrl+.", "lsp.codeAction", editing),
+		keymap.Bind("ctrl+shift+r", "macro.record", ""),
+		keymap.Bind("ctrl+shift+e", "macro.play", ""),
+	}
+}
+
+// EmacsKeybindings returns the bindings of the Emacs keymap: the Emacs
+// keys, including C-x prefix sequences, on top of the default bindings
+// whose first key they do not take. Besides the palette commands, keys are
+// bound to the emacs.* commands.
+func EmacsKeybindings() []keymap.Binding {
+	emacs := []keymap.Binding{
+		keymap.Bind("ctrl+f", "emacs.forwardChar", editing),
+		keymap.Bind("ctrl+b", "emacs.backwardChar", editing),
+		keymap.Bind("ctrl+n", "emacs.nextLine", editing),
+		keymap.Bind("ctrl+p", "emacs.previousLine", editing),
+		keymap.Bind("ctrl+a", "emacs.lineStart", editing),
+		keymap.Bind("ctrl+e", "emacs.lineEnd", editing),
+		keymap.Bind("alt+f", "emacs.forwardWord", editing),
+		keymap.Bind("alt+b", "emacs.backwardWord", editing),
+		keymap.Bind("ctrl+v", "emacs.pageDown", editing),
+		keymap.Bind("alt+v", "emacs.pageUp", editing),
+		keymap.Bind("alt+<", "emacs.bufferStart", editing),
+		keymap.Bind("alt+>", "emacs.bufferEnd", editing),
+		// With the mark active, the arrow keys extend the region too.
+		keymap.Bind("right", "emacs.forwardChar", editing+" && markActive"),
+		keymap.Bind("left", "emacs.backwardChar", editing+" && markActive"),
+		keymap.Bind("down", "emacs.nextLine", editing+" && markActive"),
+		keymap.Bind("up", "emacs.previousLine", editing+" && markActive"),
+		keymap.Bind("home", "emacs.lineStart", editing+" && markActive"),
+		keymap.Bind("end", "emacs.lineEnd", editing+" && markActive"),
+		keymap.Bind("ctrl+space", "emacs.setMark", editing),
+		keymap.Bind("ctrl+x ctrl+x", "emacs.exchangePointAndMark", editing),
+		keymap.Bind("ctrl+x h", "emacs.markWholeBuffer", editing),
+		keymap.Bind("ctrl+g", "emacs.keyboardQuit", editing),
+		keymap.Bind("ctrl+k", "emacs.killLine", editing),
+		keymap.Bind("ctrl+w", "emacs.killRegion", editing),
+		keymap.Bind("alt+w", "emacs.copyRegion", editing),
+		keymap.Bind("ctrl+w", "edit.cutCursors", editing+" && multiCursor"),
+		keymap.Bind("alt+w", "edit.copyCursors", editing+" && multiCursor"),
+		keymap.Bind("ctrl+w", "edit.blockCut", editing+" && blockSelection"),
+		keymap.Bind("alt+w", "edit.blockCopy", editing+" && blockSelection"),
+		keymap.Bind("ctrl+t", "transform.transposeChars", editing),
+		keymap.Bind("alt+t", "transform.transposeWords", editing),
+		keymap.Bind("ctrl+x ctrl+t", "transform.transposeLines", editing),
+		keymap.Bind("alt+|", "transform.filterCommand", editing),
+		keymap.Bind("alt+!", "transform.insertCommandOutput", editing),
+		keymap.Bind("ctrl+x r y", "edit.blockPaste", editing),
+		keymap.Bind("alt+d", "emacs.killWord", editing),
+		keymap.Bind("alt+backspace", "emacs.backwardKillWord", editing),
+		keymap.Bind("ctrl+d", "emacs.deleteChar", editing),
+		keymap.Bind("ctrl+y", "emacs.yank", editing),
+		keymap.Bind("alt+y", "emacs.yankPop", editing),
+		keymap.Bind("ctrl+s", "emacs.isearchForward", editing),
+		keymap.Bind("ctrl+r", "emacs.isearchBackward", editing),
+		keymap.Bind("ctrl+/", "edit.undo", ""),
+		keymap.Bind("ctrl+_", "edit.undo", ""),
+		keymap.Bind("ctrl+x u", "edit.undo", ""),
+		keymap.Bind("alt+%", "edit.replace", "!largeFile"),
+		keymap.Bind("alt+g g", "edit.gotoLine", ""),
+		keymap.Bind("alt+x", "app.commandPalette", ""),
+		keymap.Bind("alt+/", "lsp.complete", editing),
+		keymap.Bind("ctrl+x ctrl+s", "file.save", ""),
+		keymap.Bind("ctrl+x ctrl+f", "file.goToFile", ""),
+		keymap.Bind("ctrl+x k", "file.close", ""),
+		keymap.Bind("ctrl+x right", "view.nextTab", ""),
+		keymap.Bind("ctrl+x left", "view.prevTab", ""),
+		keymap.Bind("ctrl+x ctrl+c", "app.quit", ""),
+		keymap.Bind("ctrl+x (", "macro.record", ""),
+		keymap.Bind("ctrl+x )", "macro.record", ""),
+		keymap.Bind("ctrl+x e", "macro.play", ""),
+		keymap.Bind("ctrl+x ctrl+k r", "macro.playOnLines", editing),
+		keymap.Bind("ctrl+x ctrl+k x", "macro.saveRegister", ""),
+		keymap.Bind("ctrl+x r j", "macro.playRegister", ""),
+	}
+	taken := make(map[keymap.Chord]bool, len(emacs))
+	for _, b := range emacs {
+		taken[b.Keys[0]] = true
+	}
+	var bindings []keymap.Binding
+	for _, b := range DefaultKeybindings() {
+		if !taken[b.Keys[0]] {
+			bindings = append(bindings, b)
+		}
+	}
+	return append(bindings, emacs...)
+}
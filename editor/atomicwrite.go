@@ -0,0 +1,134 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// writeFileAtomic replaces the file at path with data so that readers see
+// either the old or the new content, never a partial write. The data goes to
+// a temporary file in the same directory, is synced, and is then renamed
+// over the target. Symlinks are followed so the link itself is preserved, and
+// an existing file's mode and (where permitted) ownership carry over. perm is
+// used, less the umask, only when the file does not exist yet.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return writeFileAtomicFrom(path, bytes.NewReader(data), perm)
+}
+
+// writeFileAtomicFrom is writeFileAtomic for content that is streamed from
+// src rather than held in one slice.
+func writeFileAtomicFrom(path string, src io.WriterTo, perm os.FileMode) error {
+	target, err := resolveSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	info, statErr := os.Stat(target)
+	exists := statErr == nil
+	if !exists && !errors.Is(statErr, os.ErrNotExist) {
+		return statErr
+	}
+
+	// A new file is created with perm so the umask applies to it; an
+	// existing file's mode is copied below.
+	createPerm := perm
+	if exists {
+		createPerm = 0600
+	}
+	dir := filepath.Dir(target)
+	tmp, err := createTemp(dir, "."+filepath.Base(target)+".tmp-", createPerm)
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpName)
+		}
+	}()
+
+	if _, err := src.WriteTo(tmp); err != nil {
+		return err
+	}
+	if exists {
+		// Keeping the owner needs privileges we usually lack; a file owned
+		// by the saving user is the expected fallback.
+		_ = preserveOwner(tmp, info)
+		// Changing the owner clears the setuid and setgid bits, so the
+		// mode is set after it.
+		mode := info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+		if err := tmp.Chmod(mode); err != nil {
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, target); err != nil {
+		return err
+	}
+	committed = true
+	syncDir(dir)
+	return nil
+}
+
+// createTemp creates a new file in dir whose name starts with prefix, like
+// os.CreateTemp, but with the given permissions, which the umask reduces.
+func createTemp(dir, prefix string, perm os.FileMode) (*os.File, error) {
+	for range 10000 {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if !errors.Is(err, os.ErrExist) {
+			return f, err
+		}
+	}
+	return nil, &os.PathError{Op: "createtemp", Path: filepath.Join(dir, prefix+"*"), Err: os.ErrExist}
+}
+
+// resolveSymlinks follows path through any symlinks to the file that should
+// be written. A dangling final link resolves to its (not yet existing)
+// destination.
+func resolveSymlinks(path string) (string, error) {
+	for range 40 {
+		info, err := os.Lstat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return path, nil
+		}
+		link, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(path), link)
+		}
+		path = link
+	}
+	return "", errors.New("too many levels of symbolic links")
+}
+
+// syncDir flushes the directory entry for a rename. Errors are ignored since
+// not every platform or filesystem supports syncing directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package editor
+
+import "os"
+
+// preserveOwner is a no-op on platforms without Unix file ownership.
+func preserveOwner(f *os.File, info os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,125 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomicPreservesMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("#!/bin/sh\necho hi\n"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "#!/bin/sh\necho hi\n" {
+		t.Errorf("content = %q", data)
+	}
+}
+
+func TestWriteFileAtomicPreservesSetgid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(path, []byte("v1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	want := os.FileMode(0755) | os.ModeSetgid
+	if err := os.Chmod(path, want); err != nil {
+		t.Skipf("cannot set the setgid bit: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode()&want != want {
+		t.Skip("the file system does not keep the setgid bit")
+	}
+
+	if err := writeFileAtomic(path, []byte("v2"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode() & (os.ModePerm | os.ModeSetgid); got != want {
+		t.Errorf("mode = %v, want %v", got, want)
+	}
+}
+
+func TestWriteFileAtomicNewFileUsesPerm(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "new.txt")
+	if err := writeFileAtomic(path, []byte("x"), 0600); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestWriteFileAtomicFollowsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real.txt")
+	link := filepath.Join(dir, "link.txt")
+	if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("real.txt", link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	if err := writeFileAtomic(link, []byte("new"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink was replaced by a regular file")
+	}
+	data, _ := os.ReadFile(target)
+	if string(data) != "new" {
+		t.Errorf("target content = %q, want %q", data, "new")
+	}
+}
+
+func TestWriteFileAtomicFailureLeavesNoTrace(t *testing.T) {
+	dir := t.TempDir()
+	// Renaming a file over a non-empty directory fails after the data has
+	// been written to the temp file.
+	path := filepath.Join(dir, "occupied")
+	if err := os.MkdirAll(filepath.Join(path, "child"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("data"), 0644); err == nil {
+		t.Fatal("writeFileAtomic over a directory should fail")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "occupied" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("directory contents after failed save = %v, want only the original entry", names)
+	}
+}
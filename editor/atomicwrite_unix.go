@@ -0,0 +1,17 @@
+//go:build unix
+
+package editor
+
+import (
+	"os"
+	"syscall"
+)
+
+// preserveOwner gives f the owner and group recorded in info.
+func preserveOwner(f *os.File, info os.FileInfo) error {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return f.Chown(int(st.Uid), int(st.Gid))
+}
@@ -0,0 +1,215 @@
+package editor
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backupFileVersion is bumped whenever the on-disk backup format changes.
+// Backups written with another version are discarded.
+const backupFileVersion = 1
+
+// ErrNoBackup is returned by Save for a hot exit of a buffer in large-file
+// mode with unsaved changes, which cannot be backed up.
+var ErrNoBackup = errors.New("unsaved changes in large-file mode cannot be backed up")
+
+// ErrBackupConflict is returned by TabManager.RestoreBackup when the
+// backed-up changes conflict with changes made to the file on disk since.
+var ErrBackupConflict = errors.New("conflicts with changes on disk")
+
+// BackupStore keeps snapshots of buffers with unsaved changes, so the changes
+// survive a crash or a quit without saving. Each buffer is written to its own
+// file in Dir, named after the file's path and the process ID, or randomly
+// for untitled buffers, so editors running side by side never touch each
+// other's backups.
+type BackupStore struct {
+	Dir string
+
+	// written holds the state each buffer was in when last written, so
+	// unchanged buffers are not written again.
+	written map[*Buffer]uint64
+}
+
+// NewBackupStore creates a store rooted at dir. The directory is created on
+// first save.
+func NewBackupStore(dir string) *BackupStore {
+	return &BackupStore{Dir: dir, written: make(map[*Buffer]uint64)}
+}
+
+// DefaultBackupDir returns $XDG_STATE_HOME/mane/backup, falling back to
+// ~/.local/state/mane/backup when XDG_STATE_HOME is unset.
+func DefaultBackupDir() (string, error) {
+	return stateDir("backup")
+}
+
+// Backup is an unsaved buffer found in a backup store.
+type Backup struct {
+	ID      string    // identifies the backup within its store
+	Path    string    // file the buffer was editing, or "" if untitled
+	Text    string    // buffer content
+	Time    time.Time // when the backup was written
+	HotExit bool      // written when quitting, to be restored without asking
+
+	// Base is the file content Text was edited from, and DiskHash the
+	// fingerprint of the file on disk then, or "" if there was none.
+	Base     string
+	DiskHash string
+}
+
+// Title returns the base filename of the backup, or "untitled".
+func (bk Backup) Title() string {
+	if bk.Path == "" {
+		return "untitled"
+	}
+	return filepath.Base(bk.Path)
+}
+
+type backupFile struct {
+	Version int       `json:"version"`
+	PID     int       `json:"pid"` // process that wrote the backup
+	Path    string    `json:"path,omitempty"`
+	Text    string    `json:"text"`
+	Time    time.Time `json:"time"`
+	HotExit bool      `json:"hotExit,omitempty"`
+
+	Base     string `json:"base,omitempty"`
+	DiskHash string `json:"diskHash,omitempty"`
+}
+
+// id returns the name b is backed up under, choosing one on first use.
+func (s *BackupStore) id(b *Buffer) string {
+	if b.backupID != "" {
+		return b.backupID
+	}
+	if b.Untitled() {
+		var raw [8]byte
+		_, _ = rand.Read(raw[:])
+		b.backupID = "untitled-" + hex.EncodeToString(raw[:])
+	} else {
+		sum := sha256.Sum256([]byte(b.Path()))
+		b.backupID = hex.EncodeToString(sum[:16]) + "-" + strconv.Itoa(os.Getpid())
+	}
+	return b.backupID
+}
+
+func (s *BackupStore) file(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+// Save writes a backup of b if it has unsaved changes and removes its backup
+// otherwise. A buffer is only written again once its content changed, unless
+// hotExit is set, which marks the backup for restoring without asking.
+// Buffers in large-file mode are not backed up; for a hot exit Save returns
+// ErrNoBackup if one has unsaved changes, since they would be lost.
+func (s *BackupStore) Save(b *Buffer, hotExit bool) error {
+	if s == nil {
+		return nil
+	}
+	if b.Large() {
+		if hotExit && b.Dirty() {
+			return fmt.Errorf("%s: %w", b.Title(), ErrNoBackup)
+		}
+		return nil
+	}
+	if !b.Dirty() {
+		return s.Remove(b)
+	}
+	if state, ok := s.written[b]; ok && state == b.state && !hotExit {
+		return nil
+	}
+	f := backupFile{
+		Version: backupFileVersion,
+		PID:     os.Getpid(),
+		Path:    b.Path(),
+		Text:    b.Text(),
+		Time:    time.Now(),
+		HotExit: hotExit,
+	}
+	if b.disk.exists {
+		f.Base = b.SavedText()
+		f.DiskHash = b.disk.hash
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.file(s.id(b)), data, 0o600); err != nil {
+		return err
+	}
+	s.written[b] = b.state
+	return nil
+}
+
+// Remove deletes the backup of b, if any.
+func (s *BackupStore) Remove(b *Buffer) error {
+	if s == nil {
+		return nil
+	}
+	delete(s.written, b)
+	if b.backupID == "" {
+		return nil
+	}
+	return removeIfExists(s.file(b.backupID))
+}
+
+// List returns the backups left behind by earlier sessions, oldest first.
+// Backups still owned by another running process are skipped; unreadable
+// ones and ones from another format version are deleted.
+func (s *BackupStore) List() ([]Backup, error) {
+	if s == nil {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var backups []Backup
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(s.file(id))
+		if err != nil {
+			continue
+		}
+		var f backupFile
+		if err := json.Unmarshal(data, &f); err != nil || f.Version != backupFileVersion {
+			_ = removeIfExists(s.file(id))
+			continue
+		}
+		if f.PID != os.Getpid() && processAlive(f.PID) {
+			continue
+		}
+		backups = append(backups, Backup{
+			ID: id, Path: f.Path, Text: f.Text, Time: f.Time, HotExit: f.HotExit,
+			Base: f.Base, DiskHash: f.DiskHash,
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.Before(backups[j].Time) })
+	return backups, nil
+}
+
+// Discard deletes the backup with the given ID.
+func (s *BackupStore) Discard(id string) error {
+	if s == nil {
+		return nil
+	}
+	return removeIfExists(s.file(id))
+}
@@ -0,0 +1,242 @@
+package editor
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := NewBackupStore(filepath.Join(dir, "backup"))
+	path := filepath.Join(dir, "file.txt")
+
+	file := openTestFile(t, path, "on disk")
+	file.ApplyText("Edit", "edited")
+	untitled := NewBuffer()
+	untitled.ApplyText("Edit", "scratch")
+	clean := openTestFile(t, filepath.Join(dir, "clean.txt"), "clean")
+
+	for _, b := range []*Buffer{file, untitled, clean} {
+		if err := store.Save(b, false); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	backups, err := store.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("List returned %d backups, want 2 (clean buffers are not backed up)", len(backups))
+	}
+	got := map[string]string{}
+	for _, bk := range backups {
+		got[bk.Path] = bk.Text
+		if bk.HotExit {
+			t.Errorf("backup of %q marked as hot exit", bk.Title())
+		}
+	}
+	if got[path] != "edited" || got[""] != "scratch" {
+		t.Errorf("backups = %v", got)
+	}
+
+	tm := NewTabManager()
+	for _, bk := range backups {
+		if _, err := tm.RestoreBackup(bk); err != nil {
+			t.Fatalf("RestoreBackup: %v", err)
+		}
+	}
+	for _, b := range tm.Buffers() {
+		if !b.Dirty() {
+			t.Errorf("restored %s should be dirty", b.Title())
+		}
+		if want := got[b.Path()]; b.Text() != want {
+			t.Errorf("restored %s text = %q, want %q", b.Title(), b.Text(), want)
+		}
+	}
+	restored := tm.Buffer(0)
+	if restored.Path() != path {
+		restored = tm.Buffer(1)
+	}
+	if !restored.Undo() || restored.Text() != "on disk" {
+		t.Errorf("undo after restore text = %q, want the file content", restored.Text())
+	}
+}
+
+func TestBackupStoreRemovesSavedBuffers(t *testing.T) {
+	dir := t.TempDir()
+	store := NewBackupStore(filepath.Join(dir, "backup"))
+	b := openTestFile(t, filepath.Join(dir, "file.txt"), "one")
+	b.ApplyText("Edit", "two")
+	if err := store.Save(b, false); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := b.Save(); err != nil {
+		t.Fatalf("buffer Save: %v", err)
+	}
+	if err := store.Save(b, false); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if backups, _ := store.List(); len(backups) != 0 {
+		t.Errorf("List after saving the buffer = %d backups, want 0", len(backups))
+	}
+}
+
+func TestBackupStoreHotExit(t *testing.T) {
+	dir := t.TempDir()
+	store := NewBackupStore(filepath.Join(dir, "backup"))
+	b := NewBuffer()
+	b.ApplyText("Edit", "scratch")
+	if err := store.Save(b, false); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	// An unchanged buffer is written again to record the hot exit.
+	if err := store.Save(b, true); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	backups, err := store.List()
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("List = %d backups, %v; want 1", len(backups), err)
+	}
+	if !backups[0].HotExit {
+		t.Error("backup should be marked as hot exit")
+	}
+
+	tm := NewTabManager()
+	tm.SetBackupStore(store)
+	idx, _ := tm.RestoreBackup(backups[0])
+	tm.Buffer(idx).ApplyText("Edit", "scratch, edited")
+	if err := tm.BackupAll(false); err != nil {
+		t.Fatalf("BackupAll: %v", err)
+	}
+	backups, _ = store.List()
+	if len(backups) != 1 || backups[0].Text != "scratch, edited" {
+		t.Fatalf("restored buffer should reuse its backup, got %+v", backups)
+	}
+	tm.Close(idx)
+	if backups, _ := store.List(); len(backups) != 0 {
+		t.Errorf("List after closing the tab = %d backups, want 0", len(backups))
+	}
+}
+
+func TestBackupStoreDeletedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gone.txt")
+	tm := NewTabManager()
+	idx, err := tm.RestoreBackup(Backup{ID: "x", Path: path, Text: "kept"})
+	if err != nil {
+		t.Fatalf("RestoreBackup: %v", err)
+	}
+	b := tm.Buffer(idx)
+	if b.Path() != path || b.Text() != "kept" || !b.Dirty() {
+		t.Fatalf("restored buffer = %q %q dirty=%v", b.Path(), b.Text(), b.Dirty())
+	}
+	if err := b.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "kept" {
+		t.Errorf("file content = %q, want %q", data, "kept")
+	}
+}
+
+func TestBackupStoreFileChangedSince(t *testing.T) {
+	dir := t.TempDir()
+	store := NewBackupStore(filepath.Join(dir, "backup"))
+	path := filepath.Join(dir, "file.txt")
+	b := openTestFile(t, path, "one\ntwo\nthree")
+	b.ApplyText("Edit", "one\ntwo\nTHREE")
+	if err := store.Save(b, true); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("ONE\ntwo\nthree"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	backups, _ := store.List()
+	tm := NewTabManager()
+	idx, err := tm.RestoreBackup(backups[0])
+	if err != nil {
+		t.Fatalf("RestoreBackup: %v", err)
+	}
+	if got := tm.Buffer(idx).Text(); got != "ONE\ntwo\nTHREE" {
+		t.Fatalf("restored text = %q, want both changes merged", got)
+	}
+
+	b = openTestFile(t, path, "ONE\ntwo\nthree")
+	b.ApplyText("Edit", "ONE\nmine\nthree")
+	if err := store.Save(b, true); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("ONE\ntheirs\nthree"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	backups, _ = store.List()
+	tm = NewTabManager()
+	idx, err = tm.RestoreBackup(backups[len(backups)-1])
+	if !errors.Is(err, ErrBackupConflict) {
+		t.Fatalf("RestoreBackup = %v, want ErrBackupConflict", err)
+	}
+	if got := tm.Buffer(idx).Text(); !strings.Contains(got, ConflictMine) {
+		t.Fatalf("restored text = %q, want conflict markers", got)
+	}
+}
+
+func TestBackupStoreLargeHotExit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.log")
+	if err := os.WriteFile(path, []byte("data\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tm := NewTabManager()
+	tm.SetBackupStore(NewBackupStore(filepath.Join(dir, "backup")))
+	tm.SetLargeFileThreshold(1)
+	if _, err := tm.OpenFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.BackupAll(true); err != nil {
+		t.Fatalf("BackupAll with a clean large file = %v", err)
+	}
+	buf := tm.ActiveBuffer()
+	buf.SetReadOnly(false)
+	buf.ApplyEdit(0, "d", "D")
+	if err := tm.BackupAll(true); !errors.Is(err, ErrNoBackup) {
+		t.Fatalf("BackupAll with unsaved large-file changes = %v, want ErrNoBackup", err)
+	}
+	if err := tm.BackupAll(false); err != nil {
+		t.Fatalf("periodic BackupAll = %v", err)
+	}
+}
+
+func TestBackupStoreSkipsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	store := NewBackupStore(dir)
+	write := func(name string, f backupFile) {
+		data, _ := json.Marshal(f)
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("old.json", backupFile{Version: backupFileVersion + 1, Text: "x"})
+	write("running.json", backupFile{Version: backupFileVersion, PID: os.Getppid(), Text: "x"})
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The parent process is running, so its backup is skipped on platforms
+	// where that can be checked.
+	want := 0
+	if !processAlive(os.Getppid()) {
+		want = 1
+	}
+	backups, err := store.List()
+	if err != nil || len(backups) != want {
+		t.Fatalf("List = %+v, %v; want %d backups", backups, err, want)
+	}
+	for name, want := range map[string]bool{"old.json": false, "bad.json": false, "running.json": true} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", name, err == nil, want)
+		}
+	}
+}
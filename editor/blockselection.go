@@ -1,14 +1,26 @@
 package editor
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// defaultBlockTabSize is the tab width of a block selection without one.
+const defaultBlockTabSize = 4
 
 // BlockSelection represents a rectangular text selection spanning multiple lines.
+// Columns are visual: tabs reach the next multiple of TabSize and wide
+// characters take two columns. A character is in the block if its first
+// column is.
 type BlockSelection struct {
 	StartLine int
 	EndLine   int
 	StartCol  int
 	EndCol    int
 	Active    bool
+	TabSize   int // columns per tab stop; 4 when unset
 }
 
 // NewBlockSelection creates an inactive block selection.
@@ -94,46 +106,26 @@ func (bs *BlockSelection) ExtractBlock(text string) []string {
 
 	for i := bs.StartLine; i <= bs.EndLine && i < len(lines); i++ {
 		runes := []rune(lines[i])
-		start := bs.StartCol
-		end := bs.EndCol
-		if start > len(runes) {
-			start = len(runes)
-		}
-		if end > len(runes) {
-			end = len(runes)
-		}
-		if start > end {
-			start = end
-		}
+		start, end := bs.RuneRange(lines[i])
 		result = append(result, string(runes[start:end]))
 	}
 	return result
 }
 
+// RuneRange returns the runes of line inside the block's columns, as rune
+// indices [start, end).
+func (bs *BlockSelection) RuneRange(line string) (int, int) {
+	return RuneColumn(line, bs.StartCol, bs.tabSize()), RuneColumn(line, bs.EndCol, bs.tabSize())
+}
+
 // InsertAtBlock inserts text at each line of the block selection at the start column.
 func (bs *BlockSelection) InsertAtBlock(text string, insert string) string {
 	if !bs.Active {
 		return text
 	}
 	lines := strings.Split(text, "\n")
-	insertRunes := []rune(insert)
-
 	for i := bs.StartLine; i <= bs.EndLine && i < len(lines); i++ {
-		runes := []rune(lines[i])
-		col := bs.StartCol
-		if col > len(runes) {
-			// Pad with spaces
-			pad := make([]rune, col-len(runes))
-			for j := range pad {
-				pad[j] = ' '
-			}
-			runes = append(runes, pad...)
-		}
-		newRunes := make([]rune, 0, len(runes)+len(insertRunes))
-		newRunes = append(newRunes, runes[:col]...)
-		newRunes = append(newRunes, insertRunes...)
-		newRunes = append(newRunes, runes[col:]...)
-		lines[i] = string(newRunes)
+		lines[i] = insertAtColumn(lines[i], bs.StartCol, insert, bs.tabSize())
 	}
 	return strings.Join(lines, "\n")
 }
@@ -147,14 +139,10 @@ func (bs *BlockSelection) DeleteBlock(text string) string {
 
 	for i := bs.StartLine; i <= bs.EndLine && i < len(lines); i++ {
 		runes := []rune(lines[i])
-		start := bs.StartCol
-		end := bs.EndCol
-		if start > len(runes) {
+		start, end := bs.RuneRange(lines[i])
+		if start == end {
 			continue // nothing to delete on this line
 		}
-		if end > len(runes) {
-			end = len(runes)
-		}
 		newRunes := make([]rune, 0, len(runes)-(end-start))
 		newRunes = append(newRunes, runes[:start]...)
 		newRunes = append(newRunes, runes[end:]...)
@@ -162,3 +150,149 @@ func (bs *BlockSelection) DeleteBlock(text string) string {
 	}
 	return strings.Join(lines, "\n")
 }
+
+// DeleteBefore deletes the character before the block's start column on
+// each line, as backspace in an empty block does. Lines ending before the
+// column are left alone.
+func (bs *BlockSelection) DeleteBefore(text string) string {
+	if !bs.Active {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i := bs.StartLine; i <= bs.EndLine && i < len(lines); i++ {
+		at := RuneColumn(lines[i], bs.StartCol, bs.tabSize())
+		if at == 0 || VisualColumn(lines[i], at, bs.tabSize()) < bs.StartCol {
+			continue
+		}
+		runes := []rune(lines[i])
+		lines[i] = string(runes[:at-1]) + string(runes[at:])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FillNumbers replaces the block on each line with a number, counting up
+// from start. The numbers are right-aligned to the widest, and short lines
+// are padded with spaces up to the block.
+func (bs *BlockSelection) FillNumbers(text string, start int) string {
+	if !bs.Active {
+		return text
+	}
+	lines := strings.Split(bs.DeleteBlock(text), "\n")
+	last := min(bs.EndLine, len(lines)-1)
+	width := max(len(strconv.Itoa(start)), len(strconv.Itoa(start+last-bs.StartLine)))
+	for i := bs.StartLine; i <= last; i++ {
+		n := strconv.Itoa(start + i - bs.StartLine)
+		lines[i] = insertAtColumn(lines[i], bs.StartCol, strings.Repeat(" ", width-len(n))+n, bs.tabSize())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PasteBlock inserts the lines of block one below the other at visual
+// column col, starting on line, so they keep their shape. Short lines are
+// padded with spaces up to col, lines are added at the end of text as
+// needed, and block lines followed by text are padded to the block's width
+// so that text stays lined up.
+func PasteBlock(text string, block []string, line, col, tabSize int) string {
+	if len(block) == 0 {
+		return text
+	}
+	if tabSize <= 0 {
+		tabSize = defaultBlockTabSize
+	}
+	lines := strings.Split(text, "\n")
+	for len(lines) < line+len(block) {
+		lines = append(lines, "")
+	}
+	// Tabs in the block are as wide as the column they land in makes them,
+	// so each line is measured from where it is inserted.
+	widths := make([]int, len(block))
+	width := 0
+	for i, b := range block {
+		widths[i] = VisualWidth(columnPrefix(lines[line+i], col, tabSize)+b, tabSize) - col
+		width = max(width, widths[i])
+	}
+	for i, b := range block {
+		target := lines[line+i]
+		if RuneColumn(target, col, tabSize) < len([]rune(target)) {
+			b += strings.Repeat(" ", width-widths[i])
+		}
+		lines[line+i] = insertAtColumn(target, col, b, tabSize)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// columnPrefix returns the part of line that insertAtColumn puts text
+// after, padding included.
+func columnPrefix(line string, col int, tabSize int) string {
+	runes := []rune(line)
+	prefix := string(runes[:RuneColumn(line, col, tabSize)])
+	if pad := col - VisualWidth(prefix, tabSize); pad > 0 {
+		prefix += strings.Repeat(" ", pad)
+	}
+	return prefix
+}
+
+// insertAtColumn inserts insert into line at visual column col, padding the
+// line with spaces if it is shorter.
+func insertAtColumn(line string, col int, insert string, tabSize int) string {
+	runes := []rune(line)
+	at := RuneColumn(line, col, tabSize)
+	if at == len(runes) {
+		if pad := col - VisualWidth(line, tabSize); pad > 0 {
+			return line + strings.Repeat(" ", pad) + insert
+		}
+	}
+	return string(runes[:at]) + insert + string(runes[at:])
+}
+
+func (bs *BlockSelection) tabSize() int {
+	if bs.TabSize <= 0 {
+		return defaultBlockTabSize
+	}
+	return bs.TabSize
+}
+
+// VisualColumn returns the column rune index col of line is shown at, with
+// tabs reaching the next multiple of tabSize and wide characters taking two
+// columns.
+func VisualColumn(line string, col, tabSize int) int {
+	x := 0
+	for i, r := range []rune(line) {
+		if i >= col {
+			break
+		}
+		x += runeCells(r, x, tabSize)
+	}
+	return x
+}
+
+// VisualWidth returns the number of columns line takes.
+func VisualWidth(line string, tabSize int) int {
+	return VisualColumn(line, len([]rune(line)), tabSize)
+}
+
+// RuneColumn returns the index of the first rune of line shown at or after
+// visual column x, or the length of line if there is none; the reverse of
+// VisualColumn.
+func RuneColumn(line string, x, tabSize int) int {
+	sx := 0
+	runes := []rune(line)
+	for i, r := range runes {
+		if sx >= x {
+			return i
+		}
+		sx += runeCells(r, sx, tabSize)
+	}
+	return len(runes)
+}
+
+// runeCells returns the columns r takes when shown at column x.
+func runeCells(r rune, x, tabSize int) int {
+	if r == '\t' {
+		if tabSize <= 0 {
+			tabSize = defaultBlockTabSize
+		}
+		return tabSize - x%tabSize
+	}
+	return runewidth.RuneWidth(r)
+}
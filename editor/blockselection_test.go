@@ -2,6 +2,7 @@ package editor
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -116,3 +117,89 @@ func TestBlockSelectionShortLines(t *testing.T) {
 		t.Errorf("ExtractBlock with short lines = %v, want %v", got, want)
 	}
 }
+
+func TestBlockSelectionVisualColumns(t *testing.T) {
+	// The tab reaches column 4 and each CJK character takes two columns, so
+	// columns 4 to 6 select "xy" on the first line, "語" on the second and
+	// "ef" on the third.
+	text := "a\txyz\n日本語\nabcdefg"
+	bs := &BlockSelection{StartLine: 0, EndLine: 2, StartCol: 4, EndCol: 6, Active: true, TabSize: 4}
+	got := bs.ExtractBlock(text)
+	want := []string{"xy", "語", "ef"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractBlock = %q, want %q", got, want)
+	}
+	if got, want := bs.DeleteBlock(text), "a\tz\n日本\nabcdg"; got != want {
+		t.Errorf("DeleteBlock = %q, want %q", got, want)
+	}
+
+	for _, tc := range []struct {
+		line    string
+		col, x  int
+		tabSize int
+	}{
+		{"\tx", 1, 4, 4},
+		{"ab\tx", 3, 8, 8},
+		{"日本", 1, 2, 4},
+		{"日本", 2, 4, 4},
+	} {
+		if got := VisualColumn(tc.line, tc.col, tc.tabSize); got != tc.x {
+			t.Errorf("VisualColumn(%q, %d) = %d, want %d", tc.line, tc.col, got, tc.x)
+		}
+		if got := RuneColumn(tc.line, tc.x, tc.tabSize); got != tc.col {
+			t.Errorf("RuneColumn(%q, %d) = %d, want %d", tc.line, tc.x, got, tc.col)
+		}
+	}
+	// A column inside a wide character goes to the character after it.
+	if got := RuneColumn("日本", 1, 4); got != 1 {
+		t.Errorf("RuneColumn inside a wide character = %d, want 1", got)
+	}
+}
+
+func TestBlockSelectionFillNumbers(t *testing.T) {
+	text := strings.Repeat("x = ;\n", 10) + "ab"
+	bs := &BlockSelection{StartLine: 0, EndLine: 10, StartCol: 4, EndCol: 4, Active: true}
+	lines := strings.Split(bs.FillNumbers(text, 1), "\n")
+	if lines[0] != "x =  1;" || lines[9] != "x = 10;" {
+		t.Errorf("FillNumbers lines = %q, %q", lines[0], lines[9])
+	}
+	if lines[10] != "ab  11" {
+		t.Errorf("FillNumbers on a short line = %q, want %q", lines[10], "ab  11")
+	}
+
+	// A block with width is replaced.
+	bs = &BlockSelection{StartLine: 0, EndLine: 1, StartCol: 1, EndCol: 3, Active: true}
+	if got, want := bs.FillNumbers("a--b\nc--d", 7), "a7b\nc8d"; got != want {
+		t.Errorf("FillNumbers = %q, want %q", got, want)
+	}
+}
+
+func TestBlockSelectionDeleteBefore(t *testing.T) {
+	// The column is after "ab", inside the tab, which goes, and past the
+	// end of "c".
+	bs := &BlockSelection{StartLine: 0, EndLine: 2, StartCol: 2, EndCol: 2, Active: true}
+	if got, want := bs.DeleteBefore("abc\n\tx\nc"), "ac\nx\nc"; got != want {
+		t.Errorf("DeleteBefore = %q, want %q", got, want)
+	}
+	bs.Set(0, 1, 4, 4)
+	if got, want := bs.DeleteBefore("\tx\n語語x"), "x\n語x"; got != want {
+		t.Errorf("DeleteBefore = %q, want %q", got, want)
+	}
+}
+
+func TestPasteBlock(t *testing.T) {
+	text := "one two\nx\nthree four"
+	got := PasteBlock(text, []string{"AA", "B", "CCC", "D"}, 0, 4, 4)
+	want := "one AA two\nx   B\nthreCCCe four\n    D"
+	if got != want {
+		t.Errorf("PasteBlock = %q, want %q", got, want)
+	}
+	if got := PasteBlock(text, nil, 0, 0, 4); got != text {
+		t.Errorf("PasteBlock with no lines = %q, want the text unchanged", got)
+	}
+	// A tab pasted at column 1 is three columns wide, so the lines below
+	// are padded to three, not four.
+	if got, want := PasteBlock("a|\na|", []string{"\t", "x"}, 0, 1, 4), "a\t|\nax  |"; got != want {
+		t.Errorf("PasteBlock with a tab = %q, want %q", got, want)
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // Range represents a byte range [Start, End) within buffer text.
@@ -12,20 +16,76 @@ type Range struct {
 	Start, End int
 }
 
-// editOp records a single edit for undo/redo support.
+// TextEdit replaces the byte range [Start, End) of the buffer text with Text.
+type TextEdit struct {
+	Start, End int
+	Text       string
+}
+
+// editOp records a single edit for undo/redo support. before and after
+// identify the buffer state on either side of the edit for dirty tracking.
 type editOp struct {
 	offset  int
 	oldText string
 	newText string
+	before  uint64
+	after   uint64
+}
+
+// undoGroup is the unit of undo/redo: one or more edits that are reverted
+// and reapplied together. label describes the group for display, e.g.
+// "Replace All"; it may be empty.
+type undoGroup struct {
+	label string
+	ops   []editOp
 }
 
-// Buffer manages the text content of a single open file.
+// Buffer manages the text content of a single open file. Text is stored in a
+// rope so edits cost O(log n) regardless of file size.
 type Buffer struct {
-	path      string // absolute path, or "" if untitled
-	text      string // current text content
-	savedText string // text at last save/open (for dirty comparison)
-	undoStack []editOp
-	redoStack []editOp
+	path  string    // absolute path, or "" if untitled
+	text  rope      // current text content
+	saved rope      // text at last save/open (snapshot for content comparison)
+	disk  diskStamp // fingerprint of the file at last save/open
+
+	encoding     Encoding   // encoding of the file on disk
+	lineEnding   LineEnding // line ending written on save
+	savedEnding  LineEnding // line ending of the file at last save/open
+	mixedEndings bool       // the file had mixed line endings when read
+
+	// Large-file mode.
+	large        bool            // text is mapped from the file and kept as is
+	readOnly     bool            // edits are ignored
+	unmap        func()          // releases the mapping the text points into
+	unmapCleanup runtime.Cleanup // calls unmap if the buffer is collected
+
+	// backupID names the buffer's backup; see BackupStore.
+	backupID string
+
+	// config holds the .editorconfig properties of the file.
+	config EditorConfig
+
+	// cache holds the materialized text until the next edit.
+	cache      string
+	cacheValid bool
+
+	version    uint64 // incremented on every content change
+	state      uint64 // identity of the current content state
+	savedState uint64 // state at last save/open
+	lastState  uint64 // last state identity handed out
+
+	// nodes is the undo tree; nodes[current] is the state the text is in.
+	nodes   []undoNode
+	current int
+	now     func() time.Time // overrides time.Now for undo timestamps
+
+	// group collects edits between BeginGroup and the matching CommitGroup.
+	group      *undoGroup
+	groupDepth int
+
+	// coalesce reports whether the top undo group may absorb the next
+	// ApplyTyping call with the same label.
+	coalesce bool
 }
 
 // NewBuffer creates a new empty, untitled buffer.
@@ -34,7 +94,9 @@ func NewBuffer() *Buffer {
 }
 
 // Open reads the file at path into the buffer, replacing any existing content.
-// The stored path is converted to an absolute path.
+// The file's encoding is detected and its content decoded to UTF-8, and its
+// line endings are normalized to "\n". The stored path is converted to an
+// absolute path.
 func (b *Buffer) Open(path string) error {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -47,22 +109,30 @@ func (b *Buffer) Open(path string) error {
 	}
 
 	b.path = absPath
-	b.text = string(data)
-	b.savedText = b.text
+	b.setContent(newRope(b.loadText(data, DetectEncoding(data))))
+	b.disk = stampFile(absPath, data)
+	b.markSaved()
+	b.resetHistory()
 	return nil
 }
 
-// Save writes the current text to the stored path.
-// Returns an error if the buffer has no path (untitled).
+// Save writes the current text to the stored path. The write is atomic: on
+// failure the file on disk is left untouched. Returns ErrFileChanged instead
+// of overwriting a file modified on disk since it was last read; use
+// ForceSave to overwrite anyway. Returns an error if the buffer has no path
+// (untitled).
 func (b *Buffer) Save() error {
 	if b.path == "" {
 		return errors.New("buffer has no path; use SaveAs")
 	}
-	if err := os.WriteFile(b.path, []byte(b.text), 0644); err != nil {
+	changed, err := b.DiskChanged()
+	if err != nil {
 		return err
 	}
-	b.savedText = b.text
-	return nil
+	if changed {
+		return ErrFileChanged
+	}
+	return b.writeTo(b.path)
 }
 
 // SaveAs writes the current text to the given path, updates the stored path,
@@ -72,13 +142,42 @@ func (b *Buffer) SaveAs(path string) error {
 	if err != nil {
 		return err
 	}
+	return b.writeTo(absPath)
+}
 
-	if err := os.WriteFile(absPath, []byte(b.text), 0644); err != nil {
+// writeTo atomically writes the text, with the buffer's line ending and
+// encoding, to path and records it as the buffer's saved state. The
+// .editorconfig save rules are applied first, as an undoable edit. Large
+// files are streamed out unchanged.
+func (b *Buffer) writeTo(path string) error {
+	if b.large {
+		var err error
+		if ferr := b.GuardMapped(func() { err = writeFileAtomicFrom(path, b.text, 0644) }); ferr != nil {
+			return ferr
+		}
+		if err != nil {
+			return err
+		}
+		b.path = path
+		b.disk = stampStat(path)
+		b.markSaved()
+		return nil
+	}
+	if edits := b.config.SaveEdits(b.Text()); len(edits) > 0 {
+		b.ApplyEdits("Format on Save", edits)
+	}
+	data, err := Encode(ApplyLineEnding(b.Text(), b.LineEnding()), b.encoding)
+	if err != nil {
 		return err
 	}
-
-	b.path = absPath
-	b.savedText = b.text
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return err
+	}
+	b.path = path
+	b.disk = stampFile(path, data)
+	b.lineEnding = b.LineEnding()
+	b.mixedEndings = false
+	b.markSaved()
 	return nil
 }
 
@@ -87,20 +186,114 @@ func (b *Buffer) Path() string {
 	return b.path
 }
 
-// Text returns the current text content of the buffer.
+// Text returns the current text content of the buffer. The materialized
+// string is cached until the next edit.
 func (b *Buffer) Text() string {
-	return b.text
+	if !b.cacheValid {
+		b.cache = b.text.String()
+		b.cacheValid = true
+	}
+	return b.cache
 }
 
-// SetText updates the buffer's text content. Dirty status is computed by
-// comparing the current text with the saved text.
+// SetText updates the buffer's text content without recording an undo step.
+// Setting text identical to the saved text marks the buffer clean again.
+// CRLF line endings in text are normalized to LF.
 func (b *Buffer) SetText(text string) {
-	b.text = text
+	text = NormalizeLineEndings(text)
+	if text == b.Text() {
+		return
+	}
+	b.setContent(newRope(text))
+	b.cache = text
+	b.cacheValid = true
+	b.coalesce = false
+	if len(text) == b.saved.Len() && text == b.saved.String() {
+		b.state = b.savedState
+	} else {
+		b.state = b.newState()
+	}
 }
 
-// Dirty reports whether the buffer's text differs from the last saved/opened text.
+// Dirty reports whether the buffer's text or line ending differs from the
+// last saved/opened file. It compares state identities, so it costs O(1).
 func (b *Buffer) Dirty() bool {
-	return b.text != b.savedText
+	return b.state != b.savedState || b.lineEnding != b.savedEnding
+}
+
+// Version returns a counter that increases on every content change. It can be
+// used to cheaply detect whether the text changed between two observations.
+func (b *Buffer) Version() uint64 {
+	return b.version
+}
+
+// Len returns the length of the buffer text in bytes.
+func (b *Buffer) Len() int {
+	return b.text.Len()
+}
+
+// Slice returns the text in the byte range [start, end), clamped to the
+// buffer bounds.
+func (b *Buffer) Slice(start, end int) string {
+	if b.cacheValid {
+		start = max(start, 0)
+		end = min(end, len(b.cache))
+		if start >= end {
+			return ""
+		}
+		return b.cache[start:end]
+	}
+	return b.text.Slice(start, end)
+}
+
+// LineCount returns the number of lines in the buffer. An empty buffer has
+// one line.
+func (b *Buffer) LineCount() int {
+	return b.text.Newlines() + 1
+}
+
+// LineOffset returns the byte offset where the given 0-based line starts.
+// Lines past the end clamp to the buffer length.
+func (b *Buffer) LineOffset(line int) int {
+	return b.text.LineStart(line)
+}
+
+// LineAt returns the 0-based line containing the given byte offset.
+func (b *Buffer) LineAt(offset int) int {
+	return b.text.LineOf(offset)
+}
+
+// Line returns the text of the 0-based line without its line terminator.
+func (b *Buffer) Line(line int) string {
+	if line < 0 || line >= b.LineCount() {
+		return ""
+	}
+	text := b.Slice(b.LineOffset(line), b.LineOffset(line+1))
+	if strings.HasSuffix(text, "\n") {
+		text = strings.TrimSuffix(text[:len(text)-1], "\r")
+	}
+	return text
+}
+
+// setContent swaps in new content and invalidates cached views of it.
+func (b *Buffer) setContent(r rope) {
+	b.text = r
+	b.cache = ""
+	b.cacheValid = false
+	b.version++
+}
+
+// markSaved records the current content as the on-disk state.
+func (b *Buffer) markSaved() {
+	b.saved = b.text
+	b.savedState = b.state
+	b.savedEnding = b.lineEnding
+}
+
+// newState hands out a fresh content state identity.
+func (b *Buffer) newState() uint64 {
+	b.lastState++
+	return b.lastState
 }
 
 // Untitled reports whether the buffer has no associated file path.
@@ -116,86 +309,252 @@ func (b *Buffer) Title() string {
 	return filepath.Base(b.path)
 }
 
-// ApplyEdit records the edit on the undo stack, clears the redo stack,
-// and applies the edit to the buffer text. The edit replaces the text at
-// [offset, offset+len(oldText)) with newText.
+// BeginGroup starts an undo group. Every edit recorded until the matching
+// CommitGroup is undone and redone as a single step. Groups nest; only the
+// outermost label is kept.
+func (b *Buffer) BeginGroup(label string) {
+	b.groupDepth++
+	if b.groupDepth == 1 {
+		b.group = &undoGroup{label: label}
+	}
+}
+
+// CommitGroup closes the group opened by the matching BeginGroup. When the
+// outermost group closes, its edits become one new state in the undo tree.
+// Empty groups are discarded.
+func (b *Buffer) CommitGroup() {
+	if b.groupDepth == 0 {
+		return
+	}
+	b.groupDepth--
+	if b.groupDepth > 0 {
+		return
+	}
+	if len(b.group.ops) > 0 {
+		b.pushGroup(*b.group)
+	}
+	b.group = nil
+}
+
+// ApplyEdit records the edit as a new undo state and applies it to the
+// buffer text. The edit replaces the text at [offset, offset+len(oldText))
+// with newText. States previously undone are kept as a separate branch.
+// Inside a group the edit joins the group instead of forming its own undo
+// step. Read-only buffers ignore the edit.
 func (b *Buffer) ApplyEdit(offset int, oldText, newText string) {
-	b.undoStack = append(b.undoStack, editOp{
+	if b.readOnly {
+		return
+	}
+	// Create the root before the state changes, so it records the text the
+	// history starts from.
+	b.undoTree()
+	op := editOp{
 		offset:  offset,
 		oldText: oldText,
 		newText: newText,
+		before:  b.state,
+		after:   b.newState(),
+	}
+	if b.group != nil {
+		b.group.ops = append(b.group.ops, op)
+	} else {
+		b.pushGroup(undoGroup{ops: []editOp{op}})
+	}
+	b.coalesce = false
+	b.setContent(b.text.Replace(offset, len(oldText), newText))
+	b.state = op.after
+}
+
+// ApplyEdits applies edits as a single undo group with the given label.
+// Offsets refer to the text before any of the edits are applied. Edits that
+// fall outside the buffer or overlap an earlier edit are skipped. Returns the
+// number of edits applied. CRLF line endings in the new text are normalized,
+// except in large-file mode, where text is kept as is.
+func (b *Buffer) ApplyEdits(label string, edits []TextEdit) int {
+	if b.readOnly {
+		return 0
+	}
+	sorted := make([]TextEdit, 0, len(edits))
+	for _, e := range edits {
+		if e.Start < 0 || e.End < e.Start || e.Start > b.Len() {
+			continue
+		}
+		e.End = min(e.End, b.Len())
+		if !b.large {
+			e.Text = NormalizeLineEndings(e.Text)
+		}
+		sorted = append(sorted, e)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
 	})
-	b.redoStack = nil
-	b.text = b.text[:offset] + newText + b.text[offset+len(oldText):]
+	valid := sorted[:0]
+	prevEnd := 0
+	for _, e := range sorted {
+		if e.Start < prevEnd {
+			continue
+		}
+		valid = append(valid, e)
+		prevEnd = e.End
+	}
+
+	b.BeginGroup(label)
+	defer b.CommitGroup()
+
+	// Apply from back to front so earlier offsets stay valid.
+	for i := len(valid) - 1; i >= 0; i-- {
+		e := valid[i]
+		if oldText := b.Slice(e.Start, e.End); oldText != e.Text {
+			b.ApplyEdit(e.Start, oldText, e.Text)
+		}
+	}
+	return len(valid)
+}
+
+// ApplyText replaces the whole buffer text with text, recording only the
+// changed region as a single undoable step with the given label. CRLF line
+// endings in text are normalized. Returns false if text is identical to the
+// current content or the buffer is read-only.
+func (b *Buffer) ApplyText(label, text string) bool {
+	return b.applyText(label, text, false)
 }
 
-// Undo reverses the last edit. Returns true if an edit was undone, false if
-// the undo stack is empty.
+// ApplyTyping is like ApplyText, but consecutive calls with the same label
+// whose changes touch are merged into one undo step, so a typed word undoes
+// as a unit. A change that inserts a newline ends the run.
+func (b *Buffer) ApplyTyping(label, text string) bool {
+	return b.applyText(label, text, true)
+}
+
+func (b *Buffer) applyText(label, text string, coalesce bool) bool {
+	if b.readOnly {
+		return false
+	}
+	text = NormalizeLineEndings(text)
+	old := b.Text()
+	if old == text {
+		return false
+	}
+	start, oldEnd, newEnd := ChangedRegion(old, text)
+	merge := coalesce && b.coalesce && b.group == nil &&
+		b.UndoLabel() == label && b.touchesLastEdit(start, oldEnd)
+
+	if merge {
+		op := editOp{
+			offset:  start,
+			oldText: old[start:oldEnd],
+			newText: text[start:newEnd],
+			before:  b.state,
+			after:   b.newState(),
+		}
+		b.mergeIntoCurrent(undoGroup{ops: []editOp{op}})
+		b.setContent(b.text.Replace(op.offset, len(op.oldText), op.newText))
+		b.state = op.after
+	} else {
+		b.BeginGroup(label)
+		b.ApplyEdit(start, old[start:oldEnd], text[start:newEnd])
+		b.CommitGroup()
+	}
+	b.coalesce = coalesce && b.group == nil && !strings.Contains(text[start:newEnd], "\n")
+	return true
+}
+
+// touchesLastEdit reports whether the range [start, end) of the current
+// text overlaps or abuts the text written by the most recent edit.
+func (b *Buffer) touchesLastEdit(start, end int) bool {
+	if b.current == 0 {
+		return false
+	}
+	ops := b.nodes[b.current].group.ops
+	last := ops[len(ops)-1]
+	return start <= last.offset+len(last.newText) && end >= last.offset
+}
+
+// ChangedRegion returns the region where a and b differ: a[start:aEnd] must
+// be replaced by b[start:bEnd] to turn a into b. The bounds never split a
+// UTF-8 sequence.
+func ChangedRegion(a, b string) (start, aEnd, bEnd int) {
+	n := min(len(a), len(b))
+	for start < n && a[start] == b[start] {
+		start++
+	}
+	for start > 0 && start < len(a) && !utf8.RuneStart(a[start]) {
+		start--
+	}
+	aEnd, bEnd = len(a), len(b)
+	for aEnd > start && bEnd > start && a[aEnd-1] == b[bEnd-1] {
+		aEnd--
+		bEnd--
+	}
+	for aEnd < len(a) && !utf8.RuneStart(a[aEnd]) {
+		aEnd++
+		bEnd++
+	}
+	return start, aEnd, bEnd
+}
+
+// Undo moves to the parent of the current undo state. Returns true if
+// anything was undone, false if there is no earlier state.
 func (b *Buffer) Undo() bool {
-	if len(b.undoStack) == 0 {
+	if b.current == 0 || b.group != nil || b.readOnly {
 		return false
 	}
-	op := b.undoStack[len(b.undoStack)-1]
-	b.undoStack = b.undoStack[:len(b.undoStack)-1]
-	// Reverse the edit: replace newText back with oldText.
-	b.text = b.text[:op.offset] + op.oldText + b.text[op.offset+len(op.newText):]
-	b.redoStack = append(b.redoStack, op)
+	b.revertNode(b.current)
+	b.coalesce = false
 	return true
 }
 
-// Redo reapplies the last undone edit. Returns true if an edit was redone,
-// false if the redo stack is empty.
+// Redo moves to the most recently visited child of the current undo state.
+// Returns true if anything was redone, false if there is no later state.
 func (b *Buffer) Redo() bool {
-	if len(b.redoStack) == 0 {
+	if len(b.nodes) == 0 || b.group != nil || b.readOnly {
 		return false
 	}
-	op := b.redoStack[len(b.redoStack)-1]
-	b.redoStack = b.redoStack[:len(b.redoStack)-1]
-	// Reapply the edit.
-	b.text = b.text[:op.offset] + op.newText + b.text[op.offset+len(op.oldText):]
-	b.undoStack = append(b.undoStack, op)
+	next := b.nodes[b.current].redo
+	if next < 0 {
+		return false
+	}
+	b.replayNode(next)
+	b.coalesce = false
 	return true
 }
 
+// UndoLabel returns the label of the step Undo would revert, or "" if there
+// is none or it is unlabeled.
+func (b *Buffer) UndoLabel() string {
+	if b.current == 0 {
+		return ""
+	}
+	return b.nodes[b.current].group.label
+}
+
+// RedoLabel returns the label of the step Redo would reapply, or "" if
+// there is none or it is unlabeled.
+func (b *Buffer) RedoLabel() string {
+	if len(b.nodes) == 0 || b.nodes[b.current].redo < 0 {
+		return ""
+	}
+	return b.nodes[b.nodes[b.current].redo].group.label
+}
+
 // Find returns all byte ranges where query appears as a substring in the
 // buffer text. Returns nil if query is empty or not found.
 func (b *Buffer) Find(query string) []Range {
-	if query == "" {
-		return nil
-	}
-	var results []Range
-	start := 0
-	for {
-		idx := strings.Index(b.text[start:], query)
-		if idx < 0 {
-			break
-		}
-		absIdx := start + idx
-		results = append(results, Range{Start: absIdx, End: absIdx + len(query)})
-		start = absIdx + len(query)
-	}
-	return results
+	ranges, _ := b.FindWithOptions(query, FindOptions{})
+	return ranges
 }
 
 // Replace replaces the text at the given range with replacement, recording
 // the edit on the undo stack.
 func (b *Buffer) Replace(query, replacement string, r Range) {
-	oldText := b.text[r.Start:r.End]
+	oldText := b.Slice(r.Start, r.End)
 	b.ApplyEdit(r.Start, oldText, replacement)
 }
 
 // ReplaceAll replaces all occurrences of query with replacement. Returns the
-// number of replacements made. Each replacement is recorded as a single undo
-// operation processed from back to front so that offsets remain valid.
+// number of replacements made. All replacements form one undo group, so a
+// single Undo reverts them.
 func (b *Buffer) ReplaceAll(query, replacement string) int {
-	ranges := b.Find(query)
-	if len(ranges) == 0 {
-		return 0
-	}
-	// Apply replacements from back to front so earlier offsets stay valid.
-	for i := len(ranges) - 1; i >= 0; i-- {
-		r := ranges[i]
-		b.ApplyEdit(r.Start, query, replacement)
-	}
-	return len(ranges)
+	n, _ := b.ReplaceAllWithOptions(query, replacement, FindOptions{})
+	return n
 }
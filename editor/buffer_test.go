@@ -493,10 +493,263 @@ func TestBufferReplaceAll(t *testing.T) {
 	if b5.Text() != "cc bb cc" {
 		t.Fatalf("text = %q, want %q", b5.Text(), "cc bb cc")
 	}
-	// Undo all the individual replacements
-	b5.Undo()
-	b5.Undo()
+	// A single undo reverts every replacement.
+	if !b5.Undo() {
+		t.Fatal("Undo returned false after ReplaceAll")
+	}
 	if b5.Text() != "aa bb aa" {
 		t.Fatalf("after undo text = %q, want %q", b5.Text(), "aa bb aa")
 	}
 }
+
+func TestBufferUndoToSavedStateIsClean(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "clean.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	b := NewBuffer()
+	if err := b.Open(path); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	b.ApplyEdit(5, "", " world")
+	if !b.Dirty() {
+		t.Fatal("buffer should be dirty after edit")
+	}
+	b.Undo()
+	if b.Dirty() {
+		t.Error("buffer should be clean after undoing back to the saved state")
+	}
+	b.Redo()
+	if !b.Dirty() {
+		t.Error("buffer should be dirty after redo")
+	}
+	if err := b.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	b.Undo()
+	if !b.Dirty() {
+		t.Error("buffer should be dirty after undoing past the saved state")
+	}
+}
+
+func TestBufferVersion(t *testing.T) {
+	b := NewBuffer()
+	v0 := b.Version()
+
+	b.SetText("abc")
+	v1 := b.Version()
+	if v1 <= v0 {
+		t.Fatalf("Version did not advance after SetText: %d -> %d", v0, v1)
+	}
+
+	b.SetText("abc")
+	if b.Version() != v1 {
+		t.Errorf("Version changed after SetText with identical text")
+	}
+
+	b.ApplyEdit(3, "", "d")
+	if b.Version() <= v1 {
+		t.Errorf("Version did not advance after ApplyEdit")
+	}
+	v2 := b.Version()
+	b.Undo()
+	if b.Version() <= v2 {
+		t.Errorf("Version did not advance after Undo")
+	}
+}
+
+func TestBufferLineIndex(t *testing.T) {
+	b := NewBuffer()
+	b.SetText("alpha\nbeta\ngamma")
+
+	if got := b.LineCount(); got != 3 {
+		t.Fatalf("LineCount() = %d, want 3", got)
+	}
+	if got := b.LineOffset(1); got != 6 {
+		t.Errorf("LineOffset(1) = %d, want 6", got)
+	}
+	if got := b.LineOffset(2); got != 11 {
+		t.Errorf("LineOffset(2) = %d, want 11", got)
+	}
+	if got := b.LineAt(8); got != 1 {
+		t.Errorf("LineAt(8) = %d, want 1", got)
+	}
+	if got := b.Slice(6, 10); got != "beta" {
+		t.Errorf("Slice(6, 10) = %q, want %q", got, "beta")
+	}
+
+	b.ApplyEdit(5, "", "\nalpha2")
+	if got := b.LineCount(); got != 4 {
+		t.Errorf("LineCount() after edit = %d, want 4", got)
+	}
+	if got := b.Slice(b.LineOffset(1), b.LineOffset(2)-1); got != "alpha2" {
+		t.Errorf("line 1 after edit = %q, want %q", got, "alpha2")
+	}
+	if got := b.Len(); got != len(b.Text()) {
+		t.Errorf("Len() = %d, want %d", got, len(b.Text()))
+	}
+}
+
+func TestBufferGroupUndoesAsOneStep(t *testing.T) {
+	b := NewBuffer()
+	b.SetText("abc")
+
+	b.BeginGroup("Edit")
+	b.ApplyEdit(3, "", "d")
+	b.BeginGroup("Nested")
+	b.ApplyEdit(0, "a", "A")
+	b.CommitGroup()
+	b.ApplyEdit(1, "b", "B")
+	b.CommitGroup()
+
+	if b.Text() != "ABcd" {
+		t.Fatalf("text = %q, want %q", b.Text(), "ABcd")
+	}
+	if got := b.UndoLabel(); got != "Edit" {
+		t.Errorf("UndoLabel() = %q, want %q", got, "Edit")
+	}
+	if !b.Undo() {
+		t.Fatal("Undo returned false")
+	}
+	if b.Text() != "abc" {
+		t.Fatalf("after undo text = %q, want %q", b.Text(), "abc")
+	}
+	if b.Undo() {
+		t.Error("nested group should not have produced a separate undo step")
+	}
+	if got := b.RedoLabel(); got != "Edit" {
+		t.Errorf("RedoLabel() = %q, want %q", got, "Edit")
+	}
+	if !b.Redo() || b.Text() != "ABcd" {
+		t.Fatalf("after redo text = %q, want %q", b.Text(), "ABcd")
+	}
+}
+
+func TestBufferEmptyGroupIsDiscarded(t *testing.T) {
+	b := NewBuffer()
+	b.ApplyEdit(0, "", "x")
+	b.BeginGroup("Nothing")
+	b.CommitGroup()
+	b.CommitGroup() // unbalanced commits are ignored
+
+	if !b.Undo() || b.Text() != "" {
+		t.Fatalf("Undo should revert the edit before the empty group, text = %q", b.Text())
+	}
+	if b.Undo() {
+		t.Error("empty group should not be on the undo stack")
+	}
+}
+
+func TestBufferApplyEdits(t *testing.T) {
+	b := NewBuffer()
+	b.SetText("one two three")
+
+	n := b.ApplyEdits("Rename", []TextEdit{
+		{Start: 8, End: 13, Text: "3"},
+		{Start: 0, End: 3, Text: "1"},
+		{Start: 4, End: 7, Text: "2"},
+		{Start: 5, End: 6, Text: "overlap"},
+		{Start: 50, End: 60, Text: "out of range"},
+	})
+	if n != 3 {
+		t.Errorf("ApplyEdits applied %d edits, want 3", n)
+	}
+	if b.Text() != "1 2 3" {
+		t.Fatalf("text = %q, want %q", b.Text(), "1 2 3")
+	}
+	b.Undo()
+	if b.Text() != "one two three" {
+		t.Fatalf("after undo text = %q, want %q", b.Text(), "one two three")
+	}
+}
+
+func TestBufferApplyText(t *testing.T) {
+	b := NewBuffer()
+	b.SetText("a\nb\nc\n")
+
+	if b.ApplyText("Same", "a\nb\nc\n") {
+		t.Error("ApplyText with identical text should report no change")
+	}
+	if !b.ApplyText("Multi-Cursor Edit", "ax\nbx\ncx\n") {
+		t.Fatal("ApplyText reported no change")
+	}
+	if b.Text() != "ax\nbx\ncx\n" {
+		t.Fatalf("text = %q", b.Text())
+	}
+	if got := b.UndoLabel(); got != "Multi-Cursor Edit" {
+		t.Errorf("UndoLabel() = %q, want %q", got, "Multi-Cursor Edit")
+	}
+	b.Undo()
+	if b.Text() != "a\nb\nc\n" {
+		t.Fatalf("after undo text = %q", b.Text())
+	}
+}
+
+func TestBufferApplyTypingCoalesces(t *testing.T) {
+	b := NewBuffer()
+	text := ""
+	for _, r := range "hello" {
+		text += string(r)
+		b.ApplyTyping("Typing", text)
+	}
+	b.ApplyTyping("Typing", "hello\n")
+	b.ApplyTyping("Typing", "hello\nw")
+	b.ApplyTyping("Typing", "hello\nwo")
+
+	if !b.Undo() || b.Text() != "hello\n" {
+		t.Fatalf("first undo text = %q, want %q", b.Text(), "hello\n")
+	}
+	if !b.Undo() || b.Text() != "" {
+		t.Fatalf("second undo text = %q, want %q", b.Text(), "")
+	}
+	if b.Undo() {
+		t.Error("typing should have produced exactly two undo steps")
+	}
+}
+
+func TestBufferApplyTypingBreaksOnNonAdjacentEdit(t *testing.T) {
+	b := NewBuffer()
+	b.SetText("abc def")
+	b.ApplyTyping("Typing", "abcX def")
+	b.ApplyTyping("Typing", "abcX defY")
+	b.ApplyTyping("Typing", "abcX defYZ")
+
+	b.Undo()
+	if b.Text() != "abcX def" {
+		t.Fatalf("after undo text = %q, want %q", b.Text(), "abcX def")
+	}
+
+	// An explicit ApplyText never merges into a typing run.
+	b2 := NewBuffer()
+	b2.ApplyTyping("Typing", "a")
+	b2.ApplyText("Typing", "ab")
+	b2.Undo()
+	if b2.Text() != "a" {
+		t.Fatalf("ApplyText merged into typing run, text = %q", b2.Text())
+	}
+}
+
+func TestChangedRegion(t *testing.T) {
+	tests := []struct {
+		a, b              string
+		start, aEnd, bEnd int
+	}{
+		{"hello", "hello", 5, 5, 5},
+		{"hello", "help", 3, 5, 4},
+		{"abc", "aXbc", 1, 1, 2},
+		{"aaa", "aaaa", 3, 3, 4},
+		{"héllo", "hèllo", 1, 3, 3}, // é and è share their first byte
+	}
+	for _, tt := range tests {
+		start, aEnd, bEnd := ChangedRegion(tt.a, tt.b)
+		if start != tt.start || aEnd != tt.aEnd || bEnd != tt.bEnd {
+			t.Errorf("ChangedRegion(%q, %q) = (%d, %d, %d), want (%d, %d, %d)",
+				tt.a, tt.b, start, aEnd, bEnd, tt.start, tt.aEnd, tt.bEnd)
+		}
+		if got := tt.a[:start] + tt.b[start:bEnd] + tt.a[aEnd:]; got != tt.b {
+			t.Errorf("ChangedRegion(%q, %q) does not reconstruct b: %q", tt.a, tt.b, got)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package editor
+
+import "strings"
+
+// DiffKind classifies a line in a diff.
+type DiffKind int
+
+const (
+	DiffEqual DiffKind = iota
+	DiffInsert
+	DiffDelete
+)
+
+// DiffLine is one line of a line-level diff.
+type DiffLine struct {
+	Kind DiffKind
+	Text string
+}
+
+// LineDiff returns the line-level difference between a and b as a sequence
+// of equal, deleted and inserted lines, computed with Myers' algorithm. If
+// the changed region takes more than a few thousand edits, it is reported as
+// deleted and inserted whole.
+func LineDiff(a, b string) []DiffLine {
+	x := strings.Split(a, "\n")
+	y := strings.Split(b, "\n")
+
+	// Trim the common prefix and suffix so the search only covers the
+	// region that changed.
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+
+	out := make([]DiffLine, 0, len(x)+len(y))
+	for _, line := range x[:pre] {
+		out = append(out, DiffLine{Kind: DiffEqual, Text: line})
+	}
+	out = append(out, myersDiff(x[pre:len(x)-suf], y[pre:len(y)-suf])...)
+	for _, line := range x[len(x)-suf:] {
+		out = append(out, DiffLine{Kind: DiffEqual, Text: line})
+	}
+	return out
+}
+
+// maxDiffEdits bounds the number of edits myersDiff searches for. The
+// frontiers it keeps grow with the square of the edit count, so texts that
+// differ by more are diffed as the whole changed region replaced.
+const maxDiffEdits = 2000
+
+// myersDiff computes a shortest edit script between x and y, or deletes all
+// of x and inserts all of y if that takes more than maxDiffEdits edits.
+func myersDiff(x, y []string) []DiffLine {
+	n, m := len(x), len(y)
+	// frontier[d][(k+d)/2] is the furthest i reached on diagonal k = i-j
+	// with d edits; only diagonals -d..d can be reached.
+	var frontier [][]int
+	for d := 0; d <= min(n+m, maxDiffEdits); d++ {
+		v := make([]int, d+1)
+		for k := -d; k <= d; k += 2 {
+			var i int
+			switch {
+			case d == 0:
+			case k == -d || (k != d && frontierAt(frontier, d-1, k-1) < frontierAt(frontier, d-1, k+1)):
+				i = frontierAt(frontier, d-1, k+1)
+			default:
+				i = frontierAt(frontier, d-1, k-1) + 1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			v[(k+d)/2] = i
+			if i >= n && j >= m {
+				return myersBacktrack(x, y, append(frontier, v))
+			}
+		}
+		frontier = append(frontier, v)
+	}
+	out := make([]DiffLine, 0, n+m)
+	for _, line := range x {
+		out = append(out, DiffLine{Kind: DiffDelete, Text: line})
+	}
+	for _, line := range y {
+		out = append(out, DiffLine{Kind: DiffInsert, Text: line})
+	}
+	return out
+}
+
+// frontierAt returns the furthest i reached on diagonal k with d edits.
+func frontierAt(frontier [][]int, d, k int) int {
+	return frontier[d][(k+d)/2]
+}
+
+// myersBacktrack walks the recorded frontiers from the end of both
+// sequences back to the start, emitting the edit script in order.
+func myersBacktrack(x, y []string, frontier [][]int) []DiffLine {
+	i, j := len(x), len(y)
+	var rev []DiffLine
+	for d := len(frontier) - 1; d >= 0; d-- {
+		k := i - j
+		prevK, prevI := 0, 0
+		if d > 0 {
+			if k == -d || (k != d && frontierAt(frontier, d-1, k-1) < frontierAt(frontier, d-1, k+1)) {
+				prevK = k + 1
+			} else {
+				prevK = k - 1
+			}
+			prevI = frontierAt(frontier, d-1, prevK)
+		}
+		prevJ := prevI - prevK
+		for i > prevI && j > prevJ {
+			i--
+			j--
+			rev = append(rev, DiffLine{Kind: DiffEqual, Text: x[i]})
+		}
+		if d == 0 {
+			break
+		}
+		if i == prevI {
+			j--
+			rev = append(rev, DiffLine{Kind: DiffInsert, Text: y[j]})
+		} else {
+			i--
+			rev = append(rev, DiffLine{Kind: DiffDelete, Text: x[i]})
+		}
+	}
+	for l, r := 0, len(rev)-1; l < r; l, r = l+1, r-1 {
+		rev[l], rev[r] = rev[r], rev[l]
+	}
+	return rev
+}
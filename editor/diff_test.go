@@ -0,0 +1,84 @@
+package editor
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// reconstruct rebuilds both sides of a diff.
+func reconstruct(lines []DiffLine) (string, string) {
+	var a, b []string
+	for _, l := range lines {
+		if l.Kind != DiffInsert {
+			a = append(a, l.Text)
+		}
+		if l.Kind != DiffDelete {
+			b = append(b, l.Text)
+		}
+	}
+	return strings.Join(a, "\n"), strings.Join(b, "\n")
+}
+
+func TestLineDiff(t *testing.T) {
+	got := LineDiff("a\nb\nc\nd", "a\nc\nx\nd")
+	want := []DiffLine{
+		{DiffEqual, "a"},
+		{DiffDelete, "b"},
+		{DiffEqual, "c"},
+		{DiffInsert, "x"},
+		{DiffEqual, "d"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("LineDiff returned %d lines, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLineDiffIdentical(t *testing.T) {
+	for _, l := range LineDiff("same\ntext", "same\ntext") {
+		if l.Kind != DiffEqual {
+			t.Fatalf("identical inputs produced %+v", l)
+		}
+	}
+}
+
+func TestLineDiffReconstructsInputs(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	words := []string{"a", "b", "c", "d", ""}
+	randomText := func() string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = words[rng.Intn(len(words))]
+		}
+		return strings.Join(lines, "\n")
+	}
+	for i := 0; i < 500; i++ {
+		a, b := randomText(), randomText()
+		gotA, gotB := reconstruct(LineDiff(a, b))
+		if gotA != a || gotB != b {
+			t.Fatalf("diff of %q -> %q reconstructs %q -> %q", a, b, gotA, gotB)
+		}
+	}
+}
+
+func TestLineDiffManyEdits(t *testing.T) {
+	var x, y []string
+	for i := 0; i < 4000; i++ {
+		x = append(x, fmt.Sprintf("a%d", i))
+		y = append(y, fmt.Sprintf("b%d", i))
+	}
+	a, b := strings.Join(x, "\n"), strings.Join(y, "\n")
+	got := LineDiff(a, b)
+	if len(got) != 8000 {
+		t.Fatalf("LineDiff returned %d lines, want 8000", len(got))
+	}
+	if gotA, gotB := reconstruct(got); gotA != a || gotB != b {
+		t.Fatal("diff past the edit limit does not reconstruct its inputs")
+	}
+}
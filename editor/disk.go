@@ -0,0 +1,217 @@
+package editor
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// ErrFileChanged is returned by Save when the file was modified on disk after
+// the buffer last read or wrote it.
+var ErrFileChanged = errors.New("file changed on disk")
+
+// diskStamp fingerprints the file content a buffer last read or wrote, so
+// external modifications can be detected.
+type diskStamp struct {
+	known   bool // false for untitled buffers
+	exists  bool
+	modTime time.Time
+	size    int64
+	hash    string
+}
+
+// stampFile records the fingerprint of path, whose content is data.
+func stampFile(path string, data []byte) diskStamp {
+	st := stampStat(path)
+	st.hash = contentHash(string(data))
+	return st
+}
+
+// stampStat records the size and modification time of path without hashing
+// its content.
+func stampStat(path string) diskStamp {
+	st := diskStamp{known: true}
+	if info, err := os.Stat(path); err == nil {
+		st.exists = true
+		st.modTime = info.ModTime()
+		st.size = info.Size()
+	}
+	return st
+}
+
+// DiskChanged reports whether the file on disk differs from what the buffer
+// last read or wrote. A changed modification time alone does not count: the
+// content is hashed to rule out touches and rewrites of identical content,
+// except in large-file mode, where any change of size or time counts.
+func (b *Buffer) DiskChanged() (bool, error) {
+	if b.path == "" || !b.disk.known {
+		return false, nil
+	}
+	info, err := os.Stat(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b.disk.exists, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if b.disk.exists && info.ModTime().Equal(b.disk.modTime) && info.Size() == b.disk.size {
+		return false, nil
+	}
+	if b.large {
+		return true, nil
+	}
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return false, err
+	}
+	if b.disk.exists && contentHash(string(data)) == b.disk.hash {
+		// Same content with a new timestamp; remember it to skip rehashing.
+		b.disk.modTime = info.ModTime()
+		b.disk.size = info.Size()
+		return false, nil
+	}
+	return true, nil
+}
+
+// DiskText reads and decodes the current content of the buffer's file.
+func (b *Buffer) DiskText() (string, error) {
+	if b.large {
+		return "", errLargeFile
+	}
+	data, err := b.readDisk()
+	if err != nil {
+		return "", err
+	}
+	return NormalizeLineEndings(Decode(data, b.diskEncoding(data))), nil
+}
+
+func (b *Buffer) readDisk() ([]byte, error) {
+	if b.path == "" {
+		return nil, errors.New("buffer has no path")
+	}
+	return os.ReadFile(b.path)
+}
+
+// diskEncoding returns the encoding to decode data from the buffer's file
+// with: the buffer's encoding, unless a byte order mark says otherwise.
+func (b *Buffer) diskEncoding(data []byte) Encoding {
+	if enc, ok := bomEncoding(data); ok {
+		return enc
+	}
+	if b.encoding == EncodingUTF8BOM || b.encoding == EncodingUTF16LE || b.encoding == EncodingUTF16BE {
+		// The mark was removed, so the file was rewritten by something
+		// else; detect afresh.
+		return DetectEncoding(data)
+	}
+	return b.encoding
+}
+
+// SavedText returns the text as it was when the buffer last read or wrote
+// its file. It is the common base when merging external changes.
+func (b *Buffer) SavedText() string {
+	return b.saved.String()
+}
+
+// Reload replaces the buffer text with the file's current content. The
+// reload is recorded as an undoable step, and the buffer becomes clean. In
+// large-file mode the file is mapped afresh and the history starts over.
+func (b *Buffer) Reload() error {
+	if b.large {
+		return b.reopenLarge()
+	}
+	data, err := b.readDisk()
+	if err != nil {
+		return err
+	}
+	b.reload("Reload", data, b.diskEncoding(data))
+	return nil
+}
+
+// ReopenWithEncoding reads the file again, decoding it with enc. Like Reload,
+// it is recorded as an undoable step.
+func (b *Buffer) ReopenWithEncoding(enc Encoding) error {
+	if b.large {
+		return errLargeFile
+	}
+	data, err := b.readDisk()
+	if err != nil {
+		return err
+	}
+	b.reload("Reopen with Encoding", data, enc)
+	return nil
+}
+
+func (b *Buffer) reload(label string, data []byte, enc Encoding) {
+	b.ApplyText(label, b.loadText(data, enc))
+	b.disk = stampFile(b.path, data)
+	b.markSaved()
+	b.applyEditorConfig()
+}
+
+// loadText decodes data read from the buffer's file with enc and records
+// the encoding and line-ending style. Returns the normalized text.
+func (b *Buffer) loadText(data []byte, enc Encoding) string {
+	text := Decode(data, enc)
+	b.encoding = enc
+	b.lineEnding, b.mixedEndings = DetectLineEnding(text)
+	return NormalizeLineEndings(text)
+}
+
+// Encoding returns the encoding the buffer's file is read and written in.
+func (b *Buffer) Encoding() Encoding {
+	if b.encoding == "" {
+		return EncodingUTF8
+	}
+	return b.encoding
+}
+
+// SaveWithEncoding saves the buffer in enc, which becomes its encoding. The
+// encoding is left unchanged if the save fails.
+func (b *Buffer) SaveWithEncoding(enc Encoding) error {
+	if b.large {
+		return errLargeFile
+	}
+	old := b.encoding
+	b.encoding = enc
+	if err := b.Save(); err != nil {
+		b.encoding = old
+		return err
+	}
+	return nil
+}
+
+// AcknowledgeDiskChange accepts the file's current content as the new base
+// without touching the buffer text, so a following Save overwrites it. The
+// buffer stays dirty unless its text happens to match the file.
+func (b *Buffer) AcknowledgeDiskChange() error {
+	if b.large {
+		b.disk = stampStat(b.path)
+		b.savedState = b.newState()
+		return nil
+	}
+	data, err := b.readDisk()
+	if err != nil {
+		return err
+	}
+	text := Decode(data, b.diskEncoding(data))
+	b.savedEnding, b.mixedEndings = DetectLineEnding(text)
+	text = NormalizeLineEndings(text)
+	b.disk = stampFile(b.path, data)
+	b.saved = newRope(text)
+	if text == b.Text() {
+		b.savedState = b.state
+	} else {
+		// No state in the undo tree matches the file any more.
+		b.savedState = b.newState()
+	}
+	return nil
+}
+
+// ForceSave writes the buffer like Save, but overwrites the file even if it
+// changed on disk since it was last read.
+func (b *Buffer) ForceSave() error {
+	if b.path == "" {
+		return errors.New("buffer has no path; use SaveAs")
+	}
+	return b.writeTo(b.path)
+}
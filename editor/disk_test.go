@@ -0,0 +1,138 @@
+package editor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTemp(t *testing.T, content string) (*Buffer, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	b := NewBuffer()
+	if err := b.Open(path); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return b, path
+}
+
+// writeExternal modifies path as another program would, with a distinct
+// modification time so the change is visible even on coarse filesystems.
+func writeExternal(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("external write: %v", err)
+	}
+	later := time.Now().Add(2 * time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+}
+
+func TestDiskChanged(t *testing.T) {
+	b, path := openTemp(t, "one\n")
+	if changed, err := b.DiskChanged(); err != nil || changed {
+		t.Fatalf("DiskChanged after open = %v, %v; want false", changed, err)
+	}
+
+	// Rewriting identical content only moves the timestamp.
+	writeExternal(t, path, "one\n")
+	if changed, err := b.DiskChanged(); err != nil || changed {
+		t.Fatalf("DiskChanged after touch = %v, %v; want false", changed, err)
+	}
+
+	writeExternal(t, path, "two\n")
+	if changed, err := b.DiskChanged(); err != nil || !changed {
+		t.Fatalf("DiskChanged after external write = %v, %v; want true", changed, err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := b.DiskChanged(); err != nil || !changed {
+		t.Fatalf("DiskChanged after delete = %v, %v; want true", changed, err)
+	}
+}
+
+func TestSaveRefusesExternalChange(t *testing.T) {
+	b, path := openTemp(t, "one\n")
+	b.ApplyText("Edit", "mine\n")
+	writeExternal(t, path, "theirs\n")
+
+	if err := b.Save(); !errors.Is(err, ErrFileChanged) {
+		t.Fatalf("Save error = %v, want ErrFileChanged", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "theirs\n" {
+		t.Fatalf("file overwritten: %q", data)
+	}
+	if !b.Dirty() {
+		t.Error("buffer should stay dirty after refused save")
+	}
+
+	if err := b.ForceSave(); err != nil {
+		t.Fatalf("ForceSave: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "mine\n" {
+		t.Fatalf("file after ForceSave = %q", data)
+	}
+	if b.Dirty() {
+		t.Error("buffer should be clean after ForceSave")
+	}
+	// Our own write is not an external change.
+	if err := b.Save(); err != nil {
+		t.Fatalf("Save after ForceSave: %v", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	b, path := openTemp(t, "one\n")
+	writeExternal(t, path, "two\n")
+
+	if err := b.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if b.Text() != "two\n" || b.Dirty() {
+		t.Fatalf("after Reload text = %q dirty = %v", b.Text(), b.Dirty())
+	}
+	if changed, _ := b.DiskChanged(); changed {
+		t.Error("DiskChanged should be false after Reload")
+	}
+
+	// The reload is undoable; the old text no longer matches the file.
+	if !b.Undo() || b.Text() != "one\n" || !b.Dirty() {
+		t.Fatalf("after Undo text = %q dirty = %v", b.Text(), b.Dirty())
+	}
+}
+
+func TestAcknowledgeDiskChange(t *testing.T) {
+	b, path := openTemp(t, "one\n")
+	b.ApplyText("Edit", "mine\n")
+	writeExternal(t, path, "theirs\n")
+
+	if err := b.AcknowledgeDiskChange(); err != nil {
+		t.Fatalf("AcknowledgeDiskChange: %v", err)
+	}
+	if b.Text() != "mine\n" || !b.Dirty() {
+		t.Fatalf("text = %q dirty = %v; want mine, dirty", b.Text(), b.Dirty())
+	}
+	if b.SavedText() != "theirs\n" {
+		t.Errorf("SavedText = %q, want theirs", b.SavedText())
+	}
+	// Undoing to the original text does not make the buffer clean any more.
+	b.Undo()
+	if !b.Dirty() {
+		t.Error("original state should be dirty after acknowledging change")
+	}
+	b.Redo()
+	if err := b.Save(); err != nil {
+		t.Fatalf("Save after acknowledge: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "mine\n" {
+		t.Fatalf("file = %q, want mine", data)
+	}
+}
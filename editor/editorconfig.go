@@ -0,0 +1,426 @@
+package editor
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EditorConfig holds the properties .editorconfig files set for a file. Zero
+// values mean the property is not set.
+type EditorConfig struct {
+	IndentStyle            string     // "tab" or "space"
+	IndentSize             int        // columns per indentation level
+	TabWidth               int        // columns a tab stands for
+	EndOfLine              LineEnding // CR line endings are not supported
+	Charset                Encoding   // "latin1" maps to Windows-1252
+	TrimTrailingWhitespace bool
+	InsertFinalNewline     bool
+	MaxLineLength          int
+}
+
+// IsZero reports whether no property is set.
+func (c EditorConfig) IsZero() bool {
+	return c == EditorConfig{}
+}
+
+// IndentUnit returns the text one level of indentation inserts, or "" if
+// the indent style is not set.
+func (c EditorConfig) IndentUnit() string {
+	switch c.IndentStyle {
+	case "tab":
+		return "\t"
+	case "space":
+		if c.IndentSize > 0 {
+			return strings.Repeat(" ", c.IndentSize)
+		}
+		return "    "
+	}
+	return ""
+}
+
+// SaveEdits returns the edits the config makes to text when it is saved:
+// trailing whitespace trimmed from every line and a final newline added.
+func (c EditorConfig) SaveEdits(text string) []TextEdit {
+	var edits []TextEdit
+	if c.TrimTrailingWhitespace {
+		start := 0
+		for start <= len(text) {
+			end := strings.IndexByte(text[start:], '\n')
+			if end < 0 {
+				end = len(text)
+			} else {
+				end += start
+			}
+			if trimmed := strings.TrimRight(text[start:end], " \t"); len(trimmed) < end-start {
+				edits = append(edits, TextEdit{Start: start + len(trimmed), End: end})
+			}
+			start = end + 1
+		}
+	}
+	if c.InsertFinalNewline && text != "" && !strings.HasSuffix(text, "\n") {
+		edits = append(edits, TextEdit{Start: len(text), End: len(text), Text: "\n"})
+	}
+	return edits
+}
+
+// editorConfigName is the name of the files LoadEditorConfig reads.
+const editorConfigName = ".editorconfig"
+
+// editorConfigSection is a [glob] section of an .editorconfig file.
+type editorConfigSection struct {
+	match func(rel string) bool
+	props [][2]string // key and value, in file order
+}
+
+// LoadEditorConfig returns the properties that apply to the file at path.
+// Every .editorconfig from the file's directory up to the filesystem root,
+// or to one declaring root = true, is read; closer files and later sections
+// override earlier ones.
+func LoadEditorConfig(path string) (EditorConfig, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return EditorConfig{}, err
+	}
+	type configFile struct {
+		dir      string
+		sections []editorConfigSection
+	}
+	var files []configFile
+	for dir := filepath.Dir(abs); ; {
+		root, sections, err := readEditorConfig(filepath.Join(dir, editorConfigName))
+		if err != nil {
+			return EditorConfig{}, err
+		}
+		files = append(files, configFile{dir, sections})
+		parent := filepath.Dir(dir)
+		if root || parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	props := make(map[string]string)
+	for i := len(files) - 1; i >= 0; i-- {
+		rel, err := filepath.Rel(files[i].dir, abs)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, s := range files[i].sections {
+			if !s.match(rel) {
+				continue
+			}
+			for _, kv := range s.props {
+				if kv[1] == "unset" {
+					delete(props, kv[0])
+				} else {
+					props[kv[0]] = kv[1]
+				}
+			}
+		}
+	}
+	return editorConfigFromProps(props), nil
+}
+
+// readEditorConfig parses the .editorconfig file at path. A missing file has
+// no sections. Lines that do not parse and sections with invalid globs are
+// skipped.
+func readEditorConfig(path string) (root bool, sections []editorConfigSection, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	defer f.Close()
+
+	var current *editorConfigSection
+	preamble := true
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			preamble = false
+			current = nil
+			end := strings.LastIndexByte(line, ']')
+			if end < 0 {
+				continue
+			}
+			if match, ok := editorConfigGlob(line[1:end]); ok {
+				sections = append(sections, editorConfigSection{match: match})
+				current = &sections[len(sections)-1]
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+		switch {
+		case preamble:
+			if key == "root" {
+				root = value == "true"
+			}
+		case current != nil:
+			current.props = append(current.props, [2]string{key, value})
+		}
+	}
+	return root, sections, scanner.Err()
+}
+
+// editorConfigFromProps converts raw properties to an EditorConfig, applying
+// the defaults the EditorConfig specification derives between indent_style,
+// indent_size and tab_width. Unknown values are ignored.
+func editorConfigFromProps(props map[string]string) EditorConfig {
+	var c EditorConfig
+	switch props["indent_style"] {
+	case "tab", "space":
+		c.IndentStyle = props["indent_style"]
+	}
+	c.TabWidth = positiveInt(props["tab_width"])
+	indentSize := props["indent_size"]
+	if indentSize == "" && c.IndentStyle == "tab" {
+		indentSize = "tab"
+	}
+	if indentSize == "tab" {
+		c.IndentSize = c.TabWidth
+	} else {
+		c.IndentSize = positiveInt(indentSize)
+		if c.TabWidth == 0 {
+			c.TabWidth = c.IndentSize
+		}
+	}
+	switch props["end_of_line"] {
+	case "lf":
+		c.EndOfLine = LineEndingLF
+	case "crlf":
+		c.EndOfLine = LineEndingCRLF
+	}
+	switch props["charset"] {
+	case "utf-8":
+		c.Charset = EncodingUTF8
+	case "utf-8-bom":
+		c.Charset = EncodingUTF8BOM
+	case "utf-16le":
+		c.Charset = EncodingUTF16LE
+	case "utf-16be":
+		c.Charset = EncodingUTF16BE
+	case "latin1":
+		c.Charset = EncodingWindows1252
+	}
+	c.TrimTrailingWhitespace = props["trim_trailing_whitespace"] == "true"
+	c.InsertFinalNewline = props["insert_final_newline"] == "true"
+	c.MaxLineLength = positiveInt(props["max_line_length"])
+	return c
+}
+
+func positiveInt(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// editorConfigGlob compiles a section name into a matcher for paths relative
+// to the directory of the .editorconfig file. A glob without a slash matches
+// the file name in any subdirectory.
+func editorConfigGlob(glob string) (func(rel string) bool, bool) {
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	}
+	glob = strings.TrimPrefix(glob, "/")
+
+	var ranges [][2]int
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !translateGlob(glob, &sb, &ranges) {
+		return nil, false
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, false
+	}
+	return func(rel string) bool {
+		m := re.FindStringSubmatch(rel)
+		if m == nil {
+			return false
+		}
+		// Numeric ranges are matched as integers and checked here.
+		for i, r := range ranges {
+			n, err := strconv.Atoi(m[i+1])
+			if err != nil || n < r[0] || n > r[1] {
+				return false
+			}
+		}
+		return true
+	}, true
+}
+
+// translateGlob writes the regular expression for glob to sb. {a,b}
+// alternatives nest; {n1..n2} ranges become capture groups, in order, with
+// their bounds appended to ranges.
+func translateGlob(glob string, sb *strings.Builder, ranges *[][2]int) bool {
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" also matches no directory at all.
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			i += end + 1
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+		case '{':
+			end := matchingBrace(glob, i)
+			if end < 0 {
+				sb.WriteString(`\{`)
+				continue
+			}
+			body := glob[i+1 : end]
+			i = end
+			if lo, hi, ok := numericRange(body); ok {
+				*ranges = append(*ranges, [2]int{lo, hi})
+				sb.WriteString(`([+-]?[0-9]+)`)
+				continue
+			}
+			alts := splitAlternatives(body)
+			if len(alts) < 2 {
+				sb.WriteString(regexp.QuoteMeta("{" + body + "}"))
+				continue
+			}
+			sb.WriteString("(?:")
+			for j, alt := range alts {
+				if j > 0 {
+					sb.WriteString("|")
+				}
+				if !translateGlob(alt, sb, ranges) {
+					return false
+				}
+			}
+			sb.WriteString(")")
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return true
+}
+
+// matchingBrace returns the index of the '}' closing the '{' at open, or -1.
+func matchingBrace(glob string, open int) int {
+	depth := 0
+	for i := open; i < len(glob); i++ {
+		switch glob[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitAlternatives splits the body of a {a,b} group at its top-level commas.
+func splitAlternatives(body string) []string {
+	var alts []string
+	depth, start := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alts = append(alts, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alts, body[start:])
+}
+
+// numericRange parses the body of a {n1..n2} group.
+func numericRange(body string) (lo, hi int, ok bool) {
+	a, b, found := strings.Cut(body, "..")
+	if !found {
+		return 0, 0, false
+	}
+	lo, errA := strconv.Atoi(a)
+	hi, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil {
+		return 0, 0, false
+	}
+	return min(lo, hi), max(lo, hi), true
+}
+
+// EditorConfig returns the .editorconfig properties of the buffer's file.
+func (b *Buffer) EditorConfig() EditorConfig {
+	return b.config
+}
+
+// SetEditorConfig applies the .editorconfig properties of the buffer's file.
+// The line ending and charset it sets are used from the next save on, so a
+// file whose line endings differ is dirty until saved, and every save trims
+// trailing whitespace and adds a final newline if the properties ask for it.
+// Files in large-file mode are saved unchanged.
+func (b *Buffer) SetEditorConfig(c EditorConfig) {
+	b.config = c
+	b.applyEditorConfig()
+}
+
+func (b *Buffer) applyEditorConfig() {
+	if b.large {
+		return
+	}
+	if b.config.EndOfLine != "" {
+		b.SetLineEnding(b.config.EndOfLine)
+	}
+	if b.config.Charset != "" {
+		b.encoding = b.config.Charset
+	}
+}
@@ -0,0 +1,135 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadEditorConfigHierarchy(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ".editorconfig"), `root = true
+
+[*]
+indent_style = space
+indent_size = 4
+end_of_line = lf
+insert_final_newline = true
+
+# Go uses tabs.
+[*.go]
+indent_style = tab
+tab_width = 8
+
+[Makefile]
+indent_style = tab
+`)
+	writeTestFile(t, filepath.Join(dir, "web", ".editorconfig"), `[*.{js,ts}]
+indent_size = 2
+trim_trailing_whitespace = true
+max_line_length = 100
+
+[lib/**.js]
+insert_final_newline = unset
+`)
+
+	tests := []struct {
+		path string
+		want EditorConfig
+	}{
+		{"main.go", EditorConfig{IndentStyle: "tab", IndentSize: 4, TabWidth: 8, EndOfLine: LineEndingLF, InsertFinalNewline: true}},
+		{"sub/Makefile", EditorConfig{IndentStyle: "tab", IndentSize: 4, TabWidth: 4, EndOfLine: LineEndingLF, InsertFinalNewline: true}},
+		{"README.md", EditorConfig{IndentStyle: "space", IndentSize: 4, TabWidth: 4, EndOfLine: LineEndingLF, InsertFinalNewline: true}},
+		{"web/app.ts", EditorConfig{IndentStyle: "space", IndentSize: 2, TabWidth: 2, EndOfLine: LineEndingLF, InsertFinalNewline: true, TrimTrailingWhitespace: true, MaxLineLength: 100}},
+		{"web/lib/deep/x.js", EditorConfig{IndentStyle: "space", IndentSize: 2, TabWidth: 2, EndOfLine: LineEndingLF, TrimTrailingWhitespace: true, MaxLineLength: 100}},
+	}
+	for _, tt := range tests {
+		got, err := LoadEditorConfig(filepath.Join(dir, tt.path))
+		if err != nil {
+			t.Fatalf("LoadEditorConfig(%s): %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("LoadEditorConfig(%s) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestEditorConfigGlob(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		want       bool
+	}{
+		{"*", "a/b/c.txt", true},
+		{"*.go", "pkg/x.go", true},
+		{"*.go", "x.gox", false},
+		{"/top.txt", "top.txt", true},
+		{"/top.txt", "sub/top.txt", false},
+		{"src/*.c", "src/a.c", true},
+		{"src/*.c", "src/sub/a.c", false},
+		{"src/**/*.c", "src/a.c", true},
+		{"src/**/*.c", "src/x/y/a.c", true},
+		{"file?.txt", "file1.txt", true},
+		{"[ab].txt", "b.txt", true},
+		{"[!ab].txt", "b.txt", false},
+		{"*.{js,ts}", "x.ts", true},
+		{"*.{js,ts}", "x.go", false},
+		{"{a,{b,c}}.md", "c.md", true},
+		{"{single}.md", "{single}.md", true},
+		{"v{1..3}.txt", "v2.txt", true},
+		{"v{1..3}.txt", "v4.txt", false},
+	}
+	for _, tt := range tests {
+		match, ok := editorConfigGlob(tt.glob)
+		if !ok {
+			t.Errorf("editorConfigGlob(%q) failed", tt.glob)
+			continue
+		}
+		if got := match(tt.path); got != tt.want {
+			t.Errorf("glob %q on %q = %v, want %v", tt.glob, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestEditorConfigSave(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ".editorconfig"), `root = true
+[*.txt]
+end_of_line = crlf
+trim_trailing_whitespace = true
+insert_final_newline = true
+`)
+	path := filepath.Join(dir, "notes.txt")
+	writeTestFile(t, path, "one  \ntwo\t\nthree")
+
+	tm := NewTabManager()
+	idx, err := tm.OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	buf := tm.Buffer(idx)
+	if buf.LineEnding() != LineEndingCRLF || !buf.Dirty() {
+		t.Errorf("line ending = %s, dirty = %v; want CRLF and dirty until saved", buf.LineEnding(), buf.Dirty())
+	}
+	if err := buf.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "one\r\ntwo\r\nthree\r\n" {
+		t.Errorf("saved %q", data)
+	}
+	if buf.Text() != "one\ntwo\nthree\n" || buf.Dirty() {
+		t.Errorf("buffer after save = %q, dirty = %v", buf.Text(), buf.Dirty())
+	}
+	if !buf.Undo() || buf.Text() != "one  \ntwo\t\nthree" {
+		t.Errorf("undo of the save formatting = %q", buf.Text())
+	}
+}
@@ -0,0 +1,113 @@
+package editor
+
+import "unicode"
+
+// KillRing holds the text removed by the kill commands of the Emacs
+// keymap, newest first, for yanking back. Consecutive kills add to the
+// newest entry instead of starting a new one.
+type KillRing struct {
+	entries []string
+	max     int
+	yank    int // entry the last Yank or YankPop returned
+}
+
+// NewKillRing returns an empty kill ring keeping up to max entries.
+func NewKillRing(max int) *KillRing {
+	return &KillRing{max: max}
+}
+
+// Kill adds text to the ring. With extend set, as for a kill right after
+// another one, text is joined to the newest entry: after it, or before it
+// if before is set, as when killing backwards.
+func (k *KillRing) Kill(text string, extend, before bool) {
+	if text == "" && !extend {
+		return
+	}
+	k.yank = 0
+	if extend && len(k.entries) > 0 {
+		if before {
+			k.entries[0] = text + k.entries[0]
+		} else {
+			k.entries[0] += text
+		}
+		return
+	}
+	k.entries = append([]string{text}, k.entries...)
+	if k.max > 0 && len(k.entries) > k.max {
+		k.entries = k.entries[:k.max]
+	}
+}
+
+// Len returns the number of entries.
+func (k *KillRing) Len() int {
+	return len(k.entries)
+}
+
+// Newest returns the newest entry, or "" if the ring is empty.
+func (k *KillRing) Newest() string {
+	if len(k.entries) == 0 {
+		return ""
+	}
+	return k.entries[0]
+}
+
+// Yank returns the newest entry, reporting false if the ring is empty.
+func (k *KillRing) Yank() (string, bool) {
+	if len(k.entries) == 0 {
+		return "", false
+	}
+	k.yank = 0
+	return k.entries[0], true
+}
+
+// YankPop returns the entry before the one the last Yank or YankPop
+// returned, going round to the newest after the oldest.
+func (k *KillRing) YankPop() (string, bool) {
+	if len(k.entries) == 0 {
+		return "", false
+	}
+	k.yank = (k.yank + 1) % len(k.entries)
+	return k.entries[k.yank], true
+}
+
+// ForwardWord returns the rune offset the Emacs forward-word command moves
+// to from off: the end of the next word.
+func ForwardWord(text []rune, off int) int {
+	for off < len(text) && !isWordRune(text[off]) {
+		off++
+	}
+	for off < len(text) && isWordRune(text[off]) {
+		off++
+	}
+	return off
+}
+
+// BackwardWord returns the rune offset the Emacs backward-word command
+// moves to from off: the start of the word before it.
+func BackwardWord(text []rune, off int) int {
+	for off > 0 && !isWordRune(text[off-1]) {
+		off--
+	}
+	for off > 0 && isWordRune(text[off-1]) {
+		off--
+	}
+	return off
+}
+
+// KillLineEnd returns the end of the text the Emacs kill-line command
+// removes from off: the rest of the line, or the line break too if only
+// blanks are left on the line.
+func KillLineEnd(text []rune, off int) int {
+	end := off
+	blank := true
+	for end < len(text) && text[end] != '\n' {
+		if !unicode.IsSpace(text[end]) {
+			blank = false
+		}
+		end++
+	}
+	if blank && end < len(text) {
+		end++
+	}
+	return end
+}
@@ -0,0 +1,74 @@
+package editor
+
+import "testing"
+
+func TestKillRing(t *testing.T) {
+	k := NewKillRing(3)
+	if _, ok := k.Yank(); ok {
+		t.Fatal("Yank on an empty ring should fail")
+	}
+	k.Kill("one", false, false)
+	k.Kill(" more", true, false)
+	k.Kill("before ", true, true)
+	if got, _ := k.Yank(); got != "before one more" || k.Len() != 1 {
+		t.Errorf("accumulated kill = %q (%d entries)", got, k.Len())
+	}
+	k.Kill("two", false, false)
+	k.Kill("three", false, false)
+	k.Kill("four", false, false)
+	if k.Len() != 3 {
+		t.Errorf("Len = %d, want the maximum of 3", k.Len())
+	}
+	var got []string
+	if s, _ := k.Yank(); s != "" {
+		got = append(got, s)
+	}
+	for range 3 {
+		s, _ := k.YankPop()
+		got = append(got, s)
+	}
+	want := []string{"four", "three", "two", "four"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Yank and YankPop = %q, want %q", got, want)
+		}
+	}
+	k.Kill("", false, false)
+	if k.Newest() != "four" {
+		t.Errorf("an empty kill should not add an entry, newest = %q", k.Newest())
+	}
+}
+
+func TestEmacsWordMotions(t *testing.T) {
+	text := []rune("foo.bar  baz")
+	for _, tc := range []struct{ from, forward, backward int }{
+		{0, 3, 0},
+		{3, 7, 0},
+		{5, 7, 4},
+		{7, 12, 4},
+		{12, 12, 9},
+	} {
+		if got := ForwardWord(text, tc.from); got != tc.forward {
+			t.Errorf("ForwardWord from %d = %d, want %d", tc.from, got, tc.forward)
+		}
+		if got := BackwardWord(text, tc.from); got != tc.backward {
+			t.Errorf("BackwardWord from %d = %d, want %d", tc.from, got, tc.backward)
+		}
+	}
+}
+
+func TestKillLineEnd(t *testing.T) {
+	text := []rune("foo bar\n  \nbaz")
+	for _, tc := range []struct{ from, want int }{
+		{0, 7},
+		{4, 7},
+		{7, 8},
+		{8, 11},
+		{11, 14},
+		{14, 14},
+	} {
+		if got := KillLineEnd(text, tc.from); got != tc.want {
+			t.Errorf("KillLineEnd from %d = %d, want %d", tc.from, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding names a character encoding a file can be read and written in.
+// Buffers always hold UTF-8 text; the encoding only applies on disk.
+type Encoding string
+
+const (
+	EncodingUTF8         Encoding = "UTF-8"
+	EncodingUTF8BOM      Encoding = "UTF-8 with BOM"
+	EncodingUTF16LE      Encoding = "UTF-16 LE"
+	EncodingUTF16BE      Encoding = "UTF-16 BE"
+	EncodingUTF16LENoBOM Encoding = "UTF-16 LE without BOM"
+	EncodingUTF16BENoBOM Encoding = "UTF-16 BE without BOM"
+	EncodingWindows1252  Encoding = "Windows-1252"
+)
+
+// Encodings lists the supported encodings.
+var Encodings = []Encoding{
+	EncodingUTF8,
+	EncodingUTF8BOM,
+	EncodingUTF16LE,
+	EncodingUTF16BE,
+	EncodingUTF16LENoBOM,
+	EncodingUTF16BENoBOM,
+	EncodingWindows1252,
+}
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// ParseEncoding looks up an encoding by name, ignoring case, spaces and
+// dashes, so "utf16le" and "UTF-16 LE" are the same.
+func ParseEncoding(name string) (Encoding, bool) {
+	norm := func(s string) string {
+		return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(s))
+	}
+	want := norm(name)
+	for _, enc := range Encodings {
+		if norm(string(enc)) == want {
+			return enc, true
+		}
+	}
+	switch want {
+	case "utf8bom":
+		return EncodingUTF8BOM, true
+	case "cp1252", "latin1", "iso88591":
+		return EncodingWindows1252, true
+	}
+	return "", false
+}
+
+// DetectEncoding guesses the encoding of data. A byte order mark decides;
+// otherwise NUL bytes in every other position mark UTF-16, valid UTF-8 is
+// taken as UTF-8, and anything else as Windows-1252, which can decode any
+// byte sequence.
+func DetectEncoding(data []byte) Encoding {
+	if enc, ok := bomEncoding(data); ok {
+		return enc
+	}
+	if enc, ok := guessUTF16(data); ok {
+		return enc
+	}
+	if utf8.Valid(data) {
+		return EncodingUTF8
+	}
+	return EncodingWindows1252
+}
+
+// utf16Sample is how many bytes guessUTF16 looks at.
+const utf16Sample = 4096
+
+// guessUTF16 recognizes UTF-16 without a byte order mark from the start of
+// data. Text that is mostly ASCII has a NUL in every other byte: the second
+// of each pair in little-endian order, the first in big-endian order. The
+// BOM-less encodings are returned so the file is saved without a mark too.
+func guessUTF16(data []byte) (Encoding, bool) {
+	n := min(len(data), utf16Sample) &^ 1
+	pairs := n / 2
+	if pairs == 0 {
+		return "", false
+	}
+	first, second := 0, 0
+	for i := 0; i < n; i += 2 {
+		if data[i] == 0 {
+			first++
+		}
+		if data[i+1] == 0 {
+			second++
+		}
+	}
+	switch {
+	case second*2 > pairs && first*10 < pairs:
+		return EncodingUTF16LENoBOM, true
+	case first*2 > pairs && second*10 < pairs:
+		return EncodingUTF16BENoBOM, true
+	}
+	return "", false
+}
+
+// bomEncoding reports the encoding announced by a byte order mark.
+func bomEncoding(data []byte) (Encoding, bool) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return EncodingUTF8BOM, true
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return EncodingUTF16LE, true
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return EncodingUTF16BE, true
+	}
+	return "", false
+}
+
+// Decode converts data in the given encoding to UTF-8, dropping any byte
+// order mark. Undecodable UTF-16 becomes U+FFFD; invalid UTF-8 is kept as is
+// so it is written back unchanged.
+func Decode(data []byte, enc Encoding) string {
+	switch enc {
+	case EncodingUTF8BOM:
+		return string(bytes.TrimPrefix(data, bomUTF8))
+	case EncodingUTF16LE, EncodingUTF16BE, EncodingUTF16LENoBOM, EncodingUTF16BENoBOM:
+		return decodeUTF16(data, enc == EncodingUTF16BE || enc == EncodingUTF16BENoBOM)
+	case EncodingWindows1252:
+		var sb strings.Builder
+		sb.Grow(len(data))
+		for _, c := range data {
+			sb.WriteRune(windows1252ToRune(c))
+		}
+		return sb.String()
+	default:
+		return string(data)
+	}
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	bom := bomUTF16LE
+	if bigEndian {
+		bom = bomUTF16BE
+	}
+	data = bytes.TrimPrefix(data, bom)
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+		}
+	}
+	text := string(utf16.Decode(units))
+	if len(data)%2 != 0 {
+		text += string(utf8.RuneError)
+	}
+	return text
+}
+
+// Encode converts UTF-8 text to the given encoding, adding the byte order
+// mark the encoding calls for. It fails if the text contains characters the
+// encoding cannot represent.
+func Encode(text string, enc Encoding) ([]byte, error) {
+	switch enc {
+	case EncodingUTF8BOM:
+		return append(append([]byte{}, bomUTF8...), text...), nil
+	case EncodingUTF16LE, EncodingUTF16BE, EncodingUTF16LENoBOM, EncodingUTF16BENoBOM:
+		bigEndian := enc == EncodingUTF16BE || enc == EncodingUTF16BENoBOM
+		var bom []byte
+		switch enc {
+		case EncodingUTF16LE:
+			bom = bomUTF16LE
+		case EncodingUTF16BE:
+			bom = bomUTF16BE
+		}
+		units := utf16.Encode([]rune(text))
+		out := make([]byte, 0, len(bom)+2*len(units))
+		out = append(out, bom...)
+		for _, u := range units {
+			if bigEndian {
+				out = append(out, byte(u>>8), byte(u))
+			} else {
+				out = append(out, byte(u), byte(u>>8))
+			}
+		}
+		return out, nil
+	case EncodingWindows1252:
+		out := make([]byte, 0, len(text))
+		line := 1
+		for _, r := range text {
+			c, ok := runeToWindows1252(r)
+			if !ok {
+				return nil, fmt.Errorf("line %d: %q cannot be encoded as %s", line, r, enc)
+			}
+			if r == '\n' {
+				line++
+			}
+			out = append(out, c)
+		}
+		return out, nil
+	case EncodingUTF8, "":
+		return []byte(text), nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", enc)
+	}
+}
+
+// windows1252High maps bytes 0x80-0x9F to runes. The five bytes Windows-1252
+// leaves undefined map to the C1 controls of the same value, as in Latin-1,
+// so every byte sequence round-trips.
+var windows1252High = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+func windows1252ToRune(c byte) rune {
+	if c >= 0x80 && c < 0xA0 {
+		return windows1252High[c-0x80]
+	}
+	return rune(c)
+}
+
+func runeToWindows1252(r rune) (byte, bool) {
+	if r < 0x80 || (r >= 0xA0 && r <= 0xFF) {
+		return byte(r), true
+	}
+	for i, hr := range windows1252High {
+		if hr == r {
+			return byte(0x80 + i), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,193 @@
+package editor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Encoding
+	}{
+		{"empty", nil, EncodingUTF8},
+		{"ascii", []byte("hello\n"), EncodingUTF8},
+		{"utf8", []byte("héllo\n"), EncodingUTF8},
+		{"utf8 bom", []byte("\xEF\xBB\xBFhi"), EncodingUTF8BOM},
+		{"utf16le bom", []byte("\xFF\xFEh\x00i\x00"), EncodingUTF16LE},
+		{"utf16be bom", []byte("\xFE\xFF\x00h\x00i"), EncodingUTF16BE},
+		{"utf16le without bom", []byte("h\x00\xe9\x00\n\x00"), EncodingUTF16LENoBOM},
+		{"utf16be without bom", []byte("\x00h\x00\xe9\x00\n"), EncodingUTF16BENoBOM},
+		{"single nul", []byte("a\x00b\n"), EncodingUTF8},
+		{"windows-1252", []byte("caf\xe9 \x93quoted\x94"), EncodingWindows1252},
+	}
+	for _, tt := range tests {
+		if got := DetectEncoding(tt.data); got != tt.want {
+			t.Errorf("%s: DetectEncoding = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	text := "naïve café — “quoted” €5\nsecond line\n"
+	for _, enc := range Encodings {
+		data, err := Encode(text, enc)
+		if err != nil {
+			t.Fatalf("Encode(%s): %v", enc, err)
+		}
+		if got := DetectEncoding(data); got != enc {
+			t.Errorf("DetectEncoding(Encode(%s)) = %s", enc, got)
+		}
+		if got := Decode(data, enc); got != text {
+			t.Errorf("Decode(Encode(%s)) = %q, want %q", enc, got, text)
+		}
+	}
+}
+
+func TestDecodeWindows1252(t *testing.T) {
+	data := []byte{'a', 0x80, 0x81, 0x93, 0xE9, 0xFF}
+	got := Decode(data, EncodingWindows1252)
+	if want := "a€\u0081“éÿ"; got != want {
+		t.Fatalf("Decode = %q, want %q", got, want)
+	}
+	back, err := Encode(got, EncodingWindows1252)
+	if err != nil || !bytes.Equal(back, data) {
+		t.Fatalf("Encode = %v, %v; want %v", back, err, data)
+	}
+}
+
+func TestEncodeUnrepresentable(t *testing.T) {
+	if _, err := Encode("ok\n日本", EncodingWindows1252); err == nil {
+		t.Fatal("expected error encoding CJK text as Windows-1252")
+	}
+}
+
+func TestDecodeUTF16Surrogates(t *testing.T) {
+	data, err := Encode("a😀b", EncodingUTF16LE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Decode(data, EncodingUTF16LE); got != "a😀b" {
+		t.Fatalf("Decode = %q", got)
+	}
+	// A dangling odd byte decodes to a replacement character.
+	if got := Decode(append(data, 'x'), EncodingUTF16LE); got != "a😀b�" {
+		t.Fatalf("Decode odd length = %q", got)
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	tests := map[string]Encoding{
+		"utf-8":        EncodingUTF8,
+		"UTF8":         EncodingUTF8,
+		"utf-8 bom":    EncodingUTF8BOM,
+		"utf16le":      EncodingUTF16LE,
+		"UTF-16 BE":    EncodingUTF16BE,
+		"windows-1252": EncodingWindows1252,
+		"cp1252":       EncodingWindows1252,
+	}
+	for name, want := range tests {
+		if got, ok := ParseEncoding(name); !ok || got != want {
+			t.Errorf("ParseEncoding(%q) = %q, %v; want %q", name, got, ok, want)
+		}
+	}
+	if _, ok := ParseEncoding("ebcdic"); ok {
+		t.Error("ParseEncoding accepted an unknown encoding")
+	}
+}
+
+func TestBufferPreservesEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.ini")
+	original := []byte("name=caf\xe9\r\n")
+	if err := os.WriteFile(path, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := NewBuffer()
+	if err := b.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if b.Encoding() != EncodingWindows1252 || b.Text() != "name=café\n" {
+		t.Fatalf("Open: encoding %s, text %q", b.Encoding(), b.Text())
+	}
+
+	b.ApplyText("Edit", "name=crème\n")
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := []byte("name=cr\xe8me\r\n"); !bytes.Equal(data, want) {
+		t.Fatalf("saved %q, want %q", data, want)
+	}
+
+	// Text the encoding cannot hold is refused, leaving the file intact.
+	b.ApplyText("Edit", "name=日本\n")
+	if err := b.Save(); err == nil {
+		t.Fatal("Save succeeded with unrepresentable text")
+	}
+	if err := b.SaveWithEncoding(EncodingUTF16LE); err != nil {
+		t.Fatal(err)
+	}
+	if b.Encoding() != EncodingUTF16LE || b.Dirty() {
+		t.Fatalf("after SaveWithEncoding: encoding %s, dirty %v", b.Encoding(), b.Dirty())
+	}
+	data, _ = os.ReadFile(path)
+	if DetectEncoding(data) != EncodingUTF16LE || Decode(data, EncodingUTF16LE) != "name=日本\r\n" {
+		t.Fatalf("saved %q", data)
+	}
+	// Saving in an encoding that cannot hold the text keeps the old one.
+	if err := b.SaveWithEncoding(EncodingWindows1252); err == nil || b.Encoding() != EncodingUTF16LE {
+		t.Fatalf("SaveWithEncoding: err %v, encoding %s", err, b.Encoding())
+	}
+}
+
+func TestBufferPreservesMissingUTF16BOM(t *testing.T) {
+	for _, original := range [][]byte{
+		[]byte("h\x00i\x00\n\x00"),
+		[]byte("\x00h\x00i\x00\n"),
+	} {
+		path := filepath.Join(t.TempDir(), "file.txt")
+		if err := os.WriteFile(path, original, 0644); err != nil {
+			t.Fatal(err)
+		}
+		b := NewBuffer()
+		if err := b.Open(path); err != nil {
+			t.Fatal(err)
+		}
+		if b.Text() != "hi\n" {
+			t.Fatalf("Open %q: text %q", original, b.Text())
+		}
+		if err := b.Save(); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(path); !bytes.Equal(data, original) {
+			t.Errorf("saved %q, want %q", data, original)
+		}
+	}
+}
+
+func TestBufferReopenWithEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	// Valid UTF-8 that was really meant as Windows-1252.
+	if err := os.WriteFile(path, []byte("\xc3\xa9"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := NewBuffer()
+	if err := b.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if b.Text() != "é" {
+		t.Fatalf("Open text = %q", b.Text())
+	}
+	if err := b.ReopenWithEncoding(EncodingWindows1252); err != nil {
+		t.Fatal(err)
+	}
+	if b.Text() != "Ã©" || b.Encoding() != EncodingWindows1252 || b.Dirty() {
+		t.Fatalf("after reopen: text %q, encoding %s, dirty %v", b.Text(), b.Encoding(), b.Dirty())
+	}
+	if !b.Undo() || b.Text() != "é" {
+		t.Fatalf("reopen should be undoable, text %q", b.Text())
+	}
+}
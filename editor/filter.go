@@ -0,0 +1,59 @@
+package editor
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// filterWaitDelay is how long Filter waits for a cancelled command's output
+// pipes to close before giving up on them.
+const filterWaitDelay = time.Second
+
+// FilterError is the error of a filter command that failed.
+type FilterError struct {
+	Err    error
+	Stderr string // what the command wrote to its standard error
+}
+
+// Error returns the command's standard error, or the error running it if
+// it wrote none.
+func (e *FilterError) Error() string {
+	if msg := strings.TrimSpace(e.Stderr); msg != "" {
+		return msg
+	}
+	return e.Err.Error()
+}
+
+func (e *FilterError) Unwrap() error { return e.Err }
+
+// Filter runs command with the shell in dir, input on its standard input,
+// and returns what it writes to its standard output, with LF line endings.
+// When input does not end in a line break, one at the end of the output is
+// dropped, so filtering part of a line keeps it one line. A command that
+// fails returns a *FilterError. Cancelling ctx kills the command and every
+// process it started.
+func Filter(ctx context.Context, command, input, dir string) (string, error) {
+	args := shellArgs(command)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	killGroupOnCancel(cmd)
+	cmd.WaitDelay = filterWaitDelay
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return "", &FilterError{Err: err, Stderr: stderr.String()}
+	}
+	out := NormalizeLineEndings(stdout.String())
+	if !strings.HasSuffix(input, "\n") {
+		out = strings.TrimSuffix(out, "\n")
+	}
+	return out, nil
+}
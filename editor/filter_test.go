@@ -0,0 +1,53 @@
+//go:build unix
+
+package editor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFilter(t *testing.T) {
+	ctx := context.Background()
+	got, err := Filter(ctx, "sort -u", "b\na\nb\n", t.TempDir())
+	if err != nil || got != "a\nb\n" {
+		t.Errorf("Filter(sort -u) = %q, %v; want %q", got, err, "a\nb\n")
+	}
+	// Without a final line break in the input, none is added.
+	got, err = Filter(ctx, "tr a-z A-Z", "abc", t.TempDir())
+	if err != nil || got != "ABC" {
+		t.Errorf("Filter(tr) = %q, %v; want %q", got, err, "ABC")
+	}
+	got, err = Filter(ctx, "printf 'x\\r\\ny\\r\\n'", "", t.TempDir())
+	if err != nil || got != "x\ny" {
+		t.Errorf("Filter(printf) = %q, %v; want %q", got, err, "x\ny")
+	}
+}
+
+func TestFilterFailure(t *testing.T) {
+	_, err := Filter(context.Background(), "echo oops >&2; exit 3", "", t.TempDir())
+	var ferr *FilterError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("Filter error = %v, want a *FilterError", err)
+	}
+	if err.Error() != "oops" {
+		t.Errorf("Filter error = %q, want %q", err.Error(), "oops")
+	}
+}
+
+func TestFilterCancelKillsChildren(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	// The shell waits on a child holding its output open; cancelling must
+	// not wait for the child to finish.
+	_, err := Filter(ctx, "sleep 30 | cat", "", t.TempDir())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Filter error = %v, want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("cancelled Filter took %v", d)
+	}
+}
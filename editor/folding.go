@@ -72,6 +72,28 @@ func (fs *FoldState) IsLineHidden(line int) bool {
 	return false
 }
 
+// Folded returns the start lines of the folded regions.
+func (fs *FoldState) Folded() []int {
+	var lines []int
+	for _, r := range fs.regions {
+		if r.Folded {
+			lines = append(lines, r.StartLine)
+		}
+	}
+	return lines
+}
+
+// SetFolded folds exactly the regions starting at the given lines.
+func (fs *FoldState) SetFolded(lines []int) {
+	folded := make(map[int]bool, len(lines))
+	for _, line := range lines {
+		folded[line] = true
+	}
+	for i := range fs.regions {
+		fs.regions[i].Folded = folded[fs.regions[i].StartLine]
+	}
+}
+
 // Regions returns all fold regions.
 func (fs *FoldState) Regions() []FoldRegion {
 	return fs.regions
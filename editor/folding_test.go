@@ -117,6 +117,20 @@ func TestVisibleLines(t *testing.T) {
 	}
 }
 
+func TestSetFolded(t *testing.T) {
+	fs := NewFoldState()
+	fs.SetRegions([]FoldRegion{
+		{StartLine: 0, EndLine: 5, Folded: true},
+		{StartLine: 10, EndLine: 15},
+		{StartLine: 20, EndLine: 25},
+	})
+	fs.SetFolded([]int{10, 20, 30})
+
+	if got, want := fs.Folded(), []int{10, 20}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Folded = %v, want %v", got, want)
+	}
+}
+
 func TestFoldAtLine(t *testing.T) {
 	fs := NewFoldState()
 	fs.SetRegions([]FoldRegion{
@@ -88,3 +88,22 @@ func ComputeIndent(line string) string {
 
 	return indent
 }
+
+// ComputeIndentWith is like ComputeIndent, but a line opening a block adds
+// unit, such as a tab or a configured number of spaces, instead of a unit
+// guessed from the line. An empty unit falls back to ComputeIndent.
+func ComputeIndentWith(line, unit string) string {
+	if unit == "" {
+		return ComputeIndent(line)
+	}
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	trimmed := strings.TrimRight(line, " \t")
+	if trimmed == "" {
+		return indent
+	}
+	switch trimmed[len(trimmed)-1] {
+	case '{', '(', '[', ':':
+		indent += unit
+	}
+	return indent
+}
@@ -54,3 +54,20 @@ func TestDetectIndentStyle(t *testing.T) {
 		})
 	}
 }
+
+func TestComputeIndentWith(t *testing.T) {
+	tests := []struct {
+		line, unit, want string
+	}{
+		{"func main() {", "  ", "  "},
+		{"  if x {", "  ", "    "},
+		{"\tcall(", "\t", "\t\t"},
+		{"    plain", "  ", "    "},
+		{"def f():", "", "\t"},
+	}
+	for _, tt := range tests {
+		if got := ComputeIndentWith(tt.line, tt.unit); got != tt.want {
+			t.Errorf("ComputeIndentWith(%q, %q) = %q, want %q", tt.line, tt.unit, got, tt.want)
+		}
+	}
+}
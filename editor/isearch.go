@@ -0,0 +1,175 @@
+package editor
+
+import "unicode"
+
+// ISearch is an incremental search, as started by C-s and C-r in the Emacs
+// keymap: each key typed extends the query and moves to the nearest match
+// from where the search is. The search ignores case unless the query has an
+// upper-case letter. Offsets are in runes.
+type ISearch struct {
+	text    []rune
+	origin  int
+	cur     isearchState
+	history []isearchState // states before each step, for Delete
+}
+
+type isearchState struct {
+	query   []rune
+	match   Range // last match found; empty before the first
+	found   bool  // query matches at match; false while failing
+	forward bool
+	wrapped bool
+}
+
+// NewISearch starts a search of text from the cursor at origin.
+func NewISearch(text string, origin int, forward bool) *ISearch {
+	s := &ISearch{text: []rune(text), origin: origin}
+	s.cur = isearchState{match: Range{Start: origin, End: origin}, found: true, forward: forward}
+	return s
+}
+
+// Query returns the text searched for.
+func (s *ISearch) Query() string {
+	return string(s.cur.query)
+}
+
+// Origin returns the cursor offset the search started from.
+func (s *ISearch) Origin() int {
+	return s.origin
+}
+
+// Match returns the match of the query, reporting false if there is none:
+// before anything is typed, or while the search is failing.
+func (s *ISearch) Match() (Range, bool) {
+	return s.cur.match, len(s.cur.query) > 0 && s.cur.found
+}
+
+// Point returns where the cursor goes: the end of the last match found for
+// a forward search, its start for a backward one.
+func (s *ISearch) Point() int {
+	if s.cur.forward {
+		return s.cur.match.End
+	}
+	return s.cur.match.Start
+}
+
+// Prompt describes the search for the status bar, such as
+// "Failing I-search backward: foo".
+func (s *ISearch) Prompt() string {
+	prompt := "I-search"
+	if !s.cur.forward {
+		prompt += " backward"
+	}
+	if s.cur.wrapped {
+		prompt = "Wrapped " + prompt
+	}
+	if !s.cur.found {
+		prompt = "Failing " + prompt
+	}
+	return prompt + ": " + string(s.cur.query)
+}
+
+// Type adds text to the query and searches again from the current match,
+// which stays if the longer query still matches there.
+func (s *ISearch) Type(text string) {
+	s.history = append(s.history, s.cur)
+	first := len(s.cur.query) == 0
+	s.cur.query = append(append([]rune(nil), s.cur.query...), []rune(text)...)
+	if !s.cur.found {
+		return // adding to a failing query cannot make it match
+	}
+	start := s.cur.match.Start
+	if first && !s.cur.forward {
+		start = s.origin - len(s.cur.query) // the match ends at the cursor at the latest
+	}
+	s.search(start, s.cur.forward)
+}
+
+// Repeat moves to the next match in the given direction. Repeating a
+// failing search wraps round the end of the text. With an empty query,
+// last is searched for instead.
+func (s *ISearch) Repeat(forward bool, last string) {
+	s.history = append(s.history, s.cur)
+	if len(s.cur.query) == 0 {
+		if last == "" {
+			s.cur.forward = forward
+			return
+		}
+		s.cur.query = []rune(last)
+		s.cur.forward = forward
+		start := s.origin
+		if !forward {
+			start = s.origin - len(s.cur.query)
+		}
+		s.search(start, forward)
+		return
+	}
+	turned := forward != s.cur.forward
+	s.cur.forward = forward
+	switch {
+	case turned && s.cur.found:
+		// Turning round first moves to the other end of the match.
+	case !s.cur.found && !turned && forward:
+		s.cur.wrapped = true
+		s.search(0, true)
+	case !s.cur.found && !turned:
+		s.cur.wrapped = true
+		s.search(len(s.text), false)
+	case forward:
+		s.search(s.cur.match.End, true)
+	default:
+		s.search(s.cur.match.Start-1, false)
+	}
+}
+
+// Delete undoes the last Type or Repeat, reporting false if there is none.
+func (s *ISearch) Delete() bool {
+	if len(s.history) == 0 {
+		return false
+	}
+	s.cur = s.history[len(s.history)-1]
+	s.history = s.history[:len(s.history)-1]
+	return true
+}
+
+// search looks for the query at or after start, or at or before it when
+// searching backwards, keeping the last match if there is none.
+func (s *ISearch) search(start int, forward bool) {
+	q := s.cur.query
+	fold := true
+	for _, r := range q {
+		if unicode.IsUpper(r) {
+			fold = false
+			break
+		}
+	}
+	matchAt := func(i int) bool {
+		for j, r := range q {
+			t := s.text[i+j]
+			if fold {
+				t = unicode.ToLower(t)
+			}
+			if t != r {
+				return false
+			}
+		}
+		return true
+	}
+	last := len(s.text) - len(q)
+	if forward {
+		for i := max(start, 0); i <= last; i++ {
+			if matchAt(i) {
+				s.cur.match, s.cur.found = Range{Start: i, End: i + len(q)}, true
+				return
+			}
+		}
+	} else {
+		for i := min(start, last); i >= 0; i-- {
+			if matchAt(i) {
+				s.cur.match, s.cur.found = Range{Start: i, End: i + len(q)}, true
+				return
+			}
+		}
+	}
+	s.cur.found = false
+}
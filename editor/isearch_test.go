@@ -0,0 +1,89 @@
+package editor
+
+import "testing"
+
+func isearchMatch(t *testing.T, s *ISearch, start, end int) {
+	t.Helper()
+	m, ok := s.Match()
+	if !ok || m != (Range{Start: start, End: end}) {
+		t.Errorf("%s: match = %v (%v), want %d-%d", s.Prompt(), m, ok, start, end)
+	}
+}
+
+func TestISearchForward(t *testing.T) {
+	s := NewISearch("foo fob Foo foo", 1, true)
+	if _, ok := s.Match(); ok || s.Point() != 1 {
+		t.Errorf("before typing: point %d", s.Point())
+	}
+	s.Type("f")
+	isearchMatch(t, s, 4, 5)
+	s.Type("o")
+	isearchMatch(t, s, 4, 6)
+	s.Type("o")
+	isearchMatch(t, s, 8, 11) // ignores case
+	if s.Point() != 11 {
+		t.Errorf("Point = %d, want 11", s.Point())
+	}
+	s.Repeat(true, "")
+	isearchMatch(t, s, 12, 15)
+	s.Repeat(true, "")
+	if _, ok := s.Match(); ok || s.Prompt() != "Failing I-search: foo" || s.Point() != 15 {
+		t.Errorf("past the last match: %q, point %d", s.Prompt(), s.Point())
+	}
+	s.Repeat(true, "")
+	isearchMatch(t, s, 0, 3)
+	if s.Prompt() != "Wrapped I-search: foo" {
+		t.Errorf("Prompt = %q", s.Prompt())
+	}
+	s.Delete()
+	s.Delete()
+	isearchMatch(t, s, 12, 15)
+	for s.Delete() {
+	}
+	if s.Query() != "" || s.Point() != 1 {
+		t.Errorf("after deleting everything: query %q, point %d", s.Query(), s.Point())
+	}
+}
+
+func TestISearchCase(t *testing.T) {
+	s := NewISearch("foo Foo", 0, true)
+	s.Type("F")
+	isearchMatch(t, s, 4, 5)
+	s.Type("x")
+	if _, ok := s.Match(); ok || s.Prompt() != "Failing I-search: Fx" {
+		t.Errorf("Prompt = %q", s.Prompt())
+	}
+	s.Delete()
+	isearchMatch(t, s, 4, 5)
+}
+
+func TestISearchBackward(t *testing.T) {
+	s := NewISearch("ab ab ab", 7, false)
+	s.Type("a")
+	isearchMatch(t, s, 6, 7)
+	s.Type("b")
+	isearchMatch(t, s, 6, 8) // stays where the shorter query matched
+	s.Repeat(false, "")
+	isearchMatch(t, s, 3, 5)
+	if s.Point() != 3 {
+		t.Errorf("Point = %d, want 3", s.Point())
+	}
+	s.Repeat(false, "")
+	isearchMatch(t, s, 0, 2)
+	s.Repeat(true, "")
+	isearchMatch(t, s, 0, 2)
+	if s.Point() != 2 {
+		t.Errorf("turning round: point %d, want 2", s.Point())
+	}
+	s.Repeat(true, "")
+	isearchMatch(t, s, 3, 5)
+}
+
+func TestISearchRepeatsLastQuery(t *testing.T) {
+	s := NewISearch("x foo foo", 0, true)
+	s.Repeat(true, "foo")
+	isearchMatch(t, s, 2, 5)
+	if s.Query() != "foo" {
+		t.Errorf("Query = %q", s.Query())
+	}
+}
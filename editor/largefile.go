@@ -0,0 +1,209 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+)
+
+// DefaultLargeFileThreshold is the file size in bytes above which
+// TabManager opens files in large-file mode.
+const DefaultLargeFileThreshold = 64 << 20
+
+// largeChunkSize is the rope leaf size for mapped file content. Bigger
+// leaves keep the tree small for files of hundreds of megabytes.
+const largeChunkSize = 64 << 10
+
+// searchWindow is the amount of text FindNext examines at a time.
+const searchWindow = 1 << 20
+
+var errLargeFile = errors.New("not available in large-file mode")
+
+// ErrFileTruncated is returned by GuardMapped when a large file shrank on
+// disk while it was mapped.
+var ErrFileTruncated = errors.New("file was truncated on disk")
+
+// OpenLarge opens the file at path in large-file mode. The file is
+// memory-mapped rather than read where the platform allows it, so opening
+// costs one pass to count lines however big the file is. The text is taken
+// as is: it is not transcoded and its line endings are not normalized, so
+// lines of a CRLF file end in "\r\n". The buffer starts read-only; edits made
+// after SetReadOnly(false) are kept in memory on top of the mapped content
+// until saved.
+func (b *Buffer) OpenLarge(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	text, release, err := mapFile(absPath)
+	if err != nil {
+		return err
+	}
+
+	b.path = absPath
+	b.setContent(newRopeChunked(text, largeChunkSize))
+	b.disk = stampStat(absPath)
+	b.encoding = EncodingUTF8
+	b.lineEnding, _ = DetectLineEnding(text[:min(len(text), largeChunkSize)])
+	b.mixedEndings = false
+	b.large = true
+	b.readOnly = true
+	b.markSaved()
+	b.resetHistory()
+
+	// The rope leaves point into the mapping, and the buffer never hands
+	// them out, so the mapping can go when the buffer does or when the file
+	// is mapped again.
+	b.releaseMapping()
+	b.unmap = release
+	b.unmapCleanup = runtime.AddCleanup(b, func(release func()) { release() }, release)
+	return nil
+}
+
+// releaseMapping unmaps the file the text was last mapped from. The text
+// must no longer point into it.
+func (b *Buffer) releaseMapping() {
+	if b.unmap == nil {
+		return
+	}
+	b.unmapCleanup.Stop()
+	b.unmap()
+	b.unmap = nil
+}
+
+// GuardMapped runs fn, which reads the buffer. Reading the content of a
+// large file that was truncated on disk since it was mapped faults; if fn
+// does, GuardMapped maps the file afresh, dropping unsaved edits, and
+// returns ErrFileTruncated. Other buffers just run fn.
+func (b *Buffer) GuardMapped(fn func()) (err error) {
+	if !b.large {
+		fn()
+		return nil
+	}
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if _, fault := r.(interface{ Addr() uintptr }); !fault {
+			panic(r)
+		}
+		err = ErrFileTruncated
+		if rerr := b.reopenLarge(); rerr != nil {
+			err = fmt.Errorf("%w: %v", ErrFileTruncated, rerr)
+		}
+	}()
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	fn()
+	return nil
+}
+
+// Large reports whether the buffer was opened in large-file mode.
+func (b *Buffer) Large() bool {
+	return b.large
+}
+
+// ReadOnly reports whether the buffer ignores edits.
+func (b *Buffer) ReadOnly() bool {
+	return b.readOnly
+}
+
+// SetReadOnly makes the buffer ignore or accept edits. While read-only,
+// ApplyEdit, ApplyEdits and ApplyText change nothing, and undo and redo are
+// disabled.
+func (b *Buffer) SetReadOnly(readOnly bool) {
+	b.readOnly = readOnly
+}
+
+// reopenLarge maps the file again, discarding the history, and keeps the
+// read-only setting.
+func (b *Buffer) reopenLarge() error {
+	readOnly := b.readOnly
+	if err := b.OpenLarge(b.path); err != nil {
+		return err
+	}
+	b.readOnly = readOnly
+	return nil
+}
+
+// FindNext returns the first match of query at or after offset from,
+// wrapping around to the start of the buffer, or with backward set the last
+// match before from, wrapping around to the end. Unlike FindWithOptions it
+// looks at the text a window of lines at a time, so it stays fast on large
+// files; matches that span more than about a megabyte are not found.
+func (b *Buffer) FindNext(query string, from int, opts FindOptions, backward bool) (Range, bool, error) {
+	if query == "" {
+		return Range{}, false, nil
+	}
+	if _, err := compileSearch(query, opts); err != nil {
+		return Range{}, false, err
+	}
+	from = min(max(from, 0), b.Len())
+	if backward {
+		r, ok, err := b.findBackward(query, opts, 0, from)
+		if err != nil || ok {
+			return r, ok, err
+		}
+		return b.findBackward(query, opts, from, b.Len())
+	}
+	r, ok, err := b.findForward(query, opts, from, b.Len())
+	if err != nil || ok {
+		return r, ok, err
+	}
+	return b.findForward(query, opts, 0, from)
+}
+
+// findForward returns the first match starting in [lo, hi).
+func (b *Buffer) findForward(query string, opts FindOptions, lo, hi int) (Range, bool, error) {
+	for start := b.LineOffset(b.LineAt(lo)); start < hi; {
+		end := b.lineEndAfter(start + searchWindow)
+		matches, err := findMatches(b.Slice(start, end), query, opts)
+		if err != nil {
+			return Range{}, false, err
+		}
+		for _, m := range matches {
+			r := Range{Start: start + m.Start, End: start + m.End}
+			if r.Start >= hi {
+				return Range{}, false, nil
+			}
+			if r.Start >= lo {
+				return r, true, nil
+			}
+		}
+		start = end
+	}
+	return Range{}, false, nil
+}
+
+// findBackward returns the last match starting in [lo, hi).
+func (b *Buffer) findBackward(query string, opts FindOptions, lo, hi int) (Range, bool, error) {
+	for end := b.lineEndAfter(hi); end > lo; {
+		start := b.LineOffset(b.LineAt(max(end-searchWindow, 0)))
+		matches, err := findMatches(b.Slice(start, end), query, opts)
+		if err != nil {
+			return Range{}, false, err
+		}
+		for i := len(matches) - 1; i >= 0; i-- {
+			r := Range{Start: start + matches[i].Start, End: start + matches[i].End}
+			if r.Start < lo {
+				return Range{}, false, nil
+			}
+			if r.Start < hi {
+				return r, true, nil
+			}
+		}
+		end = start
+	}
+	return Range{}, false, nil
+}
+
+// lineEndAfter returns the start of the line following the one containing
+// offset, or the buffer length.
+func (b *Buffer) lineEndAfter(offset int) int {
+	if offset >= b.Len() {
+		return b.Len()
+	}
+	return b.LineOffset(b.LineAt(offset) + 1)
+}
@@ -0,0 +1,199 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openLargeTemp(t *testing.T, content string) (*Buffer, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "big.log")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	b := NewBuffer()
+	if err := b.OpenLarge(path); err != nil {
+		t.Fatalf("OpenLarge: %v", err)
+	}
+	return b, path
+}
+
+func TestOpenLarge(t *testing.T) {
+	b, _ := openLargeTemp(t, "one\r\ntwo\r\nthree")
+	if !b.Large() || !b.ReadOnly() {
+		t.Fatalf("Large, ReadOnly = %v, %v; want true, true", b.Large(), b.ReadOnly())
+	}
+	if got := b.Text(); got != "one\r\ntwo\r\nthree" {
+		t.Fatalf("Text = %q; want the file unchanged", got)
+	}
+	if b.LineCount() != 3 || b.Line(1) != "two" || b.Line(2) != "three" {
+		t.Fatalf("LineCount, Line(1), Line(2) = %d, %q, %q", b.LineCount(), b.Line(1), b.Line(2))
+	}
+	if b.LineEnding() != LineEndingCRLF {
+		t.Fatalf("LineEnding = %s; want CRLF", b.LineEnding())
+	}
+}
+
+func TestOpenLargeEmpty(t *testing.T) {
+	b, _ := openLargeTemp(t, "")
+	if b.Len() != 0 || b.LineCount() != 1 {
+		t.Fatalf("Len, LineCount = %d, %d; want 0, 1", b.Len(), b.LineCount())
+	}
+}
+
+func TestLargeReadOnly(t *testing.T) {
+	b, _ := openLargeTemp(t, "abc\n")
+	b.ApplyEdit(0, "a", "x")
+	if n := b.ApplyEdits("Edit", []TextEdit{{Start: 0, End: 1, Text: "x"}}); n != 0 {
+		t.Fatalf("ApplyEdits on read-only buffer applied %d edits", n)
+	}
+	if b.ApplyText("Edit", "xyz\n") {
+		t.Fatal("ApplyText on read-only buffer reported a change")
+	}
+	if b.Text() != "abc\n" || b.Dirty() {
+		t.Fatalf("read-only buffer changed: %q, dirty %v", b.Text(), b.Dirty())
+	}
+
+	b.SetReadOnly(false)
+	b.ApplyEdit(0, "a", "x")
+	b.SetReadOnly(true)
+	if b.Undo() {
+		t.Fatal("Undo succeeded on a read-only buffer")
+	}
+	b.SetReadOnly(false)
+	if !b.Undo() || b.Text() != "abc\n" {
+		t.Fatalf("Undo = %q; want the original text", b.Text())
+	}
+}
+
+func TestLargeSave(t *testing.T) {
+	content := strings.Repeat("line of a large file\r\n", 10000)
+	b, path := openLargeTemp(t, content)
+	b.SetReadOnly(false)
+	// Large buffers keep the text as is, so the edit brings its own CRLF.
+	b.ApplyEdits("Insert", []TextEdit{{Start: 0, End: 0, Text: "first\r\n"}})
+	if !b.Dirty() {
+		t.Fatal("buffer not dirty after edit")
+	}
+	if err := b.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\r\n"+content {
+		t.Fatal("saved file does not match the buffer")
+	}
+	if b.Dirty() {
+		t.Fatal("buffer dirty after save")
+	}
+	if changed, err := b.DiskChanged(); err != nil || changed {
+		t.Fatalf("DiskChanged after save = %v, %v; want false", changed, err)
+	}
+	writeExternal(t, path, "replaced\n")
+	if changed, err := b.DiskChanged(); err != nil || !changed {
+		t.Fatalf("DiskChanged after external write = %v, %v; want true", changed, err)
+	}
+	if err := b.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	// Reloading keeps the buffer writable.
+	if b.Text() != "replaced\n" || b.ReadOnly() || b.Dirty() {
+		t.Fatalf("after Reload Text, ReadOnly, Dirty = %q, %v, %v", b.Text(), b.ReadOnly(), b.Dirty())
+	}
+}
+
+func TestLargeUnsupported(t *testing.T) {
+	b, _ := openLargeTemp(t, "abc\n")
+	if err := b.SaveWithEncoding(EncodingUTF16LE); err == nil {
+		t.Fatal("SaveWithEncoding succeeded in large-file mode")
+	}
+	if err := b.ReopenWithEncoding(EncodingWindows1252); err == nil {
+		t.Fatal("ReopenWithEncoding succeeded in large-file mode")
+	}
+	b.SetLineEnding(LineEndingCRLF)
+	if b.LineEnding() != LineEndingLF || b.Dirty() {
+		t.Fatal("SetLineEnding changed a large buffer")
+	}
+}
+
+func TestFindNext(t *testing.T) {
+	b := NewBuffer()
+	b.SetText("foo bar\nbar foo\nfoo")
+	tests := []struct {
+		from     int
+		backward bool
+		want     int
+	}{
+		{0, false, 0},
+		{1, false, 12},
+		{13, false, 16},
+		{17, false, 0}, // wraps to the start
+		{16, true, 12},
+		{12, true, 0},
+		{0, true, 16}, // wraps to the end
+	}
+	for _, tt := range tests {
+		r, ok, err := b.FindNext("foo", tt.from, FindOptions{}, tt.backward)
+		if err != nil || !ok || r.Start != tt.want || r.End != tt.want+3 {
+			t.Errorf("FindNext(from %d, backward %v) = %v, %v, %v; want start %d", tt.from, tt.backward, r, ok, err, tt.want)
+		}
+	}
+	if _, ok, _ := b.FindNext("baz", 0, FindOptions{}, false); ok {
+		t.Error("FindNext found a missing query")
+	}
+	if _, _, err := b.FindNext("(", 0, FindOptions{Regex: true}, false); err == nil {
+		t.Error("FindNext accepted an invalid regex")
+	}
+	if r, ok, _ := b.FindNext("^bar", 3, FindOptions{Regex: true}, false); !ok || r.Start != 8 {
+		t.Errorf("FindNext(^bar) = %v, %v; want start 8", r, ok)
+	}
+}
+
+func TestFindNextAcrossWindows(t *testing.T) {
+	line := strings.Repeat("x", 99) + "\n"
+	n := 3 * searchWindow / len(line)
+	b := NewBuffer()
+	b.SetText(strings.Repeat(line, n) + "needle\n" + strings.Repeat(line, n))
+	want := n * len(line)
+
+	if r, ok, _ := b.FindNext("needle", 0, FindOptions{}, false); !ok || r.Start != want {
+		t.Fatalf("forward FindNext = %v, %v; want start %d", r, ok, want)
+	}
+	if r, ok, _ := b.FindNext("needle", b.Len(), FindOptions{}, true); !ok || r.Start != want {
+		t.Fatalf("backward FindNext = %v, %v; want start %d", r, ok, want)
+	}
+	if r, ok, _ := b.FindNext("needle", want+1, FindOptions{WholeWord: true}, false); !ok || r.Start != want {
+		t.Fatalf("wrapped FindNext = %v, %v; want start %d", r, ok, want)
+	}
+}
+
+func TestTabManagerLargeFileThreshold(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(path, []byte("0123456789\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tm := NewTabManager()
+	tm.SetLargeFileThreshold(10)
+	if _, err := tm.OpenFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if !tm.ActiveBuffer().Large() {
+		t.Fatal("file above the threshold not opened in large-file mode")
+	}
+
+	small := filepath.Join(dir, "small.txt")
+	if err := os.WriteFile(small, []byte("012345\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tm.OpenFile(small); err != nil {
+		t.Fatal(err)
+	}
+	if tm.ActiveBuffer().Large() {
+		t.Fatal("file below the threshold opened in large-file mode")
+	}
+}
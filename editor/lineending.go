@@ -0,0 +1,73 @@
+package editor
+
+import "strings"
+
+// LineEnding names the line terminator a file is written with. Buffers
+// always hold text with bare "\n" line endings; the style only applies on
+// disk.
+type LineEnding string
+
+const (
+	LineEndingLF   LineEnding = "LF"
+	LineEndingCRLF LineEnding = "CRLF"
+)
+
+// DetectLineEnding returns the line ending most lines of text use, and
+// whether both styles occur. Text without line breaks is LF.
+func DetectLineEnding(text string) (LineEnding, bool) {
+	lines := strings.Count(text, "\n")
+	crlf := strings.Count(text, "\r\n")
+	lf := lines - crlf
+	style := LineEndingLF
+	if crlf > lf {
+		style = LineEndingCRLF
+	}
+	return style, crlf > 0 && lf > 0
+}
+
+// NormalizeLineEndings converts CRLF line endings in text to LF. Lone
+// carriage returns are kept.
+func NormalizeLineEndings(text string) string {
+	if !strings.Contains(text, "\r\n") {
+		return text
+	}
+	return strings.ReplaceAll(text, "\r\n", "\n")
+}
+
+// ApplyLineEnding converts every line ending in text to style.
+func ApplyLineEnding(text string, style LineEnding) string {
+	text = NormalizeLineEndings(text)
+	if style == LineEndingCRLF {
+		return strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	return text
+}
+
+// LineEnding returns the line ending the buffer's file is written with.
+func (b *Buffer) LineEnding() LineEnding {
+	if b.lineEnding == "" {
+		return LineEndingLF
+	}
+	return b.lineEnding
+}
+
+// MixedLineEndings reports whether the file used both LF and CRLF when it
+// was last read. Saving rewrites every line with LineEnding.
+func (b *Buffer) MixedLineEndings() bool {
+	return b.mixedEndings
+}
+
+// SetLineEnding changes the line ending used when saving. The buffer is
+// dirty until saved if the style differs from the file's; converting a file
+// with mixed line endings always needs a save. Large files keep the line
+// endings they have.
+func (b *Buffer) SetLineEnding(style LineEnding) {
+	if b.large {
+		return
+	}
+	b.lineEnding = style
+	if b.mixedEndings {
+		// No single style matches the file, so any choice is a change.
+		b.savedEnding = ""
+	}
+}
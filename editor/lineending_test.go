@@ -0,0 +1,99 @@
+package editor
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDetectLineEnding(t *testing.T) {
+	tests := []struct {
+		text  string
+		want  LineEnding
+		mixed bool
+	}{
+		{"", LineEndingLF, false},
+		{"no newline", LineEndingLF, false},
+		{"a\nb\n", LineEndingLF, false},
+		{"a\r\nb\r\n", LineEndingCRLF, false},
+		{"a\r\nb\r\nc\n", LineEndingCRLF, true},
+		{"a\nb\nc\r\n", LineEndingLF, true},
+		{"lone\rcr\n", LineEndingLF, false},
+	}
+	for _, tt := range tests {
+		got, mixed := DetectLineEnding(tt.text)
+		if got != tt.want || mixed != tt.mixed {
+			t.Errorf("DetectLineEnding(%q) = %s, %v; want %s, %v", tt.text, got, mixed, tt.want, tt.mixed)
+		}
+	}
+}
+
+func TestApplyLineEnding(t *testing.T) {
+	if got := ApplyLineEnding("a\nb\r\nc", LineEndingCRLF); got != "a\r\nb\r\nc" {
+		t.Errorf("CRLF: got %q", got)
+	}
+	if got := ApplyLineEnding("a\r\nb\nc\r", LineEndingLF); got != "a\nb\nc\r" {
+		t.Errorf("LF: got %q", got)
+	}
+}
+
+func TestBufferPreservesCRLF(t *testing.T) {
+	b, path := openTemp(t, "one\r\ntwo\r\n")
+	if b.Text() != "one\ntwo\n" || b.LineEnding() != LineEndingCRLF || b.MixedLineEndings() {
+		t.Fatalf("Open: text %q, ending %s, mixed %v", b.Text(), b.LineEnding(), b.MixedLineEndings())
+	}
+
+	// Edits with either style are stored with LF.
+	b.ApplyText("Edit", "one\ntwo\r\nthree\n")
+	if b.Text() != "one\ntwo\nthree\n" {
+		t.Fatalf("ApplyText did not normalize: %q", b.Text())
+	}
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "one\r\ntwo\r\nthree\r\n" {
+		t.Fatalf("saved %q", data)
+	}
+}
+
+func TestBufferConvertLineEnding(t *testing.T) {
+	b, path := openTemp(t, "a\r\nb\r\n")
+	b.SetLineEnding(LineEndingLF)
+	if !b.Dirty() {
+		t.Fatal("changing the line ending should make the buffer dirty")
+	}
+	b.SetLineEnding(LineEndingCRLF)
+	if b.Dirty() {
+		t.Fatal("switching back to the file's line ending should be clean")
+	}
+	b.SetLineEnding(LineEndingLF)
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "a\nb\n" {
+		t.Fatalf("saved %q", data)
+	}
+	if b.Dirty() {
+		t.Error("buffer should be clean after save")
+	}
+}
+
+func TestBufferMixedLineEndings(t *testing.T) {
+	b, path := openTemp(t, "a\r\nb\r\nc\n")
+	if !b.MixedLineEndings() || b.LineEnding() != LineEndingCRLF || b.Dirty() {
+		t.Fatalf("Open: mixed %v, ending %s, dirty %v", b.MixedLineEndings(), b.LineEnding(), b.Dirty())
+	}
+	// Choosing the dominant style still needs a save to fix the file.
+	b.SetLineEnding(LineEndingCRLF)
+	if !b.Dirty() {
+		t.Fatal("converting a mixed file should make the buffer dirty")
+	}
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "a\r\nb\r\nc\r\n" {
+		t.Fatalf("saved %q", data)
+	}
+	if b.MixedLineEndings() || b.Dirty() {
+		t.Errorf("after save: mixed %v, dirty %v", b.MixedLineEndings(), b.Dirty())
+	}
+}
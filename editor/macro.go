@@ -0,0 +1,167 @@
+package editor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// macroFileVersion is bumped whenever the on-disk macro format changes.
+// Macro files written with another version are ignored.
+const macroFileVersion = 1
+
+// macroFileName is the macro file in the state directory.
+const macroFileName = "macros.json"
+
+// MacroStep is a step of a keyboard macro. Exactly one field is set.
+type MacroStep struct {
+	Key     string `json:"key,omitempty"`     // key pressed, in keybinding notation such as "Ctrl+D"
+	Text    string `json:"text,omitempty"`    // characters typed, one key each
+	Command string `json:"command,omitempty"` // ID of a command run from the command palette
+}
+
+// Macro is a recorded series of keys and commands.
+type Macro []MacroStep
+
+// AddKey appends a key press.
+func (m *Macro) AddKey(key string) {
+	*m = append(*m, MacroStep{Key: key})
+}
+
+// AddText appends typed characters, joining them to the text typed just
+// before.
+func (m *Macro) AddText(text string) {
+	if n := len(*m); n > 0 && (*m)[n-1].Text != "" {
+		(*m)[n-1].Text += text
+		return
+	}
+	*m = append(*m, MacroStep{Text: text})
+}
+
+// AddCommand appends a command run from the command palette.
+func (m *Macro) AddCommand(id string) {
+	*m = append(*m, MacroStep{Command: id})
+}
+
+// DropKeys removes the last n key presses, counting each typed character
+// as one. Commands before them are kept.
+func (m *Macro) DropKeys(n int) {
+	for n > 0 && len(*m) > 0 {
+		last := &(*m)[len(*m)-1]
+		switch {
+		case last.Text != "":
+			_, size := utf8.DecodeLastRuneInString(last.Text)
+			last.Text = last.Text[:len(last.Text)-size]
+			if last.Text == "" {
+				*m = (*m)[:len(*m)-1]
+			}
+		case last.Key != "":
+			*m = (*m)[:len(*m)-1]
+		default:
+			return
+		}
+		n--
+	}
+}
+
+// String describes the macro for the status bar, such as
+// `"foo" Ctrl+D edit.duplicateLine`.
+func (m Macro) String() string {
+	parts := make([]string, len(m))
+	for i, step := range m {
+		switch {
+		case step.Text != "":
+			parts[i] = strconv.Quote(step.Text)
+		case step.Key != "":
+			parts[i] = step.Key
+		default:
+			parts[i] = step.Command
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// ValidMacroRegister reports whether name can name a macro register: a
+// single ASCII letter or digit.
+func ValidMacroRegister(name string) bool {
+	if len(name) != 1 {
+		return false
+	}
+	c := name[0]
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// MacroStore keeps the last macro recorded and the macros saved to
+// registers in a file, so they survive restarts.
+type MacroStore struct {
+	Path      string
+	Last      Macro
+	Registers map[string]Macro
+}
+
+type macroFile struct {
+	Version   int              `json:"version"`
+	Last      Macro            `json:"last,omitempty"`
+	Registers map[string]Macro `json:"registers,omitempty"`
+}
+
+// DefaultMacroPath returns $XDG_STATE_HOME/mane/macros.json, falling back
+// to ~/.local/state/mane/macros.json when XDG_STATE_HOME is unset.
+func DefaultMacroPath() (string, error) {
+	return stateDir(macroFileName)
+}
+
+// LoadMacros reads the macros stored at path. A missing file, or one
+// written in another format version, gives an empty store.
+func LoadMacros(path string) (*MacroStore, error) {
+	s := &MacroStore{Path: path, Registers: make(map[string]Macro)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	var f macroFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return s, fmt.Errorf("macros %s: %w", path, err)
+	}
+	if f.Version != macroFileVersion {
+		return s, nil
+	}
+	s.Last = f.Last
+	for name, m := range f.Registers {
+		if ValidMacroRegister(name) {
+			s.Registers[name] = m
+		}
+	}
+	return s, nil
+}
+
+// Names returns the names of the registers holding a macro, sorted.
+func (s *MacroStore) Names() []string {
+	names := make([]string, 0, len(s.Registers))
+	for name := range s.Registers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Save writes the macros to the store's file.
+func (s *MacroStore) Save() error {
+	data, err := json.MarshalIndent(macroFile{Version: macroFileVersion, Last: s.Last, Registers: s.Registers}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.Path, data, 0o600)
+}
@@ -0,0 +1,89 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMacroRecording(t *testing.T) {
+	var m Macro
+	m.AddText("f")
+	m.AddText("o")
+	m.AddKey("Ctrl+D")
+	m.AddText("é")
+	m.AddCommand("edit.duplicateLine")
+	m.AddKey("Ctrl+Shift+R")
+	want := Macro{{Text: "fo"}, {Key: "Ctrl+D"}, {Text: "é"}, {Command: "edit.duplicateLine"}, {Key: "Ctrl+Shift+R"}}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("recorded %+v, want %+v", m, want)
+	}
+	if got := m.String(); got != `"fo" Ctrl+D "é" edit.duplicateLine Ctrl+Shift+R` {
+		t.Errorf("String = %q", got)
+	}
+
+	m.DropKeys(1)
+	if len(m) != 4 {
+		t.Fatalf("DropKeys(1) left %+v", m)
+	}
+	// Commands stop the keys being dropped.
+	m.DropKeys(2)
+	if len(m) != 4 {
+		t.Errorf("DropKeys past a command left %+v", m)
+	}
+	m = m[:3]
+	m.DropKeys(3)
+	if want := (Macro{{Text: "f"}}); !reflect.DeepEqual(m, want) {
+		t.Errorf("DropKeys(3) left %+v, want %+v", m, want)
+	}
+}
+
+func TestValidMacroRegister(t *testing.T) {
+	for name, want := range map[string]bool{"a": true, "Q": true, "7": true, "": false, "ab": false, "-": false, "é": false} {
+		if got := ValidMacroRegister(name); got != want {
+			t.Errorf("ValidMacroRegister(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMacroStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", macroFileName)
+	s, err := LoadMacros(path)
+	if err != nil || s.Last != nil || len(s.Registers) != 0 {
+		t.Fatalf("LoadMacros of a missing file = %+v, %v", s, err)
+	}
+	s.Last = Macro{{Key: "Home"}, {Text: "// "}, {Key: "Down"}}
+	s.Registers["q"] = Macro{{Command: "edit.deleteLine"}}
+	s.Registers["a"] = s.Last
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := LoadMacros(path)
+	if err != nil {
+		t.Fatalf("LoadMacros: %v", err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("LoadMacros = %+v, want %+v", got, s)
+	}
+	if names := got.Names(); !reflect.DeepEqual(names, []string{"a", "q"}) {
+		t.Errorf("Names = %q", names)
+	}
+}
+
+func TestLoadMacrosIgnoresOtherVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), macroFileName)
+	if err := os.WriteFile(path, []byte(`{"version": 99, "last": [{"key": "Up"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadMacros(path)
+	if err != nil || s.Last != nil {
+		t.Errorf("LoadMacros = %+v, %v; want an empty store", s, err)
+	}
+	if err := os.WriteFile(path, []byte(`{`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMacros(path); err == nil {
+		t.Error("LoadMacros of a broken file should fail")
+	}
+}
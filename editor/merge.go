@@ -0,0 +1,128 @@
+package editor
+
+import (
+	"slices"
+	"strings"
+)
+
+// Conflict markers written around regions Merge3 cannot reconcile.
+const (
+	ConflictMine   = "<<<<<<< mine"
+	ConflictSep    = "======="
+	ConflictTheirs = ">>>>>>> theirs"
+)
+
+// mergeHunk replaces base lines [start, end) with lines.
+type mergeHunk struct {
+	start, end int
+	lines      []string
+}
+
+// diffHunks converts a line diff into hunks over the lines of its base.
+func diffHunks(diff []DiffLine) []mergeHunk {
+	var hunks []mergeHunk
+	var cur *mergeHunk
+	line := 0
+	for _, d := range diff {
+		if d.Kind == DiffEqual {
+			if cur != nil {
+				hunks = append(hunks, *cur)
+				cur = nil
+			}
+			line++
+			continue
+		}
+		if cur == nil {
+			cur = &mergeHunk{start: line, end: line}
+		}
+		if d.Kind == DiffDelete {
+			cur.end++
+			line++
+		} else {
+			cur.lines = append(cur.lines, d.Text)
+		}
+	}
+	if cur != nil {
+		hunks = append(hunks, *cur)
+	}
+	return hunks
+}
+
+// applyHunks returns base lines [start, end) with hunks applied. All hunks
+// must lie within the range.
+func applyHunks(base []string, start, end int, hunks []mergeHunk) []string {
+	var out []string
+	pos := start
+	for _, h := range hunks {
+		out = append(out, base[pos:h.start]...)
+		out = append(out, h.lines...)
+		pos = h.end
+	}
+	return append(out, base[pos:end]...)
+}
+
+// Merge3 performs a line-based three-way merge of mine and theirs, two
+// revisions of base. Changes made on only one side are taken as is; regions
+// changed differently on both sides are kept with both versions between
+// conflict markers. Returns the merged text and whether any conflict was
+// found.
+func Merge3(base, mine, theirs string) (string, bool) {
+	baseLines := strings.Split(base, "\n")
+	ours := diffHunks(LineDiff(base, mine))
+	their := diffHunks(LineDiff(base, theirs))
+
+	var out []string
+	conflict := false
+	pos := 0
+	for len(ours) > 0 || len(their) > 0 {
+		// Start a region at the earliest hunk and grow it while a hunk from
+		// either side overlaps or touches it.
+		var start, end int
+		if len(their) == 0 || (len(ours) > 0 && ours[0].start <= their[0].start) {
+			start, end = ours[0].start, ours[0].end
+		} else {
+			start, end = their[0].start, their[0].end
+		}
+		var mineRegion, theirRegion []mergeHunk
+		for {
+			grew := false
+			if len(ours) > 0 && ours[0].start <= end {
+				end = max(end, ours[0].end)
+				mineRegion = append(mineRegion, ours[0])
+				ours = ours[1:]
+				grew = true
+			}
+			if len(their) > 0 && their[0].start <= end {
+				end = max(end, their[0].end)
+				theirRegion = append(theirRegion, their[0])
+				their = their[1:]
+				grew = true
+			}
+			if !grew {
+				break
+			}
+		}
+
+		out = append(out, baseLines[pos:start]...)
+		pos = end
+		mineLines := applyHunks(baseLines, start, end, mineRegion)
+		theirLines := applyHunks(baseLines, start, end, theirRegion)
+		switch {
+		case len(theirRegion) == 0:
+			out = append(out, mineLines...)
+		case len(mineRegion) == 0:
+			out = append(out, theirLines...)
+		case slices.Equal(mineLines, theirLines):
+			out = append(out, mineLines...)
+		default:
+			conflict = true
+			out = append(out, ConflictMine)
+			out = append(out, mineLines...)
+			out = append(out, ConflictSep)
+			out = append(out, theirLines...)
+			out = append(out, ConflictTheirs)
+		}
+	}
+	out = append(out, baseLines[pos:]...)
+	return strings.Join(out, "\n"), conflict
+}
@@ -0,0 +1,72 @@
+package editor
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	tests := []struct {
+		name         string
+		mine, theirs string
+		want         string
+		conflict     bool
+	}{
+		{
+			name:   "unchanged",
+			mine:   base,
+			theirs: base,
+			want:   base,
+		},
+		{
+			name:   "only mine",
+			mine:   "a\nB\nc\nd\ne\n",
+			theirs: base,
+			want:   "a\nB\nc\nd\ne\n",
+		},
+		{
+			name:   "only theirs",
+			mine:   base,
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   "a\nb\nc\nd\nE\n",
+		},
+		{
+			name:   "separate changes",
+			mine:   "A\nb\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   "A\nb\nc\nd\nE\n",
+		},
+		{
+			name:   "insert and delete",
+			mine:   "a\nb\nx\nc\nd\ne\n",
+			theirs: "a\nb\nc\ne\n",
+			want:   "a\nb\nx\nc\ne\n",
+		},
+		{
+			name:   "same change on both sides",
+			mine:   "a\nb\nC\nd\ne\n",
+			theirs: "a\nb\nC\nd\ne\n",
+			want:   "a\nb\nC\nd\ne\n",
+		},
+		{
+			name:     "conflicting change",
+			mine:     "a\nb\nmine\nd\ne\n",
+			theirs:   "a\nb\ntheirs\nd\ne\n",
+			want:     "a\nb\n<<<<<<< mine\nmine\n=======\ntheirs\n>>>>>>> theirs\nd\ne\n",
+			conflict: true,
+		},
+		{
+			name:     "adjacent changes conflict",
+			mine:     "a\nB\nc\nd\ne\n",
+			theirs:   "a\nb\nC\nd\ne\n",
+			want:     "a\n<<<<<<< mine\nB\nc\n=======\nb\nC\n>>>>>>> theirs\nd\ne\n",
+			conflict: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflict := Merge3(base, tt.mine, tt.theirs)
+			if got != tt.want || conflict != tt.conflict {
+				t.Errorf("Merge3 = %q, %v; want %q, %v", got, conflict, tt.want, tt.conflict)
+			}
+		})
+	}
+}
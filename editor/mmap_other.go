@@ -0,0 +1,15 @@
+//go:build !unix
+
+package editor
+
+import "os"
+
+// mapFile reads the file at path into memory on platforms without mmap
+// support. The release function is a no-op.
+func mapFile(path string) (string, func(), error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(data), func() {}, nil
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package editor
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mapFile maps the file at path into memory read-only and returns its
+// content as a string backed by the mapping, with a function that unmaps
+// it. The mapping is private, so it keeps the content the file had when it
+// was mapped even if the file is later replaced by a rename. Truncating the
+// file in place, however, makes reads past the new end fault.
+func mapFile(path string) (string, func(), error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", nil, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return "", func() {}, nil
+	}
+	if int64(int(size)) != size {
+		return "", nil, syscall.EFBIG
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_PRIVATE)
+	if err != nil {
+		return "", nil, err
+	}
+	release := func() { _ = syscall.Munmap(data) }
+	return unsafe.String(&data[0], len(data)), release, nil
+}
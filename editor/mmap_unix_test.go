@@ -0,0 +1,44 @@
+//go:build unix
+
+package editor
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGuardMappedTruncated(t *testing.T) {
+	b, path := openLargeTemp(t, strings.Repeat("0123456789abcde\n", 1<<12))
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	var text string
+	err := b.GuardMapped(func() { text = b.Text() })
+	if !errors.Is(err, ErrFileTruncated) {
+		t.Fatalf("GuardMapped = %v; want ErrFileTruncated", err)
+	}
+	if text != "" || b.Len() != 0 || !b.ReadOnly() {
+		t.Fatalf("after the fault Len, ReadOnly = %d, %v; want the truncated file mapped afresh", b.Len(), b.ReadOnly())
+	}
+	if err := b.GuardMapped(func() { text = b.Text() }); err != nil {
+		t.Fatalf("GuardMapped after reload = %v", err)
+	}
+}
+
+func TestReloadLargeReleasesMapping(t *testing.T) {
+	b, path := openLargeTemp(t, "one\n")
+	released := false
+	unmap := b.unmap
+	b.unmap = func() { released = true; unmap() }
+	if err := os.WriteFile(path, []byte("two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !released || b.Text() != "two\n" {
+		t.Fatalf("released, Text = %v, %q; want the old mapping released", released, b.Text())
+	}
+}
@@ -3,18 +3,20 @@ package editor
 import (
 	"sort"
 	"strings"
+	"unicode"
 )
 
 // Cursor represents one cursor with an optional selection.
 // Offsets are rune offsets into the document.
 type Cursor struct {
-	Offset int // Cursor position.
-	Anchor int // Selection anchor; same as Offset for no selection.
+	Offset int `json:"offset"` // Cursor position.
+	Anchor int `json:"anchor"` // Selection anchor; same as Offset for no selection.
 }
 
 // MultiCursor stores a set of independent cursors.
 type MultiCursor struct {
 	cursors []Cursor
+	history [][]Cursor // cursors before each add, for UndoCursorAdd
 }
 
 // NewMultiCursor returns a single cursor at offset 0.
@@ -58,6 +60,7 @@ func (mc *MultiCursor) Reset() {
 	if mc == nil {
 		return
 	}
+	mc.history = nil
 	if len(mc.cursors) == 0 {
 		mc.cursors = []Cursor{{Offset: 0, Anchor: 0}}
 		return
@@ -79,6 +82,20 @@ func (mc *MultiCursor) SetPrimary(offset, anchor int) {
 	mc.cursors[0] = Cursor{Offset: offset, Anchor: anchor}
 }
 
+// SetCursors replaces all cursors; the first becomes the primary. An empty
+// list leaves a single cursor at offset 0.
+func (mc *MultiCursor) SetCursors(cursors []Cursor) {
+	if mc == nil {
+		return
+	}
+	mc.history = nil
+	if len(cursors) == 0 {
+		mc.cursors = []Cursor{{Offset: 0, Anchor: 0}}
+		return
+	}
+	mc.cursors = append([]Cursor(nil), cursors...)
+}
+
 // AddCursor appends a cursor at the given offset.
 func (mc *MultiCursor) AddCursor(offset int) {
 	if mc == nil {
@@ -95,56 +112,368 @@ func (mc *MultiCursor) AddSelection(start, end int) {
 	mc.cursors = append(mc.cursors, Cursor{Offset: end, Anchor: start})
 }
 
-// AddNextOccurrence selects the next occurrence of the last cursor's selection.
-// If no such occurrence exists, this returns false.
-func (mc *MultiCursor) AddNextOccurrence(text string) bool {
+// AddNextOccurrence selects the next occurrence of the last cursor's
+// selection, wrapping round the end of text. A cursor without a selection
+// selects the word it is in instead. If no such occurrence exists, this
+// returns false.
+func (mc *MultiCursor) AddNextOccurrence(text string, opts FindOptions) bool {
+	runes := []rune(text)
+	query, end, ok := mc.occurrenceQuery(runes)
+	if !ok {
+		return mc.selectWord(runes)
+	}
+	candidate := mc.nextOccurrence(runes, query, end, opts)
+	if candidate < 0 {
+		return false
+	}
+	mc.pushHistory()
+	mc.AddSelection(candidate, candidate+len(query))
+	return true
+}
+
+// SkipOccurrence moves the last cursor's selection to the next occurrence
+// of its text, as AddNextOccurrence would have added it, and reports
+// whether there was one.
+func (mc *MultiCursor) SkipOccurrence(text string, opts FindOptions) bool {
+	runes := []rune(text)
+	query, end, ok := mc.occurrenceQuery(runes)
+	if !ok {
+		return mc.selectWord(runes)
+	}
+	candidate := mc.nextOccurrence(runes, query, end, opts)
+	if candidate < 0 {
+		return false
+	}
+	mc.pushHistory()
+	mc.cursors[len(mc.cursors)-1] = Cursor{Offset: candidate + len(query), Anchor: candidate}
+	return true
+}
+
+// SelectAllOccurrences selects every occurrence of the last cursor's
+// selection, or of the word it is in, keeping the primary cursor first.
+// It reports whether the cursors changed.
+func (mc *MultiCursor) SelectAllOccurrences(text string, opts FindOptions) bool {
+	runes := []rune(text)
+	query, _, ok := mc.occurrenceQuery(runes)
+	if !ok {
+		// Selecting the word is the first step UndoCursorAdd takes back.
+		if !mc.selectWord(runes) {
+			return false
+		}
+		query, _, _ = mc.occurrenceQuery(runes)
+	} else {
+		mc.pushHistory()
+	}
+	before := len(mc.cursors)
+	for i := 0; i+len(query) <= len(runes); i++ {
+		if matchesAt(runes, query, i, opts) && !mc.overlapsSelection(i, i+len(query)) {
+			mc.AddSelection(i, i+len(query))
+			i += len(query) - 1
+		}
+	}
+	if len(mc.cursors) == before {
+		if ok {
+			mc.history = mc.history[:len(mc.history)-1]
+		}
+		return !ok
+	}
+	rest := mc.cursors[1:]
+	sort.Slice(rest, func(i, j int) bool { return rest[i].Offset < rest[j].Offset })
+	return true
+}
+
+// AddCursorAbove adds a cursor on the line above the topmost cursor, and
+// AddCursorBelow one on the line below the bottommost. The new cursor is
+// in the primary cursor's column, or at the end of a shorter line, so a
+// run of cursors keeps its column across short lines. They report false
+// at the first or last line.
+func (mc *MultiCursor) AddCursorAbove(text string) bool {
+	return mc.addCursorOnLine(text, -1)
+}
+
+// AddCursorBelow is AddCursorAbove for the line below; see there.
+func (mc *MultiCursor) AddCursorBelow(text string) bool {
+	return mc.addCursorOnLine(text, 1)
+}
+
+func (mc *MultiCursor) addCursorOnLine(text string, dir int) bool {
 	if mc == nil || len(mc.cursors) == 0 {
 		return false
 	}
+	runes := []rune(text)
+	starts := lineStarts(runes)
+	clamp := func(off int) int { return min(max(off, 0), len(runes)) }
+	primary := clamp(mc.cursors[0].Offset)
+	line := lineIndex(starts, primary)
+	col := primary - starts[line]
+	for _, c := range mc.cursors[1:] {
+		l := lineIndex(starts, clamp(c.Offset))
+		if dir < 0 {
+			line = min(line, l)
+		} else {
+			line = max(line, l)
+		}
+	}
+	line += dir
+	if line < 0 || line >= len(starts) {
+		return false
+	}
+	mc.pushHistory()
+	mc.AddCursor(min(starts[line]+col, lineEnd(runes, starts, line)))
+	return true
+}
 
-	last := mc.cursors[len(mc.cursors)-1]
-	start, end := orderedRuneRange(last.Offset, last.Anchor)
-	if start == end {
+// UndoCursorAdd takes back the last cursor added or moved by the methods
+// above, reporting false if there is none. Editing keeps the cursors to go
+// back to in step with the text.
+func (mc *MultiCursor) UndoCursorAdd() bool {
+	if mc == nil || len(mc.history) == 0 {
 		return false
 	}
+	mc.cursors = mc.history[len(mc.history)-1]
+	mc.history = mc.history[:len(mc.history)-1]
+	return true
+}
 
+func (mc *MultiCursor) pushHistory() {
+	mc.history = append(mc.history, mc.Cursors())
+}
+
+// Motion is a cursor movement Move applies at every cursor.
+type Motion int
+
+const (
+	MoveLeft      Motion = iota // one character left
+	MoveRight                   // one character right
+	MoveUp                      // same column on the line above
+	MoveDown                    // same column on the line below
+	MoveWordLeft                // start of the word before
+	MoveWordRight               // end of the word after
+	MoveLineStart               // start of the line
+	MoveLineEnd                 // end of the line
+	MoveTextStart               // start of the text
+	MoveTextEnd                 // end of the text
+)
+
+// Move moves every cursor by motion. With extend, each selection grows or
+// shrinks from its anchor. Without it, selections are dropped, and a
+// character, line or word motion from a selection stops at the selection's
+// edge in that direction, as in the text area. Cursors that end up in the
+// same place, or with selections that overlap or touch, are merged.
+func (mc *MultiCursor) Move(text string, motion Motion, extend bool) {
+	if mc == nil || len(mc.cursors) == 0 {
+		return
+	}
 	runes := []rune(text)
-	query := string(runes[start:end])
-	if query == "" {
+	starts := lineStarts(runes)
+	for i, c := range mc.cursors {
+		start, end := mc.selectionRange(c, len(runes))
+		off := min(max(c.Offset, 0), len(runes))
+		switch {
+		case extend:
+			mc.cursors[i] = Cursor{Offset: moveOffset(runes, starts, off, motion), Anchor: min(max(c.Anchor, 0), len(runes))}
+			continue
+		case start < end && (motion == MoveLeft || motion == MoveUp || motion == MoveWordLeft):
+			off = start
+		case start < end && (motion == MoveRight || motion == MoveDown || motion == MoveWordRight):
+			off = end
+		default:
+			off = moveOffset(runes, starts, off, motion)
+		}
+		mc.cursors[i] = Cursor{Offset: off, Anchor: off}
+	}
+	mc.mergeCursors()
+}
+
+// moveOffset returns where motion moves a cursor at off. Up and down keep
+// the column, or go to the end of a shorter line, and stay put on the first
+// and last line.
+func moveOffset(runes []rune, starts []int, off int, motion Motion) int {
+	line := lineIndex(starts, off)
+	switch motion {
+	case MoveLeft:
+		return max(off-1, 0)
+	case MoveRight:
+		return min(off+1, len(runes))
+	case MoveUp, MoveDown:
+		target := line - 1
+		if motion == MoveDown {
+			target = line + 1
+		}
+		if target < 0 || target >= len(starts) {
+			return off
+		}
+		return min(starts[target]+off-starts[line], lineEnd(runes, starts, target))
+	case MoveWordLeft:
+		return BackwardWord(runes, off)
+	case MoveWordRight:
+		return ForwardWord(runes, off)
+	case MoveLineStart:
+		return starts[line]
+	case MoveLineEnd:
+		return lineEnd(runes, starts, line)
+	case MoveTextStart:
+		return 0
+	case MoveTextEnd:
+		return len(runes)
+	}
+	return off
+}
+
+// mergeCursors merges cursors in the same place and cursors whose
+// selections overlap or touch. A merged cursor
+// selects all of their selections, and is the primary one if any of them
+// was. The other cursors then follow in document order.
+func (mc *MultiCursor) mergeCursors() {
+	type span struct {
+		cursor     Cursor
+		start, end int
+		primary    bool
+	}
+	spans := make([]span, len(mc.cursors))
+	for i, c := range mc.cursors {
+		start, end := orderedRuneRange(c.Offset, c.Anchor)
+		spans[i] = span{cursor: c, start: start, end: end, primary: i == 0}
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := make([]span, 0, len(spans))
+	for _, s := range spans {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if s.start <= last.end {
+				last.end = max(last.end, s.end)
+				last.primary = last.primary || s.primary
+				continue
+			}
+		}
+		merged = append(merged, s)
+	}
+	if len(merged) == len(mc.cursors) {
+		return
+	}
+	cursors := make([]Cursor, 1, len(merged))
+	for _, s := range merged {
+		c := Cursor{Offset: s.end, Anchor: s.start}
+		if s.cursor.Offset < s.cursor.Anchor {
+			c = Cursor{Offset: s.start, Anchor: s.end}
+		}
+		if s.primary {
+			cursors[0] = c
+		} else {
+			cursors = append(cursors, c)
+		}
+	}
+	mc.cursors = cursors
+}
+
+// occurrenceQuery returns the text selected by the last cursor and where
+// the selection ends, or false if it has none.
+func (mc *MultiCursor) occurrenceQuery(runes []rune) ([]rune, int, bool) {
+	if mc == nil || len(mc.cursors) == 0 {
+		return nil, 0, false
+	}
+	last := mc.cursors[len(mc.cursors)-1]
+	start, end := mc.selectionRange(last, len(runes))
+	if start == end {
+		return nil, 0, false
+	}
+	return runes[start:end], end, true
+}
+
+// selectWord selects the word around the last cursor, reporting false if
+// the cursor is not in or next to one.
+func (mc *MultiCursor) selectWord(runes []rune) bool {
+	if mc == nil || len(mc.cursors) == 0 {
+		return false
+	}
+	i := len(mc.cursors) - 1
+	start, end := WordAt(runes, mc.cursors[i].Offset)
+	if start == end {
 		return false
 	}
-	queryLen := len([]rune(query))
+	mc.pushHistory()
+	mc.cursors[i] = Cursor{Offset: end, Anchor: start}
+	return true
+}
 
+// nextOccurrence returns the start of the first match of query at or after
+// from that no cursor's selection overlaps, wrapping round to the start, or
+// -1.
+func (mc *MultiCursor) nextOccurrence(runes, query []rune, from int, opts FindOptions) int {
 	search := func(from int) int {
-		for from < len(runes) {
-			idx := strings.Index(string(runes[from:]), query)
-			if idx < 0 {
-				return -1
-			}
-			candidate := from + idx
-			if candidate < 0 || candidate+queryLen > len(runes) {
-				return -1
-			}
-			if !mc.hasRange(candidate, candidate+queryLen) {
-				return candidate
+		for i := from; i+len(query) <= len(runes); i++ {
+			if matchesAt(runes, query, i, opts) && !mc.overlapsSelection(i, i+len(query)) {
+				return i
 			}
-			from = candidate + queryLen
 		}
 		return -1
 	}
+	if i := search(from); i >= 0 {
+		return i
+	}
+	return search(0)
+}
 
-	candidate := search(end)
-	if candidate < 0 {
-		candidate = search(0)
+// overlapsSelection reports whether a cursor's selection overlaps the range
+// from start to end.
+func (mc *MultiCursor) overlapsSelection(start, end int) bool {
+	for _, c := range mc.cursors {
+		s, e := orderedRuneRange(c.Offset, c.Anchor)
+		if s < e && s < end && start < e {
+			return true
+		}
 	}
-	if candidate < 0 {
+	return false
+}
+
+// matchesAt reports whether query matches runes at i, ignoring case and
+// requiring whole words as opts asks. Regex and PreserveCase do not apply.
+func matchesAt(runes, query []rune, i int, opts FindOptions) bool {
+	if i < 0 || i+len(query) > len(runes) {
 		return false
 	}
-
-	mc.AddSelection(candidate, candidate+queryLen)
+	for j, q := range query {
+		r := runes[i+j]
+		if r != q && (!opts.IgnoreCase || unicode.ToLower(r) != unicode.ToLower(q)) {
+			return false
+		}
+	}
+	if opts.WholeWord {
+		if i > 0 && isWordRune(runes[i-1]) {
+			return false
+		}
+		if end := i + len(query); end < len(runes) && isWordRune(runes[end]) {
+			return false
+		}
+	}
 	return true
 }
 
+// lineIndex returns the line holding off, given the line starts.
+func lineIndex(starts []int, off int) int {
+	return sort.Search(len(starts), func(i int) bool { return starts[i] > off }) - 1
+}
+
+// lineEnd returns the offset of the line break ending line, or the end of
+// the text on the last line.
+func lineEnd(runes []rune, starts []int, line int) int {
+	if line+1 < len(starts) {
+		return starts[line+1] - 1
+	}
+	return len(runes)
+}
+
+// lineStarts returns the offset of the first rune of each line.
+func lineStarts(runes []rune) []int {
+	starts := []int{0}
+	for i, r := range runes {
+		if r == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
 // InsertAtAll inserts the provided text at every cursor (or replaces each
 // cursor selection).
 func (mc *MultiCursor) InsertAtAll(text string, insert string) string {
@@ -155,9 +484,9 @@ func (mc *MultiCursor) InsertAtAll(text string, insert string) string {
 	inserted := []rune(insert)
 	runeLen := len([]rune(text))
 	edits := make([]editRange, 0, len(mc.cursors))
-	for _, c := range mc.Cursors() {
+	for i, c := range mc.cursors {
 		start, end := mc.selectionRange(c, runeLen)
-		edits = append(edits, editRange{Start: start, End: end, Text: inserted})
+		edits = append(edits, editRange{Start: start, End: end, Text: inserted, cursor: i})
 	}
 	return mc.applyEdits(text, edits)
 }
@@ -166,7 +495,7 @@ func (mc *MultiCursor) InsertAtAll(text string, insert string) string {
 func (mc *MultiCursor) DeleteBackspace(text string) string {
 	runeLen := len([]rune(text))
 	edits := make([]editRange, 0, mc.Count())
-	for _, c := range mc.Cursors() {
+	for i, c := range mc.Cursors() {
 		start, end := mc.selectionRange(c, runeLen)
 		if start == end {
 			if start == 0 {
@@ -174,7 +503,7 @@ func (mc *MultiCursor) DeleteBackspace(text string) string {
 			}
 			start--
 		}
-		edits = append(edits, editRange{Start: start, End: end})
+		edits = append(edits, editRange{Start: start, End: end, cursor: i})
 	}
 	return mc.applyEdits(text, edits)
 }
@@ -183,7 +512,7 @@ func (mc *MultiCursor) DeleteBackspace(text string) string {
 func (mc *MultiCursor) DeleteForward(text string) string {
 	runeLen := len([]rune(text))
 	edits := make([]editRange, 0, mc.Count())
-	for _, c := range mc.Cursors() {
+	for i, c := range mc.Cursors() {
 		start, end := mc.selectionRange(c, runeLen)
 		if start == end {
 			if start >= runeLen {
@@ -191,15 +520,75 @@ func (mc *MultiCursor) DeleteForward(text string) string {
 			}
 			end++
 		}
-		edits = append(edits, editRange{Start: start, End: end})
+		edits = append(edits, editRange{Start: start, End: end, cursor: i})
 	}
 	return mc.applyEdits(text, edits)
 }
 
+// SelectedTexts returns the text each cursor selects, in document order.
+// A cursor without a selection gives an empty string.
+func (mc *MultiCursor) SelectedTexts(text string) []string {
+	runes := []rune(text)
+	parts := make([]string, 0, mc.Count())
+	for _, i := range mc.documentOrder() {
+		start, end := mc.selectionRange(mc.cursors[i], len(runes))
+		parts = append(parts, string(runes[start:end]))
+	}
+	return parts
+}
+
+// InsertEach replaces the selection of each cursor, in document order, with
+// the matching entry of inserts, so text copied with SelectedTexts pastes
+// back one entry per cursor. Given a different number of entries than
+// cursors, it returns text unchanged.
+func (mc *MultiCursor) InsertEach(text string, inserts []string) string {
+	if mc == nil || len(inserts) != len(mc.cursors) {
+		return text
+	}
+	runeLen := len([]rune(text))
+	edits := make([]editRange, 0, len(mc.cursors))
+	for n, i := range mc.documentOrder() {
+		start, end := mc.selectionRange(mc.cursors[i], runeLen)
+		edits = append(edits, editRange{Start: start, End: end, Text: []rune(inserts[n]), cursor: i})
+	}
+	return mc.applyEdits(text, edits)
+}
+
+// SplitPaste splits pasted text into one line for each of n cursors, or
+// returns nil if it does not have n lines. A line break ending the text
+// does not start another line.
+func SplitPaste(text string, n int) []string {
+	if n < 2 {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) != n {
+		return nil
+	}
+	return lines
+}
+
+// documentOrder returns the indexes of the cursors sorted by position.
+func (mc *MultiCursor) documentOrder() []int {
+	order := make([]int, len(mc.cursors))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, _ := orderedRuneRange(mc.cursors[order[i]].Offset, mc.cursors[order[i]].Anchor)
+		b, _ := orderedRuneRange(mc.cursors[order[j]].Offset, mc.cursors[order[j]].Anchor)
+		return a < b
+	})
+	return order
+}
+
+// editRange replaces the runes [Start, End) with Text on behalf of the
+// cursor with index cursor, which ends up after Text.
 type editRange struct {
-	Start int
-	End   int
-	Text  []rune
+	Start  int
+	End    int
+	Text   []rune
+	cursor int
 }
 
 func (mc *MultiCursor) applyEdits(text string, edits []editRange) string {
@@ -237,33 +626,59 @@ func (mc *MultiCursor) applyEdits(text string, edits []editRange) string {
 		}
 		return edits[i].Start < edits[j].Start
 	})
+	// owner maps each cursor to the edit it ends up after.
+	owner := make(map[int]int, len(edits))
 	merged := make([]editRange, 0, len(edits))
 	for _, e := range edits {
-		if len(merged) == 0 {
-			merged = append(merged, e)
-			continue
-		}
-
-		last := &merged[len(merged)-1]
-		if e.Start == last.Start && e.End == last.End {
-			continue
-		}
-		// If overlapping edits are provided, keep the first edit only.
-		if e.Start < last.End {
-			continue
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			// If overlapping edits are provided, keep the first edit only.
+			if e.Start == last.Start && e.End == last.End || e.Start < last.End {
+				owner[e.cursor] = n - 1
+				continue
+			}
 		}
+		owner[e.cursor] = len(merged)
 		merged = append(merged, e)
 	}
 	edits = merged
 
+	// ends[i] is where the text of edit i ends once all are applied.
+	ends := make([]int, len(edits))
+	delta := 0
+	for i, e := range edits {
+		ends[i] = e.Start + delta + len(e.Text)
+		delta += len(e.Text) - (e.End - e.Start)
+	}
 	for i := len(edits) - 1; i >= 0; i-- {
 		e := edits[i]
 		runes = append(runes[:e.Start], append(e.Text, runes[e.End:]...)...)
 	}
 
-	// Update cursor positions after edits.
+	// Move each cursor that made an edit after it, and the others past the
+	// edits, including those UndoCursorAdd goes back to; then merge cursors
+	// the edits brought together. Remapping a cursor from its own edit keeps
+	// it apart from a neighbour whose selection touched its own.
+	others := remapCursors(mc.Cursors(), edits, len(runes))
 	for i := range mc.cursors {
-		c := mc.cursors[i]
+		if k, ok := owner[i]; ok {
+			mc.cursors[i] = Cursor{Offset: ends[k], Anchor: ends[k]}
+		} else {
+			mc.cursors[i] = others[i]
+		}
+	}
+	for i, cursors := range mc.history {
+		mc.history[i] = remapCursors(cursors, edits, len(runes))
+	}
+	mc.mergeCursors()
+
+	return string(runes)
+}
+
+// remapCursors moves cursors past edits, keeping them within textLen.
+func remapCursors(cursors []Cursor, edits []editRange, textLen int) []Cursor {
+	for i := range cursors {
+		c := cursors[i]
 		c.Offset = updateOffset(c.Offset, edits)
 		c.Anchor = updateOffset(c.Anchor, edits)
 		if c.Offset < 0 {
@@ -272,16 +687,15 @@ func (mc *MultiCursor) applyEdits(text string, edits []editRange) string {
 		if c.Anchor < 0 {
 			c.Anchor = 0
 		}
-		if c.Offset > len(runes) {
-			c.Offset = len(runes)
+		if c.Offset > textLen {
+			c.Offset = textLen
 		}
-		if c.Anchor > len(runes) {
-			c.Anchor = len(runes)
+		if c.Anchor > textLen {
+			c.Anchor = textLen
 		}
-		mc.cursors[i] = c
+		cursors[i] = c
 	}
-
-	return string(runes)
+	return cursors
 }
 
 func updateOffset(offset int, edits []editRange) int {
@@ -306,16 +720,6 @@ func updateOffset(offset int, edits []editRange) int {
 	return cur
 }
 
-func (mc *MultiCursor) hasRange(start, end int) bool {
-	for _, c := range mc.cursors {
-		s, e := orderedRuneRange(c.Offset, c.Anchor)
-		if s == start && e == end {
-			return true
-		}
-	}
-	return false
-}
-
 func (mc *MultiCursor) selectionRange(c Cursor, textLen int) (int, int) {
 	start, end := orderedRuneRange(c.Offset, c.Anchor)
 	if start < 0 {
@@ -1,6 +1,9 @@
 package editor
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestNewMultiCursor(t *testing.T) {
 	mc := NewMultiCursor()
@@ -75,13 +78,13 @@ func TestMultiCursorAddNextOccurrence(t *testing.T) {
 	mc := NewMultiCursor()
 	mc.SetPrimary(0, 3)
 
-	if !mc.AddNextOccurrence("foo foo foo") {
+	if !mc.AddNextOccurrence("foo foo foo", FindOptions{}) {
 		t.Fatalf("first AddNextOccurrence() = false, want true")
 	}
-	if !mc.AddNextOccurrence("foo foo foo") {
+	if !mc.AddNextOccurrence("foo foo foo", FindOptions{}) {
 		t.Fatalf("second AddNextOccurrence() = false, want true")
 	}
-	if mc.AddNextOccurrence("foo foo foo") {
+	if mc.AddNextOccurrence("foo foo foo", FindOptions{}) {
 		t.Fatalf("third AddNextOccurrence() = true, want false")
 	}
 
@@ -104,3 +107,273 @@ func TestMultiCursorReset(t *testing.T) {
 		t.Fatalf("Primary() = %+v, want {10 2}", primary)
 	}
 }
+
+func TestMultiCursorSetCursors(t *testing.T) {
+	mc := NewMultiCursor()
+	cursors := []Cursor{{Offset: 4, Anchor: 2}, {Offset: 9, Anchor: 9}}
+	mc.SetCursors(cursors)
+	cursors[0].Offset = 0
+	got := mc.Cursors()
+	if len(got) != 2 || got[0] != (Cursor{Offset: 4, Anchor: 2}) || got[1].Offset != 9 {
+		t.Fatalf("Cursors() = %+v after SetCursors", got)
+	}
+	mc.SetCursors(nil)
+	if mc.Count() != 1 || mc.Primary() != (Cursor{}) {
+		t.Fatalf("SetCursors(nil) left %+v, want a single cursor at 0", mc.Cursors())
+	}
+}
+
+func cursorSelections(mc *MultiCursor) [][2]int {
+	var out [][2]int
+	for _, c := range mc.Cursors() {
+		out = append(out, [2]int{c.Anchor, c.Offset})
+	}
+	return out
+}
+
+func TestMultiCursorOccurrenceOptions(t *testing.T) {
+	text := "foo Foo food foo"
+	for _, tc := range []struct {
+		opts FindOptions
+		want int // start of the occurrence added after the first
+	}{
+		{FindOptions{}, 8},
+		{FindOptions{IgnoreCase: true}, 4},
+		{FindOptions{WholeWord: true}, 13},
+		{FindOptions{IgnoreCase: true, WholeWord: true}, 4},
+	} {
+		mc := NewMultiCursor()
+		mc.SetPrimary(3, 0)
+		if !mc.AddNextOccurrence(text, tc.opts) {
+			t.Errorf("%+v: AddNextOccurrence() = false", tc.opts)
+			continue
+		}
+		if got := mc.Cursors()[1]; got != (Cursor{Offset: tc.want + 3, Anchor: tc.want}) {
+			t.Errorf("%+v: added %+v, want the occurrence at %d", tc.opts, got, tc.want)
+		}
+	}
+}
+
+func TestMultiCursorAddNextOccurrenceSelectsWord(t *testing.T) {
+	mc := NewMultiCursor()
+	mc.SetPrimary(5, 5)
+	if !mc.AddNextOccurrence("a foo_bar b foo_bar", FindOptions{}) {
+		t.Fatal("AddNextOccurrence() on an empty selection = false")
+	}
+	if got := mc.Primary(); got != (Cursor{Offset: 9, Anchor: 2}) || mc.Count() != 1 {
+		t.Fatalf("selected %+v, want the word around the cursor", mc.Cursors())
+	}
+	mc.AddNextOccurrence("a foo_bar b foo_bar", FindOptions{})
+	if mc.Count() != 2 || mc.Cursors()[1].Anchor != 12 {
+		t.Fatalf("cursors = %+v, want the second foo_bar added", mc.Cursors())
+	}
+	mc.Reset()
+	mc.SetPrimary(2, 2)
+	if mc.AddNextOccurrence("a  b", FindOptions{}) {
+		t.Error("AddNextOccurrence() between spaces = true")
+	}
+}
+
+func TestMultiCursorSkipOccurrence(t *testing.T) {
+	text := "ab ab ab ab"
+	mc := NewMultiCursor()
+	mc.SetPrimary(2, 0)
+	mc.AddNextOccurrence(text, FindOptions{})
+	if !mc.SkipOccurrence(text, FindOptions{}) {
+		t.Fatal("SkipOccurrence() = false")
+	}
+	want := [][2]int{{0, 2}, {6, 8}}
+	if got := cursorSelections(mc); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after skip: %v, want %v", got, want)
+	}
+	mc.SkipOccurrence(text, FindOptions{})
+	mc.SkipOccurrence(text, FindOptions{}) // wraps past the primary cursor
+	want = [][2]int{{0, 2}, {3, 5}}
+	if got := cursorSelections(mc); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after wrapping: %v, want %v", got, want)
+	}
+	for range 3 {
+		mc.UndoCursorAdd()
+	}
+	want = [][2]int{{0, 2}, {3, 5}}
+	if got := cursorSelections(mc); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after undoing the skips: %v, want %v", got, want)
+	}
+	if !mc.UndoCursorAdd() || mc.Count() != 1 || mc.UndoCursorAdd() {
+		t.Fatalf("undoing the add left %v", cursorSelections(mc))
+	}
+}
+
+func TestMultiCursorSelectAllOccurrences(t *testing.T) {
+	text := "x = x + xx; X"
+	mc := NewMultiCursor()
+	mc.SetPrimary(4, 4)
+	if !mc.SelectAllOccurrences(text, FindOptions{WholeWord: true}) {
+		t.Fatal("SelectAllOccurrences() = false")
+	}
+	want := [][2]int{{4, 5}, {0, 1}}
+	if got := cursorSelections(mc); !reflect.DeepEqual(got, want) {
+		t.Fatalf("whole word: %v, want %v", got, want)
+	}
+	if mc.SelectAllOccurrences(text, FindOptions{WholeWord: true}) {
+		t.Error("SelectAllOccurrences() with all selected = true")
+	}
+	mc.UndoCursorAdd()
+	mc.UndoCursorAdd()
+	if got := mc.Primary(); mc.Count() != 1 || got != (Cursor{Offset: 4, Anchor: 4}) {
+		t.Fatalf("undo left %v, want the cursor it started from", cursorSelections(mc))
+	}
+
+	mc.SetPrimary(1, 0)
+	mc.SelectAllOccurrences(text, FindOptions{IgnoreCase: true})
+	want = [][2]int{{0, 1}, {4, 5}, {8, 9}, {9, 10}, {12, 13}}
+	if got := cursorSelections(mc); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ignoring case: %v, want %v", got, want)
+	}
+}
+
+func TestMultiCursorAddCursorAboveBelow(t *testing.T) {
+	text := "hello world\nhi\n\nsecond long line\nend"
+	mc := NewMultiCursor()
+	mc.SetPrimary(18, 18) // line 3, column 2
+	if !mc.AddCursorAbove(text) || !mc.AddCursorAbove(text) || !mc.AddCursorAbove(text) {
+		t.Fatal("AddCursorAbove() = false")
+	}
+	if mc.AddCursorAbove(text) {
+		t.Error("AddCursorAbove() on the first line = true")
+	}
+	var offsets []int
+	for _, c := range mc.Cursors() {
+		offsets = append(offsets, c.Offset)
+	}
+	// Column 2 is kept across the short and empty lines.
+	if want := []int{18, 15, 14, 2}; !reflect.DeepEqual(offsets, want) {
+		t.Fatalf("cursors above = %v, want %v", offsets, want)
+	}
+	if !mc.AddCursorBelow(text) || mc.Cursors()[4].Offset != 35 {
+		t.Fatalf("AddCursorBelow() = %v, want a cursor at 35", mc.Cursors())
+	}
+	if mc.AddCursorBelow(text) {
+		t.Error("AddCursorBelow() on the last line = true")
+	}
+}
+
+func TestMultiCursorUndoFollowsEdits(t *testing.T) {
+	mc := NewMultiCursor()
+	mc.SetPrimary(0, 0)
+	mc.AddCursorBelow("ab\ncd")
+	mc.AddCursorBelow("ab\ncd") // nothing below
+	text := mc.InsertAtAll("ab\ncd", "--")
+	mc.UndoCursorAdd()
+	if text != "--ab\n--cd" || mc.Count() != 1 || mc.Primary().Offset != 2 {
+		t.Fatalf("text %q, cursors %+v", text, mc.Cursors())
+	}
+}
+
+func TestMultiCursorMove(t *testing.T) {
+	text := "one two\nab\nthree four"
+	for _, tc := range []struct {
+		name    string
+		cursors []Cursor
+		motion  Motion
+		extend  bool
+		want    [][2]int
+	}{
+		{"right", []Cursor{{0, 0}, {8, 8}}, MoveRight, false, [][2]int{{1, 1}, {9, 9}}},
+		{"left collapses selection", []Cursor{{3, 0}, {10, 8}}, MoveLeft, false, [][2]int{{0, 0}, {8, 8}}},
+		{"down keeps column", []Cursor{{1, 1}, {9, 9}}, MoveDown, false, [][2]int{{9, 9}, {12, 12}}},
+		{"down to shorter line", []Cursor{{6, 6}}, MoveDown, false, [][2]int{{10, 10}}},
+		{"up on first line", []Cursor{{2, 2}, {9, 9}}, MoveUp, false, [][2]int{{2, 2}, {1, 1}}},
+		{"word right", []Cursor{{0, 0}, {11, 11}}, MoveWordRight, false, [][2]int{{3, 3}, {16, 16}}},
+		{"word left", []Cursor{{6, 6}, {20, 20}}, MoveWordLeft, false, [][2]int{{4, 4}, {17, 17}}},
+		{"line start", []Cursor{{5, 5}, {14, 14}}, MoveLineStart, false, [][2]int{{0, 0}, {11, 11}}},
+		{"line end", []Cursor{{5, 5}, {14, 14}}, MoveLineEnd, false, [][2]int{{7, 7}, {21, 21}}},
+		{"extend", []Cursor{{0, 0}, {8, 8}}, MoveWordRight, true, [][2]int{{0, 3}, {8, 10}}},
+		{"extend shrinks", []Cursor{{3, 0}}, MoveLeft, true, [][2]int{{0, 2}}},
+		{"text end merges", []Cursor{{0, 0}, {8, 8}}, MoveTextEnd, false, [][2]int{{21, 21}}},
+	} {
+		mc := NewMultiCursor()
+		mc.SetCursors(tc.cursors)
+		mc.Move(text, tc.motion, tc.extend)
+		if got := cursorSelections(mc); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: cursors = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMultiCursorMergesCollidingCursors(t *testing.T) {
+	mc := NewMultiCursor()
+	mc.SetCursors([]Cursor{{Offset: 6, Anchor: 6}, {Offset: 2, Anchor: 2}, {Offset: 3, Anchor: 3}})
+	mc.Move("abcdefgh", MoveLeft, false)
+	if got := mc.Count(); got != 3 {
+		t.Fatalf("Count() = %d, want 3 before cursors meet", got)
+	}
+	mc.Move("abcdefgh", MoveLineStart, false)
+	if got := cursorSelections(mc); !reflect.DeepEqual(got, [][2]int{{0, 0}}) {
+		t.Fatalf("cursors at the same place = %v, want one", got)
+	}
+
+	// Extending selections into each other merges them, keeping the
+	// primary cursor and its direction.
+	mc.SetCursors([]Cursor{{Offset: 4, Anchor: 4}, {Offset: 2, Anchor: 2}, {Offset: 7, Anchor: 7}})
+	mc.Move("abcdefgh", MoveRight, true)
+	mc.Move("abcdefgh", MoveRight, true)
+	if got := cursorSelections(mc); !reflect.DeepEqual(got, [][2]int{{2, 6}, {7, 8}}) {
+		t.Fatalf("overlapping selections = %v, want merged", got)
+	}
+
+	// Backspacing cursors into each other merges them too.
+	mc.SetCursors([]Cursor{{Offset: 1, Anchor: 1}, {Offset: 2, Anchor: 2}})
+	if got := mc.DeleteBackspace("abc"); got != "c" {
+		t.Fatalf("DeleteBackspace = %q, want %q", got, "c")
+	}
+	if got := mc.Count(); got != 1 {
+		t.Errorf("Count() after backspacing together = %d, want 1", got)
+	}
+}
+
+func TestMultiCursorTouchingSelections(t *testing.T) {
+	mc := NewMultiCursor()
+	mc.SetPrimary(3, 0)
+	if !mc.SelectAllOccurrences("foofoo", FindOptions{}) || mc.Count() != 2 {
+		t.Fatalf("SelectAllOccurrences selected %v, want both", cursorSelections(mc))
+	}
+	text := mc.InsertAtAll("foofoo", "x")
+	text = mc.InsertAtAll(text, "y")
+	if text != "xyxy" || mc.Count() != 2 {
+		t.Fatalf("typing over touching selections = %q with %d cursors, want %q with 2", text, mc.Count(), "xyxy")
+	}
+
+	mc.SetCursors([]Cursor{{Offset: 2, Anchor: 0}})
+	mc.AddSelection(2, 4)
+	text = mc.InsertEach("abcd", []string{"1", "2"})
+	if text != "12" || !reflect.DeepEqual(cursorSelections(mc), [][2]int{{1, 1}, {2, 2}}) {
+		t.Fatalf("InsertEach over touching selections = %q, cursors %v", text, cursorSelections(mc))
+	}
+}
+
+func TestMultiCursorClipboard(t *testing.T) {
+	text := "x = 1\ny = 22\nz = 333"
+	mc := NewMultiCursor()
+	mc.SetCursors([]Cursor{{Offset: 20, Anchor: 17}, {Offset: 5, Anchor: 4}, {Offset: 12, Anchor: 10}})
+	copied := mc.SelectedTexts(text)
+	if want := []string{"1", "22", "333"}; !reflect.DeepEqual(copied, want) {
+		t.Fatalf("SelectedTexts = %q, want %q", copied, want)
+	}
+
+	mc.SetCursors([]Cursor{{Offset: 0, Anchor: 0}, {Offset: 6, Anchor: 6}, {Offset: 13, Anchor: 13}})
+	got := mc.InsertEach(text, []string{"a", "bb", "ccc"})
+	if want := "ax = 1\nbby = 22\ncccz = 333"; got != want {
+		t.Fatalf("InsertEach = %q, want %q", got, want)
+	}
+	if got := mc.InsertEach(text, []string{"a"}); got != text {
+		t.Errorf("InsertEach with too few entries = %q, want the text unchanged", got)
+	}
+
+	if got := SplitPaste("one\ntwo\n", 2); !reflect.DeepEqual(got, []string{"one", "two"}) {
+		t.Errorf("SplitPaste = %q", got)
+	}
+	if got := SplitPaste("one\ntwo", 3); got != nil {
+		t.Errorf("SplitPaste with a line per cursor missing = %q, want nil", got)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package editor
+
+import "os/exec"
+
+// processAlive reports whether a process with the given ID is running. It
+// cannot tell on this platform and assumes the process has exited.
+func processAlive(pid int) bool {
+	return false
+}
+
+// shellArgs returns the command line running command with the shell.
+func shellArgs(command string) []string {
+	return []string{"cmd", "/C", command}
+}
+
+// killGroupOnCancel leaves cmd as it is: cancelling it kills the shell, and
+// WaitDelay stops waiting for what it started.
+func killGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package editor
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given ID is running.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// shellArgs returns the command line running command with the shell.
+func shellArgs(command string) []string {
+	return []string{"/bin/sh", "-c", command}
+}
+
+// killGroupOnCancel starts cmd in a process group of its own and has
+// cancelling it kill the whole group, so the commands a shell started do
+// not outlive it.
+func killGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,338 @@
+package editor
+
+import (
+	"io"
+	"strings"
+)
+
+// ropeChunkSize is the maximum number of bytes stored in a single leaf.
+const ropeChunkSize = 1024
+
+// rope is a persistent, height-balanced binary tree of text chunks. Nodes are
+// never mutated after construction, so a rope value doubles as an O(1)
+// snapshot of the text it holds. Edits cost O(log n).
+type rope struct {
+	root *ropeNode
+}
+
+// ropeNode is either a leaf (left and right are nil) holding a chunk of
+// text, or an internal node caching the length and newline count of its
+// subtree.
+type ropeNode struct {
+	left, right *ropeNode
+	text        string // leaf content; empty for internal nodes
+	length      int    // total bytes in the subtree
+	lines       int    // number of '\n' bytes in the subtree
+	height      int    // 1 for leaves
+}
+
+// newRope builds a balanced rope holding s.
+func newRope(s string) rope {
+	return newRopeChunked(s, ropeChunkSize)
+}
+
+// newRopeChunked builds a balanced rope holding s in leaves of up to chunk
+// bytes. The leaves share s's memory rather than copying it.
+func newRopeChunked(s string, chunk int) rope {
+	if s == "" {
+		return rope{}
+	}
+	leaves := make([]*ropeNode, 0, len(s)/chunk+1)
+	for len(s) > 0 {
+		n := min(len(s), chunk)
+		leaves = append(leaves, newRopeLeaf(s[:n]))
+		s = s[n:]
+	}
+	return rope{root: buildRope(leaves)}
+}
+
+func buildRope(leaves []*ropeNode) *ropeNode {
+	switch len(leaves) {
+	case 0:
+		return nil
+	case 1:
+		return leaves[0]
+	}
+	mid := len(leaves) / 2
+	return newRopeInternal(buildRope(leaves[:mid]), buildRope(leaves[mid:]))
+}
+
+func newRopeLeaf(s string) *ropeNode {
+	if s == "" {
+		return nil
+	}
+	return &ropeNode{text: s, length: len(s), lines: strings.Count(s, "\n"), height: 1}
+}
+
+func newRopeInternal(left, right *ropeNode) *ropeNode {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+	return &ropeNode{
+		left:   left,
+		right:  right,
+		length: left.length + right.length,
+		lines:  left.lines + right.lines,
+		height: max(left.height, right.height) + 1,
+	}
+}
+
+func (n *ropeNode) isLeaf() bool {
+	return n.left == nil && n.right == nil
+}
+
+func ropeHeight(n *ropeNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+// ropeBalance joins left and right whose heights differ by at most two,
+// rotating once or twice to restore the AVL invariant.
+func ropeBalance(left, right *ropeNode) *ropeNode {
+	hl, hr := ropeHeight(left), ropeHeight(right)
+	switch {
+	case hl > hr+1:
+		if ropeHeight(left.left) >= ropeHeight(left.right) {
+			return newRopeInternal(left.left, newRopeInternal(left.right, right))
+		}
+		lr := left.right
+		return newRopeInternal(newRopeInternal(left.left, lr.left), newRopeInternal(lr.right, right))
+	case hr > hl+1:
+		if ropeHeight(right.right) >= ropeHeight(right.left) {
+			return newRopeInternal(newRopeInternal(left, right.left), right.right)
+		}
+		rl := right.left
+		return newRopeInternal(newRopeInternal(left, rl.left), newRopeInternal(rl.right, right.right))
+	}
+	return newRopeInternal(left, right)
+}
+
+// ropeJoin concatenates two ropes in O(|height(a) - height(b)|). Small
+// adjacent leaves are merged so repeated single-character edits do not
+// fragment the tree.
+func ropeJoin(a, b *ropeNode) *ropeNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if b.isLeaf() {
+		if merged, ok := ropeAppendLeaf(a, b.text); ok {
+			return merged
+		}
+	}
+	if a.isLeaf() {
+		if merged, ok := ropePrependLeaf(b, a.text); ok {
+			return merged
+		}
+	}
+
+	ha, hb := ropeHeight(a), ropeHeight(b)
+	switch {
+	case ha > hb+1:
+		return ropeBalance(a.left, ropeJoin(a.right, b))
+	case hb > ha+1:
+		return ropeBalance(ropeJoin(a, b.left), b.right)
+	}
+	return newRopeInternal(a, b)
+}
+
+// ropeAppendLeaf merges s into the rightmost leaf of n when it fits.
+func ropeAppendLeaf(n *ropeNode, s string) (*ropeNode, bool) {
+	if n.isLeaf() {
+		if n.length+len(s) > ropeChunkSize {
+			return nil, false
+		}
+		return newRopeLeaf(n.text + s), true
+	}
+	right, ok := ropeAppendLeaf(n.right, s)
+	if !ok {
+		return nil, false
+	}
+	return newRopeInternal(n.left, right), true
+}
+
+// ropePrependLeaf merges s into the leftmost leaf of n when it fits.
+func ropePrependLeaf(n *ropeNode, s string) (*ropeNode, bool) {
+	if n.isLeaf() {
+		if n.length+len(s) > ropeChunkSize {
+			return nil, false
+		}
+		return newRopeLeaf(s + n.text), true
+	}
+	left, ok := ropePrependLeaf(n.left, s)
+	if !ok {
+		return nil, false
+	}
+	return newRopeInternal(left, n.right), true
+}
+
+// ropeSplit divides n at byte offset off.
+func ropeSplit(n *ropeNode, off int) (*ropeNode, *ropeNode) {
+	if n == nil {
+		return nil, nil
+	}
+	if off <= 0 {
+		return nil, n
+	}
+	if off >= n.length {
+		return n, nil
+	}
+	if n.isLeaf() {
+		return newRopeLeaf(n.text[:off]), newRopeLeaf(n.text[off:])
+	}
+	if off <= n.left.length {
+		ll, lr := ropeSplit(n.left, off)
+		return ll, ropeJoin(lr, n.right)
+	}
+	rl, rr := ropeSplit(n.right, off-n.left.length)
+	return ropeJoin(n.left, rl), rr
+}
+
+// Len returns the length of the text in bytes.
+func (r rope) Len() int {
+	if r.root == nil {
+		return 0
+	}
+	return r.root.length
+}
+
+// Newlines returns the number of '\n' bytes in the text.
+func (r rope) Newlines() int {
+	if r.root == nil {
+		return 0
+	}
+	return r.root.lines
+}
+
+// String materializes the full text. The result never shares memory with
+// the leaves, which may point into a file mapping.
+func (r rope) String() string {
+	if r.root == nil {
+		return ""
+	}
+	if r.root.isLeaf() {
+		return strings.Clone(r.root.text)
+	}
+	var sb strings.Builder
+	sb.Grow(r.root.length)
+	r.root.writeRange(&sb, 0, r.root.length)
+	return sb.String()
+}
+
+// Slice returns the text in the byte range [start, end), clamped to the
+// rope bounds.
+func (r rope) Slice(start, end int) string {
+	start = max(start, 0)
+	end = min(end, r.Len())
+	if start >= end {
+		return ""
+	}
+	var sb strings.Builder
+	sb.Grow(end - start)
+	r.root.writeRange(&sb, start, end)
+	return sb.String()
+}
+
+func (n *ropeNode) writeRange(sb *strings.Builder, start, end int) {
+	if n == nil || start >= end {
+		return
+	}
+	if n.isLeaf() {
+		sb.WriteString(n.text[start:end])
+		return
+	}
+	if start < n.left.length {
+		n.left.writeRange(sb, start, min(end, n.left.length))
+	}
+	if end > n.left.length {
+		n.right.writeRange(sb, max(start-n.left.length, 0), end-n.left.length)
+	}
+}
+
+// WriteTo writes the text to w leaf by leaf, without materializing it.
+func (r rope) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	var walk func(n *ropeNode) error
+	walk = func(n *ropeNode) error {
+		if n == nil {
+			return nil
+		}
+		if n.isLeaf() {
+			written, err := io.WriteString(w, n.text)
+			total += int64(written)
+			return err
+		}
+		if err := walk(n.left); err != nil {
+			return err
+		}
+		return walk(n.right)
+	}
+	err := walk(r.root)
+	return total, err
+}
+
+// Replace returns a new rope where the byte range [offset, offset+length)
+// has been replaced with text. The receiver is left unchanged.
+func (r rope) Replace(offset, length int, text string) rope {
+	offset = min(max(offset, 0), r.Len())
+	end := min(max(offset+length, offset), r.Len())
+	left, rest := ropeSplit(r.root, offset)
+	_, right := ropeSplit(rest, end-offset)
+	mid := newRope(text).root
+	return rope{root: ropeJoin(ropeJoin(left, mid), right)}
+}
+
+// LineStart returns the byte offset where the given 0-based line begins.
+// Lines past the end clamp to the text length.
+func (r rope) LineStart(line int) int {
+	if line <= 0 || r.root == nil {
+		return 0
+	}
+	if line > r.root.lines {
+		return r.root.length
+	}
+	n := r.root
+	base := 0
+	for !n.isLeaf() {
+		if line <= n.left.lines {
+			n = n.left
+			continue
+		}
+		line -= n.left.lines
+		base += n.left.length
+		n = n.right
+	}
+	idx := 0
+	for ; line > 0; line-- {
+		next := strings.IndexByte(n.text[idx:], '\n')
+		idx += next + 1
+	}
+	return base + idx
+}
+
+// LineOf returns the 0-based line containing the byte at offset.
+func (r rope) LineOf(offset int) int {
+	offset = min(max(offset, 0), r.Len())
+	n := r.root
+	line := 0
+	for n != nil && !n.isLeaf() {
+		if offset < n.left.length {
+			n = n.left
+			continue
+		}
+		offset -= n.left.length
+		line += n.left.lines
+		n = n.right
+	}
+	if n != nil {
+		line += strings.Count(n.text[:offset], "\n")
+	}
+	return line
+}
@@ -0,0 +1,98 @@
+package editor
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestRopeMatchesStringModel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "b", "\n", "é", "xyz", strings.Repeat("q", 700)}
+
+	model := ""
+	r := newRope("")
+	for i := 0; i < 2000; i++ {
+		offset := 0
+		if len(model) > 0 {
+			offset = rng.Intn(len(model) + 1)
+		}
+		length := 0
+		if rem := len(model) - offset; rem > 0 && rng.Intn(3) == 0 {
+			length = rng.Intn(min(rem, 50) + 1)
+		}
+		insert := ""
+		for n := rng.Intn(3); n > 0; n-- {
+			insert += alphabet[rng.Intn(len(alphabet))]
+		}
+
+		before := r
+		beforeText := model
+		r = r.Replace(offset, length, insert)
+		model = model[:offset] + insert + model[offset+length:]
+
+		if got := r.String(); got != model {
+			t.Fatalf("step %d: rope text mismatch (len %d vs %d)", i, len(got), len(model))
+		}
+		if before.String() != beforeText {
+			t.Fatalf("step %d: Replace mutated the previous snapshot", i)
+		}
+	}
+
+	if r.Len() != len(model) {
+		t.Fatalf("Len() = %d, want %d", r.Len(), len(model))
+	}
+	if r.Newlines() != strings.Count(model, "\n") {
+		t.Fatalf("Newlines() = %d, want %d", r.Newlines(), strings.Count(model, "\n"))
+	}
+}
+
+func TestRopeStaysBalanced(t *testing.T) {
+	r := newRope("")
+	for i := 0; i < 20000; i++ {
+		r = r.Replace(r.Len()/2, 0, "x\n")
+	}
+	// A balanced tree over ~40KB of 1KB chunks must stay shallow.
+	if h := ropeHeight(r.root); h > 20 {
+		t.Fatalf("rope height = %d after sequential inserts, want <= 20", h)
+	}
+}
+
+func TestRopeSlice(t *testing.T) {
+	text := strings.Repeat("0123456789", 500)
+	r := newRope(text)
+	cases := [][2]int{{0, 0}, {0, 10}, {1020, 1030}, {2040, 5000}, {-5, 3}, {4990, 6000}, {7, 3}}
+	for _, c := range cases {
+		start, end := max(c[0], 0), min(c[1], len(text))
+		want := ""
+		if start < end {
+			want = text[start:end]
+		}
+		if got := r.Slice(c[0], c[1]); got != want {
+			t.Errorf("Slice(%d, %d) = %q, want %q", c[0], c[1], got, want)
+		}
+	}
+}
+
+func TestRopeLineIndex(t *testing.T) {
+	text := strings.Repeat("line one\nsecond\n\nlast line without newline", 100)
+	r := newRope(text)
+	lines := strings.Split(text, "\n")
+
+	offset := 0
+	for i, line := range lines {
+		if got := r.LineStart(i); got != offset {
+			t.Fatalf("LineStart(%d) = %d, want %d", i, got, offset)
+		}
+		if got := r.LineOf(offset); got != i {
+			t.Fatalf("LineOf(%d) = %d, want %d", offset, got, i)
+		}
+		offset += len(line) + 1
+	}
+	if got := r.LineStart(len(lines) + 5); got != len(text) {
+		t.Fatalf("LineStart past end = %d, want %d", got, len(text))
+	}
+	if got := r.LineOf(len(text)); got != len(lines)-1 {
+		t.Fatalf("LineOf(len) = %d, want %d", got, len(lines)-1)
+	}
+}
@@ -0,0 +1,180 @@
+package editor
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FindOptions controls how a query matches. The zero value is a plain,
+// case-sensitive substring search.
+type FindOptions struct {
+	Regex        bool // the query is an RE2 regular expression; ^ and $ match at line breaks
+	IgnoreCase   bool
+	WholeWord    bool // matches must not touch letters, digits or underscores
+	PreserveCase bool // replacements follow the case of the text they replace
+}
+
+// searchMatch is a match and, for regular expressions, its submatch indices.
+type searchMatch struct {
+	Range
+	sub []int
+}
+
+// compileSearch turns query into a regular expression honouring opts.
+func compileSearch(query string, opts FindOptions) (*regexp.Regexp, error) {
+	pattern := query
+	if opts.Regex {
+		pattern = "(?m)" + pattern
+	} else {
+		pattern = regexp.QuoteMeta(query)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// findMatches returns the non-overlapping matches of query in text.
+func findMatches(text, query string, opts FindOptions) ([]searchMatch, error) {
+	if query == "" {
+		return nil, nil
+	}
+	if !opts.Regex && !opts.IgnoreCase && !opts.WholeWord {
+		var matches []searchMatch
+		for start := 0; ; {
+			idx := strings.Index(text[start:], query)
+			if idx < 0 {
+				return matches, nil
+			}
+			abs := start + idx
+			matches = append(matches, searchMatch{Range: Range{Start: abs, End: abs + len(query)}})
+			start = abs + len(query)
+		}
+	}
+
+	re, err := compileSearch(query, opts)
+	if err != nil {
+		return nil, err
+	}
+	var matches []searchMatch
+	for _, sub := range re.FindAllStringSubmatchIndex(text, -1) {
+		r := Range{Start: sub[0], End: sub[1]}
+		if opts.WholeWord && !isWholeWord(text, r) {
+			continue
+		}
+		matches = append(matches, searchMatch{Range: r, sub: sub})
+	}
+	return matches, nil
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isWholeWord reports whether r is not directly preceded or followed by a
+// word character.
+func isWholeWord(text string, r Range) bool {
+	if before, _ := utf8.DecodeLastRuneInString(text[:r.Start]); r.Start > 0 && isWordRune(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(text[r.End:]); r.End < len(text) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+// expandReplacement builds the replacement for m. With Regex set, $1, $name
+// and ${name} refer to capture groups and $$ is a literal dollar sign.
+func expandReplacement(re *regexp.Regexp, text, replacement string, m searchMatch, opts FindOptions) string {
+	out := replacement
+	if opts.Regex && re != nil && m.sub != nil {
+		out = string(re.ExpandString(nil, replacement, text, m.sub))
+	}
+	if opts.PreserveCase {
+		out = matchCase(text[m.Start:m.End], out)
+	}
+	return out
+}
+
+// matchCase adapts replacement to the case pattern of matched: all upper,
+// all lower, or capitalized. Other patterns leave replacement unchanged.
+func matchCase(matched, replacement string) string {
+	hasLetter := false
+	upper, lower := true, true
+	for _, r := range matched {
+		if unicode.IsLetter(r) {
+			hasLetter = true
+			upper = upper && !unicode.IsLower(r)
+			lower = lower && !unicode.IsUpper(r)
+		}
+	}
+	switch {
+	case !hasLetter:
+		return replacement
+	case upper && utf8.RuneCountInString(matched) > 1:
+		return strings.ToUpper(replacement)
+	case lower:
+		return strings.ToLower(replacement)
+	}
+	first, size := utf8.DecodeRuneInString(matched)
+	if unicode.IsUpper(first) && strings.ToLower(matched[size:]) == matched[size:] {
+		r, n := utf8.DecodeRuneInString(replacement)
+		return string(unicode.ToUpper(r)) + replacement[n:]
+	}
+	return replacement
+}
+
+// FindWithOptions returns the byte ranges of all non-overlapping matches of
+// query. It fails only if a regular expression query does not compile.
+func (b *Buffer) FindWithOptions(query string, opts FindOptions) ([]Range, error) {
+	matches, err := findMatches(b.Text(), query, opts)
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	ranges := make([]Range, len(matches))
+	for i, m := range matches {
+		ranges[i] = m.Range
+	}
+	return ranges, nil
+}
+
+// ReplaceWithOptions replaces the match of query starting at r.Start,
+// expanding capture groups and adapting case as opts asks. It does nothing
+// if no match starts there any more.
+func (b *Buffer) ReplaceWithOptions(query, replacement string, r Range, opts FindOptions) error {
+	text := b.Text()
+	matches, err := findMatches(text, query, opts)
+	if err != nil {
+		return err
+	}
+	re, _ := compileSearch(query, opts)
+	for _, m := range matches {
+		if m.Start == r.Start {
+			b.ApplyEdits("Replace", []TextEdit{{
+				Start: m.Start,
+				End:   m.End,
+				Text:  expandReplacement(re, text, replacement, m, opts),
+			}})
+			return nil
+		}
+	}
+	return nil
+}
+
+// ReplaceAllWithOptions replaces every match of query as one undoable step
+// and returns the number of replacements.
+func (b *Buffer) ReplaceAllWithOptions(query, replacement string, opts FindOptions) (int, error) {
+	text := b.Text()
+	matches, err := findMatches(text, query, opts)
+	if err != nil || len(matches) == 0 {
+		return 0, err
+	}
+	re, _ := compileSearch(query, opts)
+	edits := make([]TextEdit, len(matches))
+	for i, m := range matches {
+		edits[i] = TextEdit{Start: m.Start, End: m.End, Text: expandReplacement(re, text, replacement, m, opts)}
+	}
+	return b.ApplyEdits("Replace All", edits), nil
+}
@@ -0,0 +1,109 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindWithOptions(t *testing.T) {
+	text := "Foo foo food _foo FOO\nfoo2 café caf"
+	tests := []struct {
+		name  string
+		query string
+		opts  FindOptions
+		want  []Range
+	}{
+		{"plain", "foo", FindOptions{}, []Range{{4, 7}, {8, 11}, {14, 17}, {22, 25}}},
+		{"ignore case", "foo", FindOptions{IgnoreCase: true}, []Range{{0, 3}, {4, 7}, {8, 11}, {14, 17}, {18, 21}, {22, 25}}},
+		{"whole word", "foo", FindOptions{WholeWord: true}, []Range{{4, 7}}},
+		{"whole word ignore case", "foo", FindOptions{WholeWord: true, IgnoreCase: true}, []Range{{0, 3}, {4, 7}, {18, 21}}},
+		{"whole word unicode", "caf", FindOptions{WholeWord: true}, []Range{{33, 36}}},
+		{"regex", `fo+\d`, FindOptions{Regex: true}, []Range{{22, 26}}},
+		{"regex line anchor", `^foo`, FindOptions{Regex: true}, []Range{{22, 25}}},
+		{"regex chars are literal without regex", `fo+`, FindOptions{}, nil},
+	}
+	b := NewBuffer()
+	b.SetText(text)
+	for _, tt := range tests {
+		got, err := b.FindWithOptions(tt.query, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := b.FindWithOptions("(", FindOptions{Regex: true}); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+}
+
+func TestReplaceAllCaptureGroups(t *testing.T) {
+	b := NewBuffer()
+	b.SetText("x = foo(1)\ny = bar(22)\n")
+	n, err := b.ReplaceAllWithOptions(`(\w+)\((?P<arg>\d+)\)`, "${arg}.$1()", FindOptions{Regex: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || b.Text() != "x = 1.foo()\ny = 22.bar()\n" {
+		t.Fatalf("n = %d, text = %q", n, b.Text())
+	}
+	// One undo step reverts all replacements.
+	b.Undo()
+	if b.Text() != "x = foo(1)\ny = bar(22)\n" {
+		t.Fatalf("after undo: %q", b.Text())
+	}
+
+	// Without regex, dollars are literal.
+	b.SetText("a a")
+	if n, _ := b.ReplaceAllWithOptions("a", "$1", FindOptions{}); n != 2 || b.Text() != "$1 $1" {
+		t.Fatalf("literal: n = %d, text = %q", n, b.Text())
+	}
+}
+
+func TestReplacePreserveCase(t *testing.T) {
+	b := NewBuffer()
+	b.SetText("value Value VALUE vALue")
+	opts := FindOptions{IgnoreCase: true, PreserveCase: true}
+	if _, err := b.ReplaceAllWithOptions("value", "result", opts); err != nil {
+		t.Fatal(err)
+	}
+	if want := "result Result RESULT result"; b.Text() != want {
+		t.Fatalf("got %q, want %q", b.Text(), want)
+	}
+}
+
+func TestReplaceWithOptions(t *testing.T) {
+	b := NewBuffer()
+	b.SetText("id1 id2 id3")
+	opts := FindOptions{Regex: true}
+	ranges, _ := b.FindWithOptions(`id(\d)`, opts)
+	if err := b.ReplaceWithOptions(`id(\d)`, "n$1", ranges[1], opts); err != nil {
+		t.Fatal(err)
+	}
+	if b.Text() != "id1 n2 id3" {
+		t.Fatalf("got %q", b.Text())
+	}
+	// A stale range that no longer starts a match is ignored.
+	if err := b.ReplaceWithOptions(`id(\d)`, "n$1", Range{Start: 5, End: 7}, opts); err != nil || b.Text() != "id1 n2 id3" {
+		t.Fatalf("stale range: err %v, text %q", err, b.Text())
+	}
+}
+
+func TestMatchCase(t *testing.T) {
+	tests := []struct{ matched, repl, want string }{
+		{"foo", "Bar", "bar"},
+		{"FOO", "bar", "BAR"},
+		{"Foo", "bar", "Bar"},
+		{"F", "bar", "Bar"},
+		{"fOO", "bar", "bar"},
+		{"123", "bar", "bar"},
+		{"fooBar", "bazQux", "bazQux"},
+	}
+	for _, tt := range tests {
+		if got := matchCase(tt.matched, tt.repl); got != tt.want {
+			t.Errorf("matchCase(%q, %q) = %q, want %q", tt.matched, tt.repl, got, tt.want)
+		}
+	}
+}
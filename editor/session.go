@@ -0,0 +1,126 @@
+package editor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// sessionFileVersion is bumped whenever the on-disk session format changes.
+// Sessions written with another version are ignored.
+const sessionFileVersion = 1
+
+// Session is the workspace layout saved between runs: the open tabs with
+// their cursor, scroll and folds, and the state of the window around them.
+type Session struct {
+	Root          string       `json:"root"`                    // directory shown in the file tree
+	Tabs          []SessionTab `json:"tabs"`                    // files open in tabs, in tab order
+	Active        int          `json:"active"`                  // index into Tabs of the active tab
+	SidebarHidden bool         `json:"sidebarHidden,omitempty"` // the file tree was toggled off
+	SidebarRatio  float64      `json:"sidebarRatio,omitempty"`  // share of the width taken by the file tree
+	WordWrap      bool         `json:"wordWrap,omitempty"`
+}
+
+// SessionTab is a tab of a saved session: the file and how it was shown.
+type SessionTab struct {
+	Path string `json:"path"`
+	ViewState
+}
+
+type sessionFile struct {
+	Version int `json:"version"`
+	Session
+}
+
+// SessionStore keeps sessions in Dir. Unnamed sessions belong to a project
+// directory; named ones can be opened from anywhere.
+type SessionStore struct {
+	Dir string
+}
+
+// NewSessionStore creates a store rooted at dir. The directory is created on
+// first save.
+func NewSessionStore(dir string) *SessionStore {
+	return &SessionStore{Dir: dir}
+}
+
+// DefaultSessionDir returns $XDG_STATE_HOME/mane/sessions, falling back to
+// ~/.local/state/mane/sessions when XDG_STATE_HOME is unset.
+func DefaultSessionDir() (string, error) {
+	return stateDir("sessions")
+}
+
+// ValidSessionName reports whether name can be used for a named session:
+// letters, digits, '.', '-' and '_', not starting with a dot.
+func ValidSessionName(name string) bool {
+	if name == "" || name[0] == '.' {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// file returns the session file for the named session, or for the project
+// at root if name is empty.
+func (s *SessionStore) file(name, root string) (string, error) {
+	if name == "" {
+		sum := sha256.Sum256([]byte(root))
+		return filepath.Join(s.Dir, "dir-"+hex.EncodeToString(sum[:16])+".json"), nil
+	}
+	if !ValidSessionName(name) {
+		return "", fmt.Errorf("invalid session name %q", name)
+	}
+	return filepath.Join(s.Dir, name+".json"), nil
+}
+
+// Load reads the named session, or the session of the project at root if
+// name is empty. It returns nil without an error if there is no session or
+// it was written in another format version.
+func (s *SessionStore) Load(name, root string) (*Session, error) {
+	path, err := s.file(name, root)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f sessionFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("session %s: %w", path, err)
+	}
+	if f.Version != sessionFileVersion {
+		return nil, nil
+	}
+	return &f.Session, nil
+}
+
+// Save writes sess as the named session, or as the session of its root
+// directory if name is empty.
+func (s *SessionStore) Save(name string, sess *Session) error {
+	path, err := s.file(name, sess.Root)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(sessionFile{Version: sessionFileVersion, Session: *sess}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0o600)
+}
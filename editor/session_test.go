@@ -0,0 +1,79 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSessionStoreRoundTrip(t *testing.T) {
+	store := NewSessionStore(t.TempDir())
+	sess := &Session{
+		Root: "/work/project",
+		Tabs: []SessionTab{
+			{Path: "/work/project/main.go", ViewState: ViewState{
+				Cursors: []Cursor{{Offset: 120, Anchor: 110}, {Offset: 200, Anchor: 200}},
+				Folds:   []int{20, 40},
+				Scroll:  3,
+			}},
+			{Path: "/work/project/README.md"},
+		},
+		Active:        1,
+		SidebarHidden: true,
+		SidebarRatio:  0.3,
+		WordWrap:      true,
+	}
+	if err := store.Save("", sess); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := store.Load("", "/work/project")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !reflect.DeepEqual(got, sess) {
+		t.Errorf("Load = %+v, want %+v", got, sess)
+	}
+
+	if other, err := store.Load("", "/work/other"); other != nil || err != nil {
+		t.Errorf("Load of another project = %+v, %v; want nil, nil", other, err)
+	}
+}
+
+func TestSessionStoreNamed(t *testing.T) {
+	store := NewSessionStore(t.TempDir())
+	sess := &Session{Root: "/work/a", Tabs: []SessionTab{{Path: "/work/a/x.go"}}}
+	if err := store.Save("review-2", sess); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	// Named sessions do not depend on the directory they are opened from.
+	got, err := store.Load("review-2", "/somewhere/else")
+	if err != nil || got == nil || got.Root != "/work/a" {
+		t.Fatalf("Load = %+v, %v", got, err)
+	}
+	if got, _ := store.Load("", "/work/a"); got != nil {
+		t.Error("a named session should not be the project's session")
+	}
+
+	for _, name := range []string{"../escape", ".hidden", "a/b", "sp ace"} {
+		if err := store.Save(name, sess); err == nil {
+			t.Errorf("Save(%q) should fail", name)
+		}
+	}
+}
+
+func TestSessionStoreIgnoresOtherVersions(t *testing.T) {
+	store := NewSessionStore(t.TempDir())
+	if err := os.WriteFile(filepath.Join(store.Dir, "old.json"), []byte(`{"version":99,"root":"/x"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Load("old", ""); got != nil || err != nil {
+		t.Errorf("Load = %+v, %v; want nil, nil", got, err)
+	}
+	if err := os.WriteFile(filepath.Join(store.Dir, "bad.json"), []byte(`{`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("bad", ""); err == nil {
+		t.Error("Load of a corrupt session should fail")
+	}
+}
@@ -0,0 +1,190 @@
+package editor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Settings are the user preferences read from settings.json files.
+type Settings struct {
+	WordWrap            bool     `json:"wordWrap"`
+	InsertSpaces        bool     `json:"insertSpaces"`        // Tab inserts spaces instead of a tab
+	TabSize             int      `json:"tabSize"`             // columns per indentation level
+	HighlightDebounceMs int      `json:"highlightDebounceMs"` // delay before re-highlighting after an edit
+	SidebarRatio        float64  `json:"sidebarRatio"`        // share of the width taken by the file tree
+	FinderExclude       []string `json:"finderExclude"`       // directory name patterns the file finder skips
+	Keymap              string   `json:"keymap"`              // editing keymap: "default", "vim" or "emacs"
+}
+
+// Keymaps selectable with the keymap setting.
+const (
+	KeymapDefault = "default"
+	KeymapVim     = "vim"   // modal editing, see Vim
+	KeymapEmacs   = "emacs" // Emacs keys, with a mark, a KillRing and ISearch
+)
+
+// validKeymap reports whether name is a keymap the keymap setting accepts.
+func validKeymap(name string) bool {
+	return name == KeymapDefault || name == KeymapVim || name == KeymapEmacs
+}
+
+// DefaultSettings returns the built-in settings.
+func DefaultSettings() Settings {
+	return Settings{
+		TabSize:             4,
+		HighlightDebounceMs: 50,
+		SidebarRatio:        0.22,
+		FinderExclude:       []string{".git", "node_modules", "vendor"},
+		Keymap:              KeymapDefault,
+	}
+}
+
+// settingsFileName is the name of the files LoadSettings reads.
+const settingsFileName = "settings.json"
+
+// UserConfigPath returns the file called name in $XDG_CONFIG_HOME/mane,
+// falling back to ~/.config/mane when XDG_CONFIG_HOME is unset.
+func UserConfigPath(name string) (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "mane", name), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "mane", name), nil
+}
+
+// UserSettingsPath returns the user settings file.
+func UserSettingsPath() (string, error) {
+	return UserConfigPath(settingsFileName)
+}
+
+// ProjectSettingsPath returns the settings file of the project at root.
+func ProjectSettingsPath(root string) string {
+	return filepath.Join(root, ".mane", settingsFileName)
+}
+
+// SettingsPaths returns the settings files for the project at root, in the
+// order they apply: the user's, then the project's. root may be empty.
+func SettingsPaths(root string) []string {
+	var paths []string
+	if path, err := UserSettingsPath(); err == nil {
+		paths = append(paths, path)
+	}
+	if root != "" {
+		paths = append(paths, ProjectSettingsPath(root))
+	}
+	return paths
+}
+
+// SettingsLayers are the settings read from a stack of settings files. Each
+// file overrides the keys it sets in the files before it and in the
+// defaults. A key of the form "[lang]" holds settings that apply only to
+// files of that language, such as "[go]": {"insertSpaces": false}.
+type SettingsLayers struct {
+	global map[string]json.RawMessage
+	langs  map[string]map[string]json.RawMessage // by lower-case language ID
+}
+
+// LoadSettings reads the settings files at paths, later files overriding
+// earlier ones. Missing files are skipped.
+func LoadSettings(paths ...string) (*SettingsLayers, error) {
+	l := &SettingsLayers{
+		global: make(map[string]json.RawMessage),
+		langs:  make(map[string]map[string]json.RawMessage),
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := l.add(data); err != nil {
+			return nil, fmt.Errorf("settings %s: %w", path, err)
+		}
+	}
+	return l, nil
+}
+
+// add applies the settings file data on top of the layers so far.
+func (l *SettingsLayers) add(data []byte) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	for key, value := range keys {
+		lang, ok := languageSection(key)
+		if !ok {
+			l.global[key] = value
+			continue
+		}
+		var section map[string]json.RawMessage
+		if err := json.Unmarshal(value, &section); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if l.langs[lang] == nil {
+			l.langs[lang] = make(map[string]json.RawMessage)
+		}
+		for k, v := range section {
+			l.langs[lang][k] = v
+		}
+	}
+	return nil
+}
+
+// languageSection returns the language ID of a "[lang]" key.
+func languageSection(key string) (string, bool) {
+	if len(key) < 3 || key[0] != '[' || key[len(key)-1] != ']' {
+		return "", false
+	}
+	return strings.ToLower(strings.TrimSpace(key[1 : len(key)-1])), true
+}
+
+// Global returns the settings outside any language section.
+func (l *SettingsLayers) Global() Settings {
+	return l.For("")
+}
+
+// For returns the settings for files of the language with ID lang: the
+// global settings with the keys of the language's sections on top. Values
+// of the wrong type or out of range are ignored.
+func (l *SettingsLayers) For(lang string) Settings {
+	s := DefaultSettings()
+	if l == nil {
+		return s
+	}
+	s.apply(l.global)
+	if lang != "" {
+		s.apply(l.langs[strings.ToLower(lang)])
+	}
+	return s
+}
+
+// apply decodes the keys one at a time, so a bad value only loses its own
+// key.
+func (s *Settings) apply(keys map[string]json.RawMessage) {
+	for key, value := range keys {
+		next := *s
+		next.FinderExclude = slices.Clone(s.FinderExclude)
+		data, err := json.Marshal(map[string]json.RawMessage{key: value})
+		if err != nil || json.Unmarshal(data, &next) != nil {
+			continue
+		}
+		if next.TabSize <= 0 || next.HighlightDebounceMs < 0 || next.SidebarRatio <= 0 || next.SidebarRatio >= 1 || !validKeymap(next.Keymap) {
+			continue
+		}
+		*s = next
+	}
+}
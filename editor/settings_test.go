@@ -0,0 +1,102 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeSettings(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadSettingsLayers(t *testing.T) {
+	dir := t.TempDir()
+	user := filepath.Join(dir, "user", "settings.json")
+	project := filepath.Join(dir, "project", ".mane", "settings.json")
+	writeSettings(t, user, `{
+		"wordWrap": true,
+		"tabSize": 8,
+		"finderExclude": [".git", "dist"],
+		"[go]": {"insertSpaces": false, "tabSize": 4},
+		"[Markdown]": {"wordWrap": false}
+	}`)
+	writeSettings(t, project, `{
+		"tabSize": 2,
+		"insertSpaces": true,
+		"[go]": {"tabSize": 8}
+	}`)
+
+	l, err := LoadSettings(user, project, filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	want := DefaultSettings()
+	want.WordWrap = true
+	want.TabSize = 2
+	want.InsertSpaces = true
+	want.FinderExclude = []string{".git", "dist"}
+	if got := l.Global(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Global = %+v, want %+v", got, want)
+	}
+
+	// Language sections override the global settings of every file, and
+	// the project's section only overrides the keys it sets.
+	goWant := want
+	goWant.InsertSpaces = false
+	goWant.TabSize = 8
+	if got := l.For("go"); !reflect.DeepEqual(got, goWant) {
+		t.Errorf("For(go) = %+v, want %+v", got, goWant)
+	}
+	mdWant := want
+	mdWant.WordWrap = false
+	if got := l.For("markdown"); !reflect.DeepEqual(got, mdWant) {
+		t.Errorf("For(markdown) = %+v, want %+v", got, mdWant)
+	}
+	if got := l.For("python"); !reflect.DeepEqual(got, want) {
+		t.Errorf("For(python) = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadSettingsBadValues(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	writeSettings(t, path, `{"tabSize": 0, "sidebarRatio": 1.5, "wordWrap": "yes", "highlightDebounceMs": 120, "keymap": "nano"}`)
+	l, err := LoadSettings(path)
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	want := DefaultSettings()
+	want.HighlightDebounceMs = 120
+	if got := l.Global(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Global = %+v, want %+v", got, want)
+	}
+
+	writeSettings(t, path, `{"tabSize": 2,`)
+	if _, err := LoadSettings(path); err == nil {
+		t.Error("LoadSettings of invalid JSON should fail")
+	}
+	writeSettings(t, path, `{"[go]": 4}`)
+	if _, err := LoadSettings(path); err == nil {
+		t.Error("LoadSettings of a language section that is not an object should fail")
+	}
+}
+
+func TestSettingsPaths(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/cfg")
+	got := SettingsPaths("/work/project")
+	want := []string{
+		filepath.Join("/cfg", "mane", "settings.json"),
+		filepath.Join("/work/project", ".mane", "settings.json"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SettingsPaths = %v, want %v", got, want)
+	}
+}
@@ -1,19 +1,159 @@
 package editor
 
-import "path/filepath"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
 
 // TabManager tracks open file buffers and which one is active.
 // It is pure data management — no UI widget dependency.
 type TabManager struct {
 	buffers []*Buffer
 	active  int // index of active tab, or -1 if none
+
+	undoStore   *UndoStore   // persists undo history across sessions; may be nil
+	backupStore *BackupStore // snapshots unsaved changes; may be nil
+
+	largeFileThreshold int64 // size above which files open in large-file mode; 0 disables it
+
+	views map[*Buffer]ViewState // how each buffer was last shown
 }
 
 // NewTabManager creates a TabManager with no open buffers.
 func NewTabManager() *TabManager {
 	return &TabManager{
-		active: -1,
+		active:             -1,
+		largeFileThreshold: DefaultLargeFileThreshold,
+	}
+}
+
+// SetUndoStore enables persistent undo history. Histories are restored by
+// OpenFile and written by SaveUndo and Close.
+func (tm *TabManager) SetUndoStore(store *UndoStore) {
+	tm.undoStore = store
+}
+
+// SaveUndo persists the undo history of buf if an undo store is set.
+func (tm *TabManager) SaveUndo(buf *Buffer) error {
+	if tm.undoStore == nil || buf == nil {
+		return nil
+	}
+	return tm.undoStore.Save(buf)
+}
+
+// SaveAllUndo persists the undo history of every open buffer, returning the
+// first error encountered.
+func (tm *TabManager) SaveAllUndo() error {
+	var firstErr error
+	for _, buf := range tm.buffers {
+		if err := tm.SaveUndo(buf); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// SetLargeFileThreshold sets the file size in bytes above which OpenFile
+// uses large-file mode. Zero or less always reads files whole.
+func (tm *TabManager) SetLargeFileThreshold(size int64) {
+	tm.largeFileThreshold = size
+}
+
+// SetBackupStore enables backups of unsaved changes. Backups are written by
+// BackupAll and removed by Close.
+func (tm *TabManager) SetBackupStore(store *BackupStore) {
+	tm.backupStore = store
+}
+
+// BackupAll writes a backup of every buffer with unsaved changes and removes
+// the backups of the others, returning the first error encountered. With
+// hotExit set the backups are marked for restoring on the next start without
+// asking; if a buffer in large-file mode has unsaved changes, nothing is
+// written and ErrNoBackup is returned.
+func (tm *TabManager) BackupAll(hotExit bool) error {
+	if hotExit && tm.backupStore != nil {
+		for _, buf := range tm.buffers {
+			if buf.Large() && buf.Dirty() {
+				return fmt.Errorf("%s: %w", buf.Title(), ErrNoBackup)
+			}
+		}
 	}
+	var firstErr error
+	for _, buf := range tm.buffers {
+		if err := tm.backupStore.Save(buf, hotExit); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// DiscardBackups removes the backups of all open buffers, returning the
+// first error encountered.
+func (tm *TabManager) DiscardBackups() error {
+	var firstErr error
+	for _, buf := range tm.buffers {
+		if err := tm.backupStore.Remove(buf); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// RestoreBackup opens bk in a tab, or in the tab already showing its file,
+// and replaces the content with the backed-up text as one undoable edit, so
+// undo returns to the file as it is on disk. A backup of a file that no
+// longer exists is restored into a new buffer for that path, so saving
+// recreates the file. If the file changed on disk after the backup was
+// written, the backed-up changes are merged into its current content; when
+// they conflict, both versions are kept between conflict markers and
+// ErrBackupConflict is returned. The buffer is set as active and bk is
+// handed over to the backup store, if set, as the buffer's own backup.
+// Returns the tab index and any error from opening the file or writing the
+// backup.
+func (tm *TabManager) RestoreBackup(bk Backup) (int, error) {
+	if bk.Path == "" {
+		idx := tm.NewUntitled()
+		return idx, tm.restoreBackup(tm.buffers[idx], bk)
+	}
+	idx, err := tm.OpenFile(bk.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		buf := NewBuffer()
+		buf.path = bk.Path
+		buf.markSaved()
+		loadEditorConfig(buf)
+		tm.buffers = append(tm.buffers, buf)
+		tm.active = len(tm.buffers) - 1
+		idx, err = tm.active, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	if buf := tm.buffers[idx]; buf.Large() {
+		return idx, fmt.Errorf("%s is open in large-file mode", buf.Title())
+	}
+	return idx, tm.restoreBackup(tm.buffers[idx], bk)
+}
+
+func (tm *TabManager) restoreBackup(buf *Buffer, bk Backup) error {
+	text, conflict := bk.Text, false
+	if bk.DiskHash != "" && buf.disk.exists && buf.disk.hash != bk.DiskHash {
+		text, conflict = Merge3(bk.Base, bk.Text, buf.Text())
+	}
+	buf.ApplyText("Restore Backup", text)
+	if tm.backupStore != nil {
+		if err := tm.backupStore.Save(buf, false); err != nil {
+			return err
+		}
+		if err := tm.backupStore.Discard(bk.ID); err != nil {
+			return err
+		}
+	}
+	if conflict {
+		return ErrBackupConflict
+	}
+	return nil
 }
 
 // Count returns the number of open buffers.
@@ -61,8 +201,10 @@ func (tm *TabManager) NewUntitled() int {
 
 // OpenFile opens the file at path. If a buffer with the same absolute path
 // is already open, it switches to that buffer instead of opening a duplicate.
-// The new (or existing) buffer is set as active. Returns the tab index and
-// any error from opening the file.
+// Files bigger than the large-file threshold are opened with OpenLarge. The
+// .editorconfig properties of the file are applied to the buffer. The
+// new (or existing) buffer is set as active. Returns the tab index and any
+// error from opening the file.
 func (tm *TabManager) OpenFile(path string) (int, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -79,9 +221,18 @@ func (tm *TabManager) OpenFile(path string) (int, error) {
 
 	// Open into a new buffer.
 	buf := NewBuffer()
-	if err := buf.Open(absPath); err != nil {
+	open := buf.Open
+	if info, err := os.Stat(absPath); err == nil && tm.largeFileThreshold > 0 && info.Size() > tm.largeFileThreshold {
+		open = buf.OpenLarge
+	}
+	if err := open(absPath); err != nil {
 		return -1, err
 	}
+	loadEditorConfig(buf)
+	if tm.undoStore != nil {
+		// A missing or stale history just means starting fresh.
+		_, _ = tm.undoStore.Restore(buf)
+	}
 
 	tm.buffers = append(tm.buffers, buf)
 	tm.active = len(tm.buffers) - 1
@@ -97,8 +248,11 @@ func (tm *TabManager) SetActive(index int) {
 	tm.active = index
 }
 
-// Close removes the buffer at the given index. If the index is out of range,
-// this is a no-op. After removal the active index is adjusted:
+// Close removes the buffer at the given index, persisting its undo history
+// first if an undo store is set and dropping its backup, since closing
+// discards unsaved changes, and its view state. If the index is out of
+// range, this is a no-op.
+// After removal the active index is adjusted:
 //   - If the closed tab was before the active tab, active shifts down by one.
 //   - If the closed tab was the active tab (or after it and active is now out
 //     of range), active is clamped to the last valid index.
@@ -107,6 +261,9 @@ func (tm *TabManager) Close(index int) {
 	if index < 0 || index >= len(tm.buffers) {
 		return
 	}
+	_ = tm.SaveUndo(tm.buffers[index])
+	_ = tm.backupStore.Remove(tm.buffers[index])
+	delete(tm.views, tm.buffers[index])
 
 	// Remove the buffer at index.
 	tm.buffers = append(tm.buffers[:index], tm.buffers[index+1:]...)
@@ -127,3 +284,11 @@ func (tm *TabManager) Close(index int) {
 	}
 	// If index > tm.active, active stays the same (still valid).
 }
+
+// loadEditorConfig applies the .editorconfig properties of buf's file. An
+// unreadable .editorconfig is ignored, like a missing one.
+func loadEditorConfig(buf *Buffer) {
+	if cfg, err := LoadEditorConfig(buf.Path()); err == nil {
+		buf.SetEditorConfig(cfg)
+	}
+}
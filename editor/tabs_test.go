@@ -524,3 +524,32 @@ func TestOpenFileSetsActive(t *testing.T) {
 		t.Errorf("ActiveBuffer title = %q, want %q", buf.Title(), "a.txt")
 	}
 }
+
+func TestOpenFileRestoresUndoHistory(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tm := NewTabManager()
+	tm.SetUndoStore(NewUndoStore(filepath.Join(dir, "undo")))
+	idx, err := tm.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := tm.Buffer(idx)
+	buf.ApplyEdit(5, "", "!")
+	if err := buf.Save(); err != nil {
+		t.Fatal(err)
+	}
+	tm.Close(idx)
+
+	idx, err = tm.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tm.Buffer(idx).Undo() || tm.Buffer(idx).Text() != "hello" {
+		t.Fatalf("undo after reopen text = %q, want %q", tm.Buffer(idx).Text(), "hello")
+	}
+}
@@ -0,0 +1,335 @@
+package editor
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SortOrder is how SortLines compares lines.
+type SortOrder int
+
+const (
+	// SortNatural compares runs of digits by their value, so "item2" comes
+	// before "item10".
+	SortNatural SortOrder = iota
+	// SortNumeric compares the numbers the lines start with. Lines without
+	// one come first.
+	SortNumeric
+	// SortIgnoreCase compares lines with case folded.
+	SortIgnoreCase
+)
+
+// SortLines returns lines sorted in order, or in the opposite order with
+// reverse set. Lines that compare equal keep their order.
+func SortLines(lines []string, order SortOrder, reverse bool) []string {
+	var cmp func(a, b string) int
+	switch order {
+	case SortNumeric:
+		cmp = compareNumeric
+	case SortIgnoreCase:
+		cmp = func(a, b string) int {
+			return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+		}
+	default:
+		cmp = compareNatural
+	}
+	out := slices.Clone(lines)
+	slices.SortStableFunc(out, func(a, b string) int {
+		if reverse {
+			return cmp(b, a)
+		}
+		return cmp(a, b)
+	})
+	return out
+}
+
+// compareNatural compares a and b rune by rune, except that runs of digits
+// compare by their value.
+func compareNatural(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			si, sj := i, j
+			for i < len(ra) && unicode.IsDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && unicode.IsDigit(rb[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if c := len(na) - len(nb); c != 0 {
+				return c
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			continue
+		}
+		if ra[i] != rb[j] {
+			return int(ra[i]) - int(rb[j])
+		}
+		i++
+		j++
+	}
+	return (len(ra) - i) - (len(rb) - j)
+}
+
+// compareNumeric compares the numbers a and b start with, after any
+// blanks.
+func compareNumeric(a, b string) int {
+	na, oka := leadingNumber(a)
+	nb, okb := leadingNumber(b)
+	switch {
+	case !oka || !okb:
+		return boolOrder(oka) - boolOrder(okb)
+	case na < nb:
+		return -1
+	case na > nb:
+		return 1
+	}
+	return 0
+}
+
+func boolOrder(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// leadingNumber parses the number at the start of line, after any blanks,
+// reporting false if there is none.
+func leadingNumber(line string) (float64, bool) {
+	line = strings.TrimLeft(line, " \t")
+	end := 0
+	for end < len(line) && (line[end] >= '0' && line[end] <= '9' || line[end] == '.' ||
+		end == 0 && (line[end] == '-' || line[end] == '+')) {
+		end++
+	}
+	for ; end > 0; end-- {
+		if n, err := strconv.ParseFloat(line[:end], 64); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// UniqueLines returns lines without the ones repeating a line before them.
+func UniqueLines(lines []string) []string {
+	seen := make(map[string]bool, len(lines))
+	out := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if !seen[l] {
+			seen[l] = true
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// ShuffleLines returns lines in a random order drawn from r.
+func ShuffleLines(lines []string, r *rand.Rand) []string {
+	out := slices.Clone(lines)
+	r.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	return out
+}
+
+// ReverseLines returns lines last to first.
+func ReverseLines(lines []string) []string {
+	out := slices.Clone(lines)
+	slices.Reverse(out)
+	return out
+}
+
+// JoinLines joins lines into one, as Vim's J does: the blanks at the start
+// of each line after the first are dropped and a space put between lines,
+// except after a line ending in a blank or before an empty one.
+func JoinLines(lines []string) string {
+	var b strings.Builder
+	for i, l := range lines {
+		if i > 0 {
+			l = strings.TrimLeft(l, " \t")
+			if s := b.String(); l != "" && s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\t") {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(l)
+	}
+	return b.String()
+}
+
+// SplitLine splits line at each sep into lines of their own. Each piece
+// keeps line's indentation, with the blanks around it dropped.
+func SplitLine(line, sep string) []string {
+	if sep == "" {
+		return []string{line}
+	}
+	body := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(body)]
+	pieces := strings.Split(body, sep)
+	for i, p := range pieces {
+		pieces[i] = indent + strings.TrimSpace(p)
+	}
+	return pieces
+}
+
+// WordAt returns the word around rune offset off of text, as [start, end);
+// start == end if off is not in or next to one.
+func WordAt(text []rune, off int) (start, end int) {
+	off = min(max(off, 0), len(text))
+	start, end = off, off
+	for start > 0 && isWordRune(text[start-1]) {
+		start--
+	}
+	for end < len(text) && isWordRune(text[end]) {
+		end++
+	}
+	return start, end
+}
+
+// TransposeChars swaps the characters before and at rune offset off, as
+// Emacs's C-t does, and returns the text and the offset after them. At the
+// end of a line the two characters before off are swapped. It reports false
+// if there are not two characters on the line to swap.
+func TransposeChars(text []rune, off int) ([]rune, int, bool) {
+	if off >= len(text) || text[off] == '\n' {
+		off--
+	}
+	if off <= 0 || off >= len(text) || text[off] == '\n' || text[off-1] == '\n' {
+		return text, off, false
+	}
+	out := slices.Clone(text)
+	out[off-1], out[off] = out[off], out[off-1]
+	return out, off + 1, true
+}
+
+// TransposeWords swaps the word at or after rune offset off with the word
+// before it, as Emacs's M-t does, and returns the text and the offset after
+// them. It reports false if there are not two words to swap.
+func TransposeWords(text []rune, off int) ([]rune, int, bool) {
+	end2 := ForwardWord(text, off)
+	start2 := BackwardWord(text, end2)
+	start1 := BackwardWord(text, start2)
+	end1 := ForwardWord(text, start1)
+	if start2 == end2 || start1 == end1 || end1 > start2 {
+		return text, off, false
+	}
+	out := make([]rune, 0, len(text))
+	out = append(out, text[:start1]...)
+	out = append(out, text[start2:end2]...)
+	out = append(out, text[end1:start2]...)
+	out = append(out, text[start1:end1]...)
+	out = append(out, text[end2:]...)
+	return out, end2, true
+}
+
+// CaseStyle is a case ConvertCase converts text to.
+type CaseStyle int
+
+const (
+	UpperCase CaseStyle = iota
+	LowerCase
+	TitleCase // Each Word Capitalized
+	SnakeCase // snake_case
+	CamelCase // camelCase
+	KebabCase // kebab-case
+)
+
+// ConvertCase returns text in style. Snake, camel and kebab case convert
+// each identifier in text, splitting it into words at underscores, hyphens
+// and changes of case; what lies between identifiers is kept.
+func ConvertCase(text string, style CaseStyle) string {
+	switch style {
+	case UpperCase:
+		return strings.ToUpper(text)
+	case LowerCase:
+		return strings.ToLower(text)
+	case TitleCase:
+		runes := []rune(text)
+		for i, r := range runes {
+			if i == 0 || !isWordRune(runes[i-1]) && runes[i-1] != '\'' {
+				runes[i] = unicode.ToTitle(r)
+			} else {
+				runes[i] = unicode.ToLower(r)
+			}
+		}
+		return string(runes)
+	}
+	runes := []rune(text)
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		if !isIdentRune(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isIdentRune(runes[j]) {
+			j++
+		}
+		words := identWords(runes[i:j])
+		if len(words) == 0 {
+			b.WriteString(string(runes[i:j]))
+		} else {
+			b.WriteString(joinWords(words, style))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func isIdentRune(r rune) bool {
+	return isWordRune(r) || r == '-'
+}
+
+// identWords splits an identifier into lower case words at underscores,
+// hyphens, a lower case letter or digit followed by an upper case one, and
+// the last letter of a run of upper case ones followed by a lower case one,
+// as in "HTTPServer".
+func identWords(ident []rune) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = nil
+		}
+	}
+	for i, r := range ident {
+		if r == '_' || r == '-' {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := word[len(word)-1]
+			nextLower := i+1 < len(ident) && unicode.IsLower(ident[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// joinWords joins lower case words in style.
+func joinWords(words []string, style CaseStyle) string {
+	switch style {
+	case CamelCase:
+		for i := 1; i < len(words); i++ {
+			r := []rune(words[i])
+			r[0] = unicode.ToUpper(r[0])
+			words[i] = string(r)
+		}
+		return strings.Join(words, "")
+	case KebabCase:
+		return strings.Join(words, "-")
+	}
+	return strings.Join(words, "_")
+}
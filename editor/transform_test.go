@@ -0,0 +1,113 @@
+package editor
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestSortLines(t *testing.T) {
+	lines := []string{"item10", "Item2", "item2", "item1"}
+	tests := []struct {
+		name    string
+		order   SortOrder
+		reverse bool
+		want    []string
+	}{
+		{"natural", SortNatural, false, []string{"Item2", "item1", "item2", "item10"}},
+		{"natural reversed", SortNatural, true, []string{"item10", "item2", "item1", "Item2"}},
+		{"ignore case", SortIgnoreCase, false, []string{"item1", "item10", "Item2", "item2"}},
+	}
+	for _, tt := range tests {
+		if got := SortLines(lines, tt.order, tt.reverse); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: SortLines = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if !slices.Equal(lines, []string{"item10", "Item2", "item2", "item1"}) {
+		t.Errorf("SortLines changed its argument: %q", lines)
+	}
+
+	numeric := SortLines([]string{"10 ten", "  -2.5", "x", "3"}, SortNumeric, false)
+	if want := []string{"x", "  -2.5", "3", "10 ten"}; !slices.Equal(numeric, want) {
+		t.Errorf("numeric SortLines = %q, want %q", numeric, want)
+	}
+}
+
+func TestUniqueShuffleAndReverseLines(t *testing.T) {
+	if got, want := UniqueLines([]string{"b", "a", "b", "c", "a"}), []string{"b", "a", "c"}; !slices.Equal(got, want) {
+		t.Errorf("UniqueLines = %q, want %q", got, want)
+	}
+	if got, want := ReverseLines([]string{"a", "b", "c"}), []string{"c", "b", "a"}; !slices.Equal(got, want) {
+		t.Errorf("ReverseLines = %q, want %q", got, want)
+	}
+	lines := []string{"a", "b", "c", "d", "e"}
+	got := ShuffleLines(lines, rand.New(rand.NewPCG(1, 2)))
+	slices.Sort(got)
+	if !slices.Equal(got, lines) {
+		t.Errorf("ShuffleLines lost or added lines: %q", got)
+	}
+}
+
+func TestJoinAndSplitLines(t *testing.T) {
+	if got, want := JoinLines([]string{"func f(", "\ta int,", "", "  b int)"}), "func f( a int, b int)"; got != want {
+		t.Errorf("JoinLines = %q, want %q", got, want)
+	}
+	if got, want := SplitLine("\ta, b ,c", ","), []string{"\ta", "\tb", "\tc"}; !slices.Equal(got, want) {
+		t.Errorf("SplitLine = %q, want %q", got, want)
+	}
+	if got := SplitLine("a,b", ""); !slices.Equal(got, []string{"a,b"}) {
+		t.Errorf("SplitLine with no separator = %q", got)
+	}
+}
+
+func TestTransposeChars(t *testing.T) {
+	tests := []struct {
+		text    string
+		off     int
+		want    string
+		wantOff int
+		ok      bool
+	}{
+		{"abc", 1, "bac", 2, true},
+		{"abc\nd", 3, "acb\nd", 3, true}, // at the end of a line
+		{"abc", 3, "acb", 3, true},
+		{"abc", 0, "abc", 0, false},
+		{"a\nbc", 2, "a\nbc", 2, false}, // at the start of a line
+	}
+	for _, tt := range tests {
+		got, off, ok := TransposeChars([]rune(tt.text), tt.off)
+		if string(got) != tt.want || ok != tt.ok || ok && off != tt.wantOff {
+			t.Errorf("TransposeChars(%q, %d) = %q, %d, %v; want %q, %d, %v", tt.text, tt.off, string(got), off, ok, tt.want, tt.wantOff, tt.ok)
+		}
+	}
+}
+
+func TestTransposeWords(t *testing.T) {
+	got, off, ok := TransposeWords([]rune("one, two three"), 4)
+	if !ok || string(got) != "two, one three" || off != 8 {
+		t.Errorf("TransposeWords = %q, %d, %v; want %q, 8, true", string(got), off, ok, "two, one three")
+	}
+	if _, _, ok := TransposeWords([]rune("one"), 1); ok {
+		t.Error("TransposeWords with one word reported true")
+	}
+}
+
+func TestConvertCase(t *testing.T) {
+	tests := []struct {
+		style CaseStyle
+		text  string
+		want  string
+	}{
+		{UpperCase, "héllo World", "HÉLLO WORLD"},
+		{LowerCase, "Hello WORLD", "hello world"},
+		{TitleCase, "hello wORLD, don't", "Hello World, Don't"},
+		{SnakeCase, "fooBar HTTPServer kebab-case", "foo_bar http_server kebab_case"},
+		{CamelCase, "foo_bar Some-Thing item2Name", "fooBar someThing item2Name"},
+		{KebabCase, "FooBar x - y", "foo-bar x - y"},
+	}
+	for _, tt := range tests {
+		if got := ConvertCase(tt.text, tt.style); got != tt.want {
+			t.Errorf("ConvertCase(%q, %d) = %q, want %q", tt.text, tt.style, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package editor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// undoFileVersion is bumped whenever the on-disk history format changes.
+// Histories written with another version are discarded.
+const undoFileVersion = 1
+
+// UndoStore persists buffer undo histories between sessions. Each file's
+// history is stored in Dir under a name derived from the file's absolute
+// path, together with a hash of the file content it was recorded against.
+// A history is only restored when that hash still matches the file.
+type UndoStore struct {
+	Dir string
+}
+
+// NewUndoStore creates a store rooted at dir. The directory is created on
+// first save.
+func NewUndoStore(dir string) *UndoStore {
+	return &UndoStore{Dir: dir}
+}
+
+// DefaultUndoDir returns $XDG_STATE_HOME/mane/undo, falling back to
+// ~/.local/state/mane/undo when XDG_STATE_HOME is unset.
+func DefaultUndoDir() (string, error) {
+	return stateDir("undo")
+}
+
+// stateDir returns the directory name under $XDG_STATE_HOME/mane, or
+// ~/.local/state/mane when XDG_STATE_HOME is unset.
+func stateDir(name string) (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "mane", name), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "mane", name), nil
+}
+
+type undoFile struct {
+	Version int            `json:"version"`
+	Path    string         `json:"path"`
+	Hash    string         `json:"hash"`  // sha256 of the file content
+	Saved   int            `json:"saved"` // node the file content corresponds to
+	Nodes   []undoFileNode `json:"nodes"`
+}
+
+type undoFileNode struct {
+	Parent int          `json:"parent"`
+	Label  string       `json:"label,omitempty"`
+	Time   time.Time    `json:"time"`
+	Redo   int          `json:"redo"`
+	Ops    []undoFileOp `json:"ops,omitempty"`
+}
+
+type undoFileOp struct {
+	Offset int    `json:"offset"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// file returns the history file used for the given absolute path.
+func (s *UndoStore) file(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// Save writes b's undo history. Buffers without a path, in large-file mode
+// or without any recorded edits are skipped, and a stale history for the
+// path is removed. The history is anchored at the state matching the text
+// last saved to disk; if no state matches, nothing is written.
+func (s *UndoStore) Save(b *Buffer) error {
+	if s == nil || b.Untitled() || b.Large() {
+		return nil
+	}
+	path := s.file(b.Path())
+	saved := -1
+	for i, n := range b.nodes {
+		if n.state == b.savedState {
+			saved = i
+			break
+		}
+	}
+	if len(b.nodes) <= 1 || saved < 0 {
+		return removeIfExists(path)
+	}
+
+	f := undoFile{
+		Version: undoFileVersion,
+		Path:    b.Path(),
+		Hash:    contentHash(b.saved.String()),
+		Saved:   saved,
+		Nodes:   make([]undoFileNode, len(b.nodes)),
+	}
+	for i, n := range b.nodes {
+		node := undoFileNode{
+			Parent: n.parent,
+			Label:  n.group.label,
+			Time:   n.time,
+			Redo:   n.redo,
+			Ops:    make([]undoFileOp, len(n.group.ops)),
+		}
+		for j, op := range n.group.ops {
+			node.Ops[j] = undoFileOp{Offset: op.offset, Old: op.oldText, New: op.newText}
+		}
+		f.Nodes[i] = node
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0o600)
+}
+
+// Restore loads the stored history for b if one exists and was recorded
+// against b's current content. A history that is unreadable, from another
+// format version, or recorded against different content is deleted.
+// Returns true if a history was restored.
+func (s *UndoStore) Restore(b *Buffer) (bool, error) {
+	if s == nil || b.Untitled() || b.Large() {
+		return false, nil
+	}
+	path := s.file(b.Path())
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var f undoFile
+	if err := json.Unmarshal(data, &f); err != nil || !f.valid(b) {
+		return false, removeIfExists(path)
+	}
+
+	nodes := make([]undoNode, len(f.Nodes))
+	for i, fn := range f.Nodes {
+		nodes[i] = undoNode{
+			parent: fn.Parent,
+			state:  b.newState(),
+			time:   fn.Time,
+			redo:   fn.Redo,
+			group:  undoGroup{label: fn.Label},
+		}
+		if fn.Parent >= 0 {
+			nodes[fn.Parent].children = append(nodes[fn.Parent].children, i)
+		}
+	}
+	for i, fn := range f.Nodes {
+		for _, op := range fn.Ops {
+			nodes[i].group.ops = append(nodes[i].group.ops, editOp{
+				offset:  op.Offset,
+				oldText: op.Old,
+				newText: op.New,
+				before:  nodes[max(fn.Parent, 0)].state,
+				after:   nodes[i].state,
+			})
+		}
+	}
+
+	b.resetHistory()
+	b.nodes = nodes
+	b.current = f.Saved
+	b.state = nodes[f.Saved].state
+	b.savedState = b.state
+	return true, nil
+}
+
+// valid reports whether f is a well-formed history for b's current content.
+func (f *undoFile) valid(b *Buffer) bool {
+	if f.Version != undoFileVersion || f.Path != b.Path() || f.Hash != contentHash(b.Text()) {
+		return false
+	}
+	if len(f.Nodes) == 0 || f.Saved < 0 || f.Saved >= len(f.Nodes) || f.Nodes[0].Parent != -1 {
+		return false
+	}
+	for i, n := range f.Nodes[1:] {
+		// Parents always precede their children, and only the root is
+		// without edits.
+		if n.Parent < 0 || n.Parent > i || len(n.Ops) == 0 {
+			return false
+		}
+	}
+	for i, n := range f.Nodes {
+		if n.Redo != -1 && (n.Redo <= 0 || n.Redo >= len(f.Nodes) || f.Nodes[n.Redo].Parent != i) {
+			return false
+		}
+	}
+	return true
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
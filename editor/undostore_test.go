@@ -0,0 +1,157 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTestFile(t *testing.T, path, content string) *Buffer {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	b := NewBuffer()
+	if err := b.Open(path); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return b
+}
+
+func TestUndoStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := NewUndoStore(filepath.Join(dir, "state"))
+	path := filepath.Join(dir, "file.txt")
+
+	b := openTestFile(t, path, "one")
+	b.ApplyEdits("Edit", []TextEdit{{Start: 3, End: 3, Text: " two"}})
+	b.Undo()
+	b.ApplyEdit(3, "", " three") // creates a second branch
+	if err := b.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Save(b); err != nil {
+		t.Fatalf("store.Save: %v", err)
+	}
+
+	reopened := NewBuffer()
+	if err := reopened.Open(path); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	ok, err := store.Restore(reopened)
+	if err != nil || !ok {
+		t.Fatalf("Restore = %v, %v; want true, nil", ok, err)
+	}
+	if reopened.Dirty() {
+		t.Error("restored buffer should be clean")
+	}
+	if len(reopened.UndoStates()) != 3 {
+		t.Fatalf("restored %d states, want 3", len(reopened.UndoStates()))
+	}
+	if got := reopened.UndoLabel(); got != "" {
+		t.Errorf("UndoLabel() = %q, want unlabeled edit", got)
+	}
+	if !reopened.Undo() || reopened.Text() != "one" {
+		t.Fatalf("undo after restore text = %q, want %q", reopened.Text(), "one")
+	}
+	if !reopened.Dirty() {
+		t.Error("buffer should be dirty after undoing past the saved state")
+	}
+	for _, s := range reopened.UndoStates() {
+		if s.Label == "Edit" {
+			reopened.GoToState(s.ID)
+		}
+	}
+	if reopened.Text() != "one two" {
+		t.Errorf("other branch text = %q, want %q", reopened.Text(), "one two")
+	}
+}
+
+func TestUndoStoreDiscardsStaleHistory(t *testing.T) {
+	dir := t.TempDir()
+	store := NewUndoStore(filepath.Join(dir, "state"))
+	path := filepath.Join(dir, "file.txt")
+
+	b := openTestFile(t, path, "abc")
+	b.ApplyEdit(3, "", "d")
+	if err := b.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Save(b); err != nil {
+		t.Fatalf("store.Save: %v", err)
+	}
+
+	// The file changes outside the editor.
+	changed := openTestFile(t, path, "something else")
+	ok, err := store.Restore(changed)
+	if err != nil || ok {
+		t.Fatalf("Restore = %v, %v; want false, nil", ok, err)
+	}
+	if changed.Undo() {
+		t.Error("stale history must not be restored")
+	}
+	if _, err := os.Stat(store.file(changed.Path())); !os.IsNotExist(err) {
+		t.Errorf("stale history file should be removed, stat err = %v", err)
+	}
+}
+
+func TestUndoStoreAnchorsDirtyBufferAtSavedState(t *testing.T) {
+	dir := t.TempDir()
+	store := NewUndoStore(filepath.Join(dir, "state"))
+	path := filepath.Join(dir, "file.txt")
+
+	b := openTestFile(t, path, "x")
+	b.ApplyEdit(1, "", "y")
+	if err := b.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	b.ApplyEdit(2, "", "z") // unsaved edit; closed without saving
+	if err := store.Save(b); err != nil {
+		t.Fatalf("store.Save: %v", err)
+	}
+
+	reopened := NewBuffer()
+	if err := reopened.Open(path); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if ok, _ := store.Restore(reopened); !ok {
+		t.Fatal("Restore returned false")
+	}
+	if reopened.Text() != "xy" {
+		t.Fatalf("text = %q, want %q", reopened.Text(), "xy")
+	}
+	if !reopened.Redo() || reopened.Text() != "xyz" {
+		t.Errorf("redo should reach the unsaved edit, text = %q", reopened.Text())
+	}
+}
+
+func TestUndoStoreSkipsUntouchedBuffers(t *testing.T) {
+	dir := t.TempDir()
+	store := NewUndoStore(filepath.Join(dir, "state"))
+	b := openTestFile(t, filepath.Join(dir, "file.txt"), "plain")
+
+	if err := store.Save(b); err != nil {
+		t.Fatalf("store.Save: %v", err)
+	}
+	if _, err := os.Stat(store.Dir); !os.IsNotExist(err) {
+		t.Errorf("store directory should not be created for a buffer without edits")
+	}
+	if err := store.Save(NewBuffer()); err != nil {
+		t.Errorf("store.Save(untitled) = %v, want nil", err)
+	}
+}
+
+func TestDefaultUndoDir(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/tmp/state")
+	dir, err := DefaultUndoDir()
+	if err != nil || dir != filepath.Join("/tmp/state", "mane", "undo") {
+		t.Errorf("DefaultUndoDir() = %q, %v", dir, err)
+	}
+
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/someone")
+	dir, err = DefaultUndoDir()
+	if err != nil || dir != filepath.Join("/home/someone", ".local", "state", "mane", "undo") {
+		t.Errorf("DefaultUndoDir() without XDG_STATE_HOME = %q, %v", dir, err)
+	}
+}
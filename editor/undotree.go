@@ -0,0 +1,224 @@
+package editor
+
+import "time"
+
+// undoNode is one state in a buffer's undo tree. The root (index 0) is the
+// state the history starts from; every other node records the group of
+// edits that turns its parent's text into its own.
+type undoNode struct {
+	parent   int
+	children []int
+	group    undoGroup
+	state    uint64    // content state identity after group is applied
+	time     time.Time // when the state was last changed
+	redo     int       // child that Redo follows, or -1
+}
+
+// UndoState describes a node of the undo tree for display.
+type UndoState struct {
+	ID      int // stable index; 0 is the root
+	Parent  int // -1 for the root
+	Label   string
+	Time    time.Time
+	Current bool // the buffer is at this state
+	Tip     bool // no later states hang off this one
+}
+
+// clock returns the current time, allowing tests to control timestamps.
+func (b *Buffer) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// undoTree returns the tree, creating the root on first use.
+func (b *Buffer) undoTree() []undoNode {
+	if len(b.nodes) == 0 {
+		b.nodes = []undoNode{{parent: -1, state: b.state, time: b.clock(), redo: -1}}
+		b.current = 0
+	}
+	return b.nodes
+}
+
+// resetHistory discards the undo tree.
+func (b *Buffer) resetHistory() {
+	b.nodes = nil
+	b.current = 0
+	b.group = nil
+	b.groupDepth = 0
+	b.coalesce = false
+}
+
+// pushGroup records g as a new child of the current state and moves to it.
+// Earlier redo branches are kept as siblings.
+func (b *Buffer) pushGroup(g undoGroup) {
+	b.undoTree()
+	id := len(b.nodes)
+	b.nodes = append(b.nodes, undoNode{
+		parent: b.current,
+		group:  g,
+		state:  g.ops[len(g.ops)-1].after,
+		time:   b.clock(),
+		redo:   -1,
+	})
+	b.nodes[b.current].children = append(b.nodes[b.current].children, id)
+	b.nodes[b.current].redo = id
+	b.current = id
+}
+
+// mergeIntoCurrent appends g's edits to the current state's group.
+func (b *Buffer) mergeIntoCurrent(g undoGroup) {
+	n := &b.nodes[b.current]
+	n.group.ops = append(n.group.ops, g.ops...)
+	n.state = g.ops[len(g.ops)-1].after
+	n.time = b.clock()
+}
+
+// revertNode undoes the edits of node id, which must be the current state,
+// and moves to its parent.
+func (b *Buffer) revertNode(id int) {
+	n := b.nodes[id]
+	b.setContent(revertGroup(b.text, n.group))
+	b.state = b.nodes[n.parent].state
+	b.nodes[n.parent].redo = id
+	b.current = n.parent
+}
+
+// replayNode reapplies the edits of node id, which must be a child of the
+// current state, and moves to it.
+func (b *Buffer) replayNode(id int) {
+	n := b.nodes[id]
+	b.setContent(replayGroup(b.text, n.group))
+	b.state = n.state
+	b.nodes[n.parent].redo = id
+	b.current = id
+}
+
+func revertGroup(r rope, g undoGroup) rope {
+	for i := len(g.ops) - 1; i >= 0; i-- {
+		op := g.ops[i]
+		r = r.Replace(op.offset, len(op.newText), op.oldText)
+	}
+	return r
+}
+
+func replayGroup(r rope, g undoGroup) rope {
+	for _, op := range g.ops {
+		r = r.Replace(op.offset, len(op.oldText), op.newText)
+	}
+	return r
+}
+
+// undoPath returns the states to revert (walking up from the current state)
+// and the states to replay (walking down to target) to reach target.
+func (b *Buffer) undoPath(target int) (up, down []int) {
+	onPath := make(map[int]bool)
+	for n := b.current; n >= 0; n = b.nodes[n].parent {
+		onPath[n] = true
+	}
+	common := target
+	for !onPath[common] {
+		down = append(down, common)
+		common = b.nodes[common].parent
+	}
+	for n := b.current; n != common; n = b.nodes[n].parent {
+		up = append(up, n)
+	}
+	// down was collected bottom-up; replay top-down.
+	for i, j := 0, len(down)-1; i < j; i, j = i+1, j-1 {
+		down[i], down[j] = down[j], down[i]
+	}
+	return up, down
+}
+
+// GoToState moves the buffer to the undo state with the given ID, undoing
+// and redoing along the tree as needed. Returns false if id is unknown or
+// already current.
+func (b *Buffer) GoToState(id int) bool {
+	b.undoTree()
+	if id < 0 || id >= len(b.nodes) || id == b.current || b.group != nil || b.readOnly {
+		return false
+	}
+	up, down := b.undoPath(id)
+	for _, n := range up {
+		b.revertNode(n)
+	}
+	for _, n := range down {
+		b.replayNode(n)
+	}
+	b.coalesce = false
+	return true
+}
+
+// StateText returns the buffer text as it is at the undo state with the
+// given ID, without moving the buffer there.
+func (b *Buffer) StateText(id int) string {
+	b.undoTree()
+	if id < 0 || id >= len(b.nodes) {
+		return ""
+	}
+	r := b.text
+	up, down := b.undoPath(id)
+	for _, n := range up {
+		r = revertGroup(r, b.nodes[n].group)
+	}
+	for _, n := range down {
+		r = replayGroup(r, b.nodes[n].group)
+	}
+	return r.String()
+}
+
+// CurrentState returns the ID of the undo state the buffer is at.
+func (b *Buffer) CurrentState() int {
+	return b.current
+}
+
+// UndoStates lists every state of the undo tree in creation order.
+func (b *Buffer) UndoStates() []UndoState {
+	nodes := b.undoTree()
+	states := make([]UndoState, len(nodes))
+	for i, n := range nodes {
+		states[i] = UndoState{
+			ID:      i,
+			Parent:  n.parent,
+			Label:   n.group.label,
+			Time:    n.time,
+			Current: i == b.current,
+			Tip:     len(n.children) == 0,
+		}
+	}
+	return states
+}
+
+// Earlier moves to the newest state that existed at least d before the
+// current one, across branches. Returns false if there is no such state.
+func (b *Buffer) Earlier(d time.Duration) bool {
+	nodes := b.undoTree()
+	target := nodes[b.current].time.Add(-d)
+	for id := b.current - 1; id >= 0; id-- {
+		if !nodes[id].time.After(target) {
+			return b.GoToState(id)
+		}
+	}
+	return b.GoToState(0)
+}
+
+// Later moves to the newest state created no more than d after the current
+// one, across branches. Returns false if there is no later state.
+func (b *Buffer) Later(d time.Duration) bool {
+	nodes := b.undoTree()
+	target := nodes[b.current].time.Add(d)
+	best := b.current
+	for id := b.current + 1; id < len(nodes); id++ {
+		if nodes[id].time.After(target) {
+			break
+		}
+		best = id
+	}
+	if best == b.current && b.current+1 < len(nodes) {
+		// Always make progress, even if the next state is further away.
+		best = b.current + 1
+	}
+	return b.GoToState(best)
+}
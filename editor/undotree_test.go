@@ -0,0 +1,123 @@
+package editor
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock returns a clock function whose time advances by step each call.
+func fakeClock(start time.Time, step time.Duration) func() time.Time {
+	t := start
+	return func() time.Time {
+		now := t
+		t = t.Add(step)
+		return now
+	}
+}
+
+func TestUndoTreeKeepsRedoBranch(t *testing.T) {
+	b := NewBuffer()
+	b.ApplyEdit(0, "", "a")
+	b.ApplyEdit(1, "", "b")
+	b.Undo()
+	b.ApplyEdit(1, "", "c") // new branch; "ab" must survive
+
+	if b.Text() != "ac" {
+		t.Fatalf("text = %q, want %q", b.Text(), "ac")
+	}
+	var tips []string
+	for _, s := range b.UndoStates() {
+		if s.Tip {
+			tips = append(tips, b.StateText(s.ID))
+		}
+	}
+	if len(tips) != 2 || tips[0] != "ab" || tips[1] != "ac" {
+		t.Fatalf("branch tips = %q, want [ab ac]", tips)
+	}
+
+	// Jump across branches.
+	for _, s := range b.UndoStates() {
+		if s.Tip && !s.Current {
+			if !b.GoToState(s.ID) {
+				t.Fatalf("GoToState(%d) returned false", s.ID)
+			}
+		}
+	}
+	if b.Text() != "ab" {
+		t.Fatalf("after jump text = %q, want %q", b.Text(), "ab")
+	}
+	if b.StateText(0) != "" {
+		t.Errorf("StateText(root) = %q, want empty", b.StateText(0))
+	}
+
+	// Redo follows the branch that was visited last.
+	b.Undo()
+	b.Redo()
+	if b.Text() != "ab" {
+		t.Errorf("redo after jump text = %q, want %q", b.Text(), "ab")
+	}
+}
+
+func TestUndoTreeGoToStateRejectsInvalid(t *testing.T) {
+	b := NewBuffer()
+	b.ApplyEdit(0, "", "x")
+	if b.GoToState(b.CurrentState()) {
+		t.Error("GoToState(current) should return false")
+	}
+	if b.GoToState(42) || b.GoToState(-1) {
+		t.Error("GoToState with unknown id should return false")
+	}
+}
+
+func TestUndoTreeDirtyTrackingAcrossBranches(t *testing.T) {
+	b := NewBuffer()
+	b.ApplyEdit(0, "", "saved")
+	b.markSaved()
+	saved := b.CurrentState()
+
+	b.ApplyEdit(5, "", "!")
+	b.Undo()
+	b.ApplyEdit(5, "", "?")
+	if !b.Dirty() {
+		t.Fatal("buffer should be dirty on a new branch")
+	}
+	b.GoToState(saved)
+	if b.Dirty() {
+		t.Error("buffer should be clean after jumping back to the saved state")
+	}
+}
+
+func TestUndoTreeEarlierLater(t *testing.T) {
+	b := NewBuffer()
+	b.now = fakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), time.Minute)
+
+	// Root is created at 12:00; edits land at 12:01, 12:02, ...
+	text := ""
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		b.ApplyEdit(len(text), "", s)
+		text += s
+	}
+
+	if !b.Earlier(2 * time.Minute) {
+		t.Fatal("Earlier returned false")
+	}
+	if b.Text() != "abc" {
+		t.Fatalf("after Earlier(2m) text = %q, want %q", b.Text(), "abc")
+	}
+	if !b.Earlier(time.Hour) || b.Text() != "" {
+		t.Fatalf("Earlier past the start should reach the root, text = %q", b.Text())
+	}
+	if b.Earlier(time.Minute) {
+		t.Error("Earlier at the root should return false")
+	}
+	if !b.Later(3*time.Minute) || b.Text() != "abc" {
+		t.Fatalf("after Later(3m) text = %q, want %q", b.Text(), "abc")
+	}
+	if !b.Later(time.Second) || b.Text() != "abcd" {
+		t.Fatalf("Later should always advance one state, text = %q", b.Text())
+	}
+	b.Later(time.Hour)
+	if b.Later(time.Hour) {
+		t.Error("Later at the newest state should return false")
+	}
+}
@@ -0,0 +1,46 @@
+package editor
+
+// ViewState is how a buffer was shown when its tab was left: its cursors and
+// selections, folds and scroll position, so the tab comes back the same way.
+// Offsets count runes, or bytes for buffers in large-file mode.
+type ViewState struct {
+	Cursors []Cursor `json:"cursors,omitempty"` // primary first
+	Folds   []int    `json:"folds,omitempty"`   // start lines of folded regions
+	Scroll  int      `json:"scroll,omitempty"`  // line shown at the top
+}
+
+// Primary returns the primary cursor, or a cursor at the start if there is
+// none.
+func (v ViewState) Primary() Cursor {
+	if len(v.Cursors) == 0 {
+		return Cursor{}
+	}
+	return v.Cursors[0]
+}
+
+// SetView records the view state of buf, which must be open. It is dropped
+// when the tab is closed.
+func (tm *TabManager) SetView(buf *Buffer, view ViewState) {
+	if tm.indexOf(buf) < 0 {
+		return
+	}
+	if tm.views == nil {
+		tm.views = make(map[*Buffer]ViewState)
+	}
+	tm.views[buf] = view
+}
+
+// View returns the view state recorded for buf and whether there is one.
+func (tm *TabManager) View(buf *Buffer) (ViewState, bool) {
+	view, ok := tm.views[buf]
+	return view, ok
+}
+
+func (tm *TabManager) indexOf(buf *Buffer) int {
+	for i, b := range tm.buffers {
+		if b == buf {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,48 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTabManagerView(t *testing.T) {
+	tm := NewTabManager()
+	first := tm.Buffer(tm.NewUntitled())
+	second := tm.Buffer(tm.NewUntitled())
+
+	if _, ok := tm.View(first); ok {
+		t.Fatal("a new buffer should have no view state")
+	}
+	view := ViewState{
+		Cursors: []Cursor{{Offset: 5, Anchor: 2}, {Offset: 9, Anchor: 9}},
+		Folds:   []int{3},
+		Scroll:  1,
+	}
+	tm.SetView(first, view)
+	tm.SetView(second, ViewState{Scroll: 7})
+	if got, ok := tm.View(first); !ok || !reflect.DeepEqual(got, view) {
+		t.Errorf("View(first) = %+v, %v; want %+v", got, ok, view)
+	}
+	if got, _ := tm.View(second); got.Scroll != 7 {
+		t.Errorf("View(second).Scroll = %d, want 7", got.Scroll)
+	}
+
+	tm.Close(0)
+	if _, ok := tm.View(first); ok {
+		t.Error("closing a tab should drop its view state")
+	}
+	tm.SetView(first, view)
+	if _, ok := tm.View(first); ok {
+		t.Error("SetView should ignore buffers that are not open")
+	}
+}
+
+func TestViewStatePrimary(t *testing.T) {
+	if got := (ViewState{}).Primary(); got != (Cursor{}) {
+		t.Errorf("Primary of an empty view = %+v, want the start", got)
+	}
+	view := ViewState{Cursors: []Cursor{{Offset: 4, Anchor: 1}, {Offset: 8, Anchor: 8}}}
+	if got := view.Primary(); got != (Cursor{Offset: 4, Anchor: 1}) {
+		t.Errorf("Primary = %+v", got)
+	}
+}
@@ -0,0 +1,851 @@
+package editor
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// VimMode is a mode of the Vim layer.
+type VimMode int
+
+const (
+	VimNormal VimMode = iota
+	VimInsert
+	VimVisual
+	VimVisualLine
+	VimVisualBlock
+	VimCommandLine
+)
+
+// String returns the name of the mode as the status line shows it.
+func (m VimMode) String() string {
+	switch m {
+	case VimInsert:
+		return "INSERT"
+	case VimVisual:
+		return "VISUAL"
+	case VimVisualLine:
+		return "VISUAL LINE"
+	case VimVisualBlock:
+		return "VISUAL BLOCK"
+	case VimCommandLine:
+		return "COMMAND"
+	}
+	return "NORMAL"
+}
+
+func (m VimMode) visual() bool {
+	return m == VimVisual || m == VimVisualLine || m == VimVisualBlock
+}
+
+// VimHost is the editor the Vim layer drives. Offsets are rune offsets.
+type VimHost interface {
+	Text() string
+	Cursor() int
+	// SetCursor moves the cursor to offset, dropping any selection and
+	// extra cursors.
+	SetCursor(offset int)
+	// Edit replaces the text as one undo step called label and moves the
+	// cursor to offset.
+	Edit(label, text string, offset int)
+	Undo()
+	Redo()
+	// Select shows a selection from anchor to offset, the cursor being at
+	// offset.
+	Select(anchor, offset int)
+	// SelectBlock shows a block selection; nil hides it.
+	SelectBlock(bs *BlockSelection)
+	// InsertAt puts a cursor at each offset, for the text typed in insert
+	// mode to go to all of them.
+	InsertAt(offsets []int)
+	// IndentUnit returns the text > adds in front of a line.
+	IndentUnit() string
+	// TabSize returns the columns per tab stop, for the columns of visual
+	// block mode.
+	TabSize() int
+	// Ex runs an ex command the layer leaves to the editor: "w", "q",
+	// "wq", "wa", "qa" or "wqa". bang is set when the command ended in
+	// "!"; arg is what followed it.
+	Ex(command string, bang bool, arg string) error
+}
+
+// vimRegister is the content of a register.
+type vimRegister struct {
+	text     string
+	linewise bool // whole lines, each ending in a newline
+	block    bool // the lines of a block, joined by newlines
+}
+
+// vimCommand is a normal or visual mode command as typed.
+type vimCommand struct {
+	reg   rune   // register, 0 for the unnamed one
+	count int    // 0 when no count was typed
+	op    string // operator, such as "d" or "gU"; "" for none
+	name  string // motion, text object or command; the operator again for dd, cc...
+	arg   string // the character f, t, F, T and r take
+}
+
+func (c vimCommand) n() int { return max(c.count, 1) }
+
+// Vim is a modal editing layer in the manner of Vim: keys typed in normal
+// mode are commands, made of an optional register, a count, an operator and
+// a motion or text object. Insert mode leaves typing to the editor; visual
+// modes select text for an operator. The layer works on a VimHost and is not
+// safe for concurrent use.
+type Vim struct {
+	host      VimHost
+	mode      VimMode
+	keys      []string // keys of the command being typed
+	registers map[rune]vimRegister
+	message   string
+
+	wantCol    int // column j and k keep to; -1 for the cursor's
+	lastCursor int // cursor after the last command, to notice other moves
+	lastFind   string
+
+	// Visual modes.
+	anchor, cur int
+	lastVisual  [2]int // first and last line of the last visual selection
+
+	// The last change, for ".", and the one in progress in insert mode.
+	last         *vimCommand
+	lastInsert   []string
+	change       *vimCommand
+	typed        []string // keys typed since insert mode started
+	insertCount  int
+	insertPrefix []string // keys typed before each repeat of the insert
+
+	cmdline []rune
+	lastSub [3]string // pattern, replacement and flags of the last :s
+}
+
+// NewVim returns a Vim layer in normal mode driving host.
+func NewVim(host VimHost) *Vim {
+	return &Vim{host: host, registers: make(map[rune]vimRegister), wantCol: -1}
+}
+
+// Mode returns the current mode.
+func (v *Vim) Mode() VimMode { return v.mode }
+
+// Status describes the mode for the status line, followed by the keys of a
+// command being typed, such as "NORMAL 2d". On the command line it is the
+// command line.
+func (v *Vim) Status() string {
+	if v.mode == VimCommandLine {
+		return ":" + string(v.cmdline)
+	}
+	if len(v.keys) > 0 {
+		return v.mode.String() + " " + strings.Join(v.keys, "")
+	}
+	return v.mode.String()
+}
+
+// TakeMessage returns the message the last key left for the user, such as
+// an error, and clears it.
+func (v *Vim) TakeMessage() string {
+	msg := v.message
+	v.message = ""
+	return msg
+}
+
+// Reset goes back to normal mode, dropping the command being typed. The
+// editor calls it when it shows another text.
+func (v *Vim) Reset() {
+	v.mode = VimNormal
+	v.keys = nil
+	v.change = nil
+	v.cmdline = nil
+	v.wantCol = -1
+}
+
+// Key handles a key named the way Vim writes keys: a character such as "d"
+// or "$", or a special key such as "<Esc>", "<CR>", "<BS>", "<Del>",
+// "<Tab>", "<Left>" or "<C-r>". It reports whether the key was used; keys
+// that are not are left to the editor, such as text typed in insert mode.
+func (v *Vim) Key(key string) bool {
+	switch v.mode {
+	case VimInsert:
+		return v.insertKey(key)
+	case VimCommandLine:
+		v.commandLineKey(key)
+		return true
+	}
+	if key == "<Esc>" {
+		switch {
+		case len(v.keys) > 0:
+			v.keys = nil
+		case v.mode.visual():
+			v.exitVisual()
+		default:
+			return false
+		}
+		return true
+	}
+	v.keys = append(v.keys, key)
+	cmd, state := parseVimCommand(v.keys, v.mode.visual())
+	switch state {
+	case vimIncomplete:
+		return true
+	case vimInvalid:
+		single := len(v.keys) == 1
+		v.keys = nil
+		// Shortcuts such as Ctrl+S keep working.
+		if single && strings.HasPrefix(key, "<C-") {
+			if v.mode.visual() {
+				v.exitVisual()
+			}
+			return false
+		}
+		return true
+	}
+	v.keys = nil
+	if v.mode.visual() {
+		v.runVisual(cmd)
+	} else {
+		v.run(cmd)
+	}
+	return true
+}
+
+const (
+	vimIncomplete = iota
+	vimComplete
+	vimInvalid
+)
+
+// vimOperators are the operators, by name.
+var vimOperators = map[string]bool{
+	"d": true, "c": true, "y": true, ">": true, "<": true,
+	"g~": true, "gu": true, "gU": true,
+}
+
+// vimNormalCommands are the normal mode commands other than operators and
+// motions.
+var vimNormalCommands = map[string]bool{
+	"x": true, "X": true, "<Del>": true, "s": true, "S": true, "D": true,
+	"C": true, "Y": true, "p": true, "P": true, "J": true, "r": true,
+	"~": true, "u": true, "<C-r>": true, ".": true, "i": true, "a": true,
+	"I": true, "A": true, "o": true, "O": true, "v": true, "V": true,
+	"<C-v>": true, ":": true,
+}
+
+// vimVisualCommands are the visual mode commands other than motions and
+// text objects.
+var vimVisualCommands = map[string]bool{
+	"d": true, "x": true, "<Del>": true, "X": true, "D": true, "y": true,
+	"Y": true, "c": true, "s": true, "C": true, "S": true, "R": true,
+	">": true, "<": true, "~": true, "u": true, "U": true, "g~": true,
+	"gu": true, "gU": true, "J": true, "p": true, "P": true, "r": true,
+	"o": true, "I": true, "A": true, ":": true, "v": true, "V": true,
+	"<C-v>": true,
+}
+
+// parseVimCommand parses keys as a command: ["x] [count] [operator [count]]
+// motion, or ["x] [count] command. In visual mode operators apply to the
+// selection, so they are commands, and "i" and "a" start text objects.
+func parseVimCommand(keys []string, visual bool) (vimCommand, int) {
+	var c vimCommand
+	i := 0
+	if keys[0] == `"` {
+		if len(keys) < 2 {
+			return c, vimIncomplete
+		}
+		r, size := utf8.DecodeRuneInString(keys[1])
+		if size != len(keys[1]) || !isVimRegister(r) {
+			return c, vimInvalid
+		}
+		c.reg, i = r, 2
+	}
+	c.count, i = parseVimCount(keys, i)
+	if i == len(keys) {
+		return c, vimIncomplete
+	}
+	name, arg, i, state := readVimName(keys, i, visual)
+	if state != vimComplete {
+		return c, state
+	}
+	if !visual && vimOperators[name] {
+		c.op = name
+		var count int
+		count, i = parseVimCount(keys, i)
+		if i == len(keys) {
+			return c, vimIncomplete
+		}
+		if count > 0 {
+			c.count = c.n() * count
+		}
+		name, arg, i, state = readVimName(keys, i, true)
+		if state != vimComplete {
+			return c, state
+		}
+		switch {
+		case name == c.op, len(c.op) == 2 && name == c.op[1:]:
+			name = c.op // dd, g~~, gUU...
+		case !vimMotions[name] && !isVimTextObject(name):
+			return c, vimInvalid
+		}
+	} else if !vimMotions[name] && !(visual && (vimVisualCommands[name] || isVimTextObject(name))) && !(!visual && vimNormalCommands[name]) {
+		return c, vimInvalid
+	}
+	c.name, c.arg = name, arg
+	return c, vimComplete
+}
+
+// parseVimCount reads a count from keys[i:], returning 0 if there is none.
+func parseVimCount(keys []string, i int) (int, int) {
+	count := 0
+	for ; i < len(keys); i++ {
+		k := keys[i]
+		if len(k) != 1 || k[0] < '0' || k[0] > '9' || (k == "0" && count == 0) {
+			break
+		}
+		count = min(count*10+int(k[0]-'0'), 99999)
+	}
+	return count, i
+}
+
+// readVimName reads the name of a motion, text object or command from
+// keys[i:], with the character some of them take. objects says whether "i"
+// and "a" start text objects.
+func readVimName(keys []string, i int, objects bool) (name, arg string, next, state int) {
+	k := keys[i]
+	switch {
+	case k == "g", objects && (k == "i" || k == "a"):
+		if i+1 == len(keys) {
+			return "", "", i, vimIncomplete
+		}
+		return k + keys[i+1], "", i + 2, vimComplete
+	case k == "f" || k == "t" || k == "F" || k == "T" || k == "r":
+		if i+1 == len(keys) {
+			return "", "", i, vimIncomplete
+		}
+		if utf8.RuneCountInString(keys[i+1]) != 1 {
+			return "", "", i, vimInvalid
+		}
+		return k, keys[i+1], i + 2, vimComplete
+	}
+	return k, "", i + 1, vimComplete
+}
+
+func isVimRegister(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '"' || r == '-' || r == '_'
+}
+
+// store puts text deleted or yanked into registers: reg if one was given
+// and the unnamed register, as well as "0 for a yank, or "1 to "9 for a
+// deletion of lines and "- for a smaller one.
+func (v *Vim) store(reg rune, r vimRegister, yank bool) {
+	switch {
+	case reg == '_':
+		return
+	case reg >= 'A' && reg <= 'Z':
+		lower := unicode.ToLower(reg)
+		prev := v.registers[lower]
+		if prev.linewise && !r.linewise {
+			r.text += "\n"
+		}
+		r.text = prev.text + r.text
+		r.linewise = prev.linewise || r.linewise
+		v.registers[lower] = r
+	case reg != 0 && reg != '"':
+		v.registers[reg] = r
+	case yank:
+		v.registers['0'] = r
+	case r.linewise || strings.Contains(r.text, "\n"):
+		for i := '9'; i > '1'; i-- {
+			v.registers[i] = v.registers[i-1]
+		}
+		v.registers['1'] = r
+	default:
+		v.registers['-'] = r
+	}
+	v.registers['"'] = r
+}
+
+// register returns the content of reg, the unnamed register for 0.
+func (v *Vim) register(reg rune) vimRegister {
+	if reg == 0 {
+		reg = '"'
+	}
+	return v.registers[unicode.ToLower(reg)]
+}
+
+// cursor returns the host's cursor, forgetting the column j and k keep to
+// if it moved since the last command.
+func (v *Vim) cursor(t *vimText) int {
+	cur := max(0, min(v.host.Cursor(), len(t.r)))
+	if cur != v.lastCursor {
+		v.wantCol = -1
+	}
+	return cur
+}
+
+func (v *Vim) setCursor(off int) {
+	v.host.SetCursor(off)
+	v.lastCursor = off
+}
+
+func (v *Vim) edit(label, text string, off int) {
+	v.host.Edit(label, text, off)
+	v.lastCursor = off
+	v.wantCol = -1
+}
+
+// vimShorthands are the normal mode commands that are short for an
+// operator and a motion.
+var vimShorthands = map[string][2]string{
+	"x": {"d", "l"}, "<Del>": {"d", "l"}, "X": {"d", "h"}, "D": {"d", "$"},
+	"C": {"c", "$"}, "s": {"c", "l"}, "S": {"c", "c"}, "Y": {"y", "y"},
+}
+
+// run runs a normal mode command.
+func (v *Vim) run(c vimCommand) {
+	t := newVimText(v.host.Text())
+	cur := v.cursor(t)
+	if s, ok := vimShorthands[c.name]; ok {
+		c.op, c.name = s[0], s[1]
+	}
+	switch {
+	case c.op != "":
+		v.operate(t, cur, c)
+	case vimMotions[c.name]:
+		target, _, ok := v.motion(t, c.name, c.arg, c.count, cur, "")
+		if !ok {
+			return
+		}
+		if !vimVertical[c.name] && c.name != "$" && c.name != "<End>" {
+			v.wantCol = -1
+		}
+		v.setCursor(t.clampNormal(target))
+	default:
+		v.command(t, cur, c)
+	}
+}
+
+// operate applies an operator to the text a motion or text object covers.
+func (v *Vim) operate(t *vimText, cur int, c vimCommand) {
+	var start, end int
+	linewise := false
+	switch {
+	case c.name == c.op:
+		first := t.line(cur)
+		last := min(first+c.n()-1, t.lines()-1)
+		start, end = t.startOf(first), t.endOf(last)
+		linewise = true
+	case isVimTextObject(c.name):
+		var ok bool
+		start, end, linewise, ok = t.textObject(c.name, cur, c.count)
+		if !ok {
+			return
+		}
+		if linewise {
+			end = t.endOf(t.line(max(start, end-1)))
+		}
+	default:
+		name := c.name
+		if c.op == "c" && (name == "w" || name == "W") && cur < len(t.r) && vimClass(t.r[cur], false) != 0 {
+			name = "c" + name
+		}
+		target, kind, ok := v.motion(t, name, c.arg, c.count, cur, c.op)
+		if !ok {
+			return
+		}
+		start, end = min(cur, target), max(cur, target)
+		switch kind {
+		case vimInclusive:
+			end = min(end+1, len(t.r))
+		case vimLinewise:
+			linewise = true
+		case vimExclusive:
+			// An exclusive motion that ends at the start of a line stops
+			// at the end of the line before.
+			if end > start && t.lineStart(end) == end && t.line(end) > t.line(start) {
+				end--
+			}
+		}
+	}
+	if linewise {
+		v.applyLines(t, cur, c, t.line(start), t.line(end))
+	} else {
+		v.apply(t, cur, c, start, end)
+	}
+}
+
+// apply applies the operator of c to the runes [start, end).
+func (v *Vim) apply(t *vimText, cur int, c vimCommand, start, end int) {
+	text := string(t.r[start:end])
+	switch c.op {
+	case "y":
+		v.store(c.reg, vimRegister{text: text}, true)
+		v.setCursor(t.clampNormal(start))
+		return
+	case "d", "c":
+		v.store(c.reg, vimRegister{text: text}, false)
+		out := string(t.r[:start]) + string(t.r[end:])
+		if c.op == "c" {
+			v.edit("Change", out, start)
+			v.startInsert(c, 1, nil)
+			return
+		}
+		v.edit("Delete", out, newVimText(out).clampNormal(start))
+	case ">", "<":
+		v.applyLines(t, cur, c, t.line(start), t.line(end))
+		return
+	default:
+		out := string(t.r[:start]) + vimCase(c.op, text) + string(t.r[end:])
+		v.edit("Change Case", out, t.clampNormal(start))
+	}
+	v.last, v.lastInsert = &c, nil
+}
+
+// applyLines applies the operator of c to the lines first to last.
+func (v *Vim) applyLines(t *vimText, cur int, c vimCommand, first, last int) {
+	text := string(t.r[t.startOf(first):t.endOf(last)]) + "\n"
+	switch c.op {
+	case "y":
+		v.store(c.reg, vimRegister{text: text, linewise: true}, true)
+		if t.line(cur) > first {
+			cur = t.offset(first, t.col(cur))
+		}
+		v.setCursor(t.clampNormal(cur))
+		return
+	case "d":
+		v.store(c.reg, vimRegister{text: text, linewise: true}, false)
+		start, end := t.linewise(first, last)
+		out := newVimText(string(t.r[:start]) + string(t.r[end:]))
+		line := min(first, out.lines()-1)
+		v.edit("Delete", out.String(), out.clampNormal(out.firstNonBlank(out.startOf(line))))
+	case "c":
+		v.store(c.reg, vimRegister{text: text, linewise: true}, false)
+		indent := t.indent(first)
+		start := t.startOf(first)
+		out := string(t.r[:start]) + indent + string(t.r[t.endOf(last):])
+		v.edit("Change", out, start+utf8.RuneCountInString(indent))
+		v.startInsert(c, 1, nil)
+		return
+	case ">", "<":
+		out := newVimText(shiftLines(t.String(), first, last, v.host.IndentUnit(), 1, c.op == ">"))
+		v.edit("Indent", out.String(), out.clampNormal(out.firstNonBlank(out.startOf(first))))
+	default:
+		start, end := t.startOf(first), t.endOf(last)
+		out := string(t.r[:start]) + vimCase(c.op, string(t.r[start:end])) + string(t.r[end:])
+		v.edit("Change Case", out, t.clampNormal(cur))
+	}
+	v.last, v.lastInsert = &c, nil
+}
+
+// shiftLines indents (right) or outdents the lines first to last of text by
+// times units. Empty lines are not indented.
+func shiftLines(text string, first, last int, unit string, times int, right bool) string {
+	if unit == "" {
+		unit = "\t"
+	}
+	lines := strings.Split(text, "\n")
+	for i := first; i <= last && i < len(lines); i++ {
+		for k := 0; k < times; k++ {
+			switch line := lines[i]; {
+			case right:
+				if line != "" {
+					lines[i] = unit + line
+				}
+			case strings.HasPrefix(line, unit):
+				lines[i] = line[len(unit):]
+			case strings.HasPrefix(line, "\t"):
+				lines[i] = line[1:]
+			default:
+				// A tab unit takes off up to a tab stop's worth of spaces.
+				width := len(unit)
+				if unit == "\t" {
+					width = 8
+				}
+				spaces := len(line) - len(strings.TrimLeft(line, " "))
+				lines[i] = line[min(spaces, width):]
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// vimCase applies the case operator op to s.
+func vimCase(op, s string) string {
+	switch op {
+	case "gu", "u":
+		return strings.ToLower(s)
+	case "gU", "U":
+		return strings.ToUpper(s)
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
+// command runs a normal mode command that is not an operator or motion.
+func (v *Vim) command(t *vimText, cur int, c vimCommand) {
+	n := c.n()
+	switch c.name {
+	case "p", "P":
+		v.paste(t, cur, c)
+		return
+	case "J":
+		first := t.line(cur)
+		last := min(first+max(n, 2)-1, t.lines()-1)
+		if last == first {
+			return
+		}
+		out, off := joinLines(t, first, last)
+		v.edit("Join Lines", out, off)
+	case "r":
+		end := cur + n
+		if end > t.lineEnd(cur) {
+			return
+		}
+		out := string(t.r[:cur]) + strings.Repeat(c.arg, n) + string(t.r[end:])
+		v.edit("Replace", out, end-1)
+	case "~":
+		end := min(cur+n, t.lineEnd(cur))
+		if end == cur {
+			return
+		}
+		out := string(t.r[:cur]) + vimCase("g~", string(t.r[cur:end])) + string(t.r[end:])
+		v.edit("Change Case", out, newVimText(out).clampNormal(end))
+	case "u", "<C-r>":
+		for i := 0; i < n; i++ {
+			if c.name == "u" {
+				v.host.Undo()
+			} else {
+				v.host.Redo()
+			}
+		}
+		t := newVimText(v.host.Text())
+		v.setCursor(t.clampNormal(v.host.Cursor()))
+		return
+	case ".":
+		v.repeat(c)
+		return
+	case "i", "a", "I", "A":
+		off := cur
+		switch c.name {
+		case "a":
+			off = min(cur+1, t.lineEnd(cur))
+		case "I":
+			off = t.firstNonBlank(cur)
+		case "A":
+			off = t.lineEnd(cur)
+		}
+		v.setCursor(off)
+		v.startInsert(c, n, nil)
+		return
+	case "o", "O":
+		line := t.line(cur)
+		indent := t.indent(line)
+		at, ins, off := t.endOf(line), "\n"+indent, t.endOf(line)+1
+		if c.name == "O" {
+			at, ins, off = t.startOf(line), indent+"\n", t.startOf(line)
+		}
+		off += utf8.RuneCountInString(indent)
+		v.edit("Open Line", string(t.r[:at])+ins+string(t.r[at:]), off)
+		v.startInsert(c, n, []string{"<CR>"})
+		return
+	case "v", "V", "<C-v>":
+		v.mode = map[string]VimMode{"v": VimVisual, "V": VimVisualLine, "<C-v>": VimVisualBlock}[c.name]
+		v.anchor, v.cur = cur, min(cur, max(len(t.r)-1, 0))
+		v.showVisual(t)
+		return
+	case ":":
+		v.mode = VimCommandLine
+		v.cmdline = nil
+		return
+	}
+	v.last, v.lastInsert = &c, nil
+}
+
+// joinLines joins the lines first to last the way J does: leading blanks
+// of the joined lines give way to one space, which is left out after a
+// blank, before a ")" or around an empty line. It returns the new text and
+// the offset of the last join.
+func joinLines(t *vimText, first, last int) (string, int) {
+	head := t.lineText(first)
+	join := 0
+	for l := first + 1; l <= last; l++ {
+		next := strings.TrimLeft(t.lineText(l), " \t")
+		sep := " "
+		if next == "" || head == "" || strings.HasSuffix(head, " ") || strings.HasSuffix(head, "\t") || strings.HasPrefix(next, ")") {
+			sep = ""
+		}
+		join = utf8.RuneCountInString(head)
+		head += sep + next
+	}
+	out := string(t.r[:t.startOf(first)]) + head + string(t.r[t.endOf(last):])
+	return out, t.startOf(first) + join
+}
+
+// paste puts the text of a register after (p) or before (P) the cursor, or
+// below or above its line for lines.
+func (v *Vim) paste(t *vimText, cur int, c vimCommand) {
+	r := v.register(c.reg)
+	if r.text == "" {
+		return
+	}
+	n := c.n()
+	after := c.name == "p"
+	switch {
+	case r.block:
+		line, col := t.line(cur), t.col(cur)
+		if after && cur < t.lineEnd(cur) {
+			col++
+		}
+		x := VisualColumn(t.lineText(line), col, v.host.TabSize())
+		out := pasteBlock(t.String(), line, x, v.host.TabSize(), strings.Split(r.text, "\n"), n)
+		v.edit("Paste", out, newVimText(out).offset(line, col))
+	case r.linewise:
+		ins := strings.Repeat(r.text, n)
+		line := t.line(cur)
+		at := t.startOf(line)
+		if after {
+			if line+1 < t.lines() {
+				at = t.startOf(line + 1)
+			} else {
+				at = len(t.r)
+				ins = "\n" + strings.TrimSuffix(ins, "\n")
+			}
+		}
+		out := newVimText(string(t.r[:at]) + ins + string(t.r[at:]))
+		first := out.line(at)
+		if strings.HasPrefix(ins, "\n") {
+			first++
+		}
+		v.edit("Paste", out.String(), out.firstNonBlank(out.startOf(first)))
+	default:
+		ins := strings.Repeat(r.text, n)
+		at := cur
+		if after && cur < t.lineEnd(cur) {
+			at++
+		}
+		off := at
+		if !strings.Contains(ins, "\n") {
+			off += utf8.RuneCountInString(ins) - 1
+		}
+		v.edit("Paste", string(t.r[:at])+ins+string(t.r[at:]), off)
+	}
+	v.last, v.lastInsert = &c, nil
+}
+
+// pasteBlock inserts the lines of a block register n times at visual
+// column col of successive lines from line, adding lines at the end of the
+// text as needed. Each copy but the last is padded to the width of the
+// block.
+func pasteBlock(text string, line, col, tabSize int, pieces []string, n int) string {
+	width := 0
+	for _, p := range pieces {
+		width = max(width, VisualWidth(p, tabSize))
+	}
+	block := make([]string, len(pieces))
+	for i, p := range pieces {
+		padded := p + strings.Repeat(" ", width-VisualWidth(p, tabSize))
+		block[i] = strings.Repeat(padded, n-1) + p
+	}
+	return PasteBlock(text, block, line, col, tabSize)
+}
+
+// startInsert enters insert mode for the change c. count repeats the text
+// typed when insert mode ends, after prefix each time.
+func (v *Vim) startInsert(c vimCommand, count int, prefix []string) {
+	v.mode = VimInsert
+	v.change = &c
+	v.typed = nil
+	v.insertCount = count
+	v.insertPrefix = prefix
+}
+
+// insertKey records the keys typed in insert mode, for the repeats of a
+// count and for ".", and leaves typing them to the host.
+func (v *Vim) insertKey(key string) bool {
+	if key == "<Esc>" {
+		v.finishInsert()
+		return true
+	}
+	if utf8.RuneCountInString(key) == 1 || key == "<CR>" || key == "<BS>" || key == "<Del>" || key == "<Tab>" {
+		v.typed = append(v.typed, key)
+	}
+	return false
+}
+
+// finishInsert leaves insert mode, typing the text again for a count.
+func (v *Vim) finishInsert() {
+	if v.insertCount > 1 && len(v.typed) > 0 {
+		var keys []string
+		for i := 1; i < v.insertCount; i++ {
+			keys = append(append(keys, v.insertPrefix...), v.typed...)
+		}
+		out, off := vimType(v.host.Text(), v.host.Cursor(), keys)
+		v.edit("Typing", out, off)
+	}
+	if v.change != nil {
+		v.last, v.lastInsert = v.change, slices.Clone(v.typed)
+		v.change = nil
+	}
+	v.mode = VimNormal
+	t := newVimText(v.host.Text())
+	cur := max(0, min(v.host.Cursor(), len(t.r)))
+	if cur > t.lineStart(cur) {
+		cur--
+	}
+	v.setCursor(t.clampNormal(cur))
+}
+
+// vimType applies keys typed in insert mode at off of text, returning the
+// new text and cursor.
+func vimType(text string, off int, keys []string) (string, int) {
+	r := []rune(text)
+	off = max(0, min(off, len(r)))
+	for _, k := range keys {
+		switch k {
+		case "<BS>":
+			if off > 0 {
+				r = slices.Delete(r, off-1, off)
+				off--
+			}
+		case "<Del>":
+			if off < len(r) {
+				r = slices.Delete(r, off, off+1)
+			}
+		default:
+			ins := []rune(k)
+			switch k {
+			case "<CR>":
+				ins = []rune{'\n'}
+			case "<Tab>":
+				ins = []rune{'\t'}
+			}
+			r = slices.Insert(r, off, ins...)
+			off += len(ins)
+		}
+	}
+	return string(r), off
+}
+
+// repeat runs the last change again, with the count of c instead of its
+// own if c has one.
+func (v *Vim) repeat(c vimCommand) {
+	if v.last == nil {
+		return
+	}
+	last, typed := *v.last, v.lastInsert
+	if c.count > 0 {
+		last.count = c.count
+	}
+	v.run(last)
+	if v.mode == VimInsert {
+		out, off := vimType(v.host.Text(), v.host.Cursor(), typed)
+		if len(typed) > 0 {
+			v.edit("Typing", out, off)
+		}
+		v.typed = typed
+		v.finishInsert()
+	}
+}
@@ -0,0 +1,337 @@
+package editor
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commandLineKey edits the command line typed after ":" and runs it on
+// Enter.
+func (v *Vim) commandLineKey(key string) {
+	switch {
+	case key == "<Esc>":
+		v.mode = VimNormal
+	case key == "<CR>":
+		v.mode = VimNormal
+		v.ex(string(v.cmdline))
+	case key == "<BS>":
+		if len(v.cmdline) == 0 {
+			v.mode = VimNormal
+			return
+		}
+		v.cmdline = v.cmdline[:len(v.cmdline)-1]
+	case utf8.RuneCountInString(key) == 1:
+		v.cmdline = append(v.cmdline, []rune(key)...)
+	}
+}
+
+// vimExCommands maps the names of the ex commands the host runs, and their
+// long forms, to the names passed to VimHost.Ex.
+var vimExCommands = map[string]string{
+	"w": "w", "write": "w",
+	"q": "q", "quit": "q",
+	"wq": "wq", "x": "wq", "xit": "wq", "exit": "wq",
+	"wa": "wa", "wall": "wa",
+	"qa": "qa", "qall": "qa", "quitall": "qa",
+	"wqa": "wqa", "wqall": "wqa", "xa": "wqa", "xall": "wqa",
+}
+
+// ex runs an ex command line: an optional line range followed by a
+// command. A range alone goes to its last line.
+func (v *Vim) ex(line string) {
+	t := newVimText(v.host.Text())
+	cur := v.cursor(t)
+	line = strings.TrimSpace(line)
+	first, last, rest, ok := v.exRange(t, cur, line)
+	if !ok {
+		v.message = "Invalid range"
+		return
+	}
+	end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(rest)
+	}
+	name, args := rest[:end], rest[end:]
+	switch {
+	case rest == "":
+		if line != "" {
+			v.setCursor(t.clampNormal(t.firstNonBlank(t.startOf(last))))
+		}
+	case name == "s" || name == "substitute":
+		v.substitute(t, first, last, args)
+	case vimExCommands[name] != "":
+		bang := strings.HasPrefix(args, "!")
+		if err := v.host.Ex(vimExCommands[name], bang, strings.TrimSpace(strings.TrimPrefix(args, "!"))); err != nil {
+			v.message = err.Error()
+		}
+	default:
+		v.message = "Not an editor command: " + line
+	}
+}
+
+// exRange reads the line range at the start of an ex command line: "%" for
+// every line, or one or two addresses separated by a comma. An address is a
+// line number, "." for the cursor's line, "$" for the last line or "'<" and
+// "'>" for the lines of the last visual selection, optionally followed by
+// +n or -n. Without a range, the command applies to the cursor's line. ok
+// is false for a range outside the text.
+func (v *Vim) exRange(t *vimText, cur int, line string) (first, last int, rest string, ok bool) {
+	if rest, found := strings.CutPrefix(line, "%"); found {
+		return 0, t.lines() - 1, rest, true
+	}
+	first, rest, found := v.exAddress(t, cur, line)
+	if !found {
+		first = t.line(cur)
+	}
+	last = first
+	if after, comma := strings.CutPrefix(rest, ","); comma {
+		if last, rest, found = v.exAddress(t, cur, after); !found {
+			last = t.line(cur)
+		}
+	}
+	if first > last {
+		first, last = last, first
+	}
+	return first, last, rest, first >= 0 && last < t.lines()
+}
+
+// exAddress reads a line address, reporting whether there was one.
+func (v *Vim) exAddress(t *vimText, cur int, s string) (line int, rest string, ok bool) {
+	s = strings.TrimLeft(s, " ")
+	switch {
+	case strings.HasPrefix(s, "."):
+		line, s, ok = t.line(cur), s[1:], true
+	case strings.HasPrefix(s, "$"):
+		line, s, ok = t.lines()-1, s[1:], true
+	case strings.HasPrefix(s, "'<"):
+		line, s, ok = v.lastVisual[0], s[2:], true
+	case strings.HasPrefix(s, "'>"):
+		line, s, ok = v.lastVisual[1], s[2:], true
+	default:
+		if digits := vimDigits(s); digits > 0 {
+			n, _ := strconv.Atoi(s[:digits])
+			line, s, ok = n-1, s[digits:], true
+		}
+	}
+	for len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		n := 1
+		digits := vimDigits(s[1:])
+		if digits > 0 {
+			n, _ = strconv.Atoi(s[1 : 1+digits])
+		}
+		if s[0] == '-' {
+			n = -n
+		}
+		if !ok {
+			line, ok = t.line(cur), true
+		}
+		line += n
+		s = s[1+digits:]
+	}
+	return line, s, ok
+}
+
+// vimDigits returns the number of digits s starts with.
+func vimDigits(s string) int {
+	return len(s) - len(strings.TrimLeft(s, "0123456789"))
+}
+
+// substitute runs :s/pattern/replacement/flags on the lines first to last.
+// The pattern is a regular expression in Vim's syntax, as far as Go's
+// regexp package supports it; & and \1 to \9 in the replacement stand for
+// the match and its groups. The flags are g to replace every match in a
+// line rather than the first, and i to ignore case. Without a pattern, the
+// last substitution is repeated.
+func (v *Vim) substitute(t *vimText, first, last int, args string) {
+	pattern, repl, flags := v.lastSub[0], v.lastSub[1], v.lastSub[2]
+	if args = strings.TrimLeft(args, " "); args != "" {
+		delim, size := utf8.DecodeRuneInString(args)
+		if unicode.IsLetter(delim) || unicode.IsDigit(delim) || delim == '\\' || delim == '"' || delim == '|' || unicode.IsSpace(delim) {
+			v.message = "Regular expression can't be delimited by letters"
+			return
+		}
+		parts := splitVimDelimited(args[size:], delim)
+		if parts[0] != "" {
+			pattern = parts[0]
+		}
+		repl, flags = "", ""
+		if len(parts) > 1 {
+			repl = parts[1]
+		}
+		if len(parts) > 2 {
+			flags = parts[2]
+		}
+	}
+	if pattern == "" {
+		v.message = "No previous regular expression"
+		return
+	}
+	v.lastSub = [3]string{pattern, repl, flags}
+	re, err := compileVimPattern(pattern, strings.Contains(flags, "i"))
+	if err != nil {
+		v.message = fmt.Sprintf("Invalid pattern %s: %v", pattern, err)
+		return
+	}
+	global := strings.Contains(flags, "g")
+
+	var sb strings.Builder
+	sb.WriteString(string(t.r[:t.startOf(first)]))
+	subs, lines, lastLine := 0, 0, 0
+	for l := first; l <= last; l++ {
+		if l > first {
+			sb.WriteByte('\n')
+		}
+		text := t.lineText(l)
+		var matches [][]int
+		if global {
+			matches = re.FindAllStringSubmatchIndex(text, -1)
+		} else if m := re.FindStringSubmatchIndex(text); m != nil {
+			matches = [][]int{m}
+		}
+		if len(matches) == 0 {
+			sb.WriteString(text)
+			continue
+		}
+		lastLine = sb.Len()
+		prev := 0
+		for _, m := range matches {
+			sb.WriteString(text[prev:m[0]])
+			sb.WriteString(expandVimReplacement(repl, text, m))
+			prev = m[1]
+		}
+		sb.WriteString(text[prev:])
+		subs += len(matches)
+		lines++
+	}
+	if subs == 0 {
+		v.message = "Pattern not found: " + pattern
+		return
+	}
+	sb.WriteString(string(t.r[t.endOf(last):]))
+	text := sb.String()
+	out := newVimText(text)
+	v.edit("Substitute", text, out.clampNormal(out.firstNonBlank(utf8.RuneCountInString(text[:lastLine]))))
+	if lines > 1 {
+		v.message = fmt.Sprintf("%d substitutions on %d lines", subs, lines)
+	}
+}
+
+// splitVimDelimited splits s at the delimiters not escaped by a backslash.
+// An escaped delimiter loses its backslash.
+func splitVimDelimited(s string, delim rune) []string {
+	var parts []string
+	var sb strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			if r != delim {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == delim:
+			parts = append(parts, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if escaped {
+		sb.WriteRune('\\')
+	}
+	return append(parts, sb.String())
+}
+
+// compileVimPattern compiles a pattern in Vim's default (magic) syntax,
+// where groups, alternatives and the +, ? and {n,m} quantifiers are written
+// with a backslash, and \< and \> match at word boundaries. \c anywhere
+// ignores case.
+func compileVimPattern(pattern string, ignoreCase bool) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	inBraces := false
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		if r == '\\' && i+1 < len(rs) {
+			i++
+			switch next := rs[i]; next {
+			case '(', ')', '|', '+', '?':
+				sb.WriteRune(next)
+			case '=':
+				sb.WriteByte('?')
+			case '{':
+				sb.WriteByte('{')
+				inBraces = true
+			case '}':
+				sb.WriteByte('}')
+				inBraces = false
+			case '<', '>':
+				sb.WriteString(`\b`)
+			case 'c':
+				ignoreCase = true
+			default:
+				sb.WriteRune('\\')
+				sb.WriteRune(next)
+			}
+			continue
+		}
+		switch {
+		case r == '}' && inBraces:
+			sb.WriteByte('}')
+			inBraces = false
+		case strings.ContainsRune("()|+?{}", r):
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	expr := sb.String()
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// expandVimReplacement returns the replacement for the match m of a
+// pattern in src: & and \0 stand for the match, \1 to \9 for its groups,
+// \r and \n for a newline and \t for a tab. A backslash makes any other
+// character literal.
+func expandVimReplacement(repl, src string, m []int) string {
+	group := func(k int) string {
+		if 2*k+1 < len(m) && m[2*k] >= 0 {
+			return src[m[2*k]:m[2*k+1]]
+		}
+		return ""
+	}
+	var sb strings.Builder
+	rs := []rune(repl)
+	for i := 0; i < len(rs); i++ {
+		switch r := rs[i]; {
+		case r == '&':
+			sb.WriteString(group(0))
+		case r == '\\' && i+1 < len(rs):
+			i++
+			switch next := rs[i]; {
+			case next >= '0' && next <= '9':
+				sb.WriteString(group(int(next - '0')))
+			case next == 'r' || next == 'n':
+				sb.WriteByte('\n')
+			case next == 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteRune(next)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
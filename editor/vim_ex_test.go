@@ -0,0 +1,130 @@
+package editor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVimSubstitute(t *testing.T) {
+	cases := []struct {
+		text   string
+		cursor int
+		keys   string
+		want   string
+	}{
+		{"foo foo", 0, ":s/foo/bar/<CR>", "bar foo"},
+		{"foo foo", 0, ":s/foo/bar/g<CR>", "bar bar"},
+		{"a\na\na", 0, ":%s/a/b/<CR>", "b\nb\nb"},
+		{"a\na\na", 0, ":2,3s/a/b/<CR>", "a\nb\nb"},
+		{"a\na\na", 2, ":.,$s/a/b/<CR>", "a\nb\nb"},
+		{"a\na\na", 2, ":.+1s/a/b/<CR>", "a\na\nb"},
+		{"a\na\na", 0, "Vj:s/a/b/<CR>", "b\nb\na"},
+		{"hello world", 0, `:s/\(\w\+\) \(\w\+\)/\2 \1/<CR>`, "world hello"},
+		{"foo", 0, ":s/o/<&>/g<CR>", "f<o><o>"},
+		{"Foo", 0, ":s/foo/bar/i<CR>", "bar"},
+		{"Foo", 0, `:s/\cfoo/bar/<CR>`, "bar"},
+		{"a/b", 0, `:s#/#-#<CR>`, "a-b"},
+		{"a/b", 0, `:s/\//-/<CR>`, "a-b"},
+		{"a b", 0, `:s/ /\r/<CR>`, "a\nb"},
+		{"foobar foo", 0, `:s/\<foo\>/x/<CR>`, "foobar x"},
+		{"aaa", 0, `:s/a\{2}/b/<CR>`, "ba"},
+		{"f(x)", 0, ":s/(x)/y/<CR>", "fy"},
+		{"a\na", 0, ":s/a/b/<CR>j:s<CR>", "b\nb"},
+		{"abc", 0, ":s/x/y/<CR>", "abc"},
+	}
+	for _, tc := range cases {
+		v, h := newTestVim(tc.text, tc.cursor)
+		typeVim(v, h, tc.keys)
+		if h.text != tc.want {
+			t.Errorf("%q on %q = %q, want %q", tc.keys, tc.text, h.text, tc.want)
+		}
+	}
+}
+
+func TestVimSubstituteMessages(t *testing.T) {
+	v, h := newTestVim("a\na\nb", 0)
+	typeVim(v, h, ":%s/a/x/<CR>")
+	if got := v.TakeMessage(); got != "2 substitutions on 2 lines" {
+		t.Errorf("message = %q", got)
+	}
+	typeVim(v, h, ":s/q/x/<CR>")
+	if got := v.TakeMessage(); got != "Pattern not found: q" {
+		t.Errorf("message = %q", got)
+	}
+	if got := v.TakeMessage(); got != "" {
+		t.Errorf("message after TakeMessage = %q", got)
+	}
+}
+
+func TestVimExCommands(t *testing.T) {
+	v, h := newTestVim("a\nb\nc", 0)
+	typeVim(v, h, ":w<CR>:q!<CR>:wq<CR>:x<CR>:wall<CR>:qa!<CR>:w foo.txt<CR>")
+	want := []string{"w", "q!", "wq", "wq", "wa", "qa!", "w foo.txt"}
+	if !slices.Equal(h.ex, want) {
+		t.Errorf("ex commands = %q, want %q", h.ex, want)
+	}
+
+	typeVim(v, h, ":3<CR>")
+	if h.cursor != 4 {
+		t.Errorf(":3 cursor = %d, want 4", h.cursor)
+	}
+	typeVim(v, h, ":1<CR>:$<CR>")
+	if h.cursor != 4 {
+		t.Errorf(":$ cursor = %d, want 4", h.cursor)
+	}
+
+	typeVim(v, h, ":frob<CR>")
+	if got := v.TakeMessage(); got != "Not an editor command: frob" {
+		t.Errorf("message = %q", got)
+	}
+	typeVim(v, h, ":9s/a/b/<CR>")
+	if got := v.TakeMessage(); got != "Invalid range" {
+		t.Errorf("message = %q", got)
+	}
+}
+
+func TestVimCommandLineEditing(t *testing.T) {
+	v, h := newTestVim("abc", 0)
+	typeVim(v, h, ":wx<BS>")
+	if v.Mode() != VimCommandLine || v.Status() != ":w" {
+		t.Errorf("mode %v, status %q", v.Mode(), v.Status())
+	}
+	typeVim(v, h, "<BS><BS>")
+	if v.Mode() != VimNormal {
+		t.Errorf("backspace on an empty command line: mode %v", v.Mode())
+	}
+	typeVim(v, h, ":w<Esc>")
+	if v.Mode() != VimNormal || len(h.ex) != 0 {
+		t.Errorf("esc: mode %v, ex %q", v.Mode(), h.ex)
+	}
+}
+
+func TestCompileVimPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		match   string
+		want    string
+	}{
+		{`a\+`, "baaa", "aaa"},
+		{`a+`, "a+", "a+"},
+		{`colou\=r`, "color", "color"},
+		{`\(ab\)\{2}`, "ababab", "abab"},
+		{`x{2}`, "x{2}", "x{2}"},
+		{`foo\|bar`, "bar", "bar"},
+		{`\<in\>`, "inside in", "in"},
+		{`[0-9]\+`, "v12", "12"},
+	}
+	for _, tc := range cases {
+		re, err := compileVimPattern(tc.pattern, false)
+		if err != nil {
+			t.Errorf("compileVimPattern(%q): %v", tc.pattern, err)
+			continue
+		}
+		if got := re.FindString(tc.match); got != tc.want {
+			t.Errorf("%q in %q = %q, want %q", tc.pattern, tc.match, got, tc.want)
+		}
+	}
+	if re, _ := compileVimPattern(`\<in\>`, false); re.FindStringIndex("inside in")[0] != 7 {
+		t.Errorf(`\<in\> matched inside a word`)
+	}
+}
@@ -0,0 +1,585 @@
+package editor
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// vimText is a text as runes, with the offsets of its lines. Offsets are
+// rune offsets, as in MultiCursor.
+type vimText struct {
+	r      []rune
+	starts []int // offset of the first rune of each line
+}
+
+func newVimText(text string) *vimText {
+	t := &vimText{r: []rune(text), starts: []int{0}}
+	for i, r := range t.r {
+		if r == '\n' {
+			t.starts = append(t.starts, i+1)
+		}
+	}
+	return t
+}
+
+func (t *vimText) String() string { return string(t.r) }
+
+// lines returns the number of lines.
+func (t *vimText) lines() int { return len(t.starts) }
+
+// line returns the line of off.
+func (t *vimText) line(off int) int {
+	return sort.Search(len(t.starts), func(i int) bool { return t.starts[i] > off }) - 1
+}
+
+// startOf returns the offset of the first rune of line.
+func (t *vimText) startOf(line int) int { return t.starts[line] }
+
+// endOf returns the offset of the newline ending line, or the end of the
+// text for the last line.
+func (t *vimText) endOf(line int) int {
+	if line+1 < len(t.starts) {
+		return t.starts[line+1] - 1
+	}
+	return len(t.r)
+}
+
+func (t *vimText) lineStart(off int) int { return t.startOf(t.line(off)) }
+func (t *vimText) lineEnd(off int) int   { return t.endOf(t.line(off)) }
+func (t *vimText) col(off int) int       { return off - t.lineStart(off) }
+
+// offset returns the offset of col in line, or the end of the line if it is
+// shorter.
+func (t *vimText) offset(line, col int) int {
+	return min(t.startOf(line)+col, t.endOf(line))
+}
+
+// firstNonBlank returns the offset of the first rune of the line of off
+// that is not a space or tab.
+func (t *vimText) firstNonBlank(off int) int {
+	i, end := t.lineStart(off), t.lineEnd(off)
+	for i < end && (t.r[i] == ' ' || t.r[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// indent returns the leading spaces and tabs of line.
+func (t *vimText) indent(line int) string {
+	start := t.startOf(line)
+	return string(t.r[start:t.firstNonBlank(start)])
+}
+
+// lineText returns line without its newline.
+func (t *vimText) lineText(line int) string {
+	return string(t.r[t.startOf(line):t.endOf(line)])
+}
+
+func (t *vimText) empty(line int) bool { return t.startOf(line) == t.endOf(line) }
+func (t *vimText) blank(line int) bool { return strings.TrimSpace(t.lineText(line)) == "" }
+
+// clampNormal returns the offset nearest to off that the cursor can rest on
+// in normal mode: a rune other than a newline, or the start of an empty
+// line.
+func (t *vimText) clampNormal(off int) int {
+	off = max(0, min(off, len(t.r)))
+	if off > t.lineStart(off) && (off == len(t.r) || t.r[off] == '\n') {
+		off--
+	}
+	return off
+}
+
+// linewise returns the range of lines first to last with their newlines.
+// Without a newline after last, the one before first is taken instead.
+func (t *vimText) linewise(first, last int) (start, end int) {
+	start, end = t.startOf(first), t.endOf(last)
+	if end < len(t.r) {
+		end++
+	} else if start > 0 {
+		start--
+	}
+	return start, end
+}
+
+// vimClass returns the class of r for word motions: 0 for blanks, 1 for
+// punctuation and 2 for word characters. For WORD motions (big) everything
+// that is not blank is one class.
+func vimClass(r rune, big bool) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case big, r == '_', unicode.IsLetter(r), unicode.IsDigit(r):
+		return 2
+	}
+	return 1
+}
+
+// nextWordStart returns the start of the word after the one at off. An
+// empty line counts as a word.
+func (t *vimText) nextWordStart(off int, big bool) int {
+	n := len(t.r)
+	if off >= n {
+		return n
+	}
+	i := off
+	if c := vimClass(t.r[i], big); c != 0 {
+		for i < n && vimClass(t.r[i], big) == c {
+			i++
+		}
+	}
+	for i < n && vimClass(t.r[i], big) == 0 {
+		if t.r[i] == '\n' {
+			i++
+			if i < n && t.r[i] == '\n' {
+				return i
+			}
+			continue
+		}
+		i++
+	}
+	return i
+}
+
+// wordEnd returns the last rune of the word at off, or of the next word if
+// off is already at the end of one.
+func (t *vimText) wordEnd(off int, big bool) int {
+	n := len(t.r)
+	i := off + 1
+	for i < n && vimClass(t.r[i], big) == 0 {
+		i++
+	}
+	if i >= n {
+		return n - 1
+	}
+	c := vimClass(t.r[i], big)
+	for i+1 < n && vimClass(t.r[i+1], big) == c {
+		i++
+	}
+	return i
+}
+
+// prevWordStart returns the start of the word before off, or of the word
+// off is in if it is not at its start. An empty line counts as a word.
+func (t *vimText) prevWordStart(off int, big bool) int {
+	i := off - 1
+	for i >= 0 && vimClass(t.r[i], big) == 0 {
+		if t.r[i] == '\n' && i > 0 && t.r[i-1] == '\n' {
+			return i
+		}
+		i--
+	}
+	if i < 0 {
+		return 0
+	}
+	c := vimClass(t.r[i], big)
+	for i > 0 && vimClass(t.r[i-1], big) == c {
+		i--
+	}
+	return i
+}
+
+// vimMotionKind says which part of the text an operator works on when
+// given a motion.
+type vimMotionKind int
+
+const (
+	vimExclusive vimMotionKind = iota // from the cursor up to the target
+	vimInclusive                      // from the cursor up to and including the target
+	vimLinewise                       // whole lines from the cursor's to the target's
+)
+
+// vimMotions are the motions, by name.
+var vimMotions = map[string]bool{
+	"h": true, "l": true, "j": true, "k": true, " ": true,
+	"<Left>": true, "<Right>": true, "<Up>": true, "<Down>": true, "<BS>": true,
+	"w": true, "W": true, "b": true, "B": true, "e": true, "E": true,
+	"0": true, "^": true, "$": true, "<Home>": true, "<End>": true,
+	"gg": true, "G": true, "<CR>": true, "+": true, "-": true,
+	"f": true, "t": true, "F": true, "T": true, ";": true, ",": true,
+	"%": true, "{": true, "}": true,
+}
+
+// vimVertical are the motions that keep the column the cursor wants.
+var vimVertical = map[string]bool{"j": true, "k": true, "<Up>": true, "<Down>": true}
+
+// motion returns where the motion name goes from cur when repeated count
+// times; count is 0 when none was typed. arg is the character f, t, F and T
+// look for. op is the operator the motion is for, if any. ok is false when
+// the motion cannot move.
+func (v *Vim) motion(t *vimText, name, arg string, count, cur int, op string) (target int, kind vimMotionKind, ok bool) {
+	n := max(count, 1)
+	line := t.line(cur)
+	switch name {
+	case "h", "<Left>", "<BS>":
+		start := t.lineStart(cur)
+		return max(start, cur-n), vimExclusive, cur > start
+	case "l", "<Right>", " ":
+		end := t.lineEnd(cur)
+		return min(end, cur+n), vimExclusive, cur < end
+	case "j", "<Down>", "k", "<Up>":
+		to := line + n
+		if name == "k" || name == "<Up>" {
+			to = line - n
+		}
+		to = max(0, min(to, t.lines()-1))
+		if v.wantCol < 0 {
+			v.wantCol = t.col(cur)
+		}
+		return t.offset(to, v.wantCol), vimLinewise, to != line
+	case "<CR>", "+", "-":
+		to := line + n
+		if name == "-" {
+			to = line - n
+		}
+		if to < 0 || to >= t.lines() {
+			return cur, vimLinewise, false
+		}
+		return t.firstNonBlank(t.startOf(to)), vimLinewise, true
+	case "w", "W":
+		big := name == "W"
+		target = cur
+		for i := 0; i < n && target < len(t.r); i++ {
+			next := t.nextWordStart(target, big)
+			// An operator stops at the end of the line of the last word
+			// it moves over rather than going on to the next line.
+			if op != "" && i == n-1 && next > t.lineEnd(target) && t.lineEnd(target) > target {
+				next = t.lineEnd(target)
+			}
+			target = next
+		}
+		return target, vimExclusive, target > cur
+	case "cw", "cW":
+		// cw on a word changes up to its end, like ce, but without moving
+		// on to the next word when the cursor is on the last character.
+		big := name == "cW"
+		target = cur
+		for i := 0; i < n; i++ {
+			atEnd := target+1 >= len(t.r) || vimClass(t.r[target+1], big) != vimClass(t.r[target], big)
+			if i == 0 && atEnd {
+				continue
+			}
+			target = t.wordEnd(target, big)
+		}
+		return target, vimInclusive, true
+	case "e", "E":
+		target = cur
+		for i := 0; i < n; i++ {
+			target = t.wordEnd(target, name == "E")
+		}
+		return target, vimInclusive, target > cur
+	case "b", "B":
+		target = cur
+		for i := 0; i < n; i++ {
+			target = t.prevWordStart(target, name == "B")
+		}
+		return target, vimExclusive, target < cur
+	case "0", "<Home>":
+		return t.lineStart(cur), vimExclusive, true
+	case "^":
+		return t.firstNonBlank(cur), vimExclusive, true
+	case "$", "<End>":
+		v.wantCol = len(t.r)
+		return t.endOf(min(line+n-1, t.lines()-1)), vimExclusive, true
+	case "gg", "G":
+		to := t.lines() - 1
+		if count > 0 {
+			to = min(count, t.lines()) - 1
+		} else if name == "gg" {
+			to = 0
+		}
+		return t.firstNonBlank(t.startOf(to)), vimLinewise, true
+	case "f", "t", "F", "T":
+		if arg == "" {
+			return cur, vimExclusive, false
+		}
+		v.lastFind = name + arg
+		return t.findInLine(cur, name, []rune(arg)[0], n, op != "")
+	case ";", ",":
+		if v.lastFind == "" {
+			return cur, vimExclusive, false
+		}
+		find, ch := v.lastFind[:1], []rune(v.lastFind[1:])[0]
+		if name == "," {
+			find = map[string]string{"f": "F", "F": "f", "t": "T", "T": "t"}[find]
+		}
+		return t.findInLine(cur, find, ch, n, op != "")
+	case "%":
+		if count > 0 {
+			to := (count*t.lines()+99)/100 - 1
+			return t.firstNonBlank(t.startOf(max(0, min(to, t.lines()-1)))), vimLinewise, true
+		}
+		for i := cur; i < t.lineEnd(cur); i++ {
+			if _, ok := bracketPairs[t.r[i]]; ok {
+				m, ok := FindMatchingBracket(string(t.r), i)
+				return m, vimInclusive, ok
+			}
+		}
+		return cur, vimInclusive, false
+	case "}":
+		l := line
+		for i := 0; i < n; i++ {
+			for l < t.lines() && t.empty(l) {
+				l++
+			}
+			for l < t.lines() && !t.empty(l) {
+				l++
+			}
+		}
+		if l >= t.lines() {
+			return len(t.r), vimExclusive, cur < len(t.r)
+		}
+		return t.startOf(l), vimExclusive, true
+	case "{":
+		l := line
+		for i := 0; i < n; i++ {
+			for l >= 0 && t.empty(l) {
+				l--
+			}
+			for l >= 0 && !t.empty(l) {
+				l--
+			}
+		}
+		if l < 0 {
+			return 0, vimExclusive, cur > 0
+		}
+		return t.startOf(l), vimExclusive, true
+	}
+	return cur, vimExclusive, false
+}
+
+// findInLine finds the nth ch after (f, t) or before (F, T) cur on its
+// line. t and T stop next to it, which fails if that does not move the
+// cursor unless the motion is for an operator: dtx still deletes the
+// character before an x right after the cursor.
+func (t *vimText) findInLine(cur int, find string, ch rune, n int, forOp bool) (int, vimMotionKind, bool) {
+	if find == "f" || find == "t" {
+		for i := cur + 1; i < t.lineEnd(cur); i++ {
+			if t.r[i] == ch {
+				if n--; n == 0 {
+					if find == "t" {
+						return i - 1, vimInclusive, i-1 > cur || forOp
+					}
+					return i, vimInclusive, true
+				}
+			}
+		}
+		return cur, vimInclusive, false
+	}
+	for i := cur - 1; i >= t.lineStart(cur); i-- {
+		if t.r[i] == ch {
+			if n--; n == 0 {
+				if find == "T" {
+					return i + 1, vimExclusive, i+1 < cur || forOp
+				}
+				return i, vimExclusive, true
+			}
+		}
+	}
+	return cur, vimExclusive, false
+}
+
+// vimObjectPairs maps the bracket text object names to their brackets.
+var vimObjectPairs = map[rune][2]rune{
+	'(': {'(', ')'}, ')': {'(', ')'}, 'b': {'(', ')'},
+	'[': {'[', ']'}, ']': {'[', ']'},
+	'{': {'{', '}'}, '}': {'{', '}'}, 'B': {'{', '}'},
+	'<': {'<', '>'}, '>': {'<', '>'},
+}
+
+// isVimTextObject reports whether name is a text object, such as "iw" or
+// "a(".
+func isVimTextObject(name string) bool {
+	r := []rune(name)
+	if len(r) != 2 || (r[0] != 'i' && r[0] != 'a') {
+		return false
+	}
+	_, pair := vimObjectPairs[r[1]]
+	return pair || strings.ContainsRune("wWp\"'`", r[1])
+}
+
+// textObject returns the range [start, end) of the text object name around
+// cur. linewise is set when the range is made of whole lines.
+func (t *vimText) textObject(name string, cur, count int) (start, end int, linewise, ok bool) {
+	r := []rune(name)
+	inner, obj := r[0] == 'i', r[1]
+	switch obj {
+	case 'w', 'W':
+		start, end, ok = t.wordObject(cur, inner, obj == 'W')
+		// A count takes in the words, or the runs of blanks, after it.
+		for n := 1; ok && n < count && end < t.lineEnd(cur); n++ {
+			_, end, _ = t.wordObject(end, inner, obj == 'W')
+		}
+	case '"', '\'', '`':
+		start, end, ok = t.quoteObject(cur, inner, obj)
+	case 'p':
+		return t.paragraphObject(cur, inner)
+	default:
+		return t.bracketObject(cur, inner, vimObjectPairs[obj], max(count, 1))
+	}
+	return start, end, false, ok
+}
+
+func (t *vimText) wordObject(cur int, inner, big bool) (start, end int, ok bool) {
+	ls, le := t.lineStart(cur), t.lineEnd(cur)
+	if cur >= le {
+		return cur, cur, false
+	}
+	class := func(i int) int { return vimClass(t.r[i], big) }
+	c := class(cur)
+	start, end = cur, cur+1
+	for start > ls && class(start-1) == c {
+		start--
+	}
+	for end < le && class(end) == c {
+		end++
+	}
+	if inner {
+		return start, end, true
+	}
+	if c == 0 {
+		// On blanks, "a word" is the blanks and the word after them.
+		if end < le {
+			next := class(end)
+			for end < le && class(end) == next {
+				end++
+			}
+		}
+		return start, end, true
+	}
+	if end < le && class(end) == 0 {
+		for end < le && class(end) == 0 {
+			end++
+		}
+	} else {
+		for start > ls && class(start-1) == 0 {
+			start--
+		}
+	}
+	return start, end, true
+}
+
+func (t *vimText) quoteObject(cur int, inner bool, q rune) (start, end int, ok bool) {
+	ls, le := t.lineStart(cur), t.lineEnd(cur)
+	var quotes []int
+	for i := ls; i < le; i++ {
+		if t.r[i] == q && (i == ls || t.r[i-1] != '\\') {
+			quotes = append(quotes, i)
+		}
+	}
+	open, closing := -1, -1
+	for i := 0; i+1 < len(quotes); i += 2 {
+		if quotes[i] <= cur && cur <= quotes[i+1] || quotes[i] > cur {
+			open, closing = quotes[i], quotes[i+1]
+			break
+		}
+	}
+	if open < 0 {
+		return cur, cur, false
+	}
+	if inner {
+		return open + 1, closing, true
+	}
+	start, end = open, closing+1
+	if end < le && (t.r[end] == ' ' || t.r[end] == '\t') {
+		for end < le && (t.r[end] == ' ' || t.r[end] == '\t') {
+			end++
+		}
+	} else {
+		for start > ls && (t.r[start-1] == ' ' || t.r[start-1] == '\t') {
+			start--
+		}
+	}
+	return start, end, true
+}
+
+func (t *vimText) paragraphObject(cur int, inner bool) (start, end int, linewise, ok bool) {
+	line := t.line(cur)
+	blank := t.blank(line)
+	first, last := line, line
+	for first > 0 && t.blank(first-1) == blank {
+		first--
+	}
+	for last+1 < t.lines() && t.blank(last+1) == blank {
+		last++
+	}
+	if !inner {
+		switch {
+		case last+1 < t.lines():
+			// The blank lines after a paragraph, or the paragraph after
+			// blank lines.
+			last++
+			for last+1 < t.lines() && t.blank(last+1) == !blank {
+				last++
+			}
+		case !blank:
+			for first > 0 && t.blank(first-1) {
+				first--
+			}
+		}
+	}
+	start, end = t.linewise(first, last)
+	return start, end, true, true
+}
+
+// bracketObject selects the count-th pair of brackets around cur. Inside a
+// pair that spans lines, the inner object is the lines between them.
+func (t *vimText) bracketObject(cur int, inner bool, pair [2]rune, count int) (start, end int, linewise, ok bool) {
+	open, closing := pair[0], pair[1]
+	if cur >= len(t.r) {
+		return cur, cur, false, false
+	}
+	start = cur
+	for k := 0; k < count; k++ {
+		from := start - 1
+		if k == 0 && t.r[cur] == open {
+			from = cur
+		}
+		start = -1
+		depth := 0
+		for i := from; i >= 0; i-- {
+			switch {
+			case t.r[i] == closing:
+				depth++
+			case t.r[i] == open:
+				if depth == 0 {
+					start = i
+				}
+				depth--
+			}
+			if start >= 0 {
+				break
+			}
+		}
+		if start < 0 {
+			return cur, cur, false, false
+		}
+	}
+	end = -1
+	depth := 0
+	for i := start + 1; i < len(t.r) && end < 0; i++ {
+		switch t.r[i] {
+		case open:
+			depth++
+		case closing:
+			if depth == 0 {
+				end = i
+			}
+			depth--
+		}
+	}
+	if end < 0 {
+		return cur, cur, false, false
+	}
+	if !inner {
+		return start, end + 1, false, true
+	}
+	start++
+	if start < end && t.r[start] == '\n' {
+		closeLine := t.lineStart(end)
+		if strings.TrimSpace(string(t.r[closeLine:end])) == "" && closeLine > start+1 {
+			return start + 1, closeLine, true, true
+		}
+	}
+	return start, end, false, true
+}
@@ -0,0 +1,91 @@
+package editor
+
+import "testing"
+
+func TestVimWordMotions(t *testing.T) {
+	text := newVimText("foo.bar  baz\n\nqux")
+	cases := []struct {
+		name string
+		f    func(off int) int
+		from []int
+		want []int
+	}{
+		{"w", func(off int) int { return text.nextWordStart(off, false) }, []int{0, 3, 4, 9, 13}, []int{3, 4, 9, 13, 14}},
+		{"W", func(off int) int { return text.nextWordStart(off, true) }, []int{0, 9}, []int{9, 13}},
+		{"e", func(off int) int { return text.wordEnd(off, false) }, []int{0, 2, 3, 6}, []int{2, 3, 6, 11}},
+		{"E", func(off int) int { return text.wordEnd(off, true) }, []int{0}, []int{6}},
+		{"b", func(off int) int { return text.prevWordStart(off, false) }, []int{14, 13, 9, 4}, []int{13, 9, 4, 3}},
+		{"B", func(off int) int { return text.prevWordStart(off, true) }, []int{9}, []int{0}},
+	}
+	for _, tc := range cases {
+		for i, from := range tc.from {
+			if got := tc.f(from); got != tc.want[i] {
+				t.Errorf("%s from %d = %d, want %d", tc.name, from, got, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestVimTextObjects(t *testing.T) {
+	cases := []struct {
+		text     string
+		cursor   int
+		object   string
+		count    int
+		want     string
+		linewise bool
+	}{
+		{"foo bar baz", 5, "iw", 1, "bar", false},
+		{"foo bar baz", 5, "aw", 1, "bar ", false},
+		{"foo bar", 5, "aw", 1, " bar", false},
+		{"foo bar baz", 5, "iw", 3, "bar baz", false},
+		{"a.b c", 0, "iW", 1, "a.b", false},
+		{`x "a b" y`, 4, `i"`, 1, "a b", false},
+		{`x "a b" y`, 4, `a"`, 1, `"a b" `, false},
+		{`x "a\"b" y`, 4, `i"`, 1, `a\"b`, false},
+		{"f(a, b)", 3, "i(", 1, "a, b", false},
+		{"f(a, b)", 3, "ab", 1, "(a, b)", false},
+		{"f(a(b)c)", 4, "i(", 2, "a(b)c", false},
+		{"[a]", 0, "i]", 1, "a", false},
+		{"<a>", 1, "a<", 1, "<a>", false},
+		{"f {\n  a\n}", 6, "iB", 1, "  a\n", true},
+		{"a\nb\n\nc", 2, "ip", 1, "a\nb\n", true},
+		{"a\nb\n\nc", 2, "ap", 1, "a\nb\n\n", true},
+	}
+	for _, tc := range cases {
+		text := newVimText(tc.text)
+		start, end, linewise, ok := text.textObject(tc.object, tc.cursor, tc.count)
+		if !ok {
+			t.Errorf("%s in %q at %d: not found", tc.object, tc.text, tc.cursor)
+			continue
+		}
+		if got := string(text.r[start:end]); got != tc.want || linewise != tc.linewise {
+			t.Errorf("%s in %q at %d = %q (linewise %v), want %q (linewise %v)", tc.object, tc.text, tc.cursor, got, linewise, tc.want, tc.linewise)
+		}
+	}
+	if _, _, _, ok := newVimText("abc").textObject("i(", 1, 1); ok {
+		t.Error("i( outside brackets should not be found")
+	}
+}
+
+func TestVimTextLines(t *testing.T) {
+	text := newVimText("ab\n\n  cd")
+	if text.lines() != 3 || text.line(4) != 2 || text.startOf(2) != 4 || text.endOf(0) != 2 {
+		t.Errorf("lines %d, line(4) %d, startOf(2) %d, endOf(0) %d", text.lines(), text.line(4), text.startOf(2), text.endOf(0))
+	}
+	if got := text.offset(0, 5); got != 2 {
+		t.Errorf("offset past the end of a line = %d, want 2", got)
+	}
+	if got := text.firstNonBlank(4); got != 6 {
+		t.Errorf("firstNonBlank = %d, want 6", got)
+	}
+	if got := text.clampNormal(2); got != 1 {
+		t.Errorf("clampNormal on a newline = %d, want 1", got)
+	}
+	if got := text.clampNormal(3); got != 3 {
+		t.Errorf("clampNormal on an empty line = %d, want 3", got)
+	}
+	if start, end := text.linewise(2, 2); start != 3 || end != 8 {
+		t.Errorf("linewise last line = %d-%d, want 3-8", start, end)
+	}
+}
@@ -0,0 +1,423 @@
+package editor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeVimHost is a VimHost on a plain string.
+type fakeVimHost struct {
+	text    string
+	cursor  int
+	undo    []string
+	redo    []string
+	labels  []string
+	anchor  int
+	block   *BlockSelection
+	inserts []int
+	ex      []string
+}
+
+func (h *fakeVimHost) Text() string         { return h.text }
+func (h *fakeVimHost) Cursor() int          { return h.cursor }
+func (h *fakeVimHost) SetCursor(offset int) { h.cursor, h.anchor, h.inserts = offset, offset, nil }
+func (h *fakeVimHost) IndentUnit() string   { return "\t" }
+func (h *fakeVimHost) TabSize() int         { return 4 }
+
+func (h *fakeVimHost) Edit(label, text string, offset int) {
+	h.undo = append(h.undo, h.text)
+	h.redo = nil
+	h.labels = append(h.labels, label)
+	h.text, h.cursor, h.anchor = text, offset, offset
+}
+
+func (h *fakeVimHost) Undo() {
+	if len(h.undo) > 0 {
+		h.redo = append(h.redo, h.text)
+		h.text = h.undo[len(h.undo)-1]
+		h.undo = h.undo[:len(h.undo)-1]
+	}
+}
+
+func (h *fakeVimHost) Redo() {
+	if len(h.redo) > 0 {
+		h.undo = append(h.undo, h.text)
+		h.text = h.redo[len(h.redo)-1]
+		h.redo = h.redo[:len(h.redo)-1]
+	}
+}
+
+func (h *fakeVimHost) Select(anchor, offset int)      { h.anchor, h.cursor = anchor, offset }
+func (h *fakeVimHost) SelectBlock(bs *BlockSelection) { h.block = bs }
+func (h *fakeVimHost) InsertAt(offsets []int)         { h.inserts, h.cursor = offsets, offsets[0] }
+
+func (h *fakeVimHost) Ex(command string, bang bool, arg string) error {
+	if bang {
+		command += "!"
+	}
+	h.ex = append(h.ex, strings.TrimSpace(command+" "+arg))
+	return nil
+}
+
+// splitVimKeys splits keys such as "dw<Esc>" into "d", "w" and "<Esc>".
+func splitVimKeys(keys string) []string {
+	var out []string
+	for keys != "" {
+		if end := strings.IndexByte(keys, '>'); keys[0] == '<' && end > 1 && isVimKeyName(keys[1:end]) {
+			out = append(out, keys[:end+1])
+			keys = keys[end+1:]
+			continue
+		}
+		r := []rune(keys)[0]
+		out = append(out, string(r))
+		keys = keys[len(string(r)):]
+	}
+	return out
+}
+
+func isVimKeyName(name string) bool {
+	switch name {
+	case "Esc", "CR", "BS", "Del", "Tab", "Up", "Down", "Left", "Right", "Home", "End":
+		return true
+	}
+	return strings.HasPrefix(name, "C-")
+}
+
+// typeVim sends keys to v, typing the ones it leaves to the host in insert
+// mode the way an editor would.
+func typeVim(v *Vim, h *fakeVimHost, keys string) {
+	for _, k := range splitVimKeys(keys) {
+		if !v.Key(k) && v.Mode() == VimInsert {
+			h.text, h.cursor = vimType(h.text, h.cursor, []string{k})
+		}
+	}
+}
+
+func newTestVim(text string, cursor int) (*Vim, *fakeVimHost) {
+	h := &fakeVimHost{text: text, cursor: cursor}
+	return NewVim(h), h
+}
+
+func TestVimNormalCommands(t *testing.T) {
+	cases := []struct {
+		name   string
+		text   string
+		cursor int
+		keys   string
+		want   string
+		cur    int
+	}{
+		{"x", "abc", 0, "x", "bc", 0},
+		{"count x", "abcd", 1, "2x", "ad", 1},
+		{"x at end", "abc", 2, "x", "ab", 1},
+		{"X", "abc", 2, "X", "ac", 1},
+		{"dw", "foo bar baz", 0, "dw", "bar baz", 0},
+		{"dw last word", "foo bar\nbaz", 4, "dw", "foo \nbaz", 3},
+		{"d2w", "a b c d", 0, "d2w", "c d", 0},
+		{"2dw", "a b c d", 0, "2dw", "c d", 0},
+		{"de", "foo bar", 0, "de", " bar", 0},
+		{"db", "foo bar", 4, "db", "bar", 0},
+		{"d$", "hello world", 5, "d$", "hello", 4},
+		{"D", "hello world", 5, "D", "hello", 4},
+		{"d0", "hello world", 6, "d0", "world", 0},
+		{"dd", "a\nb\nc", 2, "dd", "a\nc", 2},
+		{"dd last line", "a\nb", 2, "dd", "a", 0},
+		{"dd only line", "abc", 1, "dd", "", 0},
+		{"2dd", "a\nb\nc", 0, "2dd", "c", 0},
+		{"dj", "a\nb\nc", 0, "dj", "c", 0},
+		{"dk at top", "a\nb", 0, "dk", "a\nb", 0},
+		{"dG", "a\nb\nc", 2, "dG", "a", 0},
+		{"dgg", "a\nb\nc", 2, "dgg", "c", 0},
+		{"dt", "f(a, b)", 2, "dt)", "f()", 2},
+		{"dt next to the cursor", "axb", 0, "dtx", "xb", 0},
+		{"ct next to the cursor", "a,b", 0, "ct,z<Esc>", "z,b", 0},
+		{"df", "a,b,c", 0, "df,", "b,c", 0},
+		{"d2f", "a,b,c", 0, "d2f,", "c", 0},
+		{"dF", "a,b,c", 4, "dF,", "a,bc", 3},
+		{"d%", "(a [b] c) d", 0, "d%", " d", 0},
+		{"d}", "a\nb\n\nc", 0, "d}", "\n\nc", 0},
+		{"cw", "foo bar", 0, "cwxy<Esc>", "xy bar", 1},
+		{"cw on last char", "foo bar", 2, "cwx<Esc>", "fox bar", 2},
+		{"cc keeps indent", "  foo\nbar", 3, "ccx<Esc>", "  x\nbar", 2},
+		{"C", "foo bar", 4, "Cx<Esc>", "foo x", 4},
+		{"s", "foo", 0, "sx<Esc>", "xoo", 0},
+		{"S", "foo\nbar", 0, "Sx<Esc>", "x\nbar", 0},
+		{"r", "abc", 0, "rx", "xbc", 0},
+		{"count r", "abc", 0, "2rx", "xxc", 1},
+		{"r past the end", "abc", 1, "3rx", "abc", 1},
+		{"tilde", "ab", 0, "~~", "AB", 1},
+		{"J", "a\n  b", 0, "J", "a b", 1},
+		{"3J", "a\nb\nc", 0, "3J", "a b c", 3},
+		{"J before paren", "f(\n)", 0, "J", "f()", 2},
+		{"gUiw", "foo bar", 1, "gUiw", "FOO bar", 0},
+		{"guu", "FOO\nBAR", 0, "guu", "foo\nBAR", 0},
+		{"g~~", "Foo", 0, "g~~", "fOO", 0},
+		{"indent", "a\nb", 0, ">j", "\ta\n\tb", 1},
+		{"outdent", "\t\ta", 2, "<<", "\ta", 1},
+		{"outdent spaces", "    a", 4, "<<", "a", 0},
+		{"xp", "ab", 0, "xp", "ba", 1},
+		{"yyp", "a\nb", 0, "yyp", "a\na\nb", 2},
+		{"yyP", "a\nb", 2, "yyP", "a\nb\nb", 2},
+		{"yyp on last line", "a\nb", 2, "yyp", "a\nb\nb", 4},
+		{"3p", "ab", 0, "yl3p", "aaaab", 3},
+		{"ddp", "a\nb\nc", 0, "ddp", "b\na\nc", 2},
+		{"i", "ac", 1, "ib<Esc>", "abc", 1},
+		{"a", "ac", 0, "ab<Esc>", "abc", 1},
+		{"I", "  ab", 3, "I-<Esc>", "  -ab", 2},
+		{"A", "ab", 0, "Axy<Esc>", "abxy", 3},
+		{"o", "a\nb", 0, "ox<Esc>", "a\nx\nb", 2},
+		{"o keeps indent", "\ta", 1, "ox<Esc>", "\ta\n\tx", 4},
+		{"O", "a\nb", 2, "Ox<Esc>", "a\nx\nb", 2},
+		{"3i", "", 0, "3ix<Esc>", "xxx", 2},
+		{"3o", "a", 0, "3ox<Esc>", "a\nx\nx\nx", 6},
+		{"dot dw", "a b c d", 0, "dw.", "c d", 0},
+		{"dot with count", "a b c d e", 0, "dw3.", "e", 0},
+		{"dot cw", "foo bar", 0, "cwx<Esc>w.", "x x", 2},
+		{"dot x with count", "abcdef", 0, "x2.", "def", 0},
+		{"dot A", "a\nb", 0, "A;<Esc>j.", "a;\nb;", 4},
+		{"u", "abc", 0, "xu", "abc", 0},
+		{"redo", "abc", 0, "xu<C-r>", "bc", 0},
+		{"undo count", "abc", 0, "xx2u", "abc", 0},
+		{"diw", "foo bar", 5, "diw", "foo ", 3},
+		{"daw", "foo bar baz", 4, "daw", "foo baz", 4},
+		{"daw last word", "foo bar", 4, "daw", "foo", 2},
+		{"ci(", "f(a, b)", 3, "ci(x<Esc>", "f(x)", 2},
+		{"da(", "f(a, b) c", 3, "da(", "f c", 1},
+		{"di{ lines", "f {\n  a\n  b\n}", 6, "di{", "f {\n}", 4},
+		{"ci{ lines", "f {\n  a\n}", 6, "ci{x<Esc>", "f {\n  x\n}", 6},
+		{"2di(", "f(a(b)c)", 4, "2di(", "f()", 2},
+		{"ci\"", `s := "hi"`, 7, `ci"bye<Esc>`, `s := "bye"`, 8},
+		{"da\"", `a "b" c`, 3, `da"`, "a c", 2},
+		{"dap", "a\nb\n\nc", 0, "dap", "c", 0},
+		{"dip", "a\nb\n\nc", 0, "dip", "\nc", 0},
+	}
+	for _, tc := range cases {
+		v, h := newTestVim(tc.text, tc.cursor)
+		typeVim(v, h, tc.keys)
+		if h.text != tc.want || h.cursor != tc.cur {
+			t.Errorf("%s: %q on %q = %q, cursor %d; want %q, cursor %d", tc.name, tc.keys, tc.text, h.text, h.cursor, tc.want, tc.cur)
+		}
+		if v.Mode() != VimNormal {
+			t.Errorf("%s: mode = %v, want NORMAL", tc.name, v.Mode())
+		}
+	}
+}
+
+func TestVimMotions(t *testing.T) {
+	cases := []struct {
+		text   string
+		cursor int
+		keys   string
+		want   int
+	}{
+		{"foo.bar  baz", 0, "w", 3},
+		{"foo.bar  baz", 0, "W", 9},
+		{"foo.bar  baz", 0, "3w", 9},
+		{"foo\n\nbar", 0, "w", 4},
+		{"foo bar", 0, "e", 2},
+		{"foo bar", 2, "e", 6},
+		{"foo bar", 6, "b", 4},
+		{"foo bar", 6, "2b", 0},
+		{"  foo", 4, "^", 2},
+		{"  foo", 4, "0", 0},
+		{"foo\nbar", 0, "$", 2},
+		{"abc", 0, "l", 1},
+		{"abc", 2, "l", 2},
+		{"abc", 1, "h", 0},
+		{"abcd\nab\nabcd", 3, "j", 6},
+		{"abcd\nab\nabcd", 3, "jj", 11},
+		{"abcd\nab\nabcd", 3, "$jj", 11},
+		{"a\nb\nc", 0, "G", 4},
+		{"a\nb\nc", 4, "gg", 0},
+		{"a\nb\nc", 0, "2G", 2},
+		{"a\n  b", 0, "<CR>", 4},
+		{"a,b,c", 0, "f,;", 3},
+		{"a,b,c", 0, "2f,", 3},
+		{"a,b,c", 0, "t,", 0},
+		{"a,b,c", 0, "tc", 3},
+		{"a,b,c", 4, "F,", 3},
+		{"a,b,c", 0, "f,;,", 1},
+		{"(a [b] c)", 0, "%", 8},
+		{"x(a)", 0, "%", 3},
+		{"(a [b] c)", 8, "%", 0},
+		{"a\n\nb\nc\n\nd", 0, "}", 2},
+		{"a\n\nb\nc\n\nd", 0, "2}", 7},
+		{"a\n\nb\nc\n\nd", 8, "{", 7},
+		{"a\n\nb\nc\n\nd", 7, "{", 2},
+	}
+	for _, tc := range cases {
+		v, h := newTestVim(tc.text, tc.cursor)
+		typeVim(v, h, tc.keys)
+		if h.cursor != tc.want {
+			t.Errorf("%q on %q from %d: cursor = %d, want %d", tc.keys, tc.text, tc.cursor, h.cursor, tc.want)
+		}
+	}
+}
+
+func TestVimRegisters(t *testing.T) {
+	v, h := newTestVim("a\nb\nc", 0)
+	typeVim(v, h, `"ayyj"byyj"ap"bp`)
+	if want := "a\nb\nc\na\nb"; h.text != want {
+		t.Errorf("named registers: %q, want %q", h.text, want)
+	}
+
+	v, h = newTestVim("a\nb", 0)
+	typeVim(v, h, `"ayyj"Ayy"aP`)
+	if want := "a\na\nb\nb"; h.text != want {
+		t.Errorf("appending register: %q, want %q", h.text, want)
+	}
+
+	v, h = newTestVim("a\nb\nc", 0)
+	typeVim(v, h, `yydddd"2p`)
+	if want := "c\na"; h.text != want {
+		t.Errorf("numbered registers: %q, want %q", h.text, want)
+	}
+	typeVim(v, h, `"0p`)
+	if want := "c\na\na"; h.text != want {
+		t.Errorf("yank register: %q, want %q", h.text, want)
+	}
+
+	v, h = newTestVim("foo bar", 0)
+	typeVim(v, h, `yw"_dwP`)
+	if h.text != "foo bar" {
+		t.Errorf("black hole register: %q, want %q", h.text, "foo bar")
+	}
+
+	v, h = newTestVim("foo bar", 0)
+	typeVim(v, h, `xw"-P`)
+	if h.text != "oo fbar" {
+		t.Errorf("small delete register: %q, want %q", h.text, "oo fbar")
+	}
+}
+
+func TestVimVisualModes(t *testing.T) {
+	cases := []struct {
+		name   string
+		text   string
+		cursor int
+		keys   string
+		want   string
+		cur    int
+	}{
+		{"v d", "abcdef", 0, "vlld", "def", 0},
+		{"v backwards", "abcdef", 3, "vhhd", "aef", 1},
+		{"v o", "abcdef", 2, "vlohd", "aef", 1},
+		{"v across lines", "ab\ncd", 1, "vjd", "a", 0},
+		{"v across lines before end", "ab\ncd", 1, "vjhd", "ad", 1},
+		{"v iw", "foo bar", 1, "viwd", " bar", 0},
+		{"v y P", "ab", 0, "vly$p", "abab", 3},
+		{"v c", "abc", 0, "vlcx<Esc>", "xc", 0},
+		{"v U", "abc", 0, "vlU", "ABc", 0},
+		{"v r", "abc", 0, "vlrx", "xxc", 0},
+		{"v J", "a\nb\nc", 0, "vjJ", "a b\nc", 1},
+		{"v >", "a\nb", 0, "vj>", "\ta\n\tb", 1},
+		{"v p", "foo bar", 0, "yiwwviwp", "foo foo", 4},
+		{"V d", "a\nb\nc", 2, "Vd", "a\nc", 2},
+		{"V j d", "a\nb\nc", 0, "Vjd", "c", 0},
+		{"V y p", "a\nb", 0, "Vyp", "a\na\nb", 2},
+		{"V c", "  a\nb", 0, "Vcx<Esc>", "  x\nb", 2},
+		{"V dot", "a\nb\nc\nd", 0, "Vjd.", "", 0},
+		{"ctrl-v d", "abc\ndef\nghi", 0, "<C-v>jld", "c\nf\nghi", 0},
+		{"ctrl-v y p", "ab\ncd", 0, "<C-v>jy$p", "aba\ncdc", 2},
+		{"ctrl-v U", "ab\ncd", 0, "<C-v>jU", "Ab\nCd", 0},
+		{"ctrl-v r", "abc\ndef", 0, "<C-v>jlrx", "xxc\nxxf", 0},
+		{"v esc", "abc", 0, "vl<Esc>", "abc", 1},
+		{"v v", "abc", 0, "vlv", "abc", 1},
+	}
+	for _, tc := range cases {
+		v, h := newTestVim(tc.text, tc.cursor)
+		typeVim(v, h, tc.keys)
+		if h.text != tc.want || h.cursor != tc.cur {
+			t.Errorf("%s: %q on %q = %q, cursor %d; want %q, cursor %d", tc.name, tc.keys, tc.text, h.text, h.cursor, tc.want, tc.cur)
+		}
+		if v.Mode() != VimNormal {
+			t.Errorf("%s: mode = %v, want NORMAL", tc.name, v.Mode())
+		}
+	}
+}
+
+func TestVimVisualSelection(t *testing.T) {
+	v, h := newTestVim("abc\ndef", 1)
+	typeVim(v, h, "vl")
+	if v.Mode() != VimVisual || h.anchor != 1 || h.cursor != 3 {
+		t.Errorf("v l: mode %v, selection %d-%d", v.Mode(), h.anchor, h.cursor)
+	}
+	typeVim(v, h, "V")
+	if v.Mode() != VimVisualLine || h.anchor != 0 || h.cursor != 3 {
+		t.Errorf("V: mode %v, selection %d-%d", v.Mode(), h.anchor, h.cursor)
+	}
+	typeVim(v, h, "<C-v>j")
+	if v.Mode() != VimVisualBlock || h.block == nil || *h.block != (BlockSelection{StartLine: 0, EndLine: 1, StartCol: 1, EndCol: 3, Active: true, TabSize: 4}) {
+		t.Errorf("ctrl-v j: mode %v, block %+v", v.Mode(), h.block)
+	}
+	typeVim(v, h, "<Esc>")
+	if v.Mode() != VimNormal || h.block != nil {
+		t.Errorf("esc: mode %v, block %+v", v.Mode(), h.block)
+	}
+}
+
+func TestVimBlockInsert(t *testing.T) {
+	v, h := newTestVim("abc\nd\nefg", 1)
+	typeVim(v, h, "<C-v>jjI")
+	if v.Mode() != VimInsert || !slices.Equal(h.inserts, []int{1, 7}) {
+		t.Errorf("I: mode %v, cursors %v", v.Mode(), h.inserts)
+	}
+
+	v, h = newTestVim("abc\nd\nefg", 1)
+	typeVim(v, h, "<C-v>jjlA")
+	if h.text != "abc\nd  \nefg" || !slices.Equal(h.inserts, []int{3, 7, 11}) {
+		t.Errorf("A: %q, cursors %v", h.text, h.inserts)
+	}
+	typeVim(v, h, "<Esc>")
+	if v.Mode() != VimNormal || h.inserts != nil {
+		t.Errorf("esc: mode %v, cursors %v", v.Mode(), h.inserts)
+	}
+
+	v, h = newTestVim("abc\ndef", 0)
+	typeVim(v, h, "<C-v>jlc")
+	if h.text != "c\nf" || !slices.Equal(h.inserts, []int{0, 2}) {
+		t.Errorf("c: %q, cursors %v", h.text, h.inserts)
+	}
+}
+
+func TestVimKeysLeftToTheEditor(t *testing.T) {
+	v, _ := newTestVim("abc", 0)
+	if v.Key("<Esc>") {
+		t.Error("Esc in normal mode should be left to the editor")
+	}
+	if v.Key("<C-s>") {
+		t.Error("Ctrl+S should be left to the editor")
+	}
+	if !v.Key("q") {
+		t.Error("unknown keys should not be typed in normal mode")
+	}
+	v.Key("2")
+	v.Key("d")
+	if got := v.Status(); got != "NORMAL 2d" {
+		t.Errorf("Status = %q, want %q", got, "NORMAL 2d")
+	}
+	v.Key("<Esc>")
+	v.Key("i")
+	if v.Key("x") || v.Key("<C-s>") {
+		t.Error("insert mode should leave typing to the editor")
+	}
+	if got := v.Status(); got != "INSERT" {
+		t.Errorf("Status = %q, want INSERT", got)
+	}
+}
+
+func TestVimReset(t *testing.T) {
+	v, h := newTestVim("abc", 0)
+	typeVim(v, h, "i")
+	v.Reset()
+	if v.Mode() != VimNormal {
+		t.Errorf("mode after Reset = %v", v.Mode())
+	}
+	typeVim(v, h, "d")
+	v.Reset()
+	typeVim(v, h, "w")
+	if h.text != "abc" {
+		t.Errorf("Reset kept the pending operator: %q", h.text)
+	}
+}
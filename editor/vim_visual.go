@@ -0,0 +1,316 @@
+package editor
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// vimVisualModes maps the keys that start the visual modes to them.
+var vimVisualModes = map[string]VimMode{"v": VimVisual, "V": VimVisualLine, "<C-v>": VimVisualBlock}
+
+// visualRange returns the runes the visual selection covers, [start, end),
+// and its first and last lines. In visual line mode the range is the whole
+// lines without the last newline.
+func (v *Vim) visualRange(t *vimText) (start, end, first, last int) {
+	start, end = min(v.anchor, v.cur), max(v.anchor, v.cur)
+	first, last = t.line(start), t.line(end)
+	if v.mode == VimVisualLine {
+		return t.startOf(first), t.endOf(last), first, last
+	}
+	return start, min(end+1, len(t.r)), first, last
+}
+
+// visualBlock returns the block of visual block mode: the visual columns
+// from the anchor's to the cursor's, both characters included, on the lines
+// between them.
+func (v *Vim) visualBlock(t *vimText) BlockSelection {
+	tabSize := v.host.TabSize()
+	anchorStart, anchorEnd := t.visualCols(v.anchor, tabSize)
+	curStart, curEnd := t.visualCols(v.cur, tabSize)
+	bs := BlockSelection{TabSize: tabSize}
+	bs.Set(t.line(v.anchor), t.line(v.cur), min(anchorStart, curStart), max(anchorEnd, curEnd))
+	return bs
+}
+
+// visualCols returns the visual column of off in its line and the one after
+// the character there; past the end of the line, a character is one column.
+func (t *vimText) visualCols(off, tabSize int) (int, int) {
+	line := t.lineText(t.line(off))
+	start := VisualColumn(line, t.col(off), tabSize)
+	if off >= t.lineEnd(off) {
+		return start, start + 1
+	}
+	return start, start + max(runeCells(t.r[off], start, tabSize), 1)
+}
+
+// visualOffset returns the offset of the first rune of line at or after
+// visual column col, or the end of the line if it is shorter.
+func (t *vimText) visualOffset(line, col, tabSize int) int {
+	return t.startOf(line) + RuneColumn(t.lineText(line), col, tabSize)
+}
+
+// clampVisual keeps the ends of the selection in the text, which may have
+// changed under it.
+func (v *Vim) clampVisual(t *vimText) {
+	last := max(len(t.r)-1, 0)
+	v.anchor = max(0, min(v.anchor, last))
+	v.cur = max(0, min(v.cur, last))
+}
+
+// showVisual shows the visual selection on the host.
+func (v *Vim) showVisual(t *vimText) {
+	if v.mode == VimVisualBlock {
+		bs := v.visualBlock(t)
+		v.host.SelectBlock(&bs)
+		return
+	}
+	v.host.SelectBlock(nil)
+	start, end, _, _ := v.visualRange(t)
+	if v.cur < v.anchor {
+		v.host.Select(end, start)
+	} else {
+		v.host.Select(start, end)
+	}
+}
+
+// leaveVisual goes back to normal mode, remembering the lines of the
+// selection for '< and '>.
+func (v *Vim) leaveVisual(t *vimText) {
+	_, _, first, last := v.visualRange(t)
+	v.lastVisual = [2]int{first, last}
+	v.mode = VimNormal
+	v.host.SelectBlock(nil)
+}
+
+// exitVisual leaves visual mode with the cursor where the selection's was.
+func (v *Vim) exitVisual() {
+	t := newVimText(v.host.Text())
+	v.clampVisual(t)
+	v.leaveVisual(t)
+	v.setCursor(t.clampNormal(v.cur))
+}
+
+// runVisual runs a visual mode command: a motion or text object extends the
+// selection, an operator applies to it.
+func (v *Vim) runVisual(c vimCommand) {
+	t := newVimText(v.host.Text())
+	v.clampVisual(t)
+	switch mode, ok := vimVisualModes[c.name]; {
+	case isVimTextObject(c.name):
+		start, end, linewise, ok := t.textObject(c.name, v.cur, c.count)
+		if !ok {
+			return
+		}
+		v.anchor, v.cur = start, max(start, end-1)
+		if linewise && v.mode == VimVisual {
+			v.mode = VimVisualLine
+		}
+	case vimMotions[c.name]:
+		target, _, ok := v.motion(t, c.name, c.arg, c.count, v.cur, "")
+		if !ok {
+			return
+		}
+		if !vimVertical[c.name] && c.name != "$" && c.name != "<End>" {
+			v.wantCol = -1
+		}
+		v.cur = max(0, min(target, len(t.r)-1))
+	case c.name == "o":
+		v.anchor, v.cur = v.cur, v.anchor
+	case ok:
+		if mode == v.mode {
+			v.exitVisual()
+			return
+		}
+		v.mode = mode
+	case c.name == ":":
+		v.exitVisual()
+		v.mode = VimCommandLine
+		v.cmdline = []rune("'<,'>")
+		return
+	default:
+		v.visualOperate(t, c)
+		return
+	}
+	v.showVisual(t)
+}
+
+// vimVisualOperators maps the visual mode commands to the operators they
+// apply.
+var vimVisualOperators = map[string]string{
+	"x": "d", "<Del>": "d", "s": "c", "~": "g~", "u": "gu", "U": "gU",
+	// These work on whole lines in every visual mode.
+	"X": "d", "D": "d", "Y": "y", "C": "c", "S": "c", "R": "c",
+}
+
+// visualOperate applies a visual mode command to the selection and leaves
+// visual mode. Changes to whole lines or to part of one line can be
+// repeated with "."; they are repeated on as many lines or characters.
+func (v *Vim) visualOperate(t *vimText, c vimCommand) {
+	block, linewise := v.mode == VimVisualBlock, v.mode == VimVisualLine
+	start, end, first, last := v.visualRange(t)
+	bs := v.visualBlock(t)
+	name := c.name
+	if op, ok := vimVisualOperators[name]; ok {
+		if strings.ContainsAny(name, "XDYCSR") {
+			block, linewise = false, true
+		}
+		name = op
+	}
+	v.leaveVisual(t)
+	switch {
+	case name == "J":
+		out, off := joinLines(t, first, min(max(last, first+1), t.lines()-1))
+		v.edit("Join Lines", out, off)
+	case name == "p" || name == "P":
+		v.replaceVisual(t, c.reg, block, linewise, start, end, first, last, bs)
+	case name == "r":
+		if block {
+			v.edit("Replace", mapVimBlock(t, bs, func(s string) string {
+				return strings.Repeat(c.arg, utf8.RuneCountInString(s))
+			}), t.visualOffset(first, bs.StartCol, bs.tabSize()))
+			return
+		}
+		out := []rune(t.String())
+		for i := start; i < end; i++ {
+			if out[i] != '\n' {
+				out[i] = []rune(c.arg)[0]
+			}
+		}
+		v.edit("Replace", string(out), start)
+	case name == "I" || name == "A":
+		switch {
+		case block && name == "I":
+			v.blockInsert(t.String(), first, last, bs.StartCol, bs.StartCol+1, false)
+		case block:
+			v.blockInsert(t.String(), first, last, bs.EndCol, bs.EndCol, true)
+		default:
+			off := start
+			if name == "A" {
+				off = end
+			}
+			v.setCursor(off)
+			v.startInsert(vimCommand{}, 1, nil)
+			v.change = nil
+		}
+	case name == ">" || name == "<":
+		out := newVimText(shiftLines(t.String(), first, last, v.host.IndentUnit(), c.n(), name == ">"))
+		v.edit("Indent", out.String(), out.clampNormal(out.firstNonBlank(out.startOf(first))))
+		v.last, v.lastInsert = &vimCommand{op: name, name: name, count: last - first + 1}, nil
+	case block:
+		v.blockOperate(t, bs, c.reg, name)
+	case linewise:
+		v.applyLines(t, t.startOf(first), vimCommand{reg: c.reg, op: name, name: name, count: last - first + 1}, first, last)
+	default:
+		prev, prevInsert := v.last, v.lastInsert
+		v.apply(t, start, vimCommand{reg: c.reg, op: name, name: "l", count: end - start}, start, end)
+		if first != last {
+			// Not repeatable: l stops at the end of a line.
+			v.last, v.lastInsert, v.change = prev, prevInsert, nil
+		}
+	}
+}
+
+// blockOperate applies an operator to the block bs.
+func (v *Vim) blockOperate(t *vimText, bs BlockSelection, reg rune, op string) {
+	text := t.String()
+	at := t.visualOffset(bs.StartLine, bs.StartCol, bs.tabSize())
+	switch op {
+	case "y":
+		v.store(reg, vimRegister{text: strings.Join(bs.ExtractBlock(text), "\n"), block: true}, true)
+		v.setCursor(t.clampNormal(at))
+	case "d", "c":
+		v.store(reg, vimRegister{text: strings.Join(bs.ExtractBlock(text), "\n"), block: true}, false)
+		out := bs.DeleteBlock(text)
+		if op == "c" {
+			v.edit("Change", out, at)
+			v.blockInsert(out, bs.StartLine, bs.EndLine, bs.StartCol, bs.StartCol, false)
+			return
+		}
+		v.edit("Delete", out, newVimText(out).clampNormal(at))
+	default:
+		v.edit("Change Case", mapVimBlock(t, bs, func(s string) string { return vimCase(op, s) }), t.clampNormal(at))
+	}
+}
+
+// mapVimBlock returns the text with the part of each line in the block bs
+// replaced by f of it.
+func mapVimBlock(t *vimText, bs BlockSelection, f func(string) string) string {
+	var sb strings.Builder
+	prev := 0
+	for l := bs.StartLine; l <= bs.EndLine && l < t.lines(); l++ {
+		start, end := bs.RuneRange(t.lineText(l))
+		start, end = start+t.startOf(l), end+t.startOf(l)
+		sb.WriteString(string(t.r[prev:start]))
+		sb.WriteString(f(string(t.r[start:end])))
+		prev = end
+	}
+	sb.WriteString(string(t.r[prev:]))
+	return sb.String()
+}
+
+// blockInsert enters insert mode with a cursor at visual column col of each
+// of the lines first to last of text, for I, A and c in visual block mode.
+// Lines narrower than minLen columns are skipped; with pad set, lines
+// narrower than col are padded with spaces first.
+func (v *Vim) blockInsert(text string, first, last, col, minLen int, pad bool) {
+	tabSize := v.host.TabSize()
+	if pad {
+		bs := BlockSelection{TabSize: tabSize}
+		bs.Set(first, last, col, col)
+		if padded := bs.InsertAtBlock(text, ""); padded != text {
+			text = padded
+			v.edit("Block Insert", text, newVimText(text).visualOffset(first, col, tabSize))
+		}
+	}
+	t := newVimText(text)
+	var offsets []int
+	for l := first; l <= last && l < t.lines(); l++ {
+		if VisualWidth(t.lineText(l), tabSize) >= minLen {
+			offsets = append(offsets, t.visualOffset(l, col, tabSize))
+		}
+	}
+	if len(offsets) == 0 {
+		return
+	}
+	v.host.InsertAt(offsets)
+	v.lastCursor = offsets[0]
+	v.startInsert(vimCommand{}, 1, nil)
+	v.change = nil // not repeatable
+}
+
+// replaceVisual replaces the selection with the text of register reg for p
+// and P. The selection goes to the unnamed register.
+func (v *Vim) replaceVisual(t *vimText, reg rune, block, linewise bool, start, end, first, last int, bs BlockSelection) {
+	r := v.register(reg)
+	text := t.String()
+	var out string
+	var at int
+	switch {
+	case block:
+		v.store(0, vimRegister{text: strings.Join(bs.ExtractBlock(text), "\n"), block: true}, false)
+		out, at = bs.DeleteBlock(text), t.visualOffset(first, bs.StartCol, bs.tabSize())
+	case linewise:
+		v.store(0, vimRegister{text: string(t.r[start:end]) + "\n", linewise: true}, false)
+		out, at = string(t.r[:start])+string(t.r[end:]), start
+	default:
+		v.store(0, vimRegister{text: string(t.r[start:end])}, false)
+		out, at = string(t.r[:start])+string(t.r[end:]), start
+	}
+	if r.block {
+		ot := newVimText(out)
+		col := VisualColumn(ot.lineText(first), ot.col(at), v.host.TabSize())
+		out = pasteBlock(out, first, col, v.host.TabSize(), strings.Split(r.text, "\n"), 1)
+		v.edit("Paste", out, at)
+		return
+	}
+	ins := r.text
+	switch {
+	case linewise && r.linewise:
+		ins = strings.TrimSuffix(ins, "\n")
+	case !linewise && r.linewise:
+		ins = "\n" + ins
+	}
+	o := []rune(out)
+	out = string(o[:at]) + ins + string(o[at:])
+	v.edit("Paste", out, newVimText(out).clampNormal(at))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/odvcencio/fluffyui/backend"
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/editor"
+)
+
+// longLineStyle marks the part of a line past the .editorconfig
+// max_line_length.
+var longLineStyle = backend.DefaultStyle().Background(backend.ColorRGB(0x5A, 0x1D, 0x1D))
+
+// applyIndentation sets up the Tab key for buf from its .editorconfig: a
+// literal tab, or indent_size spaces. Without one, the insertSpaces and
+// tabSize settings for its language apply.
+func (a *maneApp) applyIndentation(buf *editor.Buffer) {
+	var cfg editor.EditorConfig
+	if buf != nil {
+		cfg = buf.EditorConfig()
+	}
+	settings := a.settingsFor(buf)
+	switch cfg.IndentStyle {
+	case "tab", "space":
+		a.textArea.SetTabMode(cfg.IndentStyle == "tab")
+	default:
+		a.textArea.SetTabMode(!settings.InsertSpaces)
+	}
+	size := cfg.IndentSize
+	if size <= 0 {
+		size = settings.TabSize
+	}
+	a.textArea.SetTabSize(size)
+}
+
+// tabSize returns the columns a tab stands for in buf: its .editorconfig
+// tab_width, or the tabSize setting for its language.
+func (a *maneApp) tabSize(buf *editor.Buffer) int {
+	if buf != nil && buf.EditorConfig().TabWidth > 0 {
+		return buf.EditorConfig().TabWidth
+	}
+	return a.settingsFor(buf).TabSize
+}
+
+// indentUnit returns the text auto-indent adds after a line opening a block
+// in buf, or "" to guess it from the line.
+func (a *maneApp) indentUnit(buf *editor.Buffer) string {
+	if unit := buf.EditorConfig().IndentUnit(); unit != "" {
+		return unit
+	}
+	if settings := a.settingsFor(buf); settings.InsertSpaces {
+		return strings.Repeat(" ", settings.TabSize)
+	}
+	return ""
+}
+
+// indentStatus describes the indentation and save rules in effect for buf
+// for the status bar. Without an .editorconfig indent style, the style is
+// detected from the text.
+func indentStatus(buf *editor.Buffer) string {
+	cfg := buf.EditorConfig()
+	var status string
+	switch cfg.IndentStyle {
+	case "tab":
+		status = "tabs"
+		if cfg.TabWidth > 0 {
+			status += fmt.Sprintf("(%d)", cfg.TabWidth)
+		}
+	case "space":
+		status = fmt.Sprintf("spaces(%d)", len(cfg.IndentUnit()))
+	default:
+		status = detectIndentMode(buf.Text())
+	}
+	if cfg.TrimTrailingWhitespace {
+		status += " trim-ws"
+	}
+	if cfg.InsertFinalNewline {
+		status += " final-nl"
+	}
+	if cfg.MaxLineLength > 0 {
+		status += fmt.Sprintf(" max:%d", cfg.MaxLineLength)
+	}
+	return status
+}
+
+// longLineHighlights marks the characters past limit on each line of text.
+// A limit of 0 marks nothing.
+func longLineHighlights(text string, limit int) []widgets.TextAreaHighlight {
+	if limit <= 0 {
+		return nil
+	}
+	var highlights []widgets.TextAreaHighlight
+	offset := 0 // rune offset of the line start
+	for line := range strings.SplitSeq(text, "\n") {
+		n := utf8.RuneCountInString(line)
+		if n > limit {
+			highlights = append(highlights, widgets.TextAreaHighlight{
+				Start: offset + limit,
+				End:   offset + n,
+				Style: longLineStyle,
+			})
+		}
+		offset += n + 1
+	}
+	return highlights
+}
+
+// syncSaveFormatting shows the edits saving made to buf for its
+// .editorconfig, such as trimmed trailing whitespace, if its version moved
+// on from version. The cursor stays where it was.
+func (a *maneApp) syncSaveFormatting(buf *editor.Buffer, version uint64) {
+	if buf.Version() == version {
+		return
+	}
+	text := buf.Text()
+	if buf == a.tabs.ActiveBuffer() {
+		cursor := a.textArea.CursorOffset()
+		a.suppressChange = true
+		a.textArea.SetText(text)
+		a.textArea.ClearHistory()
+		a.textArea.SetCursorOffset(min(cursor, utf8.RuneCountInString(text)))
+		a.suppressChange = false
+		a.syncMultiCursorFromTextArea()
+		a.rehighlight(text)
+	}
+	a.scheduleLspDidChange(buf, text)
+	a.notifyFileResource(buf.Path())
+}
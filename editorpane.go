@@ -0,0 +1,128 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/widgets"
+)
+
+// editorPane shows the TextArea, or the large-file view when the active
+// buffer is in large-file mode. Both stay in the widget tree so that either
+// can take focus.
+//
+// The pane also keeps track of how far the TextArea is scrolled, which the
+// TextArea does not expose, so the position can be saved and restored.
+type editorPane struct {
+	widgets.Base
+	text  *widgets.TextArea
+	large *largeFileView
+
+	top      int // TextArea row shown at the top, mirroring its scroll offset
+	scrollTo int // line to scroll to on the next render, or -1
+}
+
+func newEditorPane(text *widgets.TextArea, large *largeFileView) *editorPane {
+	return &editorPane{text: text, large: large, scrollTo: -1}
+}
+
+func (p *editorPane) active() runtime.Widget {
+	if p.large.shown {
+		return p.large
+	}
+	return p.text
+}
+
+func (p *editorPane) Measure(constraints runtime.Constraints) runtime.Size {
+	return p.active().Measure(constraints)
+}
+
+// Layout gives the visible editor the whole pane and the hidden one no
+// space, so it cannot be hit by the mouse.
+func (p *editorPane) Layout(bounds runtime.Rect) {
+	p.Base.Layout(bounds)
+	if p.large.shown {
+		p.text.Layout(runtime.Rect{})
+		p.large.Layout(bounds)
+	} else {
+		p.large.Layout(runtime.Rect{})
+		p.text.Layout(bounds)
+	}
+}
+
+// Render draws the active editor. A pending scroll position is applied
+// first: the TextArea only scrolls to keep the cursor in view, so the cursor
+// is moved to the first and then the last row of the wanted view, rendering
+// each time, before it is put back.
+func (p *editorPane) Render(ctx runtime.RenderContext) {
+	if p.large.shown {
+		p.large.Render(ctx)
+		return
+	}
+	height := p.text.ContentBounds().Height
+	if p.scrollTo >= 0 && height > 0 {
+		top := p.rowOfLine(p.scrollTo)
+		cursor := p.text.CursorOffset()
+		for _, row := range []int{top, top + height - 1} {
+			p.text.SetCursorPosition(0, p.lineAtRow(row))
+			p.text.Render(ctx)
+		}
+		p.text.SetCursorOffset(cursor)
+		p.top = top
+	}
+	p.scrollTo = -1
+	p.text.Render(ctx)
+
+	_, line := p.text.CursorPosition()
+	row := p.rowOfLine(line)
+	p.top = max(p.top, 0)
+	if row < p.top {
+		p.top = row
+	} else if height > 0 && row >= p.top+height {
+		p.top = row - height + 1
+	}
+}
+
+func (p *editorPane) HandleMessage(msg runtime.Message) runtime.HandleResult {
+	return p.active().HandleMessage(msg)
+}
+
+func (p *editorPane) ChildWidgets() []runtime.Widget {
+	return []runtime.Widget{p.text, p.large}
+}
+
+// scrollLine returns the line shown at the top of the TextArea, or the line
+// it is about to scroll to.
+func (p *editorPane) scrollLine() int {
+	if p.scrollTo >= 0 {
+		return p.scrollTo
+	}
+	return p.lineAtRow(p.top)
+}
+
+// setScrollLine scrolls the TextArea on the next render so that line is at
+// the top, as far as the cursor stays in view. A negative line cancels a
+// pending scroll.
+func (p *editorPane) setScrollLine(line int) {
+	p.scrollTo = line
+}
+
+// rowOfLine returns the TextArea row of a line, skipping lines hidden by
+// folds. Wrapped lines count as one row, so with word wrap on it is only an
+// approximation.
+func (p *editorPane) rowOfLine(line int) int {
+	visible := p.text.VisibleLines()
+	if visible == nil {
+		return line
+	}
+	return sort.SearchInts(visible, line)
+}
+
+// lineAtRow is the inverse of rowOfLine.
+func (p *editorPane) lineAtRow(row int) int {
+	visible := p.text.VisibleLines()
+	if len(visible) == 0 {
+		return row
+	}
+	return visible[min(max(row, 0), len(visible)-1)]
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"unicode/utf8"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/terminal"
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/editor"
+	"github.com/odvcencio/mane/keymap"
+)
+
+// killRingSize is the number of kills the Emacs kill ring keeps.
+const killRingSize = 60
+
+// emacsState is the state of the commands of the Emacs keymap. Offsets are
+// in runes.
+type emacsState struct {
+	mark        int
+	markSet     bool // the mark was set in the shown buffer
+	markActive  bool // the region between the mark and the cursor is selected
+	kills       *editor.KillRing
+	lastCommand string       // command run by the key before, or ""
+	yank        editor.Range // text inserted by the last yank
+	isearch     *editor.ISearch
+	lastSearch  string
+}
+
+// emacsKillCommands are the commands whose kills a following kill adds to.
+var emacsKillCommands = map[string]bool{
+	"emacs.killLine":         true,
+	"emacs.killRegion":       true,
+	"emacs.killWord":         true,
+	"emacs.backwardKillWord": true,
+}
+
+// emacsCommands returns the commands of the Emacs keymap by ID. They can be
+// bound in any keymap.
+func (a *maneApp) emacsCommands() map[string]func() {
+	key := func(k terminal.Key, ctrl bool) func() {
+		return func() { a.emacsMoveKey(runtime.KeyMsg{Key: k, Ctrl: ctrl}) }
+	}
+	return map[string]func(){
+		"emacs.forwardChar":  key(terminal.KeyRight, false),
+		"emacs.backwardChar": key(terminal.KeyLeft, false),
+		"emacs.nextLine":     key(terminal.KeyDown, false),
+		"emacs.previousLine": key(terminal.KeyUp, false),
+		"emacs.lineStart":    key(terminal.KeyHome, false),
+		"emacs.lineEnd":      key(terminal.KeyEnd, false),
+		"emacs.pageDown":     key(terminal.KeyPageDown, false),
+		"emacs.pageUp":       key(terminal.KeyPageUp, false),
+		"emacs.bufferStart":  key(terminal.KeyHome, true),
+		"emacs.bufferEnd":    key(terminal.KeyEnd, true),
+		"emacs.forwardWord": func() {
+			a.emacsMoveTo(editor.ForwardWord([]rune(a.textArea.Text()), a.textArea.CursorOffset()))
+		},
+		"emacs.backwardWord": func() {
+			a.emacsMoveTo(editor.BackwardWord([]rune(a.textArea.Text()), a.textArea.CursorOffset()))
+		},
+		"emacs.setMark":              a.emacsSetMark,
+		"emacs.exchangePointAndMark": a.emacsExchangePointAndMark,
+		"emacs.markWholeBuffer":      a.emacsMarkWholeBuffer,
+		"emacs.keyboardQuit":         a.emacsKeyboardQuit,
+		"emacs.killLine":             a.emacsKillLine,
+		"emacs.killRegion":           func() { a.emacsKillRegion(true) },
+		"emacs.copyRegion":           func() { a.emacsKillRegion(false) },
+		"emacs.killWord": func() {
+			cur := a.textArea.CursorOffset()
+			a.emacsKill("Kill Word", cur, editor.ForwardWord([]rune(a.textArea.Text()), cur), false)
+		},
+		"emacs.backwardKillWord": func() {
+			cur := a.textArea.CursorOffset()
+			a.emacsKill("Kill Word", editor.BackwardWord([]rune(a.textArea.Text()), cur), cur, true)
+		},
+		"emacs.deleteChar":      a.emacsDeleteChar,
+		"emacs.yank":            a.emacsYank,
+		"emacs.yankPop":         a.emacsYankPop,
+		"emacs.isearchForward":  func() { a.emacsStartSearch(true) },
+		"emacs.isearchBackward": func() { a.emacsStartSearch(false) },
+	}
+}
+
+// resetEmacs forgets the mark and ends a search, as when another buffer is
+// shown. The kill ring is kept.
+func (a *maneApp) resetEmacs() {
+	a.emacs.markSet, a.emacs.markActive = false, false
+	a.emacs.isearch = nil
+	a.emacs.lastCommand = ""
+}
+
+// emacsMarkActive reports whether the mark is active, deactivating it if
+// the selection no longer runs from the mark to the cursor, as after a
+// mouse click.
+func (a *maneApp) emacsMarkActive() bool {
+	if !a.emacs.markActive {
+		return false
+	}
+	sel := a.textArea.GetSelection()
+	cur := a.textArea.CursorOffset()
+	if cur != a.emacs.mark && (sel.Start != a.emacs.mark || sel.End != cur) {
+		a.emacs.markActive = false
+	}
+	return a.emacs.markActive
+}
+
+// emacsMoveKey moves the cursor the way key moves it in the editor,
+// extending the region if the mark is active.
+func (a *maneApp) emacsMoveKey(key runtime.KeyMsg) {
+	active := a.emacsMarkActive()
+	a.textArea.SelectNone()
+	a.textArea.HandleMessage(key)
+	a.emacsShowRegion(active)
+}
+
+// emacsMoveTo moves the cursor to off, extending the region if the mark is
+// active.
+func (a *maneApp) emacsMoveTo(off int) {
+	active := a.emacsMarkActive()
+	a.textArea.SelectNone()
+	a.textArea.SetCursorOffset(off)
+	a.emacsShowRegion(active)
+}
+
+// emacsShowRegion selects the region if active is set, and brings the rest
+// of the editor up to date with the cursor.
+func (a *maneApp) emacsShowRegion(active bool) {
+	if active {
+		a.textArea.SetSelection(widgets.Selection{Start: a.emacs.mark, End: a.textArea.CursorOffset()})
+	}
+	a.syncMultiCursorFromTextArea()
+	a.updateBracketMatch()
+	a.mergeAllHighlights()
+	a.updateStatus()
+}
+
+// emacsSetMark sets the mark at the cursor and activates it. Setting it
+// again right away deactivates it.
+func (a *maneApp) emacsSetMark() {
+	if a.emacs.lastCommand == "emacs.setMark" && a.emacsMarkActive() {
+		a.emacs.markActive = false
+		a.textArea.SelectNone()
+		a.emacsShowRegion(false)
+		a.status.Set(" Mark deactivated")
+		return
+	}
+	a.emacs.mark, a.emacs.markSet, a.emacs.markActive = a.textArea.CursorOffset(), true, true
+	a.emacsShowRegion(true)
+	a.status.Set(" Mark set")
+}
+
+// emacsRegion returns the region between the mark and the cursor, reporting
+// false if the mark was not set.
+func (a *maneApp) emacsRegion() (start, end int, ok bool) {
+	if !a.emacs.markSet {
+		a.status.Set(" The mark is not set now, so there is no region")
+		return 0, 0, false
+	}
+	size := utf8.RuneCountInString(a.textArea.Text())
+	mark, cur := clampRuneOffset(a.emacs.mark, size), a.textArea.CursorOffset()
+	return min(mark, cur), max(mark, cur), true
+}
+
+// emacsExchangePointAndMark swaps the cursor and the mark and activates the
+// mark.
+func (a *maneApp) emacsExchangePointAndMark() {
+	if _, _, ok := a.emacsRegion(); !ok {
+		return
+	}
+	cur := a.textArea.CursorOffset()
+	a.textArea.SelectNone()
+	a.textArea.SetCursorOffset(a.emacs.mark)
+	a.emacs.mark, a.emacs.markActive = cur, true
+	a.emacsShowRegion(true)
+}
+
+// emacsMarkWholeBuffer puts the cursor at the start and the mark at the
+// end.
+func (a *maneApp) emacsMarkWholeBuffer() {
+	a.emacs.mark, a.emacs.markSet, a.emacs.markActive = utf8.RuneCountInString(a.textArea.Text()), true, true
+	a.textArea.SetCursorOffset(0)
+	a.emacsShowRegion(true)
+}
+
+// emacsKeyboardQuit deactivates the mark.
+func (a *maneApp) emacsKeyboardQuit() {
+	a.emacs.markActive = false
+	a.textArea.SelectNone()
+	a.emacsShowRegion(false)
+	a.status.Set(" Quit")
+}
+
+// emacsKill removes the runes from start to end and puts them in the kill
+// ring, adding to the last kill if the command before was a kill too.
+// before is set for kills that go backwards from the cursor.
+func (a *maneApp) emacsKill(label string, start, end int, before bool) {
+	if a.emacs.kills == nil {
+		a.emacs.kills = editor.NewKillRing(killRingSize)
+	}
+	r := []rune(a.textArea.Text())
+	if start == end {
+		if end == len(r) {
+			a.status.Set(" End of buffer")
+		}
+		return
+	}
+	a.emacs.kills.Kill(string(r[start:end]), emacsKillCommands[a.emacs.lastCommand], before)
+	a.setClipboardText(a.emacs.kills.Newest())
+	a.emacs.markActive = false
+	a.textArea.SelectNone()
+	a.replaceText(label, string(r[:start])+string(r[end:]), start)
+}
+
+// emacsKillLine kills the rest of the line, or the line break at its end.
+func (a *maneApp) emacsKillLine() {
+	cur := a.textArea.CursorOffset()
+	a.emacsKill("Kill Line", cur, editor.KillLineEnd([]rune(a.textArea.Text()), cur), false)
+}
+
+// emacsKillRegion kills the region, or with kill unset copies it to the
+// kill ring, leaving the text as it is.
+func (a *maneApp) emacsKillRegion(kill bool) {
+	start, end, ok := a.emacsRegion()
+	if !ok {
+		return
+	}
+	if kill {
+		a.emacsKill("Kill Region", start, end, a.textArea.CursorOffset() == start)
+		return
+	}
+	if a.emacs.kills == nil {
+		a.emacs.kills = editor.NewKillRing(killRingSize)
+	}
+	a.emacs.kills.Kill(string([]rune(a.textArea.Text())[start:end]), false, false)
+	a.setClipboardText(a.emacs.kills.Newest())
+	a.emacs.markActive = false
+	a.textArea.SelectNone()
+	a.emacsShowRegion(false)
+}
+
+// emacsDeleteChar deletes the character after the cursor, or the region if
+// the mark is active, without putting it in the kill ring.
+func (a *maneApp) emacsDeleteChar() {
+	r := []rune(a.textArea.Text())
+	start, end := a.textArea.CursorOffset(), a.textArea.CursorOffset()+1
+	if a.emacsMarkActive() {
+		start, end, _ = a.emacsRegion()
+		a.emacs.markActive = false
+	}
+	if end > len(r) || start == end {
+		return
+	}
+	a.textArea.SelectNone()
+	a.replaceText("Delete", string(r[:start])+string(r[end:]), start)
+}
+
+// emacsYank inserts the newest kill at the cursor and sets the mark before
+// it.
+func (a *maneApp) emacsYank() {
+	if a.emacs.kills == nil || a.emacs.kills.Len() == 0 {
+		a.status.Set(" Kill ring is empty")
+		return
+	}
+	text, _ := a.emacs.kills.Yank()
+	a.emacsInsertYank(a.textArea.CursorOffset(), a.textArea.CursorOffset(), text)
+}
+
+// emacsYankPop replaces the text just yanked with the kill before it in the
+// kill ring.
+func (a *maneApp) emacsYankPop() {
+	y := a.emacs.yank
+	r := []rune(a.textArea.Text())
+	if (a.emacs.lastCommand != "emacs.yank" && a.emacs.lastCommand != "emacs.yankPop") || a.emacs.kills == nil ||
+		y.End > len(r) || a.textArea.CursorOffset() != y.End {
+		a.status.Set(" Previous command was not a yank")
+		return
+	}
+	text, _ := a.emacs.kills.YankPop()
+	a.emacsInsertYank(y.Start, y.End, text)
+}
+
+// emacsInsertYank replaces the runes from start to end with text, leaving
+// the mark before it and the cursor after it.
+func (a *maneApp) emacsInsertYank(start, end int, text string) {
+	r := []rune(a.textArea.Text())
+	a.emacs.markActive = false
+	a.textArea.SelectNone()
+	if !a.replaceText("Yank", string(r[:start])+text+string(r[end:]), start+utf8.RuneCountInString(text)) {
+		return
+	}
+	a.emacs.mark, a.emacs.markSet = start, true
+	a.emacs.yank = editor.Range{Start: start, End: start + utf8.RuneCountInString(text)}
+}
+
+// emacsStartSearch starts an incremental search from the cursor.
+func (a *maneApp) emacsStartSearch(forward bool) {
+	a.emacs.markActive = false
+	a.textArea.SelectNone()
+	a.emacs.isearch = editor.NewISearch(a.textArea.Text(), a.textArea.CursorOffset(), forward)
+	a.emacsShowSearch()
+}
+
+// emacsShowSearch selects the match of the search and shows its prompt.
+func (a *maneApp) emacsShowSearch() {
+	s := a.emacs.isearch
+	a.textArea.SelectNone()
+	a.textArea.SetCursorOffset(s.Point())
+	if m, ok := s.Match(); ok {
+		a.textArea.SetSelection(widgets.Selection{Start: m.Start, End: m.End})
+	}
+	a.emacsShowRegion(false)
+	a.status.Set(" " + s.Prompt())
+}
+
+// handleSearchKey handles key during an incremental search, reporting
+// whether it was used. Typing extends the query; the search keys move to
+// the next match and Backspace undoes the last step. The quit key goes
+// back to where the search started. Enter, Escape and any other key end
+// the search at the match, the others then doing what they usually do.
+func (a *maneApp) handleSearchKey(key runtime.KeyMsg) bool {
+	s := a.emacs.isearch
+	if s == nil {
+		return false
+	}
+	chord := keymap.FromKey(key)
+	switch {
+	case a.boundTo(chord, "emacs.isearchForward"):
+		s.Repeat(true, a.emacs.lastSearch)
+	case a.boundTo(chord, "emacs.isearchBackward"):
+		s.Repeat(false, a.emacs.lastSearch)
+	case a.boundTo(chord, "emacs.keyboardQuit"):
+		a.emacs.isearch = nil
+		a.textArea.SelectNone()
+		a.textArea.SetCursorOffset(s.Origin())
+		a.emacsShowRegion(false)
+		a.status.Set(" Quit")
+		return true
+	case key.Key == terminal.KeyBackspace:
+		s.Delete()
+	case key.Key == terminal.KeyRune && !chord.Ctrl && !chord.Alt && key.Rune != 0:
+		s.Type(string(key.Rune))
+	default:
+		a.emacs.isearch = nil
+		if s.Query() != "" {
+			a.emacs.lastSearch = s.Query()
+		}
+		a.emacs.mark, a.emacs.markSet = s.Origin(), true
+		a.textArea.SelectNone()
+		a.emacsShowRegion(false)
+		return key.Key == terminal.KeyEnter || key.Key == terminal.KeyEscape
+	}
+	a.emacsShowSearch()
+	return true
+}
+
+// boundTo reports whether chord alone runs command.
+func (a *maneApp) boundTo(chord keymap.Chord, command string) bool {
+	for _, seq := range a.keymap.Keys(command) {
+		if len(seq) == 1 && seq[0] == chord {
+			return true
+		}
+	}
+	return false
+}
+
+// emacsUnboundKey is called for keys that run no command and go to the
+// editor. Typing with the mark active inserts at the cursor rather than
+// replacing the region, while Backspace and Delete still delete it; either
+// way the mark is deactivated.
+func (a *maneApp) emacsUnboundKey(key runtime.KeyMsg) {
+	a.emacs.lastCommand = ""
+	if !a.textArea.IsFocused() || !a.emacsMarkActive() {
+		return
+	}
+	a.emacs.markActive = false
+	if key.Key != terminal.KeyBackspace && key.Key != terminal.KeyDelete {
+		a.textArea.SelectNone()
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/editor"
+	"github.com/odvcencio/mane/filewatch"
+)
+
+// startFileWatcher watches open files for changes made by other programs and
+// handles them on the UI loop of rt.
+func (a *maneApp) startFileWatcher(ctx context.Context, rt *runtime.App) {
+	a.watcher = filewatch.New(0)
+	a.syncFileWatches()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				_ = a.watcher.Close()
+				return
+			case path := <-a.watcher.Events():
+				_ = rt.Call(ctx, func(*runtime.App) error {
+					a.onExternalChange(path)
+					return nil
+				})
+			}
+		}
+	}()
+}
+
+// syncFileWatches watches exactly the files that are open in a tab.
+func (a *maneApp) syncFileWatches() {
+	if a.watcher == nil {
+		return
+	}
+	open := make(map[string]bool)
+	for _, buf := range a.tabs.Buffers() {
+		if buf != nil && !buf.Untitled() {
+			open[buf.Path()] = true
+		}
+	}
+	for path := range a.watched {
+		if !open[path] {
+			a.watcher.Remove(path)
+			delete(a.watched, path)
+		}
+	}
+	for path := range open {
+		if !a.watched[path] && a.watcher.Add(path) == nil {
+			a.watched[path] = true
+		}
+	}
+}
+
+// onExternalChange reacts to a file being changed on disk. Buffers without
+// unsaved changes are reloaded; otherwise the user decides what to keep.
+func (a *maneApp) onExternalChange(path string) {
+	buf := a.findBufferByPath(path)
+	if buf == nil {
+		return
+	}
+	changed, err := buf.DiskChanged()
+	if err != nil || !changed {
+		return
+	}
+	if _, err := os.Stat(buf.Path()); errors.Is(err, os.ErrNotExist) {
+		a.status.Set(fmt.Sprintf(" %s was deleted on disk", buf.Title()))
+		return
+	}
+	if !buf.Dirty() {
+		a.reloadBuffer(buf)
+		return
+	}
+	a.showExternalChangePrompt(buf, false)
+}
+
+// showExternalChangePrompt asks how to reconcile buf with a newer version of
+// its file on disk. saving is set when the prompt interrupts a save, which
+// adds the option to overwrite the file.
+func (a *maneApp) showExternalChangePrompt(buf *editor.Buffer, saving bool) {
+	run := func(fn func()) func() {
+		return func() {
+			a.lspPalette.Hide()
+			fn()
+		}
+	}
+	var cmds []widgets.PaletteCommand
+	if _, err := os.Stat(buf.Path()); errors.Is(err, os.ErrNotExist) {
+		if !saving {
+			return
+		}
+		cmds = append(cmds, widgets.PaletteCommand{
+			ID:          "external.recreate",
+			Label:       "Save anyway",
+			Description: "Recreate the deleted file",
+			OnExecute:   run(func() { a.forceSave(buf) }),
+		})
+		a.showLSPPalette(cmds, buf.Title()+" was deleted on disk")
+		return
+	}
+	if saving {
+		cmds = append(cmds, widgets.PaletteCommand{
+			ID:          "external.overwrite",
+			Label:       "Overwrite file on disk",
+			Description: "Save, discarding the changes made on disk",
+			OnExecute:   run(func() { a.forceSave(buf) }),
+		})
+	}
+	cmds = append(cmds,
+		widgets.PaletteCommand{
+			ID:          "external.keepMine",
+			Label:       "Keep mine",
+			Description: "Keep the buffer; the next save overwrites the file",
+			OnExecute:   run(func() { a.keepBufferVersion(buf) }),
+		},
+		widgets.PaletteCommand{
+			ID:          "external.takeTheirs",
+			Label:       "Take theirs",
+			Description: "Reload the file, discarding unsaved changes (undoable)",
+			OnExecute:   run(func() { a.reloadBuffer(buf) }),
+		},
+	)
+	if buf.Large() {
+		// Reloading a large file starts a new history and there is no
+		// merge, which would need both versions in memory.
+		cmds[len(cmds)-1].Description = "Reload the file, discarding unsaved changes"
+	} else {
+		cmds = append(cmds, widgets.PaletteCommand{
+			ID:          "external.merge",
+			Label:       "Merge (three-way)",
+			Description: "Combine both sides; conflicts are marked in the text",
+			OnExecute:   run(func() { a.mergeExternalChange(buf) }),
+		})
+	}
+	a.showLSPPalette(cmds, buf.Title()+" changed on disk")
+}
+
+// reloadBuffer replaces buf's text with the file content.
+func (a *maneApp) reloadBuffer(buf *editor.Buffer) {
+	if buf.Large() {
+		// Large files are mapped again rather than diffed.
+		if err := buf.Reload(); err != nil {
+			a.status.Set(fmt.Sprintf(" Reload error: %v", err))
+			return
+		}
+		if buf == a.tabs.ActiveBuffer() {
+			a.largeView.setBuffer(buf)
+		}
+		a.onLargeFileChange()
+		a.status.Set(fmt.Sprintf(" Reloaded %s (changed on disk)", buf.Title()))
+		return
+	}
+	before := buf.Text()
+	if err := buf.Reload(); err != nil {
+		a.status.Set(fmt.Sprintf(" Reload error: %v", err))
+		return
+	}
+	a.syncBufferChange(buf, before)
+	a.status.Set(fmt.Sprintf(" Reloaded %s (changed on disk)", buf.Title()))
+}
+
+// keepBufferVersion accepts the disk change without taking it, so the next
+// save overwrites the file.
+func (a *maneApp) keepBufferVersion(buf *editor.Buffer) {
+	if err := buf.AcknowledgeDiskChange(); err != nil {
+		a.status.Set(fmt.Sprintf(" Error: %v", err))
+		return
+	}
+	a.syncTabBar()
+	a.status.Set(fmt.Sprintf(" Kept your version of %s", buf.Title()))
+}
+
+// mergeExternalChange merges the disk version into buf, using the text last
+// read or written as the common base.
+func (a *maneApp) mergeExternalChange(buf *editor.Buffer) {
+	theirs, err := buf.DiskText()
+	if err != nil {
+		a.status.Set(fmt.Sprintf(" Merge error: %v", err))
+		return
+	}
+	before := buf.Text()
+	merged, conflict := editor.Merge3(buf.SavedText(), before, theirs)
+	buf.ApplyText("Merge External Changes", merged)
+	if err := buf.AcknowledgeDiskChange(); err != nil {
+		a.status.Set(fmt.Sprintf(" Merge error: %v", err))
+		return
+	}
+	a.syncBufferChange(buf, before)
+	if conflict {
+		n := strings.Count(merged, editor.ConflictMine)
+		a.status.Set(fmt.Sprintf(" Merged %s with %d conflict(s)", buf.Title(), n))
+		return
+	}
+	a.status.Set(fmt.Sprintf(" Merged changes to %s from disk", buf.Title()))
+}
+
+// forceSave saves buf even though its file changed on disk.
+func (a *maneApp) forceSave(buf *editor.Buffer) {
+	version := buf.Version()
+	if err := buf.ForceSave(); err != nil {
+		a.status.Set(fmt.Sprintf(" Save error: %v", err))
+		return
+	}
+	a.afterSave(buf, version)
+}
@@ -78,16 +78,18 @@ func (f *fileFinderWidget) HandleMessage(msg runtime.Message) runtime.HandleResu
 	return f.CommandPalette.HandleMessage(msg)
 }
 
-func shouldSkipFinderDir(name string) bool {
-	switch name {
-	case ".git", "node_modules", "vendor":
-		return true
-	default:
-		return false
+// shouldSkipFinderDir reports whether a directory called name matches one of
+// the exclude patterns of the finderExclude setting.
+func shouldSkipFinderDir(name string, exclude []string) bool {
+	for _, pattern := range exclude {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
 	}
+	return false
 }
 
-func collectFinderFiles(root string) ([]finderFile, error) {
+func collectFinderFiles(root string, exclude []string) ([]finderFile, error) {
 	clean := filepath.Clean(root)
 	var out []finderFile
 	err := filepath.WalkDir(clean, func(path string, d os.DirEntry, err error) error {
@@ -95,7 +97,7 @@ func collectFinderFiles(root string) ([]finderFile, error) {
 			return nil
 		}
 		if d.IsDir() {
-			if shouldSkipFinderDir(d.Name()) {
+			if shouldSkipFinderDir(d.Name(), exclude) {
 				return filepath.SkipDir
 			}
 			return nil
@@ -6,6 +6,8 @@ import (
 	"sort"
 	"strings"
 	"testing"
+
+	"github.com/odvcencio/mane/editor"
 )
 
 func TestShouldSkipFinderDir(t *testing.T) {
@@ -17,10 +19,12 @@ func TestShouldSkipFinderDir(t *testing.T) {
 		{name: "node_modules", skip: true},
 		{name: "vendor", skip: true},
 		{name: "src", skip: false},
+		{name: "build.cache", skip: true},
 	}
 
+	exclude := append(editor.DefaultSettings().FinderExclude, "*.cache")
 	for _, tc := range cases {
-		got := shouldSkipFinderDir(tc.name)
+		got := shouldSkipFinderDir(tc.name, exclude)
 		if got != tc.skip {
 			t.Errorf("shouldSkipFinderDir(%q) = %v, want %v", tc.name, got, tc.skip)
 		}
@@ -64,7 +68,7 @@ func TestCollectFinderFiles(t *testing.T) {
 		t.Fatalf("write e: %v", err)
 	}
 
-	files, err := collectFinderFiles(tmp)
+	files, err := collectFinderFiles(tmp, editor.DefaultSettings().FinderExclude)
 	if err != nil {
 		t.Fatalf("collectFinderFiles: %v", err)
 	}
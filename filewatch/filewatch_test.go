@@ -0,0 +1,80 @@
+package filewatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func waitEvent(t *testing.T, w *Watcher, want string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case got := <-w.Events():
+			if got == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no event for %s", want)
+		}
+	}
+}
+
+func testWatcher(t *testing.T, w *Watcher) {
+	t.Helper()
+	defer w.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	other := filepath.Join(dir, "other.txt")
+	if err := os.WriteFile(path, []byte("one"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(path); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// Untracked files in the same directory are not reported.
+	if err := os.WriteFile(other, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("two!"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, path)
+
+	// Atomic replacement by rename.
+	tmp := filepath.Join(dir, "tmp")
+	if err := os.WriteFile(tmp, []byte("three!!"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, path)
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, path)
+
+	w.Remove(path)
+	if err := os.WriteFile(path, []byte("four"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-w.Events():
+		t.Errorf("event after Remove: %s", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	testWatcher(t, New(50*time.Millisecond))
+}
+
+func TestWatcherPolling(t *testing.T) {
+	testWatcher(t, newWatcher(50*time.Millisecond, nil))
+}
@@ -0,0 +1,117 @@
+//go:build linux
+
+package filewatch
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask selects the events that can change a file's content: writes
+// that complete, atomic replacement by rename, creation and removal.
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM |
+	syscall.IN_CREATE | syscall.IN_DELETE
+
+type inotify struct {
+	f   *os.File
+	buf []byte
+
+	mu   sync.Mutex
+	wds  map[string]int // directory -> watch descriptor
+	dirs map[int]string
+}
+
+func newNotifier() (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	// A non-blocking descriptor is registered with the runtime poller, so
+	// closing the file unblocks a pending read.
+	return &inotify{
+		f:    os.NewFile(uintptr(fd), "inotify"),
+		buf:  make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1)),
+		wds:  make(map[string]int),
+		dirs: make(map[int]string),
+	}, nil
+}
+
+func (n *inotify) add(dir string) error {
+	conn, err := n.f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var wd int
+	var addErr error
+	err = conn.Control(func(fd uintptr) {
+		wd, addErr = syscall.InotifyAddWatch(int(fd), dir, inotifyMask)
+	})
+	if err != nil {
+		return err
+	}
+	if addErr != nil {
+		return addErr
+	}
+	n.mu.Lock()
+	n.wds[dir] = wd
+	n.dirs[wd] = dir
+	n.mu.Unlock()
+	return nil
+}
+
+func (n *inotify) remove(dir string) {
+	n.mu.Lock()
+	wd, ok := n.wds[dir]
+	delete(n.wds, dir)
+	delete(n.dirs, wd)
+	n.mu.Unlock()
+	if !ok {
+		return
+	}
+	if conn, err := n.f.SyscallConn(); err == nil {
+		_ = conn.Control(func(fd uintptr) {
+			_, _ = syscall.InotifyRmWatch(int(fd), uint32(wd))
+		})
+	}
+}
+
+func (n *inotify) read() ([]string, error) {
+	buf := n.buf
+	for {
+		size, err := n.f.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		var paths []string
+		n.mu.Lock()
+		for off := 0; off+syscall.SizeofInotifyEvent <= size; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameStart := off + syscall.SizeofInotifyEvent
+			nameEnd := nameStart + int(ev.Len)
+			off = nameEnd
+			if ev.Len == 0 || nameEnd > size {
+				continue
+			}
+			dir, ok := n.dirs[int(ev.Wd)]
+			if !ok {
+				continue
+			}
+			name := buf[nameStart:nameEnd]
+			for len(name) > 0 && name[len(name)-1] == 0 {
+				name = name[:len(name)-1]
+			}
+			paths = append(paths, filepath.Join(dir, string(name)))
+		}
+		n.mu.Unlock()
+		if len(paths) > 0 {
+			return paths, nil
+		}
+	}
+}
+
+func (n *inotify) close() error {
+	return n.f.Close()
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package filewatch
+
+import "errors"
+
+func newNotifier() (notifier, error) {
+	return nil, errors.New("filewatch: native notifications not supported")
+}
@@ -0,0 +1,201 @@
+// Package filewatch reports changes to a set of files made by other
+// programs. It uses inotify on Linux and falls back to polling elsewhere, or
+// when a directory cannot be watched.
+package filewatch
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is the polling interval used when none is given.
+const DefaultInterval = 2 * time.Second
+
+// notifier is an OS-specific source of directory change notifications.
+type notifier interface {
+	add(dir string) error
+	remove(dir string)
+	// read blocks until entries change and returns their paths. It returns
+	// an error once the notifier is closed.
+	read() ([]string, error)
+	close() error
+}
+
+// stamp is the observed state of a file, used to drop events that did not
+// change it and to detect changes while polling.
+type stamp struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func stat(path string) stamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return stamp{}
+	}
+	return stamp{exists: true, modTime: info.ModTime(), size: info.Size()}
+}
+
+// Watcher watches files for modification, replacement, creation and removal.
+// Changed paths are delivered on Events; the receiver is expected to inspect
+// the file itself. Paths are absolute.
+type Watcher struct {
+	interval time.Duration
+	events   chan string
+	done     chan struct{}
+	once     sync.Once
+	n        notifier // nil if only polling is available
+
+	mu     sync.Mutex
+	files  map[string]stamp
+	dirs   map[string]int  // watched directory -> number of tracked files
+	polled map[string]bool // directories the notifier could not watch
+}
+
+// New starts a watcher. interval is the polling interval for files that
+// cannot be watched natively; zero selects DefaultInterval.
+func New(interval time.Duration) *Watcher {
+	n, err := newNotifier()
+	if err != nil {
+		n = nil
+	}
+	return newWatcher(interval, n)
+}
+
+func newWatcher(interval time.Duration, n notifier) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	w := &Watcher{
+		interval: interval,
+		events:   make(chan string, 16),
+		done:     make(chan struct{}),
+		n:        n,
+		files:    make(map[string]stamp),
+		dirs:     make(map[string]int),
+		polled:   make(map[string]bool),
+	}
+	if n != nil {
+		go w.notifyLoop()
+	}
+	go w.pollLoop()
+	return w
+}
+
+// Events returns the channel changed paths are delivered on.
+func (w *Watcher) Events() <-chan string {
+	return w.events
+}
+
+// Add starts watching path. Adding a path twice has no effect.
+func (w *Watcher) Add(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.files[path]; ok {
+		return nil
+	}
+	w.files[path] = stat(path)
+	dir := filepath.Dir(path)
+	w.dirs[dir]++
+	if w.dirs[dir] == 1 && (w.n == nil || w.n.add(dir) != nil) {
+		w.polled[dir] = true
+	}
+	return nil
+}
+
+// Remove stops watching path.
+func (w *Watcher) Remove(path string) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.files[path]; !ok {
+		return
+	}
+	delete(w.files, path)
+	dir := filepath.Dir(path)
+	w.dirs[dir]--
+	if w.dirs[dir] > 0 {
+		return
+	}
+	delete(w.dirs, dir)
+	if w.polled[dir] {
+		delete(w.polled, dir)
+	} else if w.n != nil {
+		w.n.remove(dir)
+	}
+}
+
+// Close stops the watcher. Events is not closed; no further events are sent.
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		if w.n != nil {
+			err = w.n.close()
+		}
+	})
+	return err
+}
+
+// check compares path against its last observed state and reports a change.
+func (w *Watcher) check(path string) {
+	w.mu.Lock()
+	old, ok := w.files[path]
+	cur := stat(path)
+	if ok {
+		w.files[path] = cur
+	}
+	w.mu.Unlock()
+	if !ok || cur == old {
+		return
+	}
+	select {
+	case w.events <- path:
+	case <-w.done:
+	}
+}
+
+func (w *Watcher) notifyLoop() {
+	for {
+		paths, err := w.n.read()
+		if err != nil {
+			return
+		}
+		for _, p := range paths {
+			w.check(p)
+		}
+	}
+}
+
+func (w *Watcher) pollLoop() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		w.mu.Lock()
+		var paths []string
+		for p := range w.files {
+			if w.polled[filepath.Dir(p)] {
+				paths = append(paths, p)
+			}
+		}
+		w.mu.Unlock()
+		for _, p := range paths {
+			w.check(p)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/terminal"
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/editor"
+)
+
+// filterTimeout is how long a filter command may run before it is stopped.
+const filterTimeout = 30 * time.Second
+
+// cmdFilterCommand asks for a shell command and replaces the selection with
+// its output for it: the selection of each of multiple cursors, or the
+// whole buffer with nothing selected.
+func (a *maneApp) cmdFilterCommand() {
+	a.ask(" Filter through command: ", promptText, func(command string) {
+		if strings.TrimSpace(command) == "" {
+			a.status.Set(" No command")
+			return
+		}
+		a.filterText(command)
+	})
+}
+
+// cmdInsertCommandOutput asks for a shell command and inserts its output at
+// the cursor, or at every cursor, in place of any selection.
+func (a *maneApp) cmdInsertCommandOutput() {
+	a.ask(" Insert output of command: ", promptText, func(command string) {
+		if strings.TrimSpace(command) == "" {
+			a.status.Set(" No command")
+			return
+		}
+		a.runFilters(command, []string{""}, func(buf *editor.Buffer, outs []string) {
+			if a.isMultiCursorMode() {
+				a.applyMultiCursorEdit("Insert Command Output", a.multiCursor.InsertAtAll(buf.Text(), outs[0]), false)
+				return
+			}
+			a.replaceSelection("Insert Command Output", outs[0], false)
+		})
+	})
+}
+
+// filterText runs command on the selection, each cursor's selection, or the
+// whole buffer, and puts the output in its place as one undo step. With
+// multiple cursors, the command runs once for each selection and cursors
+// without one are left alone; if none has one, the whole buffer is
+// filtered.
+func (a *maneApp) filterText(command string) {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil {
+		return
+	}
+	if a.isMultiCursorMode() {
+		parts := a.multiCursor.SelectedTexts(buf.Text())
+		var inputs []string
+		var at []int
+		for i, part := range parts {
+			if part != "" {
+				inputs = append(inputs, part)
+				at = append(at, i)
+			}
+		}
+		if len(inputs) > 0 {
+			a.runFilters(command, inputs, func(buf *editor.Buffer, outs []string) {
+				for i, out := range outs {
+					parts[at[i]] = out
+				}
+				a.applyMultiCursorEdit("Filter", a.multiCursor.InsertEach(buf.Text(), parts), false)
+			})
+			return
+		}
+	}
+	if a.textArea.GetSelection().IsEmpty() {
+		a.runFilters(command, []string{buf.Text()}, func(_ *editor.Buffer, outs []string) {
+			if !a.replaceText("Filter", outs[0], a.textArea.CursorOffset()) {
+				a.status.Set(" No change")
+			}
+		})
+		return
+	}
+	a.runFilters(command, []string{a.textArea.GetSelectedText()}, func(_ *editor.Buffer, outs []string) {
+		a.replaceSelection("Filter", outs[0], true)
+	})
+}
+
+// replaceSelection puts text in place of the selection, or at the cursor,
+// as one undo step labelled label. With selectIt, the new text is selected.
+func (a *maneApp) replaceSelection(label, text string, selectIt bool) {
+	runes := []rune(a.textArea.Text())
+	sel := a.textArea.GetSelection()
+	start, end := a.textArea.CursorOffset(), a.textArea.CursorOffset()
+	if !sel.IsEmpty() {
+		start = clampRuneOffset(min(sel.Start, sel.End), len(runes))
+		end = clampRuneOffset(max(sel.Start, sel.End), len(runes))
+	}
+	newEnd := start + utf8.RuneCountInString(text)
+	if !a.replaceText(label, string(runes[:start])+text+string(runes[end:]), newEnd) {
+		a.status.Set(" No change")
+		return
+	}
+	if selectIt {
+		a.selectRange(start, newEnd)
+	}
+}
+
+// filterTarget is where the output of a filter command goes: the buffer,
+// its version and the cursors when the command started.
+type filterTarget struct {
+	buf     *editor.Buffer
+	version uint64
+	cursor  int
+	sel     widgets.Selection
+	cursors []editor.Cursor
+}
+
+func (a *maneApp) currentFilterTarget() filterTarget {
+	t := filterTarget{buf: a.tabs.ActiveBuffer(), cursor: a.textArea.CursorOffset(), sel: a.textArea.GetSelection()}
+	if t.buf != nil {
+		t.version = t.buf.Version()
+	}
+	if a.isMultiCursorMode() {
+		t.cursors = a.multiCursor.Cursors()
+	}
+	return t
+}
+
+func (t filterTarget) equal(u filterTarget) bool {
+	return t.buf == u.buf && t.version == u.version && t.cursor == u.cursor && t.sel == u.sel &&
+		slices.Equal(t.cursors, u.cursors)
+}
+
+// runFilters runs command once for each of inputs, in the directory of the
+// active file, off the UI goroutine, so the editor stays responsive and
+// Escape can stop it. apply gets the outputs back on the UI goroutine, unless
+// a command failed or the text or cursors changed meanwhile.
+func (a *maneApp) runFilters(command string, inputs []string, apply func(buf *editor.Buffer, outs []string)) {
+	if a.filterCancel != nil {
+		a.status.Set(" A command is already running")
+		return
+	}
+	target := a.currentFilterTarget()
+	dir := a.treeRoot
+	if target.buf != nil && target.buf.Path() != "" {
+		dir = filepath.Dir(target.buf.Path())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), filterTimeout)
+	a.filterCancel = cancel
+	a.status.Set(" Running " + command + " (Escape to cancel)")
+
+	run := func() ([]string, error) {
+		outs := make([]string, len(inputs))
+		for i, input := range inputs {
+			out, err := editor.Filter(ctx, command, input, dir)
+			if err != nil {
+				return nil, err
+			}
+			outs[i] = out
+		}
+		return outs, nil
+	}
+	finish := func(outs []string, err error) {
+		cancel()
+		a.filterCancel = nil
+		switch {
+		case errors.Is(err, context.Canceled):
+			a.status.Set(" Command cancelled")
+		case errors.Is(err, context.DeadlineExceeded):
+			a.status.Set(fmt.Sprintf(" Command timed out after %s", filterTimeout))
+		case err != nil:
+			a.status.Set(" Command failed: " + strings.Join(strings.Fields(err.Error()), " "))
+		case !target.equal(a.currentFilterTarget()):
+			a.status.Set(" The text or cursors changed while the command ran; output discarded")
+		default:
+			a.status.Set("")
+			apply(target.buf, outs)
+		}
+	}
+	if a.rt == nil {
+		// Not running yet; nothing else could happen meanwhile.
+		finish(run())
+		return
+	}
+	go func() {
+		outs, err := run()
+		_ = a.rt.Call(context.Background(), func(*runtime.App) error {
+			finish(outs, err)
+			return nil
+		})
+	}()
+}
+
+// cancelFilterKey stops the running filter command if key is Escape,
+// reporting whether it did.
+func (a *maneApp) cancelFilterKey(key runtime.KeyMsg) bool {
+	if a.filterCancel == nil || key.Key != terminal.KeyEscape {
+		return false
+	}
+	a.stopFilter()
+	return true
+}
+
+// stopFilter stops the running filter command, if any.
+func (a *maneApp) stopFilter() {
+	if a.filterCancel != nil {
+		a.filterCancel()
+	}
+}
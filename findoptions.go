@@ -0,0 +1,134 @@
+package main
+
+import (
+	"github.com/odvcencio/fluffyui/backend"
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/terminal"
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/editor"
+)
+
+// findOptionsWidth is the width of the option indicators drawn by
+// drawFindOptions without and with the preserve-case toggle.
+const (
+	findOptionsWidth        = 8
+	replaceFindOptionsWidth = 11
+)
+
+// toggleFindOption flips the option bound to Alt+key: R regex, C match case,
+// W whole word and, when withPreserve is set, P preserve case. Returns false
+// if key is not an option key.
+func toggleFindOption(opts *editor.FindOptions, key rune, withPreserve bool) bool {
+	switch key {
+	case 'r', 'R':
+		opts.Regex = !opts.Regex
+	case 'c', 'C':
+		opts.IgnoreCase = !opts.IgnoreCase
+	case 'w', 'W':
+		opts.WholeWord = !opts.WholeWord
+	case 'p', 'P':
+		if !withPreserve {
+			return false
+		}
+		opts.PreserveCase = !opts.PreserveCase
+	default:
+		return false
+	}
+	return true
+}
+
+// drawFindOptions draws the option indicators at x, y: ".*" regex, "Aa"
+// match case, "W" whole word and optionally "AB" preserve case. Active
+// options use on, the others off.
+func drawFindOptions(buf *runtime.Buffer, x, y int, opts editor.FindOptions, withPreserve bool, on, off backend.Style) {
+	items := []struct {
+		label  string
+		active bool
+	}{
+		{".*", opts.Regex},
+		{"Aa", !opts.IgnoreCase},
+		{"W", opts.WholeWord},
+	}
+	if withPreserve {
+		items = append(items, struct {
+			label  string
+			active bool
+		}{"AB", opts.PreserveCase})
+	}
+	for _, item := range items {
+		style := off
+		if item.active {
+			style = on
+		}
+		buf.SetString(x, y, item.label, style)
+		x += len(item.label) + 1
+	}
+}
+
+// findWidget is the search bar with regex, match-case and whole-word toggles
+// on Alt+R, Alt+C and Alt+W.
+type findWidget struct {
+	*widgets.SearchWidget
+
+	options  *editor.FindOptions
+	onToggle func()
+
+	onStyle  backend.Style
+	offStyle backend.Style
+}
+
+func newFindWidget(options *editor.FindOptions) *findWidget {
+	return &findWidget{
+		SearchWidget: widgets.NewSearchWidget(),
+		options:      options,
+		onStyle:      backend.DefaultStyle().Reverse(true),
+		offStyle:     backend.DefaultStyle().Foreground(backend.ColorRGB(0x88, 0x88, 0x88)),
+	}
+}
+
+// Render draws the search bar with the option indicators left of the match
+// counter.
+func (w *findWidget) Render(ctx runtime.RenderContext) {
+	w.SearchWidget.Render(ctx)
+	b := w.Bounds()
+	if b.Width < 40 || b.Height < 1 {
+		return
+	}
+	drawFindOptions(ctx.Buffer, b.X+b.Width-14-findOptionsWidth, b.Y, *w.options, false, w.onStyle, w.offStyle)
+}
+
+// HandleMessage toggles options before passing input to the search bar,
+// which would otherwise take Alt+letter as typed text.
+func (w *findWidget) HandleMessage(msg runtime.Message) runtime.HandleResult {
+	if key, ok := msg.(runtime.KeyMsg); ok && w.IsFocused() && key.Alt && key.Key == terminal.KeyRune {
+		if toggleFindOption(w.options, key.Rune, false) {
+			if w.onToggle != nil {
+				w.onToggle()
+			}
+			return runtime.Handled()
+		}
+	}
+	return w.SearchWidget.HandleMessage(msg)
+}
+
+// cmdToggleMatchCase flips the match case option of find, replace and
+// occurrence selection.
+func (a *maneApp) cmdToggleMatchCase() {
+	a.findOptions.IgnoreCase = !a.findOptions.IgnoreCase
+	if a.findOptions.IgnoreCase {
+		a.status.Set(" Match case off")
+	} else {
+		a.status.Set(" Match case on")
+	}
+}
+
+// cmdToggleWholeWord flips the whole word option of find, replace and
+// occurrence selection.
+func (a *maneApp) cmdToggleWholeWord() {
+	a.findOptions.WholeWord = !a.findOptions.WholeWord
+	if a.findOptions.WholeWord {
+		a.status.Set(" Whole word on")
+	} else {
+		a.status.Set(" Whole word off")
+	}
+}
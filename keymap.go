@@ -0,0 +1,421 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/terminal"
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/commands"
+	"github.com/odvcencio/mane/editor"
+	"github.com/odvcencio/mane/keymap"
+)
+
+// keybindingsFileName is the user keybindings file in the config directory.
+const keybindingsFileName = "keybindings.json"
+
+// paletteCommands returns the commands of the command palette.
+func (a *maneApp) paletteCommands() []widgets.PaletteCommand {
+	return commands.AllCommands(commands.Actions{
+		SaveFile:        a.cmdSaveFile,
+		NewFile:         a.cmdNewFile,
+		CloseTab:        a.cmdCloseTab,
+		ToggleSidebar:   a.toggleSidebar,
+		ToggleWordWrap:  a.textAreaOnly(a.cmdToggleWordWrap),
+		Quit:            a.cmdQuit,
+		QuitDiscard:     a.cmdQuitDiscard,
+		OpenSettings:    a.cmdOpenSettings,
+		OpenKeybindings: a.cmdOpenKeybindings,
+		GoToFile:        func() { a.cmdOpenFileFinder() },
+		NextTab:         a.nextTab,
+		PrevTab:         a.prevTab,
+		Undo:            a.cmdUndo,
+		Redo:            a.cmdRedo,
+		UndoHistory:     a.textAreaOnly(a.cmdUndoHistory),
+		GoBackInTime:    func() { a.cmdTimeTravel(true) },
+		GoForwardInTime: func() { a.cmdTimeTravel(false) },
+		Find:            func() { a.cmdFind() },
+		Replace:         a.textAreaOnly(func() { a.cmdReplace() }),
+		GotoLine:        func() { a.cmdGotoLine() },
+		DeleteLine:      a.textAreaOnly(a.cmdDeleteLine),
+		MoveLineUp:      a.textAreaOnly(a.cmdMoveLineUp),
+		MoveLineDown:    a.textAreaOnly(a.cmdMoveLineDown),
+		DuplicateLine:   a.textAreaOnly(a.cmdDuplicateLine),
+		GotoBracket:     a.textAreaOnly(a.cmdGotoMatchingBracket),
+		// Multi-cursor and block selection actions.
+		AddNextOccurrence:    a.textAreaOnly(a.addNextCursorOccurrence),
+		SkipOccurrence:       a.textAreaOnly(a.skipCursorOccurrence),
+		SelectAllOccurrences: a.textAreaOnly(a.selectAllCursorOccurrences),
+		AddCursorAbove:       a.textAreaOnly(func() { a.addCursorVertically(-1) }),
+		AddCursorBelow:       a.textAreaOnly(func() { a.addCursorVertically(1) }),
+		UndoCursorAdd:        a.textAreaOnly(a.undoCursorAdd),
+		ToggleMatchCase:      a.cmdToggleMatchCase,
+		ToggleWholeWord:      a.cmdToggleWholeWord,
+		BlockSelectUp:        a.textAreaOnly(func() { a.expandBlockSelection(-1, 0) }),
+		BlockSelectDown:      a.textAreaOnly(func() { a.expandBlockSelection(1, 0) }),
+		BlockSelectLeft:      a.textAreaOnly(func() { a.expandBlockSelection(0, -1) }),
+		BlockSelectRight:     a.textAreaOnly(func() { a.expandBlockSelection(0, 1) }),
+		BlockCopy:            a.textAreaOnly(func() { a.copyBlock(false) }),
+		BlockCut:             a.textAreaOnly(func() { a.copyBlock(true) }),
+		BlockPaste:           a.textAreaOnly(a.cmdPasteBlock),
+		BlockFill:            a.textAreaOnly(a.cmdBlockFill),
+		FoldAtCursor:         a.textAreaOnly(a.cmdFoldAtCursor),
+		UnfoldAtCursor:       a.textAreaOnly(a.cmdUnfoldAtCursor),
+		FoldAll:              a.textAreaOnly(a.cmdFoldAll),
+		UnfoldAll:            a.textAreaOnly(a.cmdUnfoldAll),
+		LspComplete:          a.textAreaOnly(a.cmdLspComplete),
+		LspDefinition:        a.textAreaOnly(a.cmdLspDefinition),
+		LspReferences:        a.textAreaOnly(a.cmdLspReferences),
+		LspHover:             a.textAreaOnly(func() { a.cmdLspHoverPanel() }),
+		LspDiagnostics:       a.textAreaOnly(a.cmdLspDiagnostics),
+		LspRename:            a.textAreaOnly(func() { a.cmdLspRename() }),
+		LspCodeAction:        a.textAreaOnly(a.cmdLspCodeAction),
+		// File format actions.
+		SaveWithEncoding:   a.textAreaOnly(a.cmdSaveWithEncoding),
+		ReopenWithEncoding: a.textAreaOnly(a.cmdReopenWithEncoding),
+		LineEndingLF:       a.textAreaOnly(func() { a.cmdSetLineEnding(editor.LineEndingLF) }),
+		LineEndingCRLF:     a.textAreaOnly(func() { a.cmdSetLineEnding(editor.LineEndingCRLF) }),
+		ToggleReadOnly:     a.cmdToggleReadOnly,
+		// Keyboard macro actions.
+		RecordMacro:       a.cmdRecordMacro,
+		PlayMacro:         a.cmdPlayMacro,
+		PlayMacroTimes:    a.cmdPlayMacroTimes,
+		PlayMacroOnLines:  a.textAreaOnly(a.cmdPlayMacroOnLines),
+		SaveMacroRegister: a.cmdSaveMacroRegister,
+		PlayMacroRegister: a.cmdPlayMacroRegister,
+		// Line and text transformation actions.
+		SortLines:           a.textAreaOnly(func() { a.cmdSortLines(editor.SortNatural, false) }),
+		SortLinesNumeric:    a.textAreaOnly(func() { a.cmdSortLines(editor.SortNumeric, false) }),
+		SortLinesIgnoreCase: a.textAreaOnly(func() { a.cmdSortLines(editor.SortIgnoreCase, false) }),
+		SortLinesDescending: a.textAreaOnly(func() { a.cmdSortLines(editor.SortNatural, true) }),
+		UniqueLines:         a.textAreaOnly(func() { a.transformLines("Remove Duplicate Lines", true, 0, editor.UniqueLines) }),
+		ShuffleLines:        a.textAreaOnly(func() { a.transformLines("Shuffle Lines", true, 0, shuffleLines) }),
+		ReverseLines:        a.textAreaOnly(func() { a.transformLines("Reverse Lines", true, 0, editor.ReverseLines) }),
+		JoinLines:           a.textAreaOnly(func() { a.transformLines("Join Lines", false, 1, joinLines) }),
+		SplitLines:          a.textAreaOnly(a.cmdSplitLines),
+		TransposeChars:      a.textAreaOnly(a.cmdTransposeChars),
+		TransposeWords:      a.textAreaOnly(a.cmdTransposeWords),
+		TransposeLines:      a.textAreaOnly(a.cmdTransposeLines),
+		UpperCase:           a.textAreaOnly(func() { a.transformSelection("Upper Case", convertCase(editor.UpperCase)) }),
+		LowerCase:           a.textAreaOnly(func() { a.transformSelection("Lower Case", convertCase(editor.LowerCase)) }),
+		TitleCase:           a.textAreaOnly(func() { a.transformSelection("Title Case", convertCase(editor.TitleCase)) }),
+		SnakeCase:           a.textAreaOnly(func() { a.transformSelection("Snake Case", convertCase(editor.SnakeCase)) }),
+		CamelCase:           a.textAreaOnly(func() { a.transformSelection("Camel Case", convertCase(editor.CamelCase)) }),
+		KebabCase:           a.textAreaOnly(func() { a.transformSelection("Kebab Case", convertCase(editor.KebabCase)) }),
+		FilterCommand:       a.textAreaOnly(a.cmdFilterCommand),
+		InsertCommandOutput: a.textAreaOnly(a.cmdInsertCommandOutput),
+	})
+}
+
+// initCommands sets up the commands keys can be bound to: the palette
+// commands, with the ones that open an overlay returning it to the runtime,
+// and a few that only make sense as keys. The keymap starts with the
+// default bindings.
+func (a *maneApp) initCommands() {
+	a.commandList = a.paletteCommands()
+	a.keyCommands = make(map[string]func() runtime.HandleResult, len(a.commandList))
+	for _, cmd := range a.commandList {
+		run := cmd.OnExecute
+		a.keyCommands[cmd.ID] = func() runtime.HandleResult {
+			run()
+			return runtime.Handled()
+		}
+	}
+	a.keyCommands["file.goToFile"] = a.cmdOpenFileFinder
+	a.keyCommands["edit.find"] = a.cmdFind
+	a.keyCommands["edit.replace"] = a.cmdReplace
+	a.keyCommands["edit.gotoLine"] = a.cmdGotoLine
+	a.keyCommands["lsp.hover"] = a.cmdLspHoverPanel
+	a.keyCommands["lsp.rename"] = a.cmdLspRename
+	a.keyCommands["app.commandPalette"] = func() runtime.HandleResult {
+		a.cmdShowPalette()
+		return runtime.Handled()
+	}
+	a.keyCommands["edit.cancelBlockSelection"] = func() runtime.HandleResult {
+		a.clearBlockSelection()
+		a.updateStatus()
+		return runtime.Handled()
+	}
+	a.keyCommands["edit.cancelMultiCursor"] = func() runtime.HandleResult {
+		a.resetMultiCursor()
+		return runtime.Handled()
+	}
+	a.keyCommands["edit.copyCursors"] = func() runtime.HandleResult {
+		a.copyCursorSelections(false)
+		return runtime.Handled()
+	}
+	a.keyCommands["edit.cutCursors"] = func() runtime.HandleResult {
+		a.copyCursorSelections(true)
+		return runtime.Handled()
+	}
+	a.keyCommands["edit.paste"] = a.cmdPaste
+	for id, run := range a.emacsCommands() {
+		run := a.textAreaOnly(run)
+		a.keyCommands[id] = func() runtime.HandleResult {
+			run()
+			return runtime.Handled()
+		}
+	}
+	a.keymap = keymap.New(commands.DefaultKeybindings()...)
+}
+
+// keymapBindings returns the built-in bindings of the keymap chosen in the
+// settings.
+func (a *maneApp) keymapBindings() []keymap.Binding {
+	if a.settings.Global().Keymap == editor.KeymapEmacs {
+		return commands.EmacsKeybindings()
+	}
+	return commands.DefaultKeybindings()
+}
+
+// loadKeymap rebuilds the keymap from the built-in bindings and the user's
+// keybindings.json and shows the bound keys in the palette. On error the
+// keymap in effect is kept.
+func (a *maneApp) loadKeymap() error {
+	path, err := editor.UserConfigPath(keybindingsFileName)
+	if err != nil {
+		return err
+	}
+	user, err := keymap.LoadFile(path)
+	if err != nil {
+		return err
+	}
+	km := keymap.New(a.keymapBindings()...)
+	for _, b := range user {
+		km.Add(b)
+	}
+	a.keymap = km
+	commands.SetShortcuts(a.commandList, km)
+	if a.palette != nil {
+		a.palette.SetCommands(a.commandList)
+	}
+	return nil
+}
+
+// reloadKeymap reads keybindings.json again after it changed.
+func (a *maneApp) reloadKeymap() {
+	if err := a.loadKeymap(); err != nil {
+		a.status.Set(fmt.Sprintf(" Keybindings error: %v", err))
+		return
+	}
+	a.status.Set(" Keybindings reloaded")
+}
+
+// keyContext returns the context keys the when expressions of bindings
+// test.
+func (a *maneApp) keyContext() keymap.Context {
+	return keymap.Context{
+		"editorFocus":    a.textArea.IsFocused() || a.largeView.IsFocused(),
+		"largeFile":      a.activeLargeBuffer() != nil,
+		"blockSelection": a.isBlockSelectionMode(),
+		"multiCursor":    a.isMultiCursorMode(),
+		"markActive":     a.emacsMarkActive(),
+	}
+}
+
+// runCommand runs the command with the given ID.
+func (a *maneApp) runCommand(id string) runtime.HandleResult {
+	run, ok := a.keyCommands[id]
+	if !ok {
+		a.status.Set(fmt.Sprintf(" Unknown command %q", id))
+		return runtime.Handled()
+	}
+	return run()
+}
+
+// handleGlobalKey runs the command bound to key. A status bar prompt, Escape
+// while a filter command runs, an incremental search, or Vim with the vim
+// keymap, gets the keys first. Text typed into a block selection or at
+// multiple cursors is applied to all of them first; a key that runs another
+// command, or none, leaves those modes. While a macro is recorded, every key
+// is added to it.
+func (a *maneApp) handleGlobalKey(key runtime.KeyMsg) runtime.HandleResult {
+	if a.handlePrompt(key) || a.cancelFilterKey(key) {
+		return runtime.Handled()
+	}
+	a.recordMacroKey(key)
+	if a.keymap.Pending() == nil {
+		if a.handleSearchKey(key) || a.handleVimKey(key) {
+			return runtime.Handled()
+		}
+		if a.isBlockSelectionMode() && a.textArea.IsFocused() && a.handleBlockSelectionKey(key) {
+			return runtime.Handled()
+		}
+		if a.isMultiCursorMode() && a.textArea.IsFocused() {
+			if result, ok := a.handleMultiCursorKey(key); ok {
+				return result
+			}
+		}
+	}
+
+	chord := keymap.FromKey(key)
+	pressed := append(slices.Clone(a.keymap.Pending()), chord)
+	command, result := a.keymap.Press(chord, a.keyContext())
+	switch result {
+	case keymap.Pending:
+		a.status.Set(fmt.Sprintf(" (%s) was pressed. Waiting for the next key...", pressed))
+		return runtime.Handled()
+	case keymap.Aborted:
+		a.status.Set(fmt.Sprintf(" (%s) is not a command", pressed))
+		return runtime.Handled()
+	case keymap.Matched:
+		a.unrecordMacroKeys(command, len(pressed))
+		a.leaveSelectionModes(command)
+		result := a.runCommand(command)
+		a.emacs.lastCommand = command
+		return result
+	}
+	a.leaveSelectionModes("")
+	a.emacsUnboundKey(key)
+	return runtime.Unhandled()
+}
+
+// leaveSelectionModes ends block selection and multi-cursor mode before
+// command runs, unless it works on them.
+func (a *maneApp) leaveSelectionModes(command string) {
+	if a.isBlockSelectionMode() && !blockSelectionCommands[command] {
+		a.clearBlockSelection()
+		a.updateStatus()
+	}
+	if a.isMultiCursorMode() && !multiCursorCommands[command] {
+		a.resetMultiCursor()
+	}
+}
+
+// blockSelectionCommands work on the block selection rather than leave it.
+// The command palette keeps it for the block commands run from there.
+var blockSelectionCommands = map[string]bool{
+	"edit.blockSelectUp":        true,
+	"edit.blockSelectDown":      true,
+	"edit.blockSelectLeft":      true,
+	"edit.blockSelectRight":     true,
+	"edit.blockCopy":            true,
+	"edit.blockCut":             true,
+	"edit.blockPaste":           true,
+	"edit.blockFill":            true,
+	"edit.cancelBlockSelection": true,
+	"app.commandPalette":        true,
+}
+
+// multiCursorCommands work on the cursors of multi-cursor mode rather than
+// leave it. The command palette keeps them for the commands run from there.
+var multiCursorCommands = map[string]bool{
+	"edit.addNextOccurrence":        true,
+	"edit.skipOccurrence":           true,
+	"edit.selectAllOccurrences":     true,
+	"edit.addCursorAbove":           true,
+	"edit.addCursorBelow":           true,
+	"edit.undoCursorAdd":            true,
+	"edit.toggleMatchCase":          true,
+	"edit.toggleWholeWord":          true,
+	"edit.copyCursors":              true,
+	"edit.cutCursors":               true,
+	"edit.cancelMultiCursor":        true,
+	"transform.filterCommand":       true,
+	"transform.insertCommandOutput": true,
+	"app.commandPalette":            true,
+}
+
+// handleBlockSelectionKey applies text editing keys and paste to every line
+// of the block selection, reporting whether key was one.
+func (a *maneApp) handleBlockSelectionKey(key runtime.KeyMsg) bool {
+	switch key.Key {
+	case terminal.KeyCtrlV:
+		text := a.clipboardText()
+		if text == "" {
+			text = strings.Join(a.blockClipboard, "\n")
+		}
+		if text == "" {
+			return false
+		}
+		a.applyPaste(text)
+	case terminal.KeyBackspace:
+		a.applyBlockBackspace()
+	case terminal.KeyDelete:
+		a.applyBlockDeleteForward()
+	case terminal.KeyEnter:
+		a.applyBlockInsert("\n")
+	case terminal.KeyTab:
+		a.applyBlockInsert("\t")
+	case terminal.KeyRune:
+		if key.Ctrl || key.Alt || key.Rune == 0 {
+			return false
+		}
+		a.applyBlockInsert(string(key.Rune))
+	default:
+		return false
+	}
+	return true
+}
+
+// handleMultiCursorKey applies text editing keys, paste and cursor motions
+// at every cursor, reporting whether key was one.
+func (a *maneApp) handleMultiCursorKey(key runtime.KeyMsg) (runtime.HandleResult, bool) {
+	if motion, ok := cursorMotion(key); ok {
+		a.moveCursors(motion, key.Shift)
+		return runtime.Handled(), true
+	}
+	switch key.Key {
+	case terminal.KeyCtrlV:
+		if text := a.cursorClipboardText(); text != "" {
+			a.applyPaste(text)
+			return runtime.Handled(), true
+		}
+		return runtime.Unhandled(), true
+	case terminal.KeyRune:
+		if key.Ctrl || key.Alt || key.Rune == 0 {
+			return runtime.Unhandled(), false
+		}
+		a.applyMultiCursorInsert(string(key.Rune))
+	case terminal.KeyBackspace:
+		a.applyMultiCursorDeleteBackspace()
+	case terminal.KeyDelete:
+		a.applyMultiCursorDeleteForward()
+	case terminal.KeyEnter:
+		a.applyMultiCursorInsert("\n")
+	case terminal.KeyTab:
+		a.applyMultiCursorInsert("\t")
+	default:
+		return runtime.Unhandled(), false
+	}
+	return runtime.Handled(), true
+}
+
+// cursorMotion returns the motion an arrow, Home or End key makes, with
+// Ctrl moving by word or to the ends of the text. Shift extends the
+// selection; keys with Alt, and Ctrl with Up or Down, are left to their
+// bindings.
+func cursorMotion(key runtime.KeyMsg) (editor.Motion, bool) {
+	if key.Alt {
+		return 0, false
+	}
+	switch key.Key {
+	case terminal.KeyLeft:
+		if key.Ctrl {
+			return editor.MoveWordLeft, true
+		}
+		return editor.MoveLeft, true
+	case terminal.KeyRight:
+		if key.Ctrl {
+			return editor.MoveWordRight, true
+		}
+		return editor.MoveRight, true
+	case terminal.KeyUp:
+		return editor.MoveUp, !key.Ctrl
+	case terminal.KeyDown:
+		return editor.MoveDown, !key.Ctrl
+	case terminal.KeyHome:
+		if key.Ctrl {
+			return editor.MoveTextStart, true
+		}
+		return editor.MoveLineStart, true
+	case terminal.KeyEnd:
+		if key.Ctrl {
+			return editor.MoveTextEnd, true
+		}
+		return editor.MoveLineEnd, true
+	}
+	return 0, false
+}
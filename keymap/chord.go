@@ -0,0 +1,272 @@
+// Package keymap binds key sequences to command IDs. A binding can be
+// limited to a context with a when expression, and bindings added later
+// take precedence, so user keybindings override the defaults.
+package keymap
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/terminal"
+)
+
+// Chord is a single key press with its modifiers. Letters are kept in lower
+// case, with Shift set for upper case; shifted symbols are kept as their
+// unshifted key with Shift set, as on a US keyboard.
+type Chord struct {
+	Key   terminal.Key
+	Rune  rune // for terminal.KeyRune
+	Ctrl  bool
+	Alt   bool
+	Shift bool
+}
+
+// ctrlKeys are the keys the terminal reports for Ctrl with a letter.
+var ctrlKeys = map[terminal.Key]rune{
+	terminal.KeyCtrlA: 'a',
+	terminal.KeyCtrlB: 'b',
+	terminal.KeyCtrlC: 'c',
+	terminal.KeyCtrlD: 'd',
+	terminal.KeyCtrlF: 'f',
+	terminal.KeyCtrlG: 'g',
+	terminal.KeyCtrlP: 'p',
+	terminal.KeyCtrlV: 'v',
+	terminal.KeyCtrlX: 'x',
+	terminal.KeyCtrlY: 'y',
+	terminal.KeyCtrlZ: 'z',
+}
+
+// shifted maps the symbols typed with Shift on a US keyboard to their key.
+var shifted = map[rune]rune{
+	'~': '`', '!': '1', '@': '2', '#': '3', '$': '4', '%': '5', '^': '6',
+	'&': '7', '*': '8', '(': '9', ')': '0', '_': '-', '+': '=', '{': '[',
+	'}': ']', '|': '\\', ':': ';', '"': '\'', '<': ',', '>': '.', '?': '/',
+}
+
+// FromKey returns the chord of a key message.
+func FromKey(msg runtime.KeyMsg) Chord {
+	c := Chord{Key: msg.Key, Rune: msg.Rune, Ctrl: msg.Ctrl, Alt: msg.Alt, Shift: msg.Shift}
+	if r, ok := ctrlKeys[c.Key]; ok {
+		c.Key, c.Rune, c.Ctrl = terminal.KeyRune, r, true
+	}
+	return c.normalize()
+}
+
+// KeyMsg returns the key message a terminal sends for the chord, the
+// reverse of FromKey: Ctrl with a letter the terminal has a key for gives
+// that key, and Shift with a letter or symbol gives the shifted rune.
+func (c Chord) KeyMsg() runtime.KeyMsg {
+	msg := runtime.KeyMsg{Key: c.Key, Rune: c.Rune, Ctrl: c.Ctrl, Alt: c.Alt, Shift: c.Shift}
+	if c.Key != terminal.KeyRune {
+		return msg
+	}
+	if c.Ctrl {
+		for key, r := range ctrlKeys {
+			if r == c.Rune {
+				msg.Key, msg.Rune, msg.Ctrl = key, 0, false
+				return msg
+			}
+		}
+	}
+	if c.Shift {
+		if unicode.IsLower(c.Rune) {
+			msg.Rune = unicode.ToUpper(c.Rune)
+			return msg
+		}
+		for sym, base := range shifted {
+			if base == c.Rune {
+				msg.Rune = sym
+				break
+			}
+		}
+	}
+	return msg
+}
+
+func (c Chord) normalize() Chord {
+	if c.Key != terminal.KeyRune {
+		c.Rune = 0
+		return c
+	}
+	if unicode.IsUpper(c.Rune) {
+		c.Rune = unicode.ToLower(c.Rune)
+		c.Shift = true
+	} else if base, ok := shifted[c.Rune]; ok {
+		c.Rune = base
+		c.Shift = true
+	}
+	return c
+}
+
+var keyNames = map[string]terminal.Key{
+	"enter":     terminal.KeyEnter,
+	"tab":       terminal.KeyTab,
+	"escape":    terminal.KeyEscape,
+	"esc":       terminal.KeyEscape,
+	"backspace": terminal.KeyBackspace,
+	"delete":    terminal.KeyDelete,
+	"insert":    terminal.KeyInsert,
+	"home":      terminal.KeyHome,
+	"end":       terminal.KeyEnd,
+	"pageup":    terminal.KeyPageUp,
+	"pagedown":  terminal.KeyPageDown,
+	"up":        terminal.KeyUp,
+	"down":      terminal.KeyDown,
+	"left":      terminal.KeyLeft,
+	"right":     terminal.KeyRight,
+	"f1":        terminal.KeyF1,
+	"f2":        terminal.KeyF2,
+	"f3":        terminal.KeyF3,
+	"f4":        terminal.KeyF4,
+	"f5":        terminal.KeyF5,
+	"f6":        terminal.KeyF6,
+	"f7":        terminal.KeyF7,
+	"f8":        terminal.KeyF8,
+	"f9":        terminal.KeyF9,
+	"f10":       terminal.KeyF10,
+	"f11":       terminal.KeyF11,
+	"f12":       terminal.KeyF12,
+}
+
+// displayNames are the names String uses for keys other than runes.
+var displayNames = map[terminal.Key]string{
+	terminal.KeyEnter:     "Enter",
+	terminal.KeyTab:       "Tab",
+	terminal.KeyEscape:    "Escape",
+	terminal.KeyBackspace: "Backspace",
+	terminal.KeyDelete:    "Delete",
+	terminal.KeyInsert:    "Insert",
+	terminal.KeyHome:      "Home",
+	terminal.KeyEnd:       "End",
+	terminal.KeyPageUp:    "PageUp",
+	terminal.KeyPageDown:  "PageDown",
+	terminal.KeyUp:        "Up",
+	terminal.KeyDown:      "Down",
+	terminal.KeyLeft:      "Left",
+	terminal.KeyRight:     "Right",
+	terminal.KeyF1:        "F1",
+	terminal.KeyF2:        "F2",
+	terminal.KeyF3:        "F3",
+	terminal.KeyF4:        "F4",
+	terminal.KeyF5:        "F5",
+	terminal.KeyF6:        "F6",
+	terminal.KeyF7:        "F7",
+	terminal.KeyF8:        "F8",
+	terminal.KeyF9:        "F9",
+	terminal.KeyF10:       "F10",
+	terminal.KeyF11:       "F11",
+	terminal.KeyF12:       "F12",
+}
+
+// ParseChord parses a chord such as "Ctrl+Shift+K", "alt+up" or "F12".
+// Modifiers and key names are case-insensitive; a letter key is the same
+// key in either case.
+func ParseChord(s string) (Chord, error) {
+	s = strings.TrimSpace(s)
+	var c Chord
+	rest := s
+	for {
+		i := strings.IndexByte(rest, '+')
+		if i <= 0 || i == len(rest)-1 {
+			break
+		}
+		switch strings.ToLower(rest[:i]) {
+		case "ctrl", "control":
+			c.Ctrl = true
+		case "alt", "meta", "option":
+			c.Alt = true
+		case "shift":
+			c.Shift = true
+		default:
+			return Chord{}, fmt.Errorf("unknown modifier %q in %q", rest[:i], s)
+		}
+		rest = rest[i+1:]
+	}
+	name := strings.ToLower(rest)
+	switch {
+	case name == "":
+		return Chord{}, fmt.Errorf("missing key in %q", s)
+	case name == "space":
+		c.Key, c.Rune = terminal.KeyRune, ' '
+	case keyNames[name] != terminal.KeyNone:
+		c.Key = keyNames[name]
+	case len([]rune(name)) == 1:
+		c.Key, c.Rune = terminal.KeyRune, []rune(name)[0]
+	default:
+		return Chord{}, fmt.Errorf("unknown key %q in %q", rest, s)
+	}
+	return c.normalize(), nil
+}
+
+// String formats the chord the way ParseChord reads it, such as
+// "Ctrl+Shift+K".
+func (c Chord) String() string {
+	var sb strings.Builder
+	if c.Ctrl {
+		sb.WriteString("Ctrl+")
+	}
+	if c.Alt {
+		sb.WriteString("Alt+")
+	}
+	if c.Shift {
+		sb.WriteString("Shift+")
+	}
+	switch {
+	case c.Key != terminal.KeyRune:
+		if name, ok := displayNames[c.Key]; ok {
+			sb.WriteString(name)
+		} else {
+			fmt.Fprintf(&sb, "Key%d", c.Key)
+		}
+	case c.Rune == ' ':
+		sb.WriteString("Space")
+	default:
+		sb.WriteString(string(unicode.ToUpper(c.Rune)))
+	}
+	return sb.String()
+}
+
+// Sequence is a series of chords pressed one after the other, such as
+// Ctrl+K Ctrl+C.
+type Sequence []Chord
+
+// ParseSequence parses chords separated by spaces.
+func ParseSequence(s string) (Sequence, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty key sequence")
+	}
+	seq := make(Sequence, len(fields))
+	for i, field := range fields {
+		c, err := ParseChord(field)
+		if err != nil {
+			return nil, err
+		}
+		seq[i] = c
+	}
+	return seq, nil
+}
+
+// String formats the sequence the way ParseSequence reads it.
+func (s Sequence) String() string {
+	parts := make([]string, len(s))
+	for i, c := range s {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// hasPrefix reports whether prefix is a leading part of s, or all of it.
+func (s Sequence) hasPrefix(prefix Sequence) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+	for i, c := range prefix {
+		if s[i] != c {
+			return false
+		}
+	}
+	return true
+}
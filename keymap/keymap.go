@@ -0,0 +1,189 @@
+package keymap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Binding binds a key sequence to a command, in the contexts where its when
+// expression holds.
+type Binding struct {
+	Keys    Sequence
+	Command string
+	When    When
+}
+
+// Bind returns the binding of keys to command, both in the syntax of
+// keybindings.json. It panics on a syntax error, so it is meant for
+// built-in bindings.
+func Bind(keys, command, when string) Binding {
+	seq, err := ParseSequence(keys)
+	if err != nil {
+		panic(err)
+	}
+	w, err := ParseWhen(when)
+	if err != nil {
+		panic(err)
+	}
+	return Binding{Keys: seq, Command: command, When: w}
+}
+
+// Result is the outcome of a key press.
+type Result int
+
+const (
+	// NoMatch means the key is not bound; it should be handled as usual.
+	NoMatch Result = iota
+	// Pending means the key starts a bound sequence; more keys are needed.
+	Pending
+	// Matched means the keys so far are bound to a command.
+	Matched
+	// Aborted means the key did not continue a pending sequence. The
+	// sequence is dropped along with the key.
+	Aborted
+)
+
+// Keymap resolves key presses to commands. It is not safe for concurrent
+// use.
+type Keymap struct {
+	bindings []Binding // in order of increasing precedence
+	pending  Sequence
+}
+
+// New returns a keymap with the given bindings; later ones take precedence.
+func New(bindings ...Binding) *Keymap {
+	k := &Keymap{}
+	for _, b := range bindings {
+		k.Add(b)
+	}
+	return k
+}
+
+// Add adds a binding that takes precedence over the ones added before. A
+// command starting with "-" removes the bindings of the rest of the command
+// name instead: the ones for Keys, or all of them if Keys is empty.
+func (k *Keymap) Add(b Binding) {
+	name, remove := strings.CutPrefix(b.Command, "-")
+	if !remove {
+		k.bindings = append(k.bindings, b)
+		return
+	}
+	kept := k.bindings[:0]
+	for _, existing := range k.bindings {
+		if existing.Command == name && (len(b.Keys) == 0 || existing.Keys.String() == b.Keys.String()) {
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	k.bindings = kept
+}
+
+// Press resolves c, following the keys pressed before it if they started a
+// sequence. Bindings whose when expression does not hold in ctx are
+// ignored. A key that completes one binding and starts a longer one waits
+// for the longer one.
+func (k *Keymap) Press(c Chord, ctx Context) (command string, result Result) {
+	seq := append(append(Sequence(nil), k.pending...), c)
+	prefix := false
+	for i := len(k.bindings) - 1; i >= 0; i-- {
+		b := k.bindings[i]
+		if !b.Keys.hasPrefix(seq) || !b.When.Eval(ctx) {
+			continue
+		}
+		if len(b.Keys) > len(seq) {
+			prefix = true
+		} else if command == "" {
+			command = b.Command
+		}
+	}
+	switch {
+	case prefix:
+		k.pending = seq
+		return "", Pending
+	case command != "":
+		k.pending = nil
+		return command, Matched
+	case len(k.pending) > 0:
+		k.pending = nil
+		return "", Aborted
+	}
+	return "", NoMatch
+}
+
+// Pending returns the keys of a sequence pressed so far, or nil.
+func (k *Keymap) Pending() Sequence {
+	return k.pending
+}
+
+// Reset drops a pending sequence.
+func (k *Keymap) Reset() {
+	k.pending = nil
+}
+
+// Keys returns the key sequences bound to command, the one that takes
+// precedence first.
+func (k *Keymap) Keys(command string) []Sequence {
+	var keys []Sequence
+	for i := len(k.bindings) - 1; i >= 0; i-- {
+		if k.bindings[i].Command == command {
+			keys = append(keys, k.bindings[i].Keys)
+		}
+	}
+	return keys
+}
+
+// Shortcut returns the key sequence shown for command, or "" if it has no
+// binding.
+func (k *Keymap) Shortcut(command string) string {
+	if keys := k.Keys(command); len(keys) > 0 {
+		return keys[0].String()
+	}
+	return ""
+}
+
+// fileBinding is an entry of a keybindings.json file.
+type fileBinding struct {
+	Key     string `json:"key"`
+	Command string `json:"command"`
+	When    string `json:"when,omitempty"`
+}
+
+// LoadFile reads the bindings of a keybindings.json file: an array of
+// {"key": "ctrl+k ctrl+c", "command": "edit.deleteLine", "when":
+// "editorFocus"} entries. A command starting with "-" removes a binding, as
+// described for Add; its key may then be left out. A missing file has no
+// bindings.
+func LoadFile(path string) ([]Binding, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []fileBinding
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("keybindings %s: %w", path, err)
+	}
+	bindings := make([]Binding, 0, len(entries))
+	for i, e := range entries {
+		if e.Command == "" || e.Command == "-" {
+			return nil, fmt.Errorf("keybindings %s: entry %d: missing command", path, i+1)
+		}
+		var b Binding
+		b.Command = e.Command
+		if e.Key != "" || !strings.HasPrefix(e.Command, "-") {
+			if b.Keys, err = ParseSequence(e.Key); err != nil {
+				return nil, fmt.Errorf("keybindings %s: entry %d: %w", path, i+1, err)
+			}
+		}
+		if b.When, err = ParseWhen(e.When); err != nil {
+			return nil, fmt.Errorf("keybindings %s: entry %d: %w", path, i+1, err)
+		}
+		bindings = append(bindings, b)
+	}
+	return bindings, nil
+}
@@ -0,0 +1,238 @@
+package keymap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/terminal"
+)
+
+func TestParseChord(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Chord
+		str  string
+	}{
+		{"ctrl+s", Chord{Key: terminal.KeyRune, Rune: 's', Ctrl: true}, "Ctrl+S"},
+		{"Ctrl+Shift+K", Chord{Key: terminal.KeyRune, Rune: 'k', Ctrl: true, Shift: true}, "Ctrl+Shift+K"},
+		{"ctrl+shift+[", Chord{Key: terminal.KeyRune, Rune: '[', Ctrl: true, Shift: true}, "Ctrl+Shift+["},
+		{"ctrl+{", Chord{Key: terminal.KeyRune, Rune: '[', Ctrl: true, Shift: true}, "Ctrl+Shift+["},
+		{"ctrl++", Chord{Key: terminal.KeyRune, Rune: '=', Ctrl: true, Shift: true}, "Ctrl+Shift+="},
+		{"ctrl+space", Chord{Key: terminal.KeyRune, Rune: ' ', Ctrl: true}, "Ctrl+Space"},
+		{"alt+up", Chord{Key: terminal.KeyUp, Alt: true}, "Alt+Up"},
+		{"shift+f12", Chord{Key: terminal.KeyF12, Shift: true}, "Shift+F12"},
+		{"Ctrl+PageDown", Chord{Key: terminal.KeyPageDown, Ctrl: true}, "Ctrl+PageDown"},
+	}
+	for _, tc := range cases {
+		got, err := ParseChord(tc.in)
+		if err != nil {
+			t.Errorf("ParseChord(%q): %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseChord(%q) = %+v, want %+v", tc.in, got, tc.want)
+		}
+		if got.String() != tc.str {
+			t.Errorf("ParseChord(%q).String() = %q, want %q", tc.in, got.String(), tc.str)
+		}
+	}
+	for _, bad := range []string{"", "hyper+x", "ctrl+", "ctrl+nosuchkey"} {
+		if _, err := ParseChord(bad); err == nil {
+			t.Errorf("ParseChord(%q) should fail", bad)
+		}
+	}
+}
+
+func TestFromKey(t *testing.T) {
+	cases := []struct {
+		msg  runtime.KeyMsg
+		want string
+	}{
+		{runtime.KeyMsg{Key: terminal.KeyCtrlD}, "Ctrl+D"},
+		{runtime.KeyMsg{Key: terminal.KeyRune, Rune: 's', Ctrl: true}, "Ctrl+S"},
+		{runtime.KeyMsg{Key: terminal.KeyRune, Rune: 'K', Ctrl: true, Shift: true}, "Ctrl+Shift+K"},
+		{runtime.KeyMsg{Key: terminal.KeyRune, Rune: 'K', Ctrl: true}, "Ctrl+Shift+K"},
+		{runtime.KeyMsg{Key: terminal.KeyRune, Rune: '}', Ctrl: true, Shift: true}, "Ctrl+Shift+]"},
+		{runtime.KeyMsg{Key: terminal.KeyF12, Shift: true}, "Shift+F12"},
+	}
+	for _, tc := range cases {
+		if got := FromKey(tc.msg).String(); got != tc.want {
+			t.Errorf("FromKey(%+v) = %q, want %q", tc.msg, got, tc.want)
+		}
+	}
+}
+
+func TestChordKeyMsg(t *testing.T) {
+	cases := []struct {
+		chord string
+		want  runtime.KeyMsg
+	}{
+		{"ctrl+d", runtime.KeyMsg{Key: terminal.KeyCtrlD}},
+		{"ctrl+s", runtime.KeyMsg{Key: terminal.KeyRune, Rune: 's', Ctrl: true}},
+		{"ctrl+shift+k", runtime.KeyMsg{Key: terminal.KeyRune, Rune: 'K', Ctrl: true, Shift: true}},
+		{"shift+a", runtime.KeyMsg{Key: terminal.KeyRune, Rune: 'A', Shift: true}},
+		{"(", runtime.KeyMsg{Key: terminal.KeyRune, Rune: '(', Shift: true}},
+		{"alt+up", runtime.KeyMsg{Key: terminal.KeyUp, Alt: true}},
+	}
+	for _, tc := range cases {
+		c, err := ParseChord(tc.chord)
+		if err != nil {
+			t.Fatalf("ParseChord(%q): %v", tc.chord, err)
+		}
+		got := c.KeyMsg()
+		if got != tc.want {
+			t.Errorf("%s.KeyMsg() = %+v, want %+v", c, got, tc.want)
+		}
+		if FromKey(got) != c {
+			t.Errorf("FromKey(%s.KeyMsg()) = %s", c, FromKey(got))
+		}
+	}
+}
+
+func TestParseWhen(t *testing.T) {
+	ctx := Context{"editorFocus": true, "blockSelection": true}
+	cases := map[string]bool{
+		"":                              true,
+		"editorFocus":                   true,
+		"largeFile":                     false,
+		"!largeFile":                    true,
+		"editorFocus && !largeFile":     true,
+		"largeFile || blockSelection":   true,
+		"!(editorFocus && largeFile)":   true,
+		"editorFocus && (a || b) || !c": true,
+		"largeFile && a || b":           false,
+	}
+	for expr, want := range cases {
+		w, err := ParseWhen(expr)
+		if err != nil {
+			t.Errorf("ParseWhen(%q): %v", expr, err)
+			continue
+		}
+		if got := w.Eval(ctx); got != want {
+			t.Errorf("ParseWhen(%q).Eval = %v, want %v", expr, got, want)
+		}
+	}
+	for _, bad := range []string{"&&", "a &&", "(a", "a b", "a)", "!"} {
+		if _, err := ParseWhen(bad); err == nil {
+			t.Errorf("ParseWhen(%q) should fail", bad)
+		}
+	}
+}
+
+func chord(t *testing.T, s string) Chord {
+	t.Helper()
+	c, err := ParseChord(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestKeymapPress(t *testing.T) {
+	k := New(
+		Bind("ctrl+s", "file.save", ""),
+		Bind("ctrl+k ctrl+c", "edit.comment", ""),
+		Bind("escape", "edit.clearBlock", "blockSelection"),
+		Bind("ctrl+d", "edit.addNext", "editorFocus"),
+		Bind("ctrl+d", "edit.other", "editorFocus && multiCursor"),
+	)
+	editor := Context{"editorFocus": true}
+
+	if cmd, res := k.Press(chord(t, "ctrl+s"), nil); res != Matched || cmd != "file.save" {
+		t.Errorf("Press(ctrl+s) = %q, %v", cmd, res)
+	}
+	if _, res := k.Press(chord(t, "ctrl+k"), nil); res != Pending {
+		t.Fatalf("Press(ctrl+k) = %v, want Pending", res)
+	}
+	if got := k.Pending().String(); got != "Ctrl+K" {
+		t.Errorf("Pending = %q", got)
+	}
+	if cmd, res := k.Press(chord(t, "ctrl+c"), nil); res != Matched || cmd != "edit.comment" {
+		t.Errorf("Press(ctrl+k ctrl+c) = %q, %v", cmd, res)
+	}
+	k.Press(chord(t, "ctrl+k"), nil)
+	if _, res := k.Press(chord(t, "x"), nil); res != Aborted || k.Pending() != nil {
+		t.Errorf("Press(ctrl+k x) = %v, want Aborted", res)
+	}
+
+	// when expressions select between bindings of the same key; the later
+	// binding wins when both hold.
+	if _, res := k.Press(chord(t, "escape"), editor); res != NoMatch {
+		t.Errorf("Press(escape) outside block selection = %v, want NoMatch", res)
+	}
+	if cmd, _ := k.Press(chord(t, "escape"), Context{"blockSelection": true}); cmd != "edit.clearBlock" {
+		t.Errorf("Press(escape) in block selection = %q", cmd)
+	}
+	if cmd, _ := k.Press(chord(t, "ctrl+d"), editor); cmd != "edit.addNext" {
+		t.Errorf("Press(ctrl+d) = %q, want edit.addNext", cmd)
+	}
+	if cmd, _ := k.Press(chord(t, "ctrl+d"), Context{"editorFocus": true, "multiCursor": true}); cmd != "edit.other" {
+		t.Errorf("Press(ctrl+d) with multiCursor = %q, want edit.other", cmd)
+	}
+	if _, res := k.Press(chord(t, "ctrl+d"), nil); res != NoMatch {
+		t.Errorf("Press(ctrl+d) without editorFocus = %v, want NoMatch", res)
+	}
+}
+
+func TestKeymapOverridesAndShortcut(t *testing.T) {
+	k := New(
+		Bind("ctrl+shift+k", "edit.deleteLine", ""),
+		Bind("ctrl+s", "file.save", ""),
+	)
+	k.Add(Bind("ctrl+k ctrl+d", "edit.deleteLine", ""))
+	if got := k.Shortcut("edit.deleteLine"); got != "Ctrl+K Ctrl+D" {
+		t.Errorf("Shortcut = %q, want the latest binding", got)
+	}
+	if got := len(k.Keys("edit.deleteLine")); got != 2 {
+		t.Errorf("Keys = %d bindings, want 2", got)
+	}
+
+	k.Add(Binding{Command: "-edit.deleteLine", Keys: Sequence{chord(t, "ctrl+shift+k")}})
+	if got := k.Keys("edit.deleteLine"); len(got) != 1 || got[0].String() != "Ctrl+K Ctrl+D" {
+		t.Errorf("Keys after removing one = %v", got)
+	}
+	k.Add(Binding{Command: "-file.save"})
+	if got := k.Shortcut("file.save"); got != "" {
+		t.Errorf("Shortcut after removing all = %q", got)
+	}
+	if _, res := k.Press(chord(t, "ctrl+s"), nil); res != NoMatch {
+		t.Errorf("Press(ctrl+s) after removal = %v", res)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keybindings.json")
+	if got, err := LoadFile(path); got != nil || err != nil {
+		t.Fatalf("LoadFile of a missing file = %v, %v", got, err)
+	}
+	data := `[
+		{"key": "ctrl+k ctrl+c", "command": "edit.deleteLine", "when": "editorFocus && !largeFile"},
+		{"command": "-file.save"}
+	]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(got) != 2 || got[0].Keys.String() != "Ctrl+K Ctrl+C" || got[0].When.String() != "editorFocus && !largeFile" || got[1].Command != "-file.save" {
+		t.Errorf("LoadFile = %+v", got)
+	}
+
+	for _, bad := range []string{
+		`{"key": "ctrl+s"}`,
+		`[{"key": "ctrl+s"}]`,
+		`[{"key": "hyper+s", "command": "file.save"}]`,
+		`[{"key": "ctrl+s", "command": "file.save", "when": "a &&"}]`,
+	} {
+		if err := os.WriteFile(path, []byte(bad), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFile(path); err == nil {
+			t.Errorf("LoadFile(%s) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package keymap
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Context holds the context keys when expressions test, such as
+// "editorFocus". Keys that are not set are false.
+type Context map[string]bool
+
+// When is a compiled when expression. The zero value is always true.
+type When struct {
+	source string
+	eval   func(Context) bool
+}
+
+// ParseWhen compiles a when expression: context keys combined with !, &&,
+// || and parentheses, such as "editorFocus && !largeFile". An empty
+// expression is always true.
+func ParseWhen(s string) (When, error) {
+	if strings.TrimSpace(s) == "" {
+		return When{}, nil
+	}
+	p := &whenParser{src: s}
+	eval, err := p.or()
+	if err == nil && p.next() != "" {
+		err = fmt.Errorf("unexpected %q", p.next())
+	}
+	if err != nil {
+		return When{}, fmt.Errorf("when %q: %w", s, err)
+	}
+	return When{source: strings.TrimSpace(s), eval: eval}, nil
+}
+
+// Eval reports whether the expression holds in ctx.
+func (w When) Eval(ctx Context) bool {
+	return w.eval == nil || w.eval(ctx)
+}
+
+// String returns the expression as written.
+func (w When) String() string {
+	return w.source
+}
+
+type whenParser struct {
+	src string
+	pos int
+}
+
+// next returns the next token without consuming it: an operator, a
+// parenthesis or a context key; "" at the end.
+func (p *whenParser) next() string {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	rest := p.src[p.pos:]
+	switch {
+	case rest == "":
+		return ""
+	case strings.HasPrefix(rest, "&&"), strings.HasPrefix(rest, "||"):
+		return rest[:2]
+	case rest[0] == '!' || rest[0] == '(' || rest[0] == ')':
+		return rest[:1]
+	}
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' && r != '-'
+	})
+	if end < 0 {
+		end = len(rest)
+	}
+	if end == 0 {
+		return rest[:1]
+	}
+	return rest[:end]
+}
+
+func (p *whenParser) take() string {
+	tok := p.next()
+	p.pos += len(tok)
+	return tok
+}
+
+func (p *whenParser) or() (func(Context) bool, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.next() == "||" {
+		p.take()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(ctx Context) bool { return l(ctx) || right(ctx) }
+	}
+	return left, nil
+}
+
+func (p *whenParser) and() (func(Context) bool, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.next() == "&&" {
+		p.take()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(ctx Context) bool { return l(ctx) && right(ctx) }
+	}
+	return left, nil
+}
+
+func (p *whenParser) unary() (func(Context) bool, error) {
+	switch tok := p.take(); tok {
+	case "!":
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx Context) bool { return !inner(ctx) }, nil
+	case "(":
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.take() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return inner, nil
+	case "", ")", "&&", "||":
+		return nil, fmt.Errorf("expected a context key")
+	default:
+		if !unicode.IsLetter([]rune(tok)[0]) {
+			return nil, fmt.Errorf("unexpected %q", tok)
+		}
+		return func(ctx Context) bool { return ctx[tok] }, nil
+	}
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/odvcencio/fluffyui/backend"
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/terminal"
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/editor"
+)
+
+// largeFileTabSize is the width tabs are expanded to in the large-file view.
+const largeFileTabSize = 4
+
+// largeFileView displays a buffer opened in large-file mode. Only the
+// visible lines are read from the buffer, so drawing and moving around cost
+// the same however big the file is. There is no syntax highlighting, folding
+// or word wrap. Typing edits the buffer directly unless it is read-only.
+type largeFileView struct {
+	widgets.Base
+	buf   *editor.Buffer
+	shown bool // the editor pane displays this view
+
+	row, col  int          // cursor line and rune column
+	top, left int          // first visible line and screen column
+	match     editor.Range // highlighted search match; empty if none
+
+	gutterStyle    backend.Style
+	gutterStyleSet bool
+	matchStyle     backend.Style
+
+	onChange func()      // the buffer was edited
+	onMove   func()      // the cursor moved
+	onReload func(error) // the buffer was mapped afresh after a fault
+}
+
+func newLargeFileView() *largeFileView {
+	return &largeFileView{
+		matchStyle: backend.DefaultStyle().Background(backend.ColorRGB(0xFF, 0x88, 0x00)).Foreground(backend.ColorBlack),
+	}
+}
+
+// setBuffer shows buf, moving the cursor to the top if it is a different
+// buffer.
+func (v *largeFileView) setBuffer(buf *editor.Buffer) {
+	if v.buf != buf {
+		v.row, v.col, v.top, v.left = 0, 0, 0, 0
+		v.match = editor.Range{}
+	}
+	v.buf = buf
+	v.clampCursor()
+}
+
+// SetGutterStyle sets the style of the line number gutter.
+func (v *largeFileView) SetGutterStyle(style backend.Style) {
+	v.gutterStyle = style
+	v.gutterStyleSet = true
+}
+
+// StyleType makes the view pick up the stylesheet rules for TextArea.
+func (v *largeFileView) StyleType() string {
+	return "TextArea"
+}
+
+func (v *largeFileView) CanFocus() bool {
+	return v.shown
+}
+
+func (v *largeFileView) Measure(constraints runtime.Constraints) runtime.Size {
+	return constraints.MaxSize()
+}
+
+// cursorPosition returns the cursor column and line, like
+// TextArea.CursorPosition.
+func (v *largeFileView) cursorPosition() (col, row int) {
+	return v.col, v.row
+}
+
+// cursorOffset returns the byte offset of the cursor in the buffer.
+func (v *largeFileView) cursorOffset() int {
+	line := v.buf.Line(v.row)
+	return v.buf.LineOffset(v.row) + runeByteOffset(line, v.col)
+}
+
+// setCursorOffset moves the cursor to the byte offset off.
+func (v *largeFileView) setCursorOffset(off int) {
+	v.row = v.buf.LineAt(off)
+	start := v.buf.LineOffset(v.row)
+	v.col = utf8.RuneCountInString(v.buf.Slice(start, off))
+	v.clampCursor()
+}
+
+// gotoLine moves the cursor to the start of the 0-based line.
+func (v *largeFileView) gotoLine(line int) {
+	v.row, v.col = line, 0
+	v.clampCursor()
+}
+
+// showMatch moves the cursor to r and highlights it.
+func (v *largeFileView) showMatch(r editor.Range) {
+	v.match = r
+	v.setCursorOffset(r.Start)
+}
+
+// clampCursor keeps the cursor inside the text after it moved or the text
+// changed.
+func (v *largeFileView) clampCursor() {
+	if v.buf == nil {
+		return
+	}
+	v.row = min(max(v.row, 0), v.buf.LineCount()-1)
+	v.col = min(max(v.col, 0), utf8.RuneCountInString(v.buf.Line(v.row)))
+}
+
+// runeByteOffset returns the byte offset of the rune at column col of line.
+func runeByteOffset(line string, col int) int {
+	off := 0
+	for i := 0; i < col && off < len(line); i++ {
+		_, size := utf8.DecodeRuneInString(line[off:])
+		off += size
+	}
+	return off
+}
+
+// screenColumn returns the screen column of rune column col in line, with
+// tabs expanded.
+func screenColumn(line string, col int) int {
+	x := 0
+	for i, r := range []rune(line) {
+		if i >= col {
+			break
+		}
+		if r == '\t' {
+			x += largeFileTabSize - x%largeFileTabSize
+		} else {
+			x++
+		}
+	}
+	return x
+}
+
+// gutterWidth returns the width of the line numbers plus a separator.
+func (v *largeFileView) gutterWidth() int {
+	return len(strconv.Itoa(v.buf.LineCount())) + 1
+}
+
+// scrollToCursor adjusts the scroll position so the cursor is visible in a
+// text area of the given size.
+func (v *largeFileView) scrollToCursor(width, height int) {
+	if v.row < v.top {
+		v.top = v.row
+	} else if v.row >= v.top+height {
+		v.top = v.row - height + 1
+	}
+	x := screenColumn(v.buf.Line(v.row), v.col)
+	if x < v.left {
+		v.left = x
+	} else if x >= v.left+width {
+		v.left = x - width + 1
+	}
+}
+
+func (v *largeFileView) Render(ctx runtime.RenderContext) {
+	b := v.Bounds()
+	if b.Width <= 0 || b.Height <= 0 {
+		return
+	}
+	style := backend.DefaultStyle()
+	if resolved := ctx.ResolveStyle(v); !resolved.IsZero() {
+		style = resolved.ToBackend()
+	}
+	ctx.Buffer.Fill(b, ' ', style)
+	if v.buf == nil {
+		return
+	}
+	gs := style.Dim(true)
+	if v.gutterStyleSet {
+		gs = v.gutterStyle
+	}
+
+	gw := v.gutterWidth()
+	textX, textW := b.X+gw, b.Width-gw
+	if textW <= 0 {
+		return
+	}
+	v.guard(func() {
+		v.scrollToCursor(textW, b.Height)
+		for i := 0; i < b.Height; i++ {
+			line := v.top + i
+			if line >= v.buf.LineCount() {
+				break
+			}
+			y := b.Y + i
+			ctx.Buffer.SetString(b.X, y, fmt.Sprintf("%*d ", gw-1, line+1), gs)
+			v.renderLine(ctx.Buffer, textX, y, textW, line, style)
+		}
+	})
+}
+
+// renderLine draws the visible part of line at x, y, with the search match
+// and the cursor highlighted.
+func (v *largeFileView) renderLine(buf *runtime.Buffer, x, y, width, line int, style backend.Style) {
+	text := v.buf.Line(line)
+	start := v.buf.LineOffset(line)
+	cursor := -1
+	if line == v.row && v.IsFocused() {
+		cursor = v.col
+	}
+	col, sx := 0, 0
+	for i, r := range text {
+		cellStyle := style
+		if off := start + i; off >= v.match.Start && off < v.match.End {
+			cellStyle = v.matchStyle
+		}
+		if col == cursor {
+			cellStyle = cellStyle.Reverse(true)
+		}
+		cells := 1
+		if r == '\t' {
+			cells = largeFileTabSize - sx%largeFileTabSize
+			r = ' '
+		}
+		for ; cells > 0; cells-- {
+			if sx >= v.left && sx < v.left+width {
+				buf.Set(x+sx-v.left, y, r, cellStyle)
+			}
+			sx++
+			r = ' '
+		}
+		if sx >= v.left+width {
+			return
+		}
+		col++
+	}
+	if col == cursor && sx >= v.left && sx < v.left+width {
+		buf.Set(x+sx-v.left, y, ' ', style.Reverse(true))
+	}
+}
+
+func (v *largeFileView) HandleMessage(msg runtime.Message) runtime.HandleResult {
+	if v.buf == nil {
+		return runtime.Unhandled()
+	}
+	result := runtime.Unhandled()
+	v.guard(func() {
+		switch msg := msg.(type) {
+		case runtime.MouseMsg:
+			result = v.handleMouse(msg)
+		case runtime.KeyMsg:
+			if v.IsFocused() {
+				result = v.handleKey(msg)
+			}
+		}
+	})
+	return result
+}
+
+// guard runs fn, which reads the buffer. If the file was truncated on disk
+// under its mapping, reading faults; the buffer is then mapped afresh and
+// onReload told, rather than the editor crashing, and guard reports false.
+func (v *largeFileView) guard(fn func()) bool {
+	err := v.buf.GuardMapped(fn)
+	if err == nil {
+		return true
+	}
+	v.match = editor.Range{}
+	v.clampCursor()
+	if v.onReload != nil {
+		v.onReload(err)
+	}
+	return false
+}
+
+func (v *largeFileView) handleMouse(mouse runtime.MouseMsg) runtime.HandleResult {
+	b := v.Bounds()
+	if mouse.X < b.X || mouse.X >= b.X+b.Width || mouse.Y < b.Y || mouse.Y >= b.Y+b.Height {
+		return runtime.Unhandled()
+	}
+	switch mouse.Button {
+	case runtime.MouseWheelUp:
+		v.top = max(v.top-3, 0)
+		v.moveLines(-3)
+	case runtime.MouseWheelDown:
+		v.top = min(v.top+3, v.buf.LineCount()-1)
+		v.moveLines(3)
+	case runtime.MouseLeft:
+		if mouse.Action != runtime.MousePress {
+			return runtime.Unhandled()
+		}
+		v.row = v.top + mouse.Y - b.Y
+		v.clampCursor()
+		v.col = columnAtScreen(v.buf.Line(v.row), mouse.X-b.X-v.gutterWidth()+v.left)
+		v.moved()
+	default:
+		return runtime.Unhandled()
+	}
+	return runtime.Handled()
+}
+
+// columnAtScreen returns the rune column of line shown at screen column x.
+func columnAtScreen(line string, x int) int {
+	sx := 0
+	for i, r := range []rune(line) {
+		w := 1
+		if r == '\t' {
+			w = largeFileTabSize - sx%largeFileTabSize
+		}
+		if x < sx+w {
+			return i
+		}
+		sx += w
+	}
+	return utf8.RuneCountInString(line)
+}
+
+func (v *largeFileView) handleKey(key runtime.KeyMsg) runtime.HandleResult {
+	b := v.Bounds()
+	switch key.Key {
+	case terminal.KeyUp:
+		v.moveLines(-1)
+	case terminal.KeyDown:
+		v.moveLines(1)
+	case terminal.KeyPageUp:
+		v.moveLines(-max(b.Height-1, 1))
+	case terminal.KeyPageDown:
+		v.moveLines(max(b.Height-1, 1))
+	case terminal.KeyLeft:
+		if v.col > 0 {
+			v.col--
+		} else if v.row > 0 {
+			v.row--
+			v.col = utf8.RuneCountInString(v.buf.Line(v.row))
+		}
+		v.moved()
+	case terminal.KeyRight:
+		if v.col < utf8.RuneCountInString(v.buf.Line(v.row)) {
+			v.col++
+		} else if v.row < v.buf.LineCount()-1 {
+			v.row++
+			v.col = 0
+		}
+		v.moved()
+	case terminal.KeyHome:
+		if key.Ctrl {
+			v.row = 0
+		}
+		v.col = 0
+		v.moved()
+	case terminal.KeyEnd:
+		if key.Ctrl {
+			v.row = v.buf.LineCount() - 1
+		}
+		v.col = utf8.RuneCountInString(v.buf.Line(v.row))
+		v.moved()
+	case terminal.KeyEnter:
+		v.insert("\n")
+	case terminal.KeyTab:
+		v.insert("\t")
+	case terminal.KeyBackspace:
+		v.deleteBackward()
+	case terminal.KeyDelete:
+		v.deleteForward()
+	case terminal.KeyRune:
+		if key.Ctrl || key.Alt || key.Rune == 0 {
+			return runtime.Unhandled()
+		}
+		v.insert(string(key.Rune))
+	default:
+		return runtime.Unhandled()
+	}
+	return runtime.Handled()
+}
+
+// moveLines moves the cursor down by delta lines, or up if negative.
+func (v *largeFileView) moveLines(delta int) {
+	v.row += delta
+	v.clampCursor()
+	v.moved()
+}
+
+func (v *largeFileView) moved() {
+	v.match = editor.Range{}
+	if v.onMove != nil {
+		v.onMove()
+	}
+}
+
+// insert types text at the cursor. Line breaks are written in the file's
+// line ending style, since large buffers keep the text as it is on disk.
+func (v *largeFileView) insert(text string) {
+	text = editor.ApplyLineEnding(text, v.buf.LineEnding())
+	off := v.cursorOffset()
+	if !v.edit("Typing", off, off, text) {
+		return
+	}
+	v.setCursorOffset(off + len(text))
+	v.changed()
+}
+
+// deleteBackward deletes the character before the cursor, joining the line
+// with the previous one at the start of a line.
+func (v *largeFileView) deleteBackward() {
+	off := v.cursorOffset()
+	start := off
+	if v.col > 0 {
+		_, size := utf8.DecodeLastRuneInString(v.buf.Slice(v.buf.LineOffset(v.row), off))
+		start -= size
+	} else if v.row > 0 {
+		start = v.buf.LineOffset(v.row-1) + len(v.buf.Line(v.row-1))
+	}
+	if start == off || !v.edit("Delete", start, off, "") {
+		return
+	}
+	v.setCursorOffset(start)
+	v.changed()
+}
+
+// deleteForward deletes the character after the cursor, joining the next
+// line at the end of a line.
+func (v *largeFileView) deleteForward() {
+	off := v.cursorOffset()
+	line := v.buf.Line(v.row)
+	end := off
+	if rest := line[runeByteOffset(line, v.col):]; rest != "" {
+		_, size := utf8.DecodeRuneInString(rest)
+		end += size
+	} else if v.row < v.buf.LineCount()-1 {
+		end = v.buf.LineOffset(v.row + 1)
+	}
+	if end == off || !v.edit("Delete", off, end, "") {
+		return
+	}
+	v.changed()
+}
+
+// edit replaces [start, end) with text as one undo step. It reports false,
+// changing nothing, if the buffer is read-only.
+func (v *largeFileView) edit(label string, start, end int, text string) bool {
+	return v.buf.ApplyEdits(label, []editor.TextEdit{{Start: start, End: end, Text: text}}) > 0
+}
+
+func (v *largeFileView) changed() {
+	v.match = editor.Range{}
+	if v.onChange != nil {
+		v.onChange()
+	}
+}
+
+// activeLargeBuffer returns the active buffer if it is in large-file mode.
+func (a *maneApp) activeLargeBuffer() *editor.Buffer {
+	if buf := a.tabs.ActiveBuffer(); buf != nil && buf.Large() {
+		return buf
+	}
+	return nil
+}
+
+// showLargeFile switches the editor to the large-file view of buf. The
+// TextArea is emptied and tree-sitter is switched off, so nothing else
+// processes the whole text.
+func (a *maneApp) showLargeFile(buf *editor.Buffer) {
+	a.suppressChange = true
+	a.textArea.SetText("")
+	a.suppressChange = false
+	a.highlight.setup("")
+	a.syntaxHighlights = nil
+	a.longLines = nil
+	a.searchMatches = nil
+	a.clearBlockSelection()
+	a.foldState.SetRegions(nil)
+	a.textArea.SetVisibleLines(nil)
+	a.syncMultiCursorFromTextArea()
+	a.mergeAllHighlights()
+	a.largeView.setBuffer(buf)
+}
+
+// syncEditorPane shows the large-file view or the TextArea to match the
+// active buffer, moving focus along if the editor had it.
+func (a *maneApp) syncEditorPane() {
+	large := a.activeLargeBuffer() != nil
+	if a.pane == nil || a.largeView.shown == large {
+		return
+	}
+	hadFocus := a.textArea.IsFocused() || a.largeView.IsFocused()
+	a.largeView.shown = large
+	if a.rt == nil {
+		// Not running yet; the first layout places the right widget.
+		return
+	}
+	if screen := a.rt.Screen(); screen != nil && hadFocus {
+		var target runtime.Focusable = a.textArea
+		if large {
+			target = a.largeView
+		}
+		screen.BaseFocusScope().SetFocus(target)
+	}
+	a.rt.Relayout()
+}
+
+// onLargeFileChange updates the UI after the large-file view edited its
+// buffer.
+func (a *maneApp) onLargeFileChange() {
+	if buf := a.activeLargeBuffer(); buf != nil {
+		a.notifyFileResource(buf.Path())
+	}
+	a.syncTabBar()
+	a.updateStatus()
+}
+
+// onLargeFileReload reports that the large-file view's buffer was mapped
+// afresh because the file shrank on disk under it.
+func (a *maneApp) onLargeFileReload(err error) {
+	if errors.Is(err, editor.ErrFileTruncated) && err != editor.ErrFileTruncated {
+		a.status.Set(fmt.Sprintf(" File shrank on disk; reload failed: %v", err))
+	} else {
+		a.status.Set(" File shrank on disk; reloaded")
+	}
+	a.syncTabBar()
+}
+
+// cmdToggleReadOnly makes the active large-file buffer writable, or
+// read-only again.
+func (a *maneApp) cmdToggleReadOnly() {
+	buf := a.activeLargeBuffer()
+	if buf == nil {
+		a.status.Set(" Read-only mode is only available for large files")
+		return
+	}
+	buf.SetReadOnly(!buf.ReadOnly())
+	a.updateStatus()
+}
+
+// textAreaOnly wraps a command that works on the TextArea or the whole
+// buffer text, so that it reports instead of running in large-file mode.
+func (a *maneApp) textAreaOnly(fn func()) func() {
+	return func() {
+		if a.activeLargeBuffer() != nil {
+			a.status.Set(" Not available in large-file mode")
+			return
+		}
+		fn()
+	}
+}
+
+// largeFileSearch moves to the next match of query from the cursor, or the
+// previous one with backward set. Matches are not counted, since that would
+// mean scanning the whole file.
+func (a *maneApp) largeFileSearch(buf *editor.Buffer, query string, backward bool) {
+	a.search.SetMatchInfo(0, 0)
+	if query == "" {
+		a.largeView.match = editor.Range{}
+		return
+	}
+	var r editor.Range
+	var ok bool
+	var err error
+	if !a.largeView.guard(func() {
+		from := a.largeView.cursorOffset()
+		if m := a.largeView.match; m.End > m.Start && !backward {
+			from = m.End
+		}
+		r, ok, err = buf.FindNext(query, from, a.findOptions, backward)
+	}) {
+		return
+	}
+	if err != nil {
+		a.status.Set(fmt.Sprintf(" Invalid regex: %v", err))
+		return
+	}
+	if !ok {
+		a.largeView.match = editor.Range{}
+		a.status.Set(" No matches")
+		return
+	}
+	a.largeView.showMatch(r)
+	a.updateStatus()
+}
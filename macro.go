@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/terminal"
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/editor"
+	"github.com/odvcencio/mane/keymap"
+)
+
+// macroState is the keyboard macro being recorded or played, and the
+// macros kept between runs.
+type macroState struct {
+	store     *editor.MacroStore
+	recording bool
+	steps     editor.Macro // macro being recorded
+	playing   bool
+}
+
+// loadMacros reads the macros kept in the state directory. Without one,
+// macros last until mane quits.
+func (a *maneApp) loadMacros() error {
+	path, err := editor.DefaultMacroPath()
+	if err != nil {
+		return nil
+	}
+	store, err := editor.LoadMacros(path)
+	a.macro.store = store
+	return err
+}
+
+// saveMacros writes the macros and shows msg, or the error.
+func (a *maneApp) saveMacros(msg string) {
+	if a.macro.store.Path != "" {
+		if err := a.macro.store.Save(); err != nil {
+			a.status.Set(fmt.Sprintf(" Macro error: %v", err))
+			return
+		}
+	}
+	a.status.Set(msg)
+}
+
+// recordsMacroKeys reports whether keys pressed now go into the macro
+// being recorded. Keys typed into the command palette are left out; the
+// command run from it is recorded instead.
+func (a *maneApp) recordsMacroKeys() bool {
+	return a.macro.recording && !a.macro.playing && !a.palette.Open()
+}
+
+// recordMacroKey adds key to the macro being recorded. Characters typed are
+// kept as text; other keys in keybinding notation.
+func (a *maneApp) recordMacroKey(key runtime.KeyMsg) {
+	if !a.recordsMacroKeys() {
+		return
+	}
+	chord := keymap.FromKey(key)
+	if chord.Key == terminal.KeyRune && !chord.Ctrl && !chord.Alt && key.Rune != 0 {
+		a.macro.steps.AddText(string(key.Rune))
+		return
+	}
+	name := chord.String()
+	if _, err := keymap.ParseChord(name); err != nil {
+		return // a key without a name cannot be played back
+	}
+	a.macro.steps.AddKey(name)
+}
+
+// unrecordMacroKeys takes the n keys that ran command back out of the
+// macro being recorded if command controls macros or opens the command
+// palette, so playing the macro does neither.
+func (a *maneApp) unrecordMacroKeys(command string, n int) {
+	if a.recordsMacroKeys() && (strings.HasPrefix(command, "macro.") || command == "app.commandPalette") {
+		a.macro.steps.DropKeys(n)
+	}
+}
+
+// recordMacroCommand adds a command run from the command palette to the
+// macro being recorded.
+func (a *maneApp) recordMacroCommand(cmd widgets.PaletteCommand) {
+	if a.macro.recording && !a.macro.playing && !strings.HasPrefix(cmd.ID, "macro.") {
+		a.macro.steps.AddCommand(cmd.ID)
+	}
+}
+
+// cmdRecordMacro starts recording a macro, or stops and keeps it as the
+// macro to play.
+func (a *maneApp) cmdRecordMacro() {
+	if a.macro.playing {
+		return
+	}
+	if !a.macro.recording {
+		a.macro.recording = true
+		a.macro.steps = nil
+		a.status.Set(" Recording macro...")
+		return
+	}
+	a.macro.recording = false
+	if len(a.macro.steps) == 0 {
+		a.status.Set(" Macro is empty")
+		return
+	}
+	a.macro.store.Last = a.macro.steps
+	a.macro.steps = nil
+	a.saveMacros(" Macro recorded: " + a.macro.store.Last.String())
+}
+
+// cmdPlayMacro plays the last macro recorded.
+func (a *maneApp) cmdPlayMacro() {
+	a.playMacro(a.macro.store.Last, 1)
+}
+
+// cmdPlayMacroTimes asks how many times to play the last macro.
+func (a *maneApp) cmdPlayMacroTimes() {
+	if !a.canPlayMacro(a.macro.store.Last) {
+		return
+	}
+	a.ask(" Play macro how many times: ", promptNumber, func(answer string) {
+		n, err := strconv.Atoi(answer)
+		if err != nil || n <= 0 {
+			a.status.Set(" Invalid count")
+			return
+		}
+		a.playMacro(a.macro.store.Last, n)
+	})
+}
+
+// cmdPlayMacroOnLines plays the last macro once on each line of the
+// selection, starting at the beginning of the line. Lines the macro adds
+// or removes are accounted for.
+func (a *maneApp) cmdPlayMacroOnLines() {
+	m := a.macro.store.Last
+	if !a.canPlayMacro(m) {
+		return
+	}
+	first, last, ok := a.selectedLineRange()
+	if !ok {
+		a.status.Set(" No selection")
+		return
+	}
+	a.textArea.SelectNone()
+	a.macro.playing = true
+	defer func() { a.macro.playing = false }()
+	for line := first; line <= last; line++ {
+		before := editor.LineCount(a.textArea.Text())
+		a.ensureLineVisible(line)
+		a.textArea.SetCursorPosition(0, line)
+		a.syncMultiCursorFromTextArea()
+		if !a.playMacroSteps(m) {
+			return
+		}
+		grown := editor.LineCount(a.textArea.Text()) - before
+		line += grown
+		last += grown
+	}
+}
+
+// cmdSaveMacroRegister asks for a register to keep the last macro in.
+func (a *maneApp) cmdSaveMacroRegister() {
+	if len(a.macro.store.Last) == 0 {
+		a.status.Set(" No macro recorded")
+		return
+	}
+	a.ask(" Save macro to register (a-z, 0-9): ", promptRegister, func(name string) {
+		a.macro.store.Registers[name] = a.macro.store.Last
+		a.saveMacros(" Macro saved to register " + name)
+	})
+}
+
+// cmdPlayMacroRegister asks for a register and plays the macro in it.
+func (a *maneApp) cmdPlayMacroRegister() {
+	names := a.macro.store.Names()
+	if len(names) == 0 {
+		a.status.Set(" No macros saved to registers")
+		return
+	}
+	a.ask(fmt.Sprintf(" Play macro from register (%s): ", strings.Join(names, ", ")), promptRegister, func(name string) {
+		m, ok := a.macro.store.Registers[name]
+		if !ok {
+			a.status.Set(" Register " + name + " is empty")
+			return
+		}
+		a.playMacro(m, 1)
+	})
+}
+
+// canPlayMacro reports whether m can be played now, showing why not.
+func (a *maneApp) canPlayMacro(m editor.Macro) bool {
+	switch {
+	case a.macro.playing:
+		return false // a macro playing itself
+	case a.macro.recording:
+		a.status.Set(" Cannot play a macro while recording one")
+		return false
+	case len(m) == 0:
+		a.status.Set(" No macro recorded")
+		return false
+	}
+	return true
+}
+
+// playMacro plays m the given number of times, stopping at a step that
+// cannot be played.
+func (a *maneApp) playMacro(m editor.Macro, times int) {
+	if !a.canPlayMacro(m) {
+		return
+	}
+	a.macro.playing = true
+	defer func() { a.macro.playing = false }()
+	for range times {
+		if !a.playMacroSteps(m) {
+			return
+		}
+	}
+}
+
+// playMacroSteps plays each step of m the way it was recorded: keys go
+// through the screen like typed ones, so the key bindings, multiple
+// cursors, folding and completion see them as they would a typed key, and
+// commands run as from the command palette. It reports false if a step
+// could not be played.
+func (a *maneApp) playMacroSteps(m editor.Macro) bool {
+	for _, step := range m {
+		switch {
+		case step.Text != "":
+			for _, r := range step.Text {
+				a.playKey(runtime.KeyMsg{Key: terminal.KeyRune, Rune: r})
+			}
+		case step.Key != "":
+			chord, err := keymap.ParseChord(step.Key)
+			if err != nil {
+				a.status.Set(fmt.Sprintf(" Macro error: %v", err))
+				return false
+			}
+			a.playKey(chord.KeyMsg())
+		default:
+			i := slices.IndexFunc(a.commandList, func(c widgets.PaletteCommand) bool { return c.ID == step.Command })
+			if i < 0 {
+				a.status.Set(fmt.Sprintf(" Macro error: unknown command %q", step.Command))
+				return false
+			}
+			a.commandList[i].OnExecute()
+		}
+	}
+	return true
+}
+
+// playKey sends key to the widgets as the event loop does with a typed
+// key.
+func (a *maneApp) playKey(key runtime.KeyMsg) {
+	if a.rt == nil || a.rt.Screen() == nil {
+		a.handleGlobalKey(key)
+		return
+	}
+	result := a.rt.Screen().HandleMessage(key)
+	for _, cmd := range result.Commands {
+		a.rt.ExecuteCommand(cmd)
+	}
+}
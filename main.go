@@ -19,6 +19,8 @@ func main() {
 	webUI := flag.String("webui", "", "custom web UI address (Monaco Editor frontend)")
 	mcp := flag.String("mcp", "", "MCP server address (empty = default socket)")
 	theme := flag.String("theme", "dark", "theme name")
+	largeFileMB := flag.Int64("large-file-mb", editor.DefaultLargeFileThreshold>>20, "open files bigger than this many MiB in large-file mode (0 = never)")
+	session := flag.String("session", "", "named session to open and save (default: the session of the directory)")
 	flag.Parse()
 
 	args := flag.Args()
@@ -44,7 +46,12 @@ func main() {
 		opts = append(opts, fluffy.WithMCP(*mcp))
 	}
 
-	if err := run(ctx, args, *theme, opts...); err != nil {
+	cfg := runConfig{
+		theme:         *theme,
+		largeFileSize: *largeFileMB << 20,
+		session:       *session,
+	}
+	if err := run(ctx, args, cfg, opts...); err != nil {
 		fmt.Fprintf(os.Stderr, "mane: %v\n", err)
 		os.Exit(1)
 	}
@@ -70,6 +77,9 @@ func (s *webUIEditorState) OpenFile(path string) (string, error) {
 	if buf == nil {
 		return "", fmt.Errorf("failed to open buffer")
 	}
+	if buf.Large() {
+		return "", fmt.Errorf("%s is too large for the web UI", buf.Title())
+	}
 	return buf.Text(), nil
 }
 
@@ -85,7 +95,7 @@ func (s *webUIEditorState) ReadBuffer(path string) (string, error) {
 func (s *webUIEditorState) WriteBuffer(path string, text string) error {
 	for _, buf := range s.tabs.Buffers() {
 		if buf.Path() == path {
-			buf.SetText(text)
+			buf.ApplyText("Web Edit", text)
 			return nil
 		}
 	}
@@ -95,7 +105,10 @@ func (s *webUIEditorState) WriteBuffer(path string, text string) error {
 func (s *webUIEditorState) SaveFile(path string) error {
 	for _, buf := range s.tabs.Buffers() {
 		if buf.Path() == path {
-			return buf.Save()
+			if err := buf.Save(); err != nil {
+				return err
+			}
+			return s.tabs.SaveUndo(buf)
 		}
 	}
 	return fmt.Errorf("buffer not open: %s", path)
@@ -150,6 +163,10 @@ func runWebUI(ctx context.Context, paths []string, addr string) error {
 		tabs: editor.NewTabManager(),
 		root: root,
 	}
+	if dir, err := editor.DefaultUndoDir(); err == nil {
+		state.tabs.SetUndoStore(editor.NewUndoStore(dir))
+	}
+	defer state.tabs.SaveAllUndo()
 
 	srv := web.NewServer(state, root)
 	server := &http.Server{Addr: addr, Handler: srv}
@@ -15,6 +15,7 @@ import (
 	"github.com/odvcencio/fluffyui/runtime"
 	"github.com/odvcencio/gotreesitter"
 	"github.com/odvcencio/gotreesitter/grammars"
+	"github.com/odvcencio/mane/editor"
 	"github.com/odvcencio/mane/lsp"
 	"github.com/odvcencio/mane/mcptools"
 )
@@ -159,6 +160,12 @@ func registerMCPRegistry(app *runtime.App, srv *fluffymcp.Server, reg *mcptools.
 
 var _ mcptools.EditorAccess = (*maneApp)(nil)
 
+// errLargeFile reports that a tool needs the whole text of a buffer opened in
+// large-file mode.
+func errLargeFile(buf *editor.Buffer) error {
+	return fmt.Errorf("%s is open in large-file mode", buf.Title())
+}
+
 func (a *maneApp) OpenFile(path string) error {
 	if path == "" {
 		return fmt.Errorf("path is required")
@@ -167,16 +174,19 @@ func (a *maneApp) OpenFile(path string) error {
 }
 
 func (a *maneApp) ReadBuffer(path string) (string, error) {
-	if path == "" {
-		if buf := a.tabs.ActiveBuffer(); buf != nil {
-			return buf.Text(), nil
-		}
-		return "", fmt.Errorf("no active buffer")
+	buf := a.tabs.ActiveBuffer()
+	if path != "" {
+		buf = a.findBufferByPath(path)
 	}
-	if buf := a.findBufferByPath(path); buf != nil {
-		return buf.Text(), nil
+	switch {
+	case buf == nil && path == "":
+		return "", fmt.Errorf("no active buffer")
+	case buf == nil:
+		return "", fmt.Errorf("buffer not open: %s", path)
+	case buf.Large():
+		return "", errLargeFile(buf)
 	}
-	return "", fmt.Errorf("buffer not open: %s", path)
+	return buf.Text(), nil
 }
 
 func (a *maneApp) WriteBuffer(path string, text string) error {
@@ -193,8 +203,12 @@ func (a *maneApp) WriteBuffer(path string, text string) error {
 	if buf == nil {
 		return fmt.Errorf("buffer not open: %s", path)
 	}
+	if buf.Large() {
+		return errLargeFile(buf)
+	}
 
-	buf.SetText(text)
+	buf.ApplyText("MCP Edit", text)
+	text = buf.Text()
 	if a.tabs.ActiveBuffer() == buf {
 		a.suppressChange = true
 		a.textArea.SetText(text)
@@ -223,6 +237,9 @@ func (a *maneApp) ApplyEdit(path string, startLine, startCol, endLine, endCol in
 	if buf == nil {
 		return fmt.Errorf("buffer not open: %s", path)
 	}
+	if buf.Large() {
+		return errLargeFile(buf)
+	}
 
 	text := buf.Text()
 	start := lspOffsetFromPosition(text, lsp.Position{Line: startLine, Character: startCol})
@@ -240,8 +257,8 @@ func (a *maneApp) ApplyEdit(path string, startLine, startCol, endLine, endCol in
 		end = len(text)
 	}
 
-	updated := text[:start] + newText + text[end:]
-	buf.SetText(updated)
+	buf.ApplyEdits("MCP Edit", []editor.TextEdit{{Start: start, End: end, Text: newText}})
+	updated := buf.Text()
 	if a.tabs.ActiveBuffer() == buf {
 		a.suppressChange = true
 		a.textArea.SetText(updated)
@@ -315,9 +332,12 @@ func (a *maneApp) SaveFile(path string) error {
 	if buf.Untitled() {
 		return fmt.Errorf("cannot save untitled buffer")
 	}
+	version := buf.Version()
 	if err := buf.Save(); err != nil {
 		return err
 	}
+	a.syncSaveFormatting(buf, version)
+	_ = a.tabs.SaveUndo(buf)
 	a.notifyLSPDidSave(buf)
 	a.syncTabBar()
 	a.updateStatus()
@@ -337,18 +357,31 @@ func (a *maneApp) GoToLine(line int) error {
 
 func (a *maneApp) GetCursorPosition() (line, col int) {
 	x, y := a.textArea.CursorPosition()
+	if a.activeLargeBuffer() != nil {
+		x, y = a.largeView.cursorPosition()
+	}
 	return y + 1, x + 1
 }
 
-func (a *maneApp) Search(query string) []mcptools.SearchResult {
+func (a *maneApp) Search(query string, opts mcptools.SearchOptions) ([]mcptools.SearchResult, error) {
 	buf := a.tabs.ActiveBuffer()
 	if buf == nil || query == "" {
-		return nil
+		return nil, nil
+	}
+	if buf.Large() {
+		return nil, errLargeFile(buf)
 	}
 
 	text := buf.Text()
 	lines := strings.Split(text, "\n")
-	matches := buf.Find(query)
+	matches, err := buf.FindWithOptions(query, editor.FindOptions{
+		Regex:      opts.Regex,
+		IgnoreCase: opts.IgnoreCase,
+		WholeWord:  opts.WholeWord,
+	})
+	if err != nil {
+		return nil, err
+	}
 	results := make([]mcptools.SearchResult, 0, len(matches))
 	for _, m := range matches {
 		pos := lspPositionFromByteOffset(text, m.Start)
@@ -369,7 +402,7 @@ func (a *maneApp) Search(query string) []mcptools.SearchResult {
 			Context: lineText,
 		})
 	}
-	return results
+	return results, nil
 }
 
 func (a *maneApp) SearchFiles(query string, root string) []mcptools.FileSearchResult {
@@ -379,7 +412,7 @@ func (a *maneApp) SearchFiles(query string, root string) []mcptools.FileSearchRe
 	if root == "" {
 		root = a.treeRoot
 	}
-	files, err := collectFinderFiles(root)
+	files, err := collectFinderFiles(root, a.settings.Global().FinderExclude)
 	if err != nil {
 		return nil
 	}
@@ -532,6 +565,9 @@ func formatNodeSExpr(node *gotreesitter.Node, lang *gotreesitter.Language, depth
 
 func (a *maneApp) sourceForPath(path string) ([]byte, error) {
 	if buf := a.findBufferByPath(path); buf != nil {
+		if buf.Large() {
+			return nil, errLargeFile(buf)
+		}
 		return []byte(buf.Text()), nil
 	}
 	data, err := os.ReadFile(path)
@@ -22,7 +22,7 @@ type EditorAccess interface {
 	GetCursorPosition() (line, col int)
 
 	// Search
-	Search(query string) []SearchResult
+	Search(query string, opts SearchOptions) ([]SearchResult, error)
 	SearchFiles(query string, root string) []FileSearchResult
 
 	// LSP
@@ -49,6 +49,13 @@ type SearchResult struct {
 	Context string `json:"context"`
 }
 
+// SearchOptions controls how Search matches its query.
+type SearchOptions struct {
+	Regex      bool `json:"regex,omitempty"`
+	IgnoreCase bool `json:"ignoreCase,omitempty"`
+	WholeWord  bool `json:"wholeWord,omitempty"`
+}
+
 // FileSearchResult represents a search match across files.
 type FileSearchResult struct {
 	Path string `json:"path"`
@@ -383,13 +390,25 @@ func (r *Registry) toolApplyEdit() ToolDef {
 func (r *Registry) toolSearch() ToolDef {
 	return ToolDef{
 		Name:        "mane_search",
-		Description: "Searches for a query string in the active buffer and returns all matches with their positions and surrounding context.",
+		Description: "Searches for a query string or regular expression in the active buffer and returns all matches with their positions and surrounding context.",
 		InputSchema: json.RawMessage(`{
 			"type": "object",
 			"properties": {
 				"query": {
 					"type": "string",
 					"description": "The search query string."
+				},
+				"regex": {
+					"type": "boolean",
+					"description": "Treat the query as an RE2 regular expression; ^ and $ match at line breaks. Defaults to false."
+				},
+				"ignoreCase": {
+					"type": "boolean",
+					"description": "Match regardless of letter case. Defaults to false."
+				},
+				"wholeWord": {
+					"type": "boolean",
+					"description": "Only match where the match is not part of a longer word. Defaults to false."
 				}
 			},
 			"required": ["query"]
@@ -397,6 +416,7 @@ func (r *Registry) toolSearch() ToolDef {
 		Handler: func(params json.RawMessage) (interface{}, error) {
 			var p struct {
 				Query string `json:"query"`
+				SearchOptions
 			}
 			if err := json.Unmarshal(params, &p); err != nil {
 				return nil, fmt.Errorf("invalid params: %w", err)
@@ -404,7 +424,10 @@ func (r *Registry) toolSearch() ToolDef {
 			if p.Query == "" {
 				return nil, fmt.Errorf("query is required")
 			}
-			results := r.editor.Search(p.Query)
+			results, err := r.editor.Search(p.Query, p.SearchOptions)
+			if err != nil {
+				return nil, fmt.Errorf("search failed: %w", err)
+			}
 			return map[string]interface{}{
 				"query":   p.Query,
 				"matches": results,
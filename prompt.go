@@ -0,0 +1,68 @@
+package main
+
+import (
+	"unicode/utf8"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/terminal"
+	"github.com/odvcencio/mane/editor"
+)
+
+// statusPrompt asks a question in the status bar.
+type statusPrompt struct {
+	label    string
+	kind     promptKind
+	input    string
+	onAnswer func(answer string)
+}
+
+// promptKind is what a status bar prompt reads.
+type promptKind int
+
+const (
+	promptRegister promptKind = iota // a register name, read from a single key
+	promptNumber                     // a number typed up to Enter
+	promptText                       // a line of text typed up to Enter
+)
+
+// ask shows a status bar prompt; handlePrompt reads the answer.
+func (a *maneApp) ask(label string, kind promptKind, onAnswer func(string)) {
+	a.prompt = &statusPrompt{label: label, kind: kind, onAnswer: onAnswer}
+	a.status.Set(label)
+}
+
+// handlePrompt passes key to the status bar prompt, reporting whether one
+// was shown. Escape cancels it; other keys it does not read are ignored.
+func (a *maneApp) handlePrompt(key runtime.KeyMsg) bool {
+	p := a.prompt
+	if p == nil {
+		return false
+	}
+	plain := key.Key == terminal.KeyRune && !key.Ctrl && !key.Alt
+	switch {
+	case key.Key == terminal.KeyEscape:
+		a.prompt = nil
+		a.status.Set(" Cancelled")
+		return true
+	case p.kind == promptRegister:
+		if !plain || !editor.ValidMacroRegister(string(key.Rune)) {
+			return true
+		}
+		a.prompt = nil
+		p.onAnswer(string(key.Rune))
+		return true
+	case key.Key == terminal.KeyEnter:
+		a.prompt = nil
+		p.onAnswer(p.input)
+		return true
+	case key.Key == terminal.KeyBackspace && p.input != "":
+		_, size := utf8.DecodeLastRuneInString(p.input)
+		p.input = p.input[:len(p.input)-size]
+	case p.kind == promptNumber && plain && key.Rune >= '0' && key.Rune <= '9':
+		p.input += string(key.Rune)
+	case p.kind == promptText && plain && key.Rune != 0:
+		p.input += string(key.Rune)
+	}
+	a.status.Set(p.label + p.input)
+	return true
+}
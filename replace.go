@@ -8,6 +8,7 @@ import (
 	"github.com/odvcencio/fluffyui/runtime"
 	"github.com/odvcencio/fluffyui/terminal"
 	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/editor"
 )
 
 // replaceField identifies which text field has focus in the replace widget.
@@ -20,7 +21,7 @@ const (
 
 // replaceWidget renders a two-row overlay at the bottom of the screen:
 //
-//	Row 1: "Find: " + search input + match counter (e.g., "1/3")
+//	Row 1: "Find: " + search input + option toggles + match counter (e.g., "1/3")
 //	Row 2: "Replace: " + replace input + [Replace] [All] buttons
 //
 // Keyboard handling:
@@ -28,6 +29,8 @@ const (
 //   - Arrow Up/Down navigates between search matches
 //   - Enter replaces the current match
 //   - Ctrl+Enter replaces all matches
+//   - Alt+R, Alt+C, Alt+W and Alt+P toggle regex, match case, whole word
+//     and preserve case
 //   - Escape closes the widget
 //   - Backspace deletes the last character in the focused field
 type replaceWidget struct {
@@ -41,6 +44,8 @@ type replaceWidget struct {
 	matchCount   int
 	currentMatch int
 
+	options *editor.FindOptions // shared with the search widget
+
 	// Callbacks
 	onSearch     func(query string)
 	onNext       func()
@@ -48,6 +53,7 @@ type replaceWidget struct {
 	onReplace    func(search, replace string)
 	onReplaceAll func(search, replace string)
 	onClose      func()
+	onToggle     func() // an option changed
 
 	// Styles
 	bgStyle     backend.Style
@@ -122,8 +128,12 @@ func (w *replaceWidget) Render(ctx runtime.RenderContext) {
 	buf.SetString(x, y0, findLabel, w.labelStyle)
 	x += len(findLabel)
 
-	// Determine max query display width (leave room for match counter).
+	// Determine max query display width (leave room for the option toggles
+	// and match counter).
 	counterReserve := 12 // enough for "999/999" + padding
+	if w.options != nil {
+		counterReserve += replaceFindOptionsWidth
+	}
 	maxQueryW := b.Width - len(findLabel) - counterReserve
 	if maxQueryW < 1 {
 		maxQueryW = 1
@@ -155,6 +165,9 @@ func (w *replaceWidget) Render(ctx runtime.RenderContext) {
 		counterX := b.X + b.Width - utf8.RuneCountInString(counter) - 1
 		buf.SetString(counterX, y0, counter, w.matchStyle)
 	}
+	if w.options != nil {
+		drawFindOptions(buf, b.X+b.Width-counterReserve, y0, *w.options, true, w.buttonStyle, w.labelStyle)
+	}
 
 	// ---- Row 2: "Replace: " + replace input + [Replace] [All] buttons ----
 	replaceLabel := "Replace: "
@@ -261,6 +274,12 @@ func (w *replaceWidget) handleKey(key runtime.KeyMsg) runtime.HandleResult {
 		return runtime.Handled()
 
 	case terminal.KeyRune:
+		if key.Alt && w.options != nil && toggleFindOption(w.options, key.Rune, true) {
+			if w.onToggle != nil {
+				w.onToggle()
+			}
+			return runtime.Handled()
+		}
 		if key.Ctrl {
 			// Ignore ctrl+letter combos (except let them bubble up).
 			return runtime.Unhandled()
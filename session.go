@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/odvcencio/mane/editor"
+)
+
+// restoreSessionTabs reopens the tabs of sess with the view each had. Files
+// deleted since are dropped. Returns the index of the tab that was active,
+// or -1.
+func (a *maneApp) restoreSessionTabs(sess *editor.Session) int {
+	active := -1
+	for i, tab := range sess.Tabs {
+		if _, err := os.Stat(tab.Path); err != nil {
+			continue
+		}
+		if err := a.openFile(tab.Path); err != nil {
+			continue
+		}
+		a.applyView(a.tabs.ActiveBuffer(), tab.ViewState)
+		if i == sess.Active {
+			active = a.tabs.Active()
+		}
+	}
+	return active
+}
+
+// restoreSessionLayout applies the window state of sess. It needs the
+// splitter and content slot.
+func (a *maneApp) restoreSessionLayout(sess *editor.Session) {
+	if sess.SidebarRatio > 0 && sess.SidebarRatio < 1 {
+		a.splitter.Ratio = sess.SidebarRatio
+	}
+	if sess.SidebarHidden == a.sidebarVisible {
+		a.toggleSidebar()
+	}
+	if sess.WordWrap != a.wordWrap {
+		a.wordWrap = sess.WordWrap
+		a.textArea.SetWordWrap(a.wordWrap)
+	}
+}
+
+// saveSession writes the open tabs and the window state to the session
+// store. Untitled buffers are left out; the backup store keeps them.
+func (a *maneApp) saveSession() {
+	if a.sessions == nil {
+		return
+	}
+	sess := &editor.Session{
+		Root:          a.treeRoot,
+		SidebarHidden: !a.sidebarVisible,
+		WordWrap:      a.wordWrap,
+	}
+	if a.splitter != nil {
+		sess.SidebarRatio = a.splitter.Ratio
+	}
+	for i, buf := range a.tabs.Buffers() {
+		if buf.Untitled() {
+			continue
+		}
+		if i == a.tabs.Active() {
+			sess.Active = len(sess.Tabs)
+		}
+		tab := editor.SessionTab{Path: buf.Path()}
+		if buf == a.shown {
+			tab.ViewState = a.captureView(buf)
+		} else {
+			tab.ViewState, _ = a.tabs.View(buf)
+		}
+		sess.Tabs = append(sess.Tabs, tab)
+	}
+	if err := a.sessions.Save(a.sessionName, sess); err != nil {
+		fmt.Fprintf(os.Stderr, "mane: saving session: %v\n", err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/mane/editor"
+	"github.com/odvcencio/mane/filewatch"
+)
+
+// loadSettings reads the user and project settings files. On error the
+// settings in effect are kept.
+func (a *maneApp) loadSettings() error {
+	settings, err := editor.LoadSettings(editor.SettingsPaths(a.treeRoot)...)
+	if err != nil {
+		return err
+	}
+	a.settings = settings
+	return nil
+}
+
+// settingsFor returns the settings for buf's language.
+func (a *maneApp) settingsFor(buf *editor.Buffer) editor.Settings {
+	if buf == nil {
+		return a.settings.Global()
+	}
+	return a.settings.For(languageIDFromPath(buf.Path()))
+}
+
+// applySettings puts the global settings into effect. Only the ones that
+// differ from prev are applied, so a reload does not undo the word wrap or
+// sidebar width the user changed since; prev is nil at startup.
+func (a *maneApp) applySettings(prev *editor.Settings) {
+	s := a.settings.Global()
+	a.highlight.debounceMs = s.HighlightDebounceMs
+	if prev == nil || s.WordWrap != prev.WordWrap {
+		a.wordWrap = s.WordWrap
+		a.textArea.SetWordWrap(a.wordWrap)
+	}
+	if a.splitter != nil && (prev == nil || s.SidebarRatio != prev.SidebarRatio) {
+		a.splitter.Ratio = s.SidebarRatio
+	}
+	if prev != nil && !slices.Equal(s.FinderExclude, prev.FinderExclude) {
+		a.finderRoot = "" // collect the files again on the next open
+	}
+	if prev == nil || s.Keymap != prev.Keymap {
+		a.setVimMode(s.Keymap == editor.KeymapVim)
+		a.resetEmacs()
+	}
+	if prev != nil && s.Keymap != prev.Keymap {
+		a.reloadKeymap()
+	}
+	a.applyIndentation(a.shown)
+}
+
+// reloadSettings reads the settings files again after one of them changed.
+func (a *maneApp) reloadSettings() {
+	prev := a.settings.Global()
+	if err := a.loadSettings(); err != nil {
+		a.status.Set(fmt.Sprintf(" Settings error: %v", err))
+		return
+	}
+	a.applySettings(&prev)
+	a.updateStatus()
+}
+
+// startConfigWatcher reloads the settings or the keybindings on the UI
+// loop of rt whenever one of their files is created, changed or removed.
+func (a *maneApp) startConfigWatcher(ctx context.Context, rt *runtime.App) {
+	a.configWatcher = filewatch.New(0)
+	for _, path := range editor.SettingsPaths(a.treeRoot) {
+		_ = a.configWatcher.Add(path)
+	}
+	keybindings, err := editor.UserConfigPath(keybindingsFileName)
+	if err == nil {
+		keybindings, _ = filepath.Abs(keybindings)
+		_ = a.configWatcher.Add(keybindings)
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				_ = a.configWatcher.Close()
+				return
+			case path := <-a.configWatcher.Events():
+				_ = rt.Call(ctx, func(*runtime.App) error {
+					if path == keybindings {
+						a.reloadKeymap()
+					} else {
+						a.reloadSettings()
+					}
+					return nil
+				})
+			}
+		}
+	}()
+}
+
+// cmdOpenSettings opens the user settings file in a tab. Saving it applies
+// the settings.
+func (a *maneApp) cmdOpenSettings() {
+	path, err := editor.UserSettingsPath()
+	if err == nil {
+		err = a.openConfigFile(path, "{\n}\n")
+	}
+	if err != nil {
+		a.status.Set(fmt.Sprintf(" Could not open the settings: %v", err))
+	}
+}
+
+// cmdOpenKeybindings opens the user keybindings file in a tab. Saving it
+// applies the bindings.
+func (a *maneApp) cmdOpenKeybindings() {
+	path, err := editor.UserConfigPath(keybindingsFileName)
+	if err == nil {
+		err = a.openConfigFile(path, "[\n]\n")
+	}
+	if err != nil {
+		a.status.Set(fmt.Sprintf(" Could not open the keybindings: %v", err))
+	}
+}
+
+// openConfigFile opens the config file at path, creating it with initial
+// content first if there is none.
+func (a *maneApp) openConfigFile(path, initial string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(initial), 0o644); err != nil {
+			return err
+		}
+	}
+	return a.openFile(path)
+}
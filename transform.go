@@ -0,0 +1,179 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/editor"
+)
+
+// selectedLineRange returns the first and last lines the selection covers,
+// reporting false if nothing is selected. A selection ending at the start of
+// a line does not cover that line.
+func (a *maneApp) selectedLineRange() (first, last int, ok bool) {
+	sel := a.textArea.GetSelection()
+	if sel.IsEmpty() {
+		return 0, 0, false
+	}
+	runes := []rune(a.textArea.Text())
+	start := clampRuneOffset(min(sel.Start, sel.End), len(runes))
+	end := clampRuneOffset(max(sel.Start, sel.End), len(runes))
+	first = strings.Count(string(runes[:start]), "\n")
+	last = strings.Count(string(runes[:end]), "\n")
+	if last > first && runes[end-1] == '\n' {
+		last-- // the selection ends at the start of the line after
+	}
+	return first, last, true
+}
+
+// transformLines replaces the lines the selection covers with what fn makes
+// of them, as one undo step labelled label, and selects the result. Without
+// a selection fn gets every line if whole is set, but the empty one after a
+// final line break, or else the cursor's line and the extra lines after it.
+func (a *maneApp) transformLines(label string, whole bool, extra int, fn func([]string) []string) {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil {
+		return
+	}
+	lines := strings.Split(buf.Text(), "\n")
+	first, last, selected := a.selectedLineRange()
+	switch {
+	case selected:
+	case whole:
+		first, last = 0, len(lines)-1
+		if last > 0 && lines[last] == "" {
+			last--
+		}
+	default:
+		_, first = a.textArea.CursorPosition()
+		last = min(first+extra, len(lines)-1)
+	}
+	out := fn(slices.Clone(lines[first : last+1]))
+	lines = slices.Concat(lines[:first], out, lines[last+1:])
+	start := 0
+	for _, l := range lines[:first] {
+		start += utf8.RuneCountInString(l) + 1
+	}
+	end := start + utf8.RuneCountInString(strings.Join(out, "\n"))
+	if !a.replaceText(label, strings.Join(lines, "\n"), end) {
+		a.status.Set(" No change")
+		return
+	}
+	if selected {
+		a.selectRange(start, end)
+	}
+}
+
+// transformSelection replaces the selection, or the word at the cursor
+// without one, with what fn makes of it, as one undo step labelled label,
+// and selects the result.
+func (a *maneApp) transformSelection(label string, fn func(string) string) {
+	runes := []rune(a.textArea.Text())
+	sel := a.textArea.GetSelection()
+	start := clampRuneOffset(min(sel.Start, sel.End), len(runes))
+	end := clampRuneOffset(max(sel.Start, sel.End), len(runes))
+	if sel.IsEmpty() {
+		start, end = editor.WordAt(runes, a.textArea.CursorOffset())
+	}
+	if start == end {
+		a.status.Set(" Nothing selected")
+		return
+	}
+	out := fn(string(runes[start:end]))
+	end2 := start + utf8.RuneCountInString(out)
+	if !a.replaceText(label, string(runes[:start])+out+string(runes[end:]), end2) {
+		a.status.Set(" No change")
+		return
+	}
+	a.selectRange(start, end2)
+}
+
+// selectRange selects the runes from start to end, with the cursor at end.
+func (a *maneApp) selectRange(start, end int) {
+	a.textArea.SetCursorOffset(end)
+	a.textArea.SetSelection(widgets.Selection{Start: start, End: end})
+	a.syncMultiCursorFromTextArea()
+}
+
+// cmdSortLines sorts the selected lines, or all of them.
+func (a *maneApp) cmdSortLines(order editor.SortOrder, reverse bool) {
+	a.transformLines("Sort Lines", true, 0, func(lines []string) []string {
+		return editor.SortLines(lines, order, reverse)
+	})
+}
+
+// cmdSplitLines asks for a delimiter and splits the selected lines, or the
+// cursor's line, at each one.
+func (a *maneApp) cmdSplitLines() {
+	a.ask(" Split lines on: ", promptText, func(sep string) {
+		if sep == "" {
+			a.status.Set(" No delimiter")
+			return
+		}
+		a.transformLines("Split Lines", false, 0, func(lines []string) []string {
+			var out []string
+			for _, l := range lines {
+				out = append(out, editor.SplitLine(l, sep)...)
+			}
+			return out
+		})
+	})
+}
+
+// cmdTransposeChars swaps the characters around the cursor.
+func (a *maneApp) cmdTransposeChars() {
+	a.transposeAtCursor(editor.TransposeChars)
+}
+
+// cmdTransposeWords swaps the word at the cursor with the one before it.
+func (a *maneApp) cmdTransposeWords() {
+	a.transposeAtCursor(editor.TransposeWords)
+}
+
+func (a *maneApp) transposeAtCursor(transpose func([]rune, int) ([]rune, int, bool)) {
+	out, off, ok := transpose([]rune(a.textArea.Text()), a.textArea.CursorOffset())
+	if !ok {
+		a.status.Set(" Nothing to transpose")
+		return
+	}
+	a.replaceText("Transpose", string(out), off)
+}
+
+// cmdTransposeLines swaps the cursor's line with the one above and moves
+// to the start of the next line, as Emacs's C-x C-t does.
+func (a *maneApp) cmdTransposeLines() {
+	buf := a.tabs.ActiveBuffer()
+	if buf == nil {
+		return
+	}
+	_, row := a.textArea.CursorPosition()
+	if row == 0 {
+		a.status.Set(" Nothing to transpose")
+		return
+	}
+	text := editor.MoveLine(buf.Text(), row, -1)
+	lines := strings.Split(text, "\n")
+	off := 0
+	for _, l := range lines[:row+1] {
+		off += utf8.RuneCountInString(l) + 1
+	}
+	a.replaceText("Transpose", text, off)
+}
+
+// shuffleLines shuffles lines in a random order.
+func shuffleLines(lines []string) []string {
+	return editor.ShuffleLines(lines, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+}
+
+// joinLines joins lines into one.
+func joinLines(lines []string) []string {
+	return []string{editor.JoinLines(lines)}
+}
+
+// convertCase returns a function converting text to style.
+func convertCase(style editor.CaseStyle) func(string) string {
+	return func(text string) string { return editor.ConvertCase(text, style) }
+}
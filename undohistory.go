@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/odvcencio/fluffyui/backend"
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/terminal"
+	"github.com/odvcencio/fluffyui/widgets"
+	"github.com/odvcencio/mane/editor"
+)
+
+// undoHistoryPreviewLines is the height of the diff preview below the list.
+const undoHistoryPreviewLines = 12
+
+// undoHistoryContext is the number of unchanged lines kept around each change
+// in the preview.
+const undoHistoryContext = 2
+
+// undoHistoryWidget lists the states of the active buffer's undo tree and
+// previews the diff from the current text to the selected state.
+type undoHistoryWidget struct {
+	*widgets.CommandPalette
+
+	states   []editor.UndoState
+	preview  func(id int) []editor.DiffLine
+	onJump   func(id int)
+	onClose  func()
+	shownFor int // state ID the cached diff was computed for
+	diff     []editor.DiffLine
+	area     runtime.Rect
+
+	borderStyle backend.Style
+	textStyle   backend.Style
+	insertStyle backend.Style
+	deleteStyle backend.Style
+}
+
+func newUndoHistoryWidget() *undoHistoryWidget {
+	w := &undoHistoryWidget{
+		CommandPalette: widgets.NewCommandPalette(),
+		shownFor:       -1,
+		borderStyle:    backend.DefaultStyle().Foreground(backend.ColorCyan),
+		textStyle:      backend.DefaultStyle(),
+		insertStyle:    backend.DefaultStyle().Foreground(backend.ColorGreen),
+		deleteStyle:    backend.DefaultStyle().Foreground(backend.ColorRed),
+	}
+	w.SetOnExecute(func(cmd widgets.PaletteCommand) {
+		id, err := strconv.Atoi(cmd.ID)
+		if err == nil && w.onJump != nil {
+			w.onJump(id)
+		}
+	})
+	return w
+}
+
+// SetStates replaces the listed states. The newest state is listed first.
+func (w *undoHistoryWidget) SetStates(states []editor.UndoState, now time.Time) {
+	w.states = make([]editor.UndoState, 0, len(states))
+	items := make([]widgets.PaletteCommand, 0, len(states))
+	for i := len(states) - 1; i >= 0; i-- {
+		s := states[i]
+		w.states = append(w.states, s)
+
+		label := s.Label
+		if s.ID == 0 {
+			label = "Original"
+		} else if label == "" {
+			label = "Edit"
+		}
+		desc := ""
+		switch {
+		case s.Current:
+			desc = "(current)"
+		case s.Tip:
+			desc = "(branch tip)"
+		}
+		items = append(items, widgets.PaletteCommand{
+			ID:          strconv.Itoa(s.ID),
+			Label:       fmt.Sprintf("#%d %s", s.ID, label),
+			Description: desc,
+			Shortcut:    formatAge(now.Sub(s.Time)),
+		})
+	}
+	w.SetCommands(items)
+	w.shownFor = -1
+}
+
+// formatAge renders a duration as a short "ago" string.
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+func (w *undoHistoryWidget) selectedState() (editor.UndoState, bool) {
+	idx := w.SelectedIndex()
+	if idx < 0 || idx >= len(w.states) {
+		return editor.UndoState{}, false
+	}
+	return w.states[idx], true
+}
+
+// Measure reserves room for the list and the preview below it.
+func (w *undoHistoryWidget) Measure(constraints runtime.Constraints) runtime.Size {
+	return runtime.Size{Width: constraints.MaxWidth, Height: constraints.MaxHeight}
+}
+
+// Layout places the state list at the top and the preview beneath it.
+func (w *undoHistoryWidget) Layout(bounds runtime.Rect) {
+	listHeight := 4 + min(len(w.states), 10)
+	listHeight = min(listHeight, bounds.Height)
+	w.CommandPalette.Layout(runtime.Rect{X: bounds.X, Y: bounds.Y, Width: bounds.Width, Height: listHeight})
+	previewHeight := min(undoHistoryPreviewLines+2, bounds.Height-listHeight)
+	w.area = runtime.Rect{X: bounds.X, Y: bounds.Y + listHeight, Width: bounds.Width, Height: max(previewHeight, 0)}
+}
+
+// Render draws the list and a diff preview of the selected state.
+func (w *undoHistoryWidget) Render(ctx runtime.RenderContext) {
+	if w == nil || !w.Open() {
+		return
+	}
+	w.CommandPalette.Render(ctx)
+
+	b := w.area
+	if b.Width < 4 || b.Height < 3 {
+		return
+	}
+	if s, ok := w.selectedState(); ok && s.ID != w.shownFor {
+		w.shownFor = s.ID
+		w.diff = nil
+		if w.preview != nil {
+			w.diff = trimDiffContext(w.preview(s.ID), undoHistoryContext)
+		}
+	}
+
+	ctx.Buffer.Fill(b, ' ', w.textStyle)
+	title := "╭─ Preview "
+	ctx.Buffer.SetString(b.X, b.Y, title+strings.Repeat("─", max(b.Width-len([]rune(title))-1, 0))+"╮", w.borderStyle)
+	bottom := b.Y + b.Height - 1
+	ctx.Buffer.SetString(b.X, bottom, "╰"+strings.Repeat("─", b.Width-2)+"╯", w.borderStyle)
+
+	row := b.Y + 1
+	if len(w.diff) == 0 {
+		ctx.Buffer.Set(b.X, row, '│', w.borderStyle)
+		ctx.Buffer.SetString(b.X+2, row, "no changes", w.textStyle)
+		ctx.Buffer.Set(b.X+b.Width-1, row, '│', w.borderStyle)
+		row++
+	}
+	for _, line := range w.diff {
+		if row >= bottom {
+			break
+		}
+		prefix, style := "  ", w.textStyle
+		switch line.Kind {
+		case editor.DiffInsert:
+			prefix, style = "+ ", w.insertStyle
+		case editor.DiffDelete:
+			prefix, style = "- ", w.deleteStyle
+		}
+		text := []rune(prefix + line.Text)
+		if len(text) > b.Width-4 {
+			text = text[:max(b.Width-4, 0)]
+		}
+		ctx.Buffer.Set(b.X, row, '│', w.borderStyle)
+		ctx.Buffer.SetString(b.X+2, row, string(text), style)
+		ctx.Buffer.Set(b.X+b.Width-1, row, '│', w.borderStyle)
+		row++
+	}
+	for ; row < bottom; row++ {
+		ctx.Buffer.Set(b.X, row, '│', w.borderStyle)
+		ctx.Buffer.Set(b.X+b.Width-1, row, '│', w.borderStyle)
+	}
+}
+
+// HandleMessage navigates the list; typing does not filter, so the
+// selection always maps onto the listed states.
+func (w *undoHistoryWidget) HandleMessage(msg runtime.Message) runtime.HandleResult {
+	if w == nil || w.CommandPalette == nil || !w.Open() {
+		return runtime.Unhandled()
+	}
+	if key, ok := msg.(runtime.KeyMsg); ok {
+		switch key.Key {
+		case terminal.KeyEscape:
+			w.Hide()
+			if w.onClose != nil {
+				w.onClose()
+			}
+			return runtime.Handled()
+		case terminal.KeyRune, terminal.KeyBackspace:
+			return runtime.Handled()
+		}
+	}
+	return w.CommandPalette.HandleMessage(msg)
+}
+
+// trimDiffContext drops unchanged lines further than context lines away
+// from a change, marking each gap with an ellipsis line.
+func trimDiffContext(lines []editor.DiffLine, context int) []editor.DiffLine {
+	keep := make([]bool, len(lines))
+	for i, l := range lines {
+		if l.Kind == editor.DiffEqual {
+			continue
+		}
+		for j := max(i-context, 0); j <= min(i+context, len(lines)-1); j++ {
+			keep[j] = true
+		}
+	}
+	var out []editor.DiffLine
+	gap := false
+	for i, l := range lines {
+		if !keep[i] {
+			gap = true
+			continue
+		}
+		if gap && len(out) > 0 {
+			out = append(out, editor.DiffLine{Kind: editor.DiffEqual, Text: "…"})
+		}
+		gap = false
+		out = append(out, l)
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"unicode/utf8"
+
+	"github.com/odvcencio/mane/editor"
+)
+
+// leaveView is called before the editor widgets switch to next. If another
+// buffer was on screen, its view is recorded so it comes back when its tab
+// is shown again, and its folds, any pending Vim command and the Emacs mark
+// are cleared so they do not carry over. The Tab key is set up for the next
+// view's indentation. enterView completes the switch.
+func (a *maneApp) leaveView(next *editor.Buffer) {
+	if a.shown == next {
+		return
+	}
+	if a.shown != nil {
+		a.tabs.SetView(a.shown, a.captureView(a.shown))
+	}
+	a.foldState.SetRegions(nil)
+	a.pane.setScrollLine(-1)
+	if a.vim != nil {
+		a.vim.Reset()
+	}
+	a.resetEmacs()
+	a.shown = next
+	a.viewPending = true
+	a.applyIndentation(next)
+}
+
+// enterView puts back the recorded view of the buffer on screen once its
+// text and fold regions are loaded.
+func (a *maneApp) enterView() {
+	if !a.viewPending {
+		return
+	}
+	a.viewPending = false
+	if a.shown == nil {
+		return
+	}
+	if view, ok := a.tabs.View(a.shown); ok {
+		a.applyView(a.shown, view)
+	}
+}
+
+// captureView returns the view of buf, which must be on screen.
+func (a *maneApp) captureView(buf *editor.Buffer) editor.ViewState {
+	if buf.Large() {
+		off := a.largeView.cursorOffset()
+		return editor.ViewState{
+			Cursors: []editor.Cursor{{Offset: off, Anchor: off}},
+			Scroll:  a.largeView.top,
+		}
+	}
+	view := editor.ViewState{
+		Folds:  a.foldState.Folded(),
+		Scroll: a.pane.scrollLine(),
+	}
+	if a.isMultiCursorMode() {
+		view.Cursors = a.multiCursor.Cursors()
+		return view
+	}
+	cursor := a.textArea.CursorOffset()
+	anchor := cursor
+	if sel := a.textArea.GetSelection(); !sel.IsEmpty() {
+		anchor = sel.Start
+		if anchor == cursor {
+			anchor = sel.End
+		}
+	}
+	view.Cursors = []editor.Cursor{{Offset: cursor, Anchor: anchor}}
+	return view
+}
+
+// applyView shows buf, which must be on screen, as described by view.
+// Offsets past the end of the text, which changed since, are clamped.
+func (a *maneApp) applyView(buf *editor.Buffer, view editor.ViewState) {
+	if buf.Large() {
+		a.largeView.setCursorOffset(min(max(view.Primary().Offset, 0), buf.Len()))
+		a.largeView.top = max(view.Scroll, 0)
+		return
+	}
+	a.foldState.SetFolded(view.Folds)
+	a.applyFoldVisibility()
+
+	textLen := utf8.RuneCountInString(a.textArea.Text())
+	cursors := make([]editor.Cursor, len(view.Cursors))
+	for i, c := range view.Cursors {
+		cursors[i] = editor.Cursor{
+			Offset: clampRuneOffset(c.Offset, textLen),
+			Anchor: clampRuneOffset(c.Anchor, textLen),
+		}
+	}
+	a.multiCursor.SetCursors(cursors)
+	a.syncTextAreaFromMultiCursor()
+	a.mergeAllHighlights()
+	a.pane.setScrollLine(max(view.Scroll, 0))
+}
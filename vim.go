@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/odvcencio/fluffyui/runtime"
+	"github.com/odvcencio/fluffyui/terminal"
+	"github.com/odvcencio/mane/editor"
+	"github.com/odvcencio/mane/keymap"
+)
+
+// setVimMode turns Vim modal editing on or off.
+func (a *maneApp) setVimMode(on bool) {
+	switch {
+	case on && a.vim == nil:
+		a.vim = editor.NewVim(vimHost{a})
+	case !on && a.vim != nil:
+		a.vim = nil
+		a.clearBlockSelection()
+		a.resetMultiCursor()
+	}
+}
+
+// handleVimKey passes key to Vim when it is on and the editor has focus,
+// reporting whether Vim used it.
+func (a *maneApp) handleVimKey(key runtime.KeyMsg) bool {
+	if a.vim == nil || !a.textArea.IsFocused() || a.activeLargeBuffer() != nil {
+		return false
+	}
+	name := vimKey(key)
+	if name == "" || !a.vim.Key(name) {
+		return false
+	}
+	a.updateStatus()
+	if msg := a.vim.TakeMessage(); msg != "" {
+		a.status.Set(" " + msg)
+	}
+	return true
+}
+
+// vimKeyNames are the Vim names of the special keys Vim handles.
+var vimKeyNames = map[terminal.Key]string{
+	terminal.KeyEscape:    "<Esc>",
+	terminal.KeyEnter:     "<CR>",
+	terminal.KeyBackspace: "<BS>",
+	terminal.KeyDelete:    "<Del>",
+	terminal.KeyTab:       "<Tab>",
+	terminal.KeyUp:        "<Up>",
+	terminal.KeyDown:      "<Down>",
+	terminal.KeyLeft:      "<Left>",
+	terminal.KeyRight:     "<Right>",
+	terminal.KeyHome:      "<Home>",
+	terminal.KeyEnd:       "<End>",
+}
+
+// vimKey returns the Vim name of key, such as "x", "<C-r>" or "<Esc>", or
+// "" for keys Vim leaves to the keybindings.
+func vimKey(key runtime.KeyMsg) string {
+	if key.Alt {
+		return ""
+	}
+	if name, ok := vimKeyNames[key.Key]; ok && !key.Ctrl {
+		return name
+	}
+	chord := keymap.FromKey(key)
+	switch {
+	case chord.Key != terminal.KeyRune || chord.Alt:
+		return ""
+	case chord.Ctrl:
+		if chord.Rune < 'a' || chord.Rune > 'z' || chord.Shift {
+			return ""
+		}
+		return "<C-" + string(chord.Rune) + ">"
+	case key.Rune != 0:
+		return string(key.Rune)
+	}
+	return ""
+}
+
+// vimHost lets Vim edit the active buffer through the editor widgets.
+type vimHost struct{ a *maneApp }
+
+func (h vimHost) Text() string { return h.a.textArea.Text() }
+
+func (h vimHost) Cursor() int { return h.a.textArea.CursorOffset() }
+
+func (h vimHost) SetCursor(offset int) {
+	h.a.clearBlockSelection()
+	h.a.textArea.SelectNone()
+	h.a.textArea.SetCursorOffset(offset)
+	h.a.syncMultiCursorFromTextArea()
+	h.a.updateBracketMatch()
+	h.a.mergeAllHighlights()
+}
+
+func (h vimHost) Edit(label, text string, offset int) {
+	h.a.replaceText(label, text, offset)
+}
+
+func (h vimHost) Undo() { h.a.cmdUndo() }
+
+func (h vimHost) Redo() { h.a.cmdRedo() }
+
+func (h vimHost) Select(anchor, offset int) {
+	h.a.clearBlockSelection()
+	h.a.syncMultiCursorFromTextArea()
+	h.a.multiCursor.SetPrimary(offset, anchor)
+	h.a.syncTextAreaFromMultiCursor()
+	h.a.mergeAllHighlights()
+}
+
+func (h vimHost) SelectBlock(bs *editor.BlockSelection) {
+	if bs == nil {
+		h.a.clearBlockSelection()
+		return
+	}
+	if h.a.blockSelection == nil {
+		h.a.blockSelection = &editor.BlockSelection{}
+	}
+	*h.a.blockSelection = *bs
+	h.a.blockSelection.Active = true
+	h.a.textArea.SelectNone()
+	h.a.syncMultiCursorFromTextArea()
+	h.a.syncBlockHighlights()
+	h.a.mergeAllHighlights()
+}
+
+func (h vimHost) InsertAt(offsets []int) {
+	cursors := make([]editor.Cursor, len(offsets))
+	for i, off := range offsets {
+		cursors[i] = editor.Cursor{Offset: off, Anchor: off}
+	}
+	h.a.clearBlockSelection()
+	h.a.syncMultiCursorFromTextArea()
+	h.a.multiCursor.SetCursors(cursors)
+	h.a.syncTextAreaFromMultiCursor()
+	h.a.mergeAllHighlights()
+}
+
+func (h vimHost) IndentUnit() string {
+	if buf := h.a.tabs.ActiveBuffer(); buf != nil {
+		return h.a.indentUnit(buf)
+	}
+	return "\t"
+}
+
+func (h vimHost) TabSize() int {
+	return h.a.tabSize(h.a.tabs.ActiveBuffer())
+}
+
+// Errors of the Vim ex commands.
+var (
+	errVimNoWrite  = errors.New("No write since last change (add ! to override)")
+	errVimFileName = errors.New("Writing to another file is not supported")
+)
+
+// Ex runs :w, :q and their combinations. :q closes the active tab, or
+// quits on the last one; :wa and :qa work on every buffer.
+func (h vimHost) Ex(command string, bang bool, arg string) error {
+	a := h.a
+	if arg != "" {
+		return errVimFileName
+	}
+	buf := a.tabs.ActiveBuffer()
+	switch command {
+	case "w":
+		return a.saveBuffer(buf)
+	case "wq":
+		if buf != nil && buf.Dirty() {
+			if err := a.saveBuffer(buf); err != nil {
+				return err
+			}
+		}
+		return h.Ex("q", bang, "")
+	case "q":
+		if buf != nil && buf.Dirty() && !bang {
+			return errVimNoWrite
+		}
+		if a.tabs.Count() > 1 {
+			a.cmdCloseTab()
+			return nil
+		}
+		if bang {
+			a.cmdQuitDiscard()
+		} else {
+			a.cmdQuit()
+		}
+	case "wa", "wqa":
+		for _, b := range a.tabs.Buffers() {
+			if b.Dirty() && !b.Untitled() {
+				if err := a.saveBuffer(b); err != nil {
+					return err
+				}
+			}
+		}
+		if command == "wqa" {
+			return h.Ex("qa", bang, "")
+		}
+	case "qa":
+		for _, b := range a.tabs.Buffers() {
+			if b.Dirty() && !bang {
+				return errVimNoWrite
+			}
+		}
+		if bang {
+			a.cmdQuitDiscard()
+		} else {
+			a.cmdQuit()
+		}
+	}
+	return nil
+}